@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/cache"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/db"
+)
+
+// TestCreatePostWithBearerToken tests that a token from /api/login creates
+// posts like the Basic Auth credentials it was issued for
+func TestCreatePostWithBearerToken(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer mockDB.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO posts").
+		WithArgs(sqlmock.AnyArg(), "user_1", "Hello with a token", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mux := http.NewServeMux()
+	setupRoutes(mux, db.NewPostRepository(db.NewPostgresDB(mockDB)), cache.NewPostCache(cache.NewRedisStub()))
+	defer waitForCacheWrites()
+
+	// Log in with the default credentials
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"username": "admin", "password": "password"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected login status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &login); err != nil || login.Token == "" {
+		t.Fatalf("Expected a token in the login response, got %s", rr.Body.String())
+	}
+
+	// A forged token is rejected
+	req := httptest.NewRequest(http.MethodPost, "/api/posts", strings.NewReader(`{"content": "Hello with a token"}`))
+	req.Header.Set("Authorization", "Bearer "+login.Token+"x")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d for a forged token, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	// The issued token creates a post as the admin
+	req = httptest.NewRequest(http.MethodPost, "/api/posts", strings.NewReader(`{"content": "Hello with a token"}`))
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -1,24 +1,40 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/JoobyPM/tiger-tail-microblog/internal/cache"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/db"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/metrics"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/requestid"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/server"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/service"
 	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // initApp initializes the application components
 func initApp() (string, error) {
+	// Never run a production deployment with the well-known admin password
+	if err := checkDefaultCredentials(); err != nil {
+		return "", err
+	}
+
 	// Read environment variables
 	dbHost := getEnv("DB_HOST", "localhost")
 	dbPort := getEnv("DB_PORT", "5432")
@@ -42,11 +58,16 @@ func initApp() (string, error) {
 	// Construct Redis address from individual environment variables
 	redisAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
 	
+	// Sentinel and cluster deployments list their addresses in REDIS_ADDRS
+	redisMode := getEnv("REDIS_MODE", cache.RedisModeStandalone)
+	redisAddrs := strings.Split(getEnv("REDIS_ADDRS", redisAddr), ",")
+	redisMasterName := getEnv("REDIS_MASTER_NAME", "mymaster")
+	
 	// Get server port
 	port := getEnv("SERVER_PORT", getEnv("PORT", "8080"))
 
 	// Log the connection details
-	log.Printf("Connecting to PostgreSQL with DSN: %s", dbDSN)
+	log.Printf("Connecting to PostgreSQL on host %s", db.DSNHost(dbDSN))
 	log.Printf("Connecting to Redis at %s (DB: %d)", redisAddr, redisDB)
 	
 	// Check if we should use real database
@@ -62,7 +83,7 @@ func initApp() (string, error) {
 			return "", fmt.Errorf("failed to connect to database: %w", err)
 		}
 	} else {
-		log.Printf("Stub: Would connect to PostgreSQL with DSN: %s", dbDSN)
+		log.Printf("Stub: Would connect to PostgreSQL on host %s", db.DSNHost(dbDSN))
 		// Create a stub implementation
 		postgres = db.NewPostgresStub()
 	}
@@ -73,7 +94,13 @@ func initApp() (string, error) {
 	
 	if useRealRedis {
 		// Initialize Redis connection
-		redisClient, err = cache.NewRedisClient(redisAddr, redisPassword, redisDB)
+		redisClient, err = cache.NewRedisClientWithOptions(cache.RedisOptions{
+			Mode:       redisMode,
+			Addrs:      redisAddrs,
+			MasterName: redisMasterName,
+			Password:   redisPassword,
+			DB:         redisDB,
+		})
 		if err != nil {
 			log.Printf("Error: Failed to connect to Redis: %v", err)
 			return "", fmt.Errorf("failed to connect to Redis: %w", err)
@@ -89,17 +116,130 @@ func initApp() (string, error) {
 	
 	// Create cache
 	postCache := cache.NewPostCache(redisClient)
+
+	// Start the retention job if a retention period is configured
+	if retentionJob != nil {
+		retentionJob.Stop()
+	}
+	retentionJob = startRetentionJob(postRepo, postCache)
+
+	// Keep idle connections to the real backends alive
+	var keepaliveTargets []service.KeepaliveTarget
+	if useRealDB {
+		keepaliveTargets = append(keepaliveTargets, service.KeepaliveTarget{Name: "PostgreSQL", Pinger: postgres})
+	}
+	if useRealRedis {
+		keepaliveTargets = append(keepaliveTargets, service.KeepaliveTarget{Name: "Redis", Pinger: redisClient})
+	}
+	if keepaliveJob != nil {
+		keepaliveJob.Stop()
+	}
+	keepaliveJob = startKeepaliveJob(keepaliveTargets)
 	
 	// Setup routes with real implementations
-	setupRoutes(postRepo, postCache)
+	setupRoutes(http.DefaultServeMux, postRepo, postCache)
 
 	return port, nil
 }
 
-// setupRoutes sets up the HTTP routes
-func setupRoutes(postRepo *db.PostRepository, postCache *cache.PostCache) {
+// retentionJob purges old posts in the background, nil when retention is disabled
+var retentionJob *service.RetentionJob
+
+// startRetentionJob starts the post retention job when POST_RETENTION_DAYS is
+// set to a positive number of days
+func startRetentionJob(purger service.PostPurger, invalidator service.PostInvalidator) *service.RetentionJob {
+	days := config.GetEnvInt("POST_RETENTION_DAYS", 0)
+	if days <= 0 {
+		return nil
+	}
+
+	interval := config.GetEnvMillis("POST_RETENTION_INTERVAL_MS", time.Hour)
+	job := service.NewRetentionJob(purger, invalidator, time.Duration(days)*24*time.Hour, interval)
+	job.Start()
+	log.Printf("Purging posts older than %d days every %s", days, interval)
+	return job
+}
+
+// keepaliveJob pings the backends in the background, nil when disabled
+var keepaliveJob *service.KeepaliveJob
+
+// cacheWrites tracks the cache writes handlers leave running after they
+// respond, so shutdown can wait for them
+var cacheWrites = &server.AsyncCacheWrites{}
+
+// metricsRegistry holds the metrics served at /metrics, recorded into
+// appMetrics
+var (
+	metricsRegistry = prometheus.NewRegistry()
+	appMetrics      = metrics.New(metricsRegistry)
+)
+
+// defaultShutdownTimeout bounds how long shutdown waits for in-flight
+// requests and for cache writes
+const defaultShutdownTimeout = 10 * time.Second
+
+// waitForCacheWrites waits up to SHUTDOWN_TIMEOUT (default 10s) for pending
+// cache writes to finish
+func waitForCacheWrites() {
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetEnvDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout))
+	defer cancel()
+	if err := cacheWrites.Wait(ctx); err != nil {
+		log.Printf("Shutting down with cache writes still pending: %v", err)
+	}
+}
+
+// startKeepaliveJob starts pinging targets every CONN_KEEPALIVE_INTERVAL
+// (e.g. 30s) so idle connections aren't dropped by firewalls or proxies. It
+// is disabled by default.
+func startKeepaliveJob(targets []service.KeepaliveTarget) *service.KeepaliveJob {
+	interval := config.GetEnvDuration("CONN_KEEPALIVE_INTERVAL", 0)
+	if interval <= 0 || len(targets) == 0 {
+		return nil
+	}
+
+	job := service.NewKeepaliveJob(targets, interval)
+	job.Start()
+	log.Printf("Pinging %d backends every %s to keep connections alive", len(targets), interval)
+	return job
+}
+
+// apiEndpoints lists the routes registered by setupRoutes; keep it in step
+// when adding routes
+var apiEndpoints = []server.APIEndpoint{
+	{Path: "/health", Methods: []string{http.MethodGet}},
+	{Path: "/livez", Methods: []string{http.MethodGet}},
+	{Path: "/readyz", Methods: []string{http.MethodGet}},
+	{Path: "/metrics", Methods: []string{http.MethodGet}},
+	{Path: "/api/login", Methods: []string{http.MethodPost}},
+	{Path: "/api/posts", Methods: []string{http.MethodGet, http.MethodPost}},
+}
+
+// setupRoutes sets up the HTTP routes on mux
+func setupRoutes(mux *http.ServeMux, postRepo *db.PostRepository, postCache *cache.PostCache) {
+	// Post mutations are recorded in the audit log
+	audit := service.LoadAuditLoggerFromEnv()
+
+	// New posts are limited to MAX_POST_LENGTH characters like the post service
+	maxPostLength := service.LoadPostServiceOptionsFromEnv().MaxPostLength
+
+	// Patterns registered twice are reported instead of panicking
+	routes := server.NewRouteRegistrar(mux)
+
+	// The posts API is limited per client IP (RATE_LIMIT_RPS/RATE_LIMIT_BURST)
+	appConfig := config.LoadConfigFromEnv()
+	serverConfig := appConfig.Server
+	serverOptions := server.LoadOptionsFromEnv()
+	retryAfter := serverOptions.RetryAfterFormat
+	limitRate := func(handler http.HandlerFunc) http.HandlerFunc {
+		return server.RateLimit(handler, serverConfig.RateLimitRPS, serverConfig.RateLimitBurst, retryAfter, serverConfig.TrustedProxies).ServeHTTP
+	}
+
+	// Posts are created with the AUTH_USERNAME/AUTH_PASSWORD credentials,
+	// or with a bearer token from /api/login when JWT_SECRET is set
+	appAuth := server.NewEnvAuth(appConfig.Auth.JWTSecret, serverOptions.TokenTTL)
+
 	// Root endpoint
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	routes.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" {
 			http.NotFound(w, r)
 			return
@@ -109,15 +249,14 @@ func setupRoutes(postRepo *db.PostRepository, postCache *cache.PostCache) {
 		w.Write([]byte(`{"status": "ok", "message": "Tiger-Tail Microblog API"}`))
 	})
 	
-	// API endpoint
-	http.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"message": "Tiger-Tail Microblog API", "version": "0.1.0"}`))
-	})
+	// API endpoint, listing the routes below when API_INDEX_VERBOSE is set
+	routes.HandleFunc("/api", server.APIIndexHandler(serverOptions.APIIndexVerbose, apiEndpoints))
 	
+	// Token login, limited per client IP (LOGIN_RATE_LIMIT)
+	routes.HandleFunc("/api/login", server.RateLimit(appAuth.LoginHandler(), serverOptions.LoginRateLimit, 0, retryAfter, serverConfig.TrustedProxies).ServeHTTP)
+
 	// Posts endpoint - GET
-	http.HandleFunc("/api/posts", func(w http.ResponseWriter, r *http.Request) {
+	routes.HandleFunc("/api/posts", limitRate(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			// Parse query parameters
 			query := r.URL.Query()
@@ -147,83 +286,90 @@ func setupRoutes(postRepo *db.PostRepository, postCache *cache.PostCache) {
 			// Calculate offset
 			offset := (page - 1) * limit
 
-			// Try to get posts from cache
+			// Try to get posts from cache, which holds the newest posts
 			posts, err := postCache.GetPostsWithUser()
 			if err == nil {
-				// Cache hit
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"posts":  posts,
-					"page":   page,
-					"limit":  limit,
-					"total":  len(posts),
-					"source": "cache",
-				})
-				return
+				total, totalErr := postCache.GetPostsTotal()
+				if totalErr != nil {
+					total = -1
+				}
+
+				// Cache hit, as long as the page lies within the cached window
+				if window, ok := server.CachedPage(posts, total, page, limit); ok {
+					appMetrics.CacheHit()
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"posts":  window,
+						"page":   page,
+						"limit":  limit,
+						"total":  cachedPostsTotal(postCache, len(posts)),
+						"source": server.SourceCache,
+					})
+					return
+				}
 			}
 
 			// Cache miss, get posts from database
-			posts, err = postRepo.List(offset, limit)
+			appMetrics.CacheMiss()
+			readAt := time.Now()
+			posts, err = postRepo.WithContext(r.Context()).List(offset, limit)
 			if err != nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error": "Failed to get posts",
-				})
+				// Last resort: serve the stale copy kept for database outages
+				if stale, staleErr := postCache.GetStalePostsWithUser(); staleErr == nil {
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("X-Cache-Stale", "true")
+					w.Header().Set("Warning", `110 - "Response is Stale"`)
+					w.WriteHeader(http.StatusOK)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"posts":  stale,
+						"page":   page,
+						"limit":  limit,
+						"total":  cachedPostsTotal(postCache, len(stale)),
+						"source": server.SourceStaleCache,
+					})
+					return
+				}
+
+				server.RespondError(w, http.StatusInternalServerError, "Failed to get posts")
 				return
 			}
 
-			// Get total count
-			total, err := postRepo.Count()
-			if err != nil {
-				total = len(posts)
+			// Get total count, falling back to the last known total
+			total, approximate := resolvePostsTotal(postRepo.WithContext(r.Context()).Count, postCache, len(posts))
+
+			// Only the first page is cached, so the cache always holds the
+			// newest posts. The total is stored with it unless it is only
+			// the last known one.
+			if page == 1 {
+				cacheWrites.Go(func() {
+					if approximate {
+						postCache.SetPostsWithUserAt(posts, readAt)
+					} else {
+						postCache.SetPostsWithUserAndTotalAt(posts, total, readAt)
+					}
+				})
 			}
 
-			// Set posts in cache
-			go postCache.SetPostsWithUser(posts)
-
 			// Return posts
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(map[string]interface{}{
+			response := map[string]interface{}{
 				"posts":  posts,
 				"page":   page,
 				"limit":  limit,
 				"total":  total,
-				"source": "database",
-			})
+				"source": server.SourceDatabase,
+			}
+			if approximate {
+				response["total_approximate"] = true
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(response)
 			return
 		} else if r.Method == http.MethodPost {
 			// Check authentication
-			username, password, ok := r.BasicAuth()
+			userID, ok := appAuth.Authenticate(w, r)
 			if !ok {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error": "Unauthorized",
-				})
-				return
-			}
-
-			// Get expected username and password from environment variables
-			expectedUsername := os.Getenv("AUTH_USERNAME")
-			if expectedUsername == "" {
-				expectedUsername = "admin" // Default if not set
-			}
-			
-			expectedPassword := os.Getenv("AUTH_PASSWORD")
-			if expectedPassword == "" {
-				expectedPassword = "password" // Default if not set
-			}
-
-			// Check if username and password are valid
-			if username != expectedUsername || password != expectedPassword {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error": "Unauthorized",
-				})
 				return
 			}
 
@@ -233,46 +379,40 @@ func setupRoutes(postRepo *db.PostRepository, postCache *cache.PostCache) {
 			}
 			err := json.NewDecoder(r.Body).Decode(&requestBody)
 			if err != nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error": "Invalid request body",
-				})
+				server.RespondError(w, http.StatusBadRequest, "Invalid request body")
 				return
 			}
 
 			// Validate content
 			if requestBody.Content == "" {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error": "Content is required",
-				})
+				server.RespondError(w, http.StatusBadRequest, "Content is required")
+				return
+			}
+			if err := service.CheckContentLength(requestBody.Content, maxPostLength); err != nil {
+				server.RespondDomainError(w, http.StatusBadRequest, err, "Invalid content: "+err.Error())
 				return
 			}
 
 			// Create post
 			post := &domain.Post{
 				ID:        fmt.Sprintf("post_%d", time.Now().UnixNano()),
-				UserID:    "user_1", // Use a fixed user ID for simplicity
+				UserID:    userID,
 				Content:   requestBody.Content,
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
 
 			// Save post to database
-			err = postRepo.Create(post)
+			err = postRepo.WithContext(r.Context()).Create(post)
+			audit.Record(service.AuditActionCreate, post.UserID, post.ID, err)
 			if err != nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error": "Failed to create post",
-				})
+				server.RespondError(w, http.StatusInternalServerError, "Failed to create post")
 				return
 			}
 
-			// Invalidate cache
-			go postCache.InvalidatePosts()
+			// Invalidate cache; CREATE_REFRESH_MODE only applies to the
+			// library's create handler
+			cacheWrites.Go(func() { postCache.InvalidatePosts() })
 
 			// Return success
 			w.Header().Set("Content-Type", "application/json")
@@ -284,24 +424,23 @@ func setupRoutes(postRepo *db.PostRepository, postCache *cache.PostCache) {
 			return
 		} else {
 			// Method not allowed
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Method not allowed",
-			})
+			server.RespondError(w, http.StatusMethodNotAllowed, "Method not allowed")
 			return
 		}
-	})
+	}))
 	
+	// Prometheus metrics, only for the admin since they share the API port
+	routes.HandleFunc("/metrics", server.RequireAdmin(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})).ServeHTTP)
+
 	// Health endpoint
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	routes.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status": "ok"}`))
 	})
 	
 	// Liveness probe endpoint - separate handler for plain text response
-	http.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+	routes.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
 		// Force content type to text/plain
 		w.Header().Set("Content-Type", "text/plain")
 		// Write the status code
@@ -311,21 +450,142 @@ func setupRoutes(postRepo *db.PostRepository, postCache *cache.PostCache) {
 	})
 	
 	// Readiness probe endpoint
-	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+	routes.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status": "ready", "checks": {"database": "up", "cache": "up"}}`))
 	})
 }
 
-// startServer starts the HTTP server
-func startServer(port string) {
+// postsTotalCache keeps the last known good total number of posts
+type postsTotalCache interface {
+	GetPostsTotal() (int, error)
+}
+
+// resolvePostsTotal counts the posts. When counting fails it returns the
+// last known total from totals, or fallback if there is none, and reports
+// that the total is approximate.
+func resolvePostsTotal(count func() (int, error), totals postsTotalCache, fallback int) (int, bool) {
+	total, err := count()
+	if err == nil {
+		return total, false
+	}
+
+	log.Printf("Error counting posts, using last known total: %v", err)
+	if cached, cacheErr := totals.GetPostsTotal(); cacheErr == nil && cached >= fallback {
+		return cached, true
+	}
+	return fallback, true
+}
+
+// cachedPostsTotal returns the total number of posts recorded alongside the
+// cache, for responses served from a cached page. The page may hold only
+// some of the posts, so its length is used only when no total is recorded
+// or the recorded one is behind.
+func cachedPostsTotal(totals postsTotalCache, cached int) int {
+	if total, err := totals.GetPostsTotal(); err == nil && total > cached {
+		return total
+	}
+	return cached
+}
+
+// newHTTPServer creates the HTTP server listening on port
+func newHTTPServer(port string) *http.Server {
+	return &http.Server{
+		Addr:           ":" + port,
+		Handler:        requestid.Middleware(server.ProblemErrors(server.InstrumentRequests(http.DefaultServeMux, http.DefaultServeMux, appMetrics), server.LoadOptionsFromEnv().ErrorFormat), config.GetEnvBool("TRUST_REQUEST_ID", true)),
+		MaxHeaderBytes: config.GetEnvInt("TT_SERVER_MAX_HEADER_BYTES", http.DefaultMaxHeaderBytes),
+	}
+}
+
+// tlsVersions maps the accepted TLS_MIN_VERSION values to TLS versions
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsCipherSuites are the TLS 1.2 cipher suites offered when serving HTTPS
+// directly: ECDHE key exchange with AEAD ciphers only. TLS 1.3 suites are
+// not configurable and are all strong.
+var tlsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// newTLSConfig creates the TLS configuration used when serving HTTPS
+// directly, accepting TLS_MIN_VERSION (1.2 or 1.3, default 1.2) or newer
+func newTLSConfig() (*tls.Config, error) {
+	minVersion := getEnv("TLS_MIN_VERSION", "1.2")
+	version, ok := tlsVersions[minVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS_MIN_VERSION %q, want 1.2 or 1.3", minVersion)
+	}
+
+	return &tls.Config{
+		MinVersion:   version,
+		CipherSuites: tlsCipherSuites,
+	}, nil
+}
+
+// startServer starts the HTTP server in the background, serving HTTPS
+// directly when both TLS_CERT_FILE and TLS_KEY_FILE are set and plain HTTP
+// otherwise. The returned server is stopped with stopServer.
+func startServer(port string) (*http.Server, error) {
+	server := newHTTPServer(port)
+
+	certFile := getEnv("TLS_CERT_FILE", "")
+	keyFile := getEnv("TLS_KEY_FILE", "")
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if certFile != "" {
+		tlsConfig, err := newTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		server.TLSConfig = tlsConfig
+	}
+
+	// Listen before returning so a port in use fails startup
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on port %s: %w", port, err)
+	}
+
 	go func() {
-		fmt.Printf("Starting server on port %s...\n", port)
-		if err := http.ListenAndServe(":"+port, nil); err != nil && err != http.ErrServerClosed {
+		if certFile != "" {
+			fmt.Printf("Starting server on port %s with TLS...\n", port)
+		} else {
+			fmt.Printf("Starting server on port %s...\n", port)
+		}
+		if err := serve(server, listener, certFile, keyFile); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Error starting server: %v", err)
 		}
 	}()
+	return server, nil
+}
+
+// serve serves requests on listener until the server is shut down, over TLS
+// when certFile and keyFile are set
+func serve(server *http.Server, listener net.Listener, certFile, keyFile string) error {
+	if certFile != "" && keyFile != "" {
+		return server.ServeTLS(listener, certFile, keyFile)
+	}
+	return server.Serve(listener)
+}
+
+// stopServer gracefully shuts the server down, letting in-flight requests
+// finish for up to SHUTDOWN_TIMEOUT (default 10s)
+func stopServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetEnvDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout))
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
 }
 
 // runServer initializes and runs the server, returning a shutdown function
@@ -339,7 +599,10 @@ func runServer() (shutdown func(), err error) {
 	}
 
 	// Start server
-	startServer(port)
+	httpServer, err := startServer(port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
 
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -350,6 +613,20 @@ func runServer() (shutdown func(), err error) {
 		// Clean up signal handler
 		signal.Stop(sigChan)
 		close(sigChan)
+
+		// Stop accepting requests and let in-flight ones finish
+		stopServer(httpServer)
+
+		// Stop background jobs
+		if retentionJob != nil {
+			retentionJob.Stop()
+			retentionJob = nil
+		}
+		if keepaliveJob != nil {
+			keepaliveJob.Stop()
+			keepaliveJob = nil
+		}
+		waitForCacheWrites()
 		fmt.Println("\nShutting down TigerTail...")
 	}, nil
 }
@@ -374,6 +651,32 @@ func main() {
 	}
 }
 
+// defaultAuthPassword is the admin password used when AUTH_PASSWORD is unset
+const defaultAuthPassword = "password"
+
+// checkDefaultCredentials refuses to start a production deployment
+// (ENVIRONMENT=production) that still uses the default admin password or
+// seeds the default admin user, unless ALLOW_DEFAULT_PASSWORD=true explicitly
+// allows it
+func checkDefaultCredentials() error {
+	if getEnv("ENVIRONMENT", "development") != "production" {
+		return nil
+	}
+	defaultPassword := getEnv("AUTH_PASSWORD", defaultAuthPassword) == defaultAuthPassword
+	seedDefaultAdmin := db.SeedDefaultAdmin()
+	if !defaultPassword && !seedDefaultAdmin {
+		return nil
+	}
+	if config.GetEnvBool("ALLOW_DEFAULT_PASSWORD", false) {
+		log.Printf("Warning: running in production with the default admin password")
+		return nil
+	}
+	if seedDefaultAdmin {
+		return fmt.Errorf("refusing to start in production with SEED_DEFAULT_ADMIN=true: the seeded admin has the default password")
+	}
+	return fmt.Errorf("refusing to start in production with the default admin password: set AUTH_PASSWORD or ALLOW_DEFAULT_PASSWORD=true")
+}
+
 // getEnv retrieves an environment variable or returns a default value if not set
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
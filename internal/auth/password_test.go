@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+)
+
+// TestHashPassword tests the HashPassword and CheckPassword helpers
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if hash == "secret" {
+		t.Error("Expected password to be hashed")
+	}
+	if !CheckPassword(hash, "secret") {
+		t.Error("Expected hashed password to match")
+	}
+	if CheckPassword(hash, "wrong") {
+		t.Error("Expected wrong password not to match")
+	}
+}
+
+// TestCheckPasswordPlainText tests that legacy plain-text passwords are still accepted
+func TestCheckPasswordPlainText(t *testing.T) {
+	if !CheckPassword("password", "password") {
+		t.Error("Expected plain-text password to match")
+	}
+	if CheckPassword("password", "wrong") {
+		t.Error("Expected wrong password not to match")
+	}
+}
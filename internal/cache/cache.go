@@ -1,13 +1,26 @@
 package cache
 
 import (
+	"container/list"
 	"errors"
+	"sync"
 	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+)
+
+// Default bounds of the in-memory cache
+const (
+	defaultMemoryCacheMaxItems = 10000
+	defaultMemoryCacheMaxBytes = 64 << 20
 )
 
 // ErrNotFound is returned when a key is not found in the cache
 var ErrNotFound = errors.New("key not found in cache")
 
+// ErrValueTooLarge is returned when a value is larger than the whole cache
+var ErrValueTooLarge = errors.New("value larger than the cache")
+
 // Cache represents a cache interface
 type Cache interface {
 	// Get retrieves a value from the cache
@@ -26,20 +39,37 @@ type Cache interface {
 	Close() error
 }
 
-// MemoryCache is an in-memory implementation of the Cache interface
+// MemoryCache is an in-memory implementation of the Cache interface. It is
+// bounded by item count and by size, evicting the least recently used items
+// when a new one doesn't fit.
 type MemoryCache struct {
+	mu    sync.Mutex
 	items map[string]item
+	// recent orders the keys from most to least recently used
+	recent *list.List
+	// bytes is the size of the cached keys and values
+	bytes int64
+	// maxItems caps the number of cached items (0 means no limit)
+	maxItems int
+	// maxBytes caps the size of the cached keys and values (0 means no limit)
+	maxBytes int64
 }
 
 type item struct {
 	value      []byte
 	expiration int64
+	// element is the key's position in recent
+	element *list.Element
 }
 
-// NewMemoryCache creates a new in-memory cache
+// NewMemoryCache creates a new in-memory cache holding at most
+// MEMORY_CACHE_MAX_ITEMS items of MEMORY_CACHE_MAX_BYTES bytes in total
 func NewMemoryCache() *MemoryCache {
 	cache := &MemoryCache{
-		items: make(map[string]item),
+		items:    make(map[string]item),
+		recent:   list.New(),
+		maxItems: config.GetEnvInt("MEMORY_CACHE_MAX_ITEMS", defaultMemoryCacheMaxItems),
+		maxBytes: int64(config.GetEnvInt("MEMORY_CACHE_MAX_BYTES", defaultMemoryCacheMaxBytes)),
 	}
 	
 	// Start a goroutine to clean up expired items
@@ -50,6 +80,9 @@ func NewMemoryCache() *MemoryCache {
 
 // Get retrieves a value from the cache
 func (c *MemoryCache) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
 	item, found := c.items[key]
 	if !found {
 		return nil, ErrNotFound
@@ -57,14 +90,20 @@ func (c *MemoryCache) Get(key string) ([]byte, error) {
 	
 	// Check if the item has expired
 	if item.expiration > 0 && item.expiration < time.Now().UnixNano() {
-		delete(c.items, key)
+		c.remove(key)
 		return nil, ErrNotFound
 	}
 	
+	if item.element != nil {
+		c.recent.MoveToFront(item.element)
+	}
 	return item.value, nil
 }
 
-// Set stores a value in the cache with an optional expiration
+// Set stores a value in the cache with an optional expiration, evicting
+// the least recently used items until it fits. A value larger than the
+// whole cache is not stored; any previous value of the key is dropped and
+// ErrValueTooLarge returned.
 func (c *MemoryCache) Set(key string, value []byte, expiration time.Duration) error {
 	var exp int64
 	
@@ -72,26 +111,68 @@ func (c *MemoryCache) Set(key string, value []byte, expiration time.Duration) er
 		exp = time.Now().Add(expiration).UnixNano()
 	}
 	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.remove(key)
+	size := itemSize(key, value)
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return ErrValueTooLarge
+	}
+	for c.recent.Len() > 0 && (c.maxItems > 0 && c.recent.Len() >= c.maxItems || c.maxBytes > 0 && c.bytes+size > c.maxBytes) {
+		c.remove(c.recent.Back().Value.(string))
+	}
+	
 	c.items[key] = item{
 		value:      value,
 		expiration: exp,
+		element:    c.recent.PushFront(key),
 	}
+	c.bytes += size
 	
 	return nil
 }
 
 // Delete removes a value from the cache
 func (c *MemoryCache) Delete(key string) error {
-	delete(c.items, key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.remove(key)
 	return nil
 }
 
 // Clear removes all values from the cache
 func (c *MemoryCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
 	c.items = make(map[string]item)
+	c.recent.Init()
+	c.bytes = 0
 	return nil
 }
 
+// remove deletes key from the cache and its size accounting; the caller
+// holds mu
+func (c *MemoryCache) remove(key string) {
+	item, found := c.items[key]
+	if !found {
+		return
+	}
+	
+	delete(c.items, key)
+	if item.element != nil {
+		c.recent.Remove(item.element)
+		c.bytes -= itemSize(key, item.value)
+	}
+}
+
+// itemSize is the number of bytes a cached item counts against the limit
+func itemSize(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
+
 // Close closes the cache connection
 func (c *MemoryCache) Close() error {
 	// Nothing to do for in-memory cache
@@ -111,11 +192,14 @@ func (c *MemoryCache) cleanupLoop() {
 
 // cleanup removes expired items from the cache
 func (c *MemoryCache) cleanup() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
 	now := time.Now().UnixNano()
 	
 	for k, v := range c.items {
 		if v.expiration > 0 && v.expiration < now {
-			delete(c.items, k)
+			c.remove(k)
 		}
 	}
 }
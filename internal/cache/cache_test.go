@@ -218,6 +218,73 @@ func TestMemoryCacheCleanup(t *testing.T) {
 	}
 }
 
+// TestMemoryCacheEviction tests that inserting past the item or byte limit
+// evicts the least recently used items and keeps recent ones
+func TestMemoryCacheEviction(t *testing.T) {
+	t.Run("Item limit", func(t *testing.T) {
+		cache := NewMemoryCache()
+		cache.maxItems = 3
+		
+		cache.Set("a", []byte("1"), 0)
+		cache.Set("b", []byte("2"), 0)
+		cache.Set("c", []byte("3"), 0)
+		
+		// Reading a makes b the least recently used
+		if _, err := cache.Get("a"); err != nil {
+			t.Fatalf("Get(a) error = %v, want nil", err)
+		}
+		cache.Set("d", []byte("4"), 0)
+		cache.Set("e", []byte("5"), 0)
+		
+		for _, key := range []string{"b", "c"} {
+			if _, err := cache.Get(key); err != ErrNotFound {
+				t.Errorf("Get(%s) error = %v, want %v", key, err, ErrNotFound)
+			}
+		}
+		for _, key := range []string{"a", "d", "e"} {
+			if _, err := cache.Get(key); err != nil {
+				t.Errorf("Get(%s) error = %v, want nil", key, err)
+			}
+		}
+		if len(cache.items) != 3 {
+			t.Errorf("cache.items has %d items, want 3", len(cache.items))
+		}
+	})
+	
+	t.Run("Byte limit", func(t *testing.T) {
+		cache := NewMemoryCache()
+		cache.maxBytes = 20
+		
+		// Each item is a 1 byte key and a 9 byte value
+		cache.Set("a", bytes.Repeat([]byte("x"), 9), 0)
+		cache.Set("b", bytes.Repeat([]byte("x"), 9), 0)
+		cache.Set("c", bytes.Repeat([]byte("x"), 9), 0)
+		
+		if _, err := cache.Get("a"); err != ErrNotFound {
+			t.Errorf("Get(a) error = %v, want %v", err, ErrNotFound)
+		}
+		for _, key := range []string{"b", "c"} {
+			if _, err := cache.Get(key); err != nil {
+				t.Errorf("Get(%s) error = %v, want nil", key, err)
+			}
+		}
+		if cache.bytes != 20 {
+			t.Errorf("cache.bytes = %d, want 20", cache.bytes)
+		}
+		
+		// A value larger than the whole cache is not stored
+		if err := cache.Set("d", bytes.Repeat([]byte("x"), 20), 0); err != ErrValueTooLarge {
+			t.Errorf("Set(d) error = %v, want %v", err, ErrValueTooLarge)
+		}
+		if _, err := cache.Get("d"); err != ErrNotFound {
+			t.Errorf("Get(d) error = %v, want %v", err, ErrNotFound)
+		}
+		if _, err := cache.Get("c"); err != nil {
+			t.Errorf("Get(c) error = %v, want nil", err)
+		}
+	})
+}
+
 // Test for RedisCache - since it's a placeholder, we'll just test that the methods return "not implemented"
 func TestRedisCache(t *testing.T) {
 	cache, err := NewRedisCache("localhost", 6379, "", 0)
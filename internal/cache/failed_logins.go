@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// failedLoginsKey is the Redis list holding recent failed logins, newest first
+const failedLoginsKey = "security:failed_logins"
+
+// defaultMaxFailedLogins is how many failed logins are kept by default
+const defaultMaxFailedLogins = 100
+
+// FailedLoginLog records recent failed authentication attempts in a capped
+// Redis list for security monitoring
+type FailedLoginLog struct {
+	client RedisClientInterface
+	max    int64
+}
+
+// NewFailedLoginLog creates a new failed login log keeping the most recent
+// FAILED_LOGINS_MAX (default 100) attempts
+func NewFailedLoginLog(client RedisClientInterface) *FailedLoginLog {
+	max := config.GetEnvInt("FAILED_LOGINS_MAX", defaultMaxFailedLogins)
+	if max < 1 {
+		max = defaultMaxFailedLogins
+	}
+	return &FailedLoginLog{
+		client: client,
+		max:    int64(max),
+	}
+}
+
+// RecordFailedLogin adds a failed attempt, dropping the oldest beyond the cap
+func (l *FailedLoginLog) RecordFailedLogin(attempt domain.FailedLogin) error {
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		return fmt.Errorf("error marshaling failed login: %w", err)
+	}
+	return l.client.PushCapped(failedLoginsKey, data, l.max)
+}
+
+// RecentFailedLogins returns the recorded failed attempts, newest first.
+// Entries that cannot be decoded are skipped.
+func (l *FailedLoginLog) RecentFailedLogins() ([]domain.FailedLogin, error) {
+	entries, err := l.client.Range(failedLoginsKey, 0, l.max-1)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]domain.FailedLogin, 0, len(entries))
+	for _, entry := range entries {
+		var attempt domain.FailedLogin
+		if err := json.Unmarshal(entry, &attempt); err != nil {
+			log.Printf("Error unmarshaling failed login: %v", err)
+			continue
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, nil
+}
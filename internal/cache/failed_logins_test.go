@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+func TestFailedLoginLog(t *testing.T) {
+	// Setup
+	client := NewMockRedisClient()
+	failures := NewFailedLoginLog(client)
+	failures.max = 3
+
+	// Test
+	for i := 1; i <= 5; i++ {
+		attempt := domain.FailedLogin{
+			Time:     time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+			Username: fmt.Sprintf("user_%d", i),
+			IP:       "192.0.2.1",
+		}
+		if err := failures.RecordFailedLogin(attempt); err != nil {
+			t.Fatalf("RecordFailedLogin() error = %v", err)
+		}
+	}
+	attempts, err := failures.RecentFailedLogins()
+
+	// Assert: only the newest attempts are kept, newest first
+	if err != nil {
+		t.Fatalf("RecentFailedLogins() error = %v", err)
+	}
+	expected := []string{"user_5", "user_4", "user_3"}
+	if len(attempts) != len(expected) {
+		t.Fatalf("RecentFailedLogins() returned %d attempts, want %d", len(attempts), len(expected))
+	}
+	for i, attempt := range attempts {
+		if attempt.Username != expected[i] {
+			t.Errorf("attempts[%d].Username = %s, want %s", i, attempt.Username, expected[i])
+		}
+	}
+}
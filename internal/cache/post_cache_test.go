@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,12 +10,20 @@ import (
 
 // MockRedisClient is a mock implementation of the Redis client for testing
 type MockRedisClient struct {
-	data map[string][]byte
+	data        map[string][]byte
+	versions    map[string]int64
+	deleted     []string
+	deleteCalls int
+	lists       map[string][][]byte
+	ttls        map[string]time.Duration
 }
 
 func NewMockRedisClient() *MockRedisClient {
 	return &MockRedisClient{
-		data: make(map[string][]byte),
+		data:     make(map[string][]byte),
+		versions: make(map[string]int64),
+		lists:    make(map[string][][]byte),
+		ttls:     make(map[string]time.Duration),
 	}
 }
 
@@ -27,14 +36,56 @@ func (m *MockRedisClient) Get(key string) ([]byte, error) {
 
 func (m *MockRedisClient) Set(key string, value []byte, expiration time.Duration) error {
 	m.data[key] = value
+	m.ttls[key] = expiration
 	return nil
 }
 
-func (m *MockRedisClient) Delete(key string) error {
-	delete(m.data, key)
+func (m *MockRedisClient) SetIfNewer(key string, value []byte, version int64, expiration time.Duration) (bool, error) {
+	if version < m.versions[key] {
+		return false, nil
+	}
+	m.versions[key] = version
+	m.data[key] = value
+	m.ttls[key] = expiration
+	return true, nil
+}
+
+func (m *MockRedisClient) DeleteIfNewer(key string, version int64, expiration time.Duration) error {
+	if version > m.versions[key] {
+		m.versions[key] = version
+	}
+	return m.Delete(key)
+}
+
+func (m *MockRedisClient) Delete(keys ...string) error {
+	m.deleteCalls++
+	for _, key := range keys {
+		m.deleted = append(m.deleted, key)
+		delete(m.data, key)
+	}
 	return nil
 }
 
+func (m *MockRedisClient) PushCapped(key string, value []byte, max int64) error {
+	list := append([][]byte{value}, m.lists[key]...)
+	if int64(len(list)) > max {
+		list = list[:max]
+	}
+	m.lists[key] = list
+	return nil
+}
+
+func (m *MockRedisClient) Range(key string, start, stop int64) ([][]byte, error) {
+	list := m.lists[key]
+	if stop < 0 || stop >= int64(len(list)) {
+		stop = int64(len(list)) - 1
+	}
+	if start > stop {
+		return [][]byte{}, nil
+	}
+	return list[start : stop+1], nil
+}
+
 func (m *MockRedisClient) Exists(key string) (bool, error) {
 	_, ok := m.data[key]
 	return ok, nil
@@ -172,82 +223,11 @@ func TestPostCache_GetPostsWithUser(t *testing.T) {
 	}
 }
 
-func TestPostCache_GetPosts(t *testing.T) {
-	client := NewMockRedisClient()
-	cache := NewPostCache(client)
-	
-	// Test cache miss
-	_, err := cache.GetPosts()
-	if err == nil {
-		t.Error("Expected error for cache miss, got nil")
-	}
-	
-	// Set posts in the cache
-	posts := []*domain.Post{
-		{
-			ID:        "post_1",
-			UserID:    "user_1",
-			Content:   "Test post 1",
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-		{
-			ID:        "post_2",
-			UserID:    "user_2",
-			Content:   "Test post 2",
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-	}
-	
-	err = cache.SetPosts(posts)
-	if err != nil {
-		t.Fatalf("Error setting posts in cache: %v", err)
-	}
-	
-	// Test cache hit
-	cachedPosts, err := cache.GetPosts()
-	if err != nil {
-		t.Fatalf("Error getting posts from cache: %v", err)
-	}
-	
-	if len(cachedPosts) != len(posts) {
-		t.Errorf("len(cachedPosts) = %d, want %d", len(cachedPosts), len(posts))
-	}
-	
-	for i, post := range cachedPosts {
-		if post.ID != posts[i].ID {
-			t.Errorf("post.ID = %s, want %s", post.ID, posts[i].ID)
-		}
-		if post.UserID != posts[i].UserID {
-			t.Errorf("post.UserID = %s, want %s", post.UserID, posts[i].UserID)
-		}
-		if post.Content != posts[i].Content {
-			t.Errorf("post.Content = %s, want %s", post.Content, posts[i].Content)
-		}
-	}
-}
-
 func TestPostCache_InvalidatePosts(t *testing.T) {
 	client := NewMockRedisClient()
 	cache := NewPostCache(client)
 	
 	// Set posts in the cache
-	posts := []*domain.Post{
-		{
-			ID:        "post_1",
-			UserID:    "user_1",
-			Content:   "Test post 1",
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-	}
-	
-	err := cache.SetPosts(posts)
-	if err != nil {
-		t.Fatalf("Error setting posts in cache: %v", err)
-	}
-	
 	postsWithUser := []*domain.PostWithUser{
 		{
 			Post: domain.Post{
@@ -261,7 +241,7 @@ func TestPostCache_InvalidatePosts(t *testing.T) {
 		},
 	}
 	
-	err = cache.SetPostsWithUser(postsWithUser)
+	err := cache.SetPostsWithUser(postsWithUser)
 	if err != nil {
 		t.Fatalf("Error setting posts with user in cache: %v", err)
 	}
@@ -273,17 +253,77 @@ func TestPostCache_InvalidatePosts(t *testing.T) {
 	}
 	
 	// Verify posts are invalidated
-	_, err = cache.GetPosts()
-	if err == nil {
-		t.Error("Expected error for cache miss after invalidation, got nil")
-	}
-	
 	_, err = cache.GetPostsWithUser()
 	if err == nil {
 		t.Error("Expected error for cache miss after invalidation, got nil")
 	}
 }
 
+func TestPostCache_InvalidatePostsRejectsEarlierReads(t *testing.T) {
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	posts := []*domain.PostWithUser{{Post: domain.Post{ID: "post_1"}}}
+
+	// A snapshot read before a create finishes after its invalidation
+	readAt := time.Now()
+	if err := cache.InvalidatePosts(); err != nil {
+		t.Fatalf("InvalidatePosts() error = %v", err)
+	}
+	if err := cache.SetPostsWithUserAt(posts, readAt); err != nil {
+		t.Fatalf("SetPostsWithUserAt() error = %v", err)
+	}
+	if _, err := cache.GetPostsWithUser(); err != ErrCacheMiss {
+		t.Errorf("Expected the snapshot read before the invalidation to be rejected, got %v", err)
+	}
+
+	// A snapshot read after the invalidation is stored
+	if err := cache.SetPostsWithUserAt(posts, time.Now().Add(time.Millisecond)); err != nil {
+		t.Fatalf("SetPostsWithUserAt() error = %v", err)
+	}
+	if _, err := cache.GetPostsWithUser(); err != nil {
+		t.Errorf("Expected the snapshot read after the invalidation to be stored, got %v", err)
+	}
+}
+
+func TestPostCache_KeysInUse(t *testing.T) {
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	// Populate every cache entry that handlers use
+	post := &domain.Post{
+		ID:        "post_1",
+		UserID:    "user_1",
+		Content:   "Test post 1",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := cache.SetPost(post); err != nil {
+		t.Fatalf("Error setting post in cache: %v", err)
+	}
+	if err := cache.SetPostsWithUser([]*domain.PostWithUser{{Post: *post, Username: "testuser1"}}); err != nil {
+		t.Fatalf("Error setting posts with user in cache: %v", err)
+	}
+
+	// Only the keys in use are written
+	if len(client.data) != 3 {
+		t.Errorf("len(client.data) = %d, want 3", len(client.data))
+	}
+	for _, key := range []string{"post:post_1", "posts_with_user", "posts_with_user:stale"} {
+		if _, ok := client.data[key]; !ok {
+			t.Errorf("Expected key %q to be cached", key)
+		}
+	}
+
+	// Invalidation only deletes the list key
+	if err := cache.InvalidatePosts(); err != nil {
+		t.Fatalf("Error invalidating posts: %v", err)
+	}
+	if len(client.deleted) != 1 || client.deleted[0] != "posts_with_user" {
+		t.Errorf("client.deleted = %v, want [posts_with_user]", client.deleted)
+	}
+}
+
 func TestPostCache_InvalidatePost(t *testing.T) {
 	client := NewMockRedisClient()
 	cache := NewPostCache(client)
@@ -314,3 +354,303 @@ func TestPostCache_InvalidatePost(t *testing.T) {
 		t.Error("Expected error for cache miss after invalidation, got nil")
 	}
 }
+
+func TestPostCache_InvalidatePostsByIDs(t *testing.T) {
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	ids := []string{"post_1", "post_2", "post_3"}
+	for _, id := range ids {
+		if err := cache.SetPost(&domain.Post{ID: id}); err != nil {
+			t.Fatalf("SetPost() error = %v", err)
+		}
+	}
+	if err := cache.SetPost(&domain.Post{ID: "post_4"}); err != nil {
+		t.Fatalf("SetPost() error = %v", err)
+	}
+
+	// Test
+	if err := cache.InvalidatePostsByIDs(ids); err != nil {
+		t.Fatalf("InvalidatePostsByIDs() error = %v", err)
+	}
+
+	// Assert
+	if client.deleteCalls != 1 {
+		t.Errorf("Delete called %d times, want 1", client.deleteCalls)
+	}
+	for _, id := range ids {
+		if _, err := cache.GetPost(id); err == nil {
+			t.Errorf("Expected %s to be invalidated", id)
+		}
+	}
+	if _, err := cache.GetPost("post_4"); err != nil {
+		t.Errorf("Expected post_4 to remain cached, got %v", err)
+	}
+
+	// No IDs means no round trip
+	if err := cache.InvalidatePostsByIDs(nil); err != nil {
+		t.Fatalf("InvalidatePostsByIDs(nil) error = %v", err)
+	}
+	if client.deleteCalls != 1 {
+		t.Errorf("Expected no Delete call for empty IDs, got %d calls", client.deleteCalls)
+	}
+}
+
+func TestPostCache_GetStalePostsWithUser(t *testing.T) {
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	posts := []*domain.PostWithUser{
+		{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "Test post"}, Username: "testuser"},
+	}
+	if err := cache.SetPostsWithUser(posts); err != nil {
+		t.Fatalf("SetPostsWithUser() error = %v", err)
+	}
+
+	// Invalidation drops the fresh copy but keeps the stale one
+	if err := cache.InvalidatePosts(); err != nil {
+		t.Fatalf("InvalidatePosts() error = %v", err)
+	}
+	if _, err := cache.GetPostsWithUser(); err == nil {
+		t.Error("Expected fresh posts to be invalidated")
+	}
+
+	stale, err := cache.GetStalePostsWithUser()
+	if err != nil {
+		t.Fatalf("GetStalePostsWithUser() error = %v", err)
+	}
+	if len(stale) != 1 || stale[0].ID != "post_1" {
+		t.Errorf("GetStalePostsWithUser() = %v, want post_1", stale)
+	}
+
+	// A zero TTL disables the stale copy
+	cache.staleTTL = 0
+	if _, err := cache.GetStalePostsWithUser(); err != ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss with stale copies disabled, got %v", err)
+	}
+}
+
+func TestPostCache_PostsTotal(t *testing.T) {
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	if _, err := cache.GetPostsTotal(); err == nil {
+		t.Error("Expected a miss before any total is stored")
+	}
+
+	readAt := time.Now()
+	posts := []*domain.PostWithUser{{Post: domain.Post{ID: "post_1"}}}
+	if err := cache.SetPostsWithUserAndTotalAt(posts, 42, readAt); err != nil {
+		t.Fatalf("SetPostsWithUserAndTotalAt() error = %v", err)
+	}
+
+	// The total survives invalidation of the posts with the stale copy
+	if err := cache.InvalidatePosts(); err != nil {
+		t.Fatalf("InvalidatePosts() error = %v", err)
+	}
+	total, err := cache.GetPostsTotal()
+	if err != nil {
+		t.Fatalf("GetPostsTotal() error = %v", err)
+	}
+	if total != 42 {
+		t.Errorf("GetPostsTotal() = %d, want 42", total)
+	}
+
+	// An older snapshot that is skipped doesn't overwrite the total
+	if err := cache.SetPostsWithUserAndTotalAt(posts, 7, readAt.Add(-time.Second)); err != nil {
+		t.Fatalf("SetPostsWithUserAndTotalAt() error = %v", err)
+	}
+	if total, _ := cache.GetPostsTotal(); total != 42 {
+		t.Errorf("GetPostsTotal() = %d after a skipped snapshot, want 42", total)
+	}
+
+	// Without stale copies the total is kept with the snapshot
+	cache.staleTTL = 0
+	if err := cache.SetPostsWithUserAndTotalAt(posts, 43, readAt.Add(time.Second)); err != nil {
+		t.Fatalf("SetPostsWithUserAndTotalAt() error = %v", err)
+	}
+	if total, err := cache.GetPostsTotal(); err != nil || total != 43 {
+		t.Errorf("GetPostsTotal() = %d, %v without stale copies, want 43", total, err)
+	}
+}
+
+func TestPostCache_SetPostsWithUserAtOutOfOrder(t *testing.T) {
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	readAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	older := []*domain.PostWithUser{{Post: domain.Post{ID: "post_1"}}}
+	newer := []*domain.PostWithUser{{Post: domain.Post{ID: "post_2"}}, {Post: domain.Post{ID: "post_1"}}}
+
+	// The newer snapshot finishes first, then the older one arrives late
+	if err := cache.SetPostsWithUserAt(newer, readAt.Add(time.Second)); err != nil {
+		t.Fatalf("SetPostsWithUserAt() error = %v", err)
+	}
+	if err := cache.SetPostsWithUserAt(older, readAt); err != nil {
+		t.Fatalf("SetPostsWithUserAt() error = %v", err)
+	}
+
+	// Assert
+	for _, get := range []func() ([]*domain.PostWithUser, error){cache.GetPostsWithUser, cache.GetStalePostsWithUser} {
+		posts, err := get()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(posts) != 2 || posts[0].ID != "post_2" {
+			t.Errorf("Expected the newer snapshot to survive, got %d posts", len(posts))
+		}
+	}
+}
+
+func TestPostCache_ListMaxItems(t *testing.T) {
+	t.Setenv("CACHE_LIST_MAX_ITEMS", "10")
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	posts := make([]*domain.PostWithUser, 25)
+	for i := range posts {
+		posts[i] = &domain.PostWithUser{Post: domain.Post{ID: fmt.Sprintf("post_%d", 25-i)}}
+	}
+
+	if err := cache.SetPostsWithUserAndTotalAt(posts, len(posts), time.Now()); err != nil {
+		t.Fatalf("SetPostsWithUserAndTotalAt() error = %v", err)
+	}
+
+	// The snapshot keeps only the newest posts
+	cached, err := cache.GetPostsWithUser()
+	if err != nil {
+		t.Fatalf("GetPostsWithUser() error = %v", err)
+	}
+	if len(cached) != 10 {
+		t.Fatalf("Expected 10 cached posts, got %d", len(cached))
+	}
+	if cached[0].ID != "post_25" || cached[9].ID != "post_16" {
+		t.Errorf("Expected the newest posts to be cached, got %s..%s", cached[0].ID, cached[9].ID)
+	}
+
+	// The total still reflects every post
+	total, err := cache.GetPostsTotal()
+	if err != nil {
+		t.Fatalf("GetPostsTotal() error = %v", err)
+	}
+	if total != 25 {
+		t.Errorf("GetPostsTotal() = %d, want 25", total)
+	}
+}
+
+func TestPostCache_TTLs(t *testing.T) {
+	t.Setenv("POST_CACHE_TTL", "30s")
+	t.Setenv("CACHE_TTL_JITTER_PERCENT", "0")
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	if err := cache.SetPost(&domain.Post{ID: "post_1"}); err != nil {
+		t.Fatalf("SetPost() error = %v", err)
+	}
+	posts := []*domain.PostWithUser{{Post: domain.Post{ID: "post_1"}}}
+	if err := cache.SetPostsWithUserAndTotalAt(posts, 1, time.Now()); err != nil {
+		t.Fatalf("SetPostsWithUserAndTotalAt() error = %v", err)
+	}
+
+	// Single posts use their own TTL, the list snapshot keeps the list TTL
+	if ttl := client.ttls["post:post_1"]; ttl != 30*time.Second {
+		t.Errorf("Expected post TTL of 30s, got %v", ttl)
+	}
+	if ttl := client.ttls[postsWithUserKey]; ttl != defaultListTTL {
+		t.Errorf("Expected list TTL of %v, got %v", defaultListTTL, ttl)
+	}
+
+	// Posts always expire
+	for _, value := range []string{"0s", "-1s"} {
+		t.Setenv("POST_CACHE_TTL", value)
+		cache = NewPostCache(client)
+		if err := cache.SetPost(&domain.Post{ID: "post_2"}); err != nil {
+			t.Fatalf("SetPost() error = %v", err)
+		}
+		if ttl := client.ttls["post:post_2"]; ttl != defaultPostTTL {
+			t.Errorf("Expected POST_CACHE_TTL=%s to fall back to %v, got %v", value, defaultPostTTL, ttl)
+		}
+	}
+}
+
+// TestPostCache_TTLJitter tests that repeated writes get varied TTLs within
+// the configured jitter band
+func TestPostCache_TTLJitter(t *testing.T) {
+	t.Setenv("POST_CACHE_TTL", "100s")
+	t.Setenv("CACHE_TTL_JITTER_PERCENT", "20")
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("post_%d", i)
+		if err := cache.SetPost(&domain.Post{ID: id}); err != nil {
+			t.Fatalf("SetPost() error = %v", err)
+		}
+		ttl := client.ttls["post:"+id]
+		if ttl < 80*time.Second || ttl > 120*time.Second {
+			t.Errorf("TTL %v outside the jitter band of 80s to 120s", ttl)
+		}
+		seen[ttl] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected varied TTLs, got %v", seen)
+	}
+
+	// The band is capped so TTLs never reach zero
+	t.Setenv("CACHE_TTL_JITTER_PERCENT", "100")
+	cache = NewPostCache(client)
+	cache.random = func() float64 { return 0 }
+	if err := cache.SetPost(&domain.Post{ID: "post_min"}); err != nil {
+		t.Fatalf("SetPost() error = %v", err)
+	}
+	if ttl := client.ttls["post:post_min"]; ttl != 50*time.Second {
+		t.Errorf("Expected the shortest TTL to be 50s, got %v", ttl)
+	}
+}
+
+// TestPostCache_Timeline tests that a user's timeline is cached under its
+// own key with the timeline TTL, and that a zero TTL disables it
+func TestPostCache_Timeline(t *testing.T) {
+	t.Setenv("TIMELINE_CACHE_TTL", "20s")
+	t.Setenv("CACHE_TTL_JITTER_PERCENT", "0")
+	client := NewMockRedisClient()
+	cache := NewPostCache(client)
+
+	if _, _, err := cache.GetTimeline("user_1"); err != ErrCacheMiss {
+		t.Fatalf("GetTimeline() error = %v, want %v", err, ErrCacheMiss)
+	}
+
+	posts := []*domain.PostWithUser{{Post: domain.Post{ID: "post_1", UserID: "user_2"}}}
+	if err := cache.SetTimeline("user_1", posts, 7); err != nil {
+		t.Fatalf("SetTimeline() error = %v", err)
+	}
+	if ttl := client.ttls["timeline:user_1"]; ttl != 20*time.Second {
+		t.Errorf("Expected timeline TTL of 20s, got %v", ttl)
+	}
+
+	cached, total, err := cache.GetTimeline("user_1")
+	if err != nil {
+		t.Fatalf("GetTimeline() error = %v", err)
+	}
+	if len(cached) != 1 || cached[0].ID != "post_1" || total != 7 {
+		t.Errorf("GetTimeline() = %d posts of %d, want post_1 of 7", len(cached), total)
+	}
+	if _, _, err := cache.GetTimeline("user_2"); err != ErrCacheMiss {
+		t.Errorf("Expected another user's timeline to miss, got %v", err)
+	}
+
+	if err := cache.InvalidateTimeline("user_1"); err != nil {
+		t.Fatalf("InvalidateTimeline() error = %v", err)
+	}
+	if _, _, err := cache.GetTimeline("user_1"); err != ErrCacheMiss {
+		t.Errorf("Expected a miss after invalidation, got %v", err)
+	}
+
+	t.Setenv("TIMELINE_CACHE_TTL", "0")
+	cache = NewPostCache(client)
+	cache.SetTimeline("user_3", posts, 1)
+	if _, ok := client.data["timeline:user_3"]; ok {
+		t.Errorf("Expected no timeline to be cached with a zero TTL")
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"strings"
 	"time"
 
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
 	"github.com/go-redis/redis/v8"
 )
@@ -15,21 +18,62 @@ import (
 // ErrCacheMiss is returned when a key is not found in the cache
 var ErrCacheMiss = errors.New("cache miss")
 
+// ErrRedisAuth is returned when Redis rejects the configured credentials
+var ErrRedisAuth = errors.New("redis authentication failed")
+
+// redisPinger is the part of the Redis client used to verify a connection
+type redisPinger interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
 // RedisClientInterface defines the interface for Redis client operations
 type RedisClientInterface interface {
 	Get(key string) ([]byte, error)
 	Set(key string, value []byte, expiration time.Duration) error
-	Delete(key string) error
+	SetIfNewer(key string, value []byte, version int64, expiration time.Duration) (bool, error)
+	DeleteIfNewer(key string, version int64, expiration time.Duration) error
+	Delete(keys ...string) error
+	PushCapped(key string, value []byte, max int64) error
+	Range(key string, start, stop int64) ([][]byte, error)
 	Exists(key string) (bool, error)
 	Ping() error
 	Close() error
 	FlushDB() error
 }
 
+// Redis deployment modes, selected with REDIS_MODE
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// RedisOptions describes the Redis deployment to connect to
+type RedisOptions struct {
+	// Mode is standalone (default), sentinel or cluster
+	Mode string
+	// Addrs is the server address in standalone mode, the sentinel addresses
+	// in sentinel mode and the seed nodes in cluster mode
+	Addrs []string
+	// MasterName is the name of the master monitored by the sentinels
+	MasterName string
+	Password   string
+	// DB is the database number; cluster mode only supports database 0
+	DB int
+}
+
+// Redis client constructors, replaced in tests to observe which one is used
+var (
+	newStandaloneClient = redis.NewClient
+	newFailoverClient   = redis.NewFailoverClient
+	newClusterClient    = redis.NewClusterClient
+)
+
 // RedisClient represents a Redis client
 type RedisClient struct {
-	client *redis.Client
-	ctx    context.Context
+	client  redis.UniversalClient
+	ctx     context.Context
+	cluster bool
 }
 
 // NewRedisStub creates a new Redis stub for testing
@@ -41,31 +85,104 @@ func NewRedisStub() *RedisClient {
 	}
 }
 
-// NewRedisClient creates a new Redis client
+// NewRedisClient creates a new Redis client for a standalone server
 func NewRedisClient(addr, password string, db int) (*RedisClient, error) {
-	log.Printf("Connecting to Redis at %s (DB: %d)", addr, db)
-	
-	// Create a new Redis client
-	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
+	return NewRedisClientWithOptions(RedisOptions{
+		Mode:     RedisModeStandalone,
+		Addrs:    []string{addr},
 		Password: password,
 		DB:       db,
 	})
+}
+
+// NewRedisClientWithOptions creates a new Redis client for a standalone,
+// sentinel or cluster deployment
+func NewRedisClientWithOptions(opts RedisOptions) (*RedisClient, error) {
+	addrs := strings.Join(opts.Addrs, ",")
+	log.Printf("Connecting to Redis (%s) at %s (DB: %d)", opts.Mode, addrs, opts.DB)
+	
+	// Create a new Redis client
+	client, err := newUniversalClient(opts)
+	if err != nil {
+		return nil, err
+	}
 	
 	// Create a context for Redis operations
 	ctx := context.Background()
 	
 	// Ping Redis to verify the connection
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	if err := pingRedis(ctx, client, addrs); err != nil {
+		client.Close()
+		return nil, err
 	}
 	
 	return &RedisClient{
-		client: client,
-		ctx:    ctx,
+		client:  client,
+		ctx:     ctx,
+		cluster: opts.Mode == RedisModeCluster,
 	}, nil
 }
 
+// newUniversalClient builds the go-redis client for the deployment mode
+func newUniversalClient(opts RedisOptions) (redis.UniversalClient, error) {
+	if len(opts.Addrs) == 0 {
+		return nil, fmt.Errorf("no Redis addresses configured")
+	}
+	
+	switch opts.Mode {
+	case "", RedisModeStandalone:
+		return newStandaloneClient(&redis.Options{
+			Addr:     opts.Addrs[0],
+			Password: opts.Password,
+			DB:       opts.DB,
+		}), nil
+	case RedisModeSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("sentinel mode requires a master name")
+		}
+		return newFailoverClient(&redis.FailoverOptions{
+			MasterName:    opts.MasterName,
+			SentinelAddrs: opts.Addrs,
+			Password:      opts.Password,
+			DB:            opts.DB,
+		}), nil
+	case RedisModeCluster:
+		if opts.DB != 0 {
+			return nil, fmt.Errorf("cluster mode only supports DB 0, got %d", opts.DB)
+		}
+		return newClusterClient(&redis.ClusterOptions{
+			Addrs:    opts.Addrs,
+			Password: opts.Password,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown Redis mode %q", opts.Mode)
+	}
+}
+
+// pingRedis pings Redis, telling authentication failures apart from
+// connectivity failures so a wrong password is easy to diagnose
+func pingRedis(ctx context.Context, client redisPinger, addr string) error {
+	err := client.Ping(ctx).Err()
+	if err == nil {
+		return nil
+	}
+
+	if isRedisAuthError(err) {
+		return fmt.Errorf("%w at %s, check REDIS_PASSWORD: %v", ErrRedisAuth, addr, err)
+	}
+
+	return fmt.Errorf("failed to ping Redis at %s: %w", addr, err)
+}
+
+// isRedisAuthError reports whether err is a Redis reply rejecting authentication
+func isRedisAuthError(err error) bool {
+	message := err.Error()
+	return strings.HasPrefix(message, "NOAUTH") ||
+		strings.HasPrefix(message, "WRONGPASS") ||
+		strings.HasPrefix(message, "ERR invalid password") ||
+		strings.HasPrefix(message, "ERR AUTH")
+}
+
 // Get retrieves a value from Redis
 func (r *RedisClient) Get(key string) ([]byte, error) {
 	if r.client == nil {
@@ -97,20 +214,129 @@ func (r *RedisClient) Set(key string, value []byte, expiration time.Duration) er
 	return nil
 }
 
-// Delete removes a key from Redis
-func (r *RedisClient) Delete(key string) error {
+// setIfNewerScript stores a value only if its version is at least the version
+// stored alongside it, so a late write of an older snapshot is discarded
+var setIfNewerScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[2]) or "0")
+if tonumber(ARGV[1]) < current then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+redis.call("SET", KEYS[2], ARGV[1], "PX", ARGV[3])
+return 1
+`)
+
+// SetIfNewer atomically stores a value unless a newer version of it is
+// already stored, reporting whether the value was written. The version is
+// kept under "{" + key + "}:version", which hashes to the same cluster slot
+// as the key so the script can touch both.
+func (r *RedisClient) SetIfNewer(key string, value []byte, version int64, expiration time.Duration) (bool, error) {
 	if r.client == nil {
 		// Stub implementation does nothing
-		return nil
+		return true, nil
 	}
 	
-	err := r.client.Del(r.ctx, key).Err()
+	keys := []string{key, "{" + key + "}:version"}
+	written, err := setIfNewerScript.Run(r.ctx, r.client, keys, version, value, expiration.Milliseconds()).Int()
 	if err != nil {
+		return false, fmt.Errorf("error setting key %s in Redis: %w", key, err)
+	}
+	return written == 1, nil
+}
+
+// deleteIfNewerScript deletes a value and raises the version stored
+// alongside it, so a snapshot read before the delete can't be stored after it
+var deleteIfNewerScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[2]) or "0")
+redis.call("DEL", KEYS[1])
+if tonumber(ARGV[1]) > current then
+	redis.call("SET", KEYS[2], ARGV[1], "PX", ARGV[2])
+end
+return 1
+`)
+
+// DeleteIfNewer atomically deletes a value stored by SetIfNewer and raises
+// its version to version, unless it is already newer. The version is kept
+// for expiration, during which SetIfNewer rejects older versions of the
+// value.
+func (r *RedisClient) DeleteIfNewer(key string, version int64, expiration time.Duration) error {
+	if r.client == nil {
+		// Stub implementation does nothing
+		return nil
+	}
+	
+	keys := []string{key, "{" + key + "}:version"}
+	if err := deleteIfNewerScript.Run(r.ctx, r.client, keys, version, expiration.Milliseconds()).Err(); err != nil {
 		return fmt.Errorf("error deleting key %s from Redis: %w", key, err)
 	}
 	return nil
 }
 
+// Delete removes keys from Redis in a single DEL command. A cluster cannot
+// delete keys from different slots in one command, so there the deletes are
+// pipelined instead.
+func (r *RedisClient) Delete(keys ...string) error {
+	if r.client == nil || len(keys) == 0 {
+		// Stub implementation does nothing
+		return nil
+	}
+	
+	var err error
+	if r.cluster {
+		_, err = r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Del(r.ctx, key)
+			}
+			return nil
+		})
+	} else {
+		err = r.client.Del(r.ctx, keys...).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting keys %v from Redis: %w", keys, err)
+	}
+	return nil
+}
+
+// PushCapped prepends a value to the list at key and trims the list to its
+// newest max entries in one transaction
+func (r *RedisClient) PushCapped(key string, value []byte, max int64) error {
+	if r.client == nil {
+		// Stub implementation does nothing
+		return nil
+	}
+	
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(r.ctx, key, value)
+		pipe.LTrim(r.ctx, key, 0, max-1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error pushing to list %s in Redis: %w", key, err)
+	}
+	return nil
+}
+
+// Range retrieves the entries of the list at key between start and stop,
+// inclusive; negative indexes count from the end of the list
+func (r *RedisClient) Range(key string, start, stop int64) ([][]byte, error) {
+	if r.client == nil {
+		// Stub implementation always returns an empty list
+		return [][]byte{}, nil
+	}
+	
+	values, err := r.client.LRange(r.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading list %s from Redis: %w", key, err)
+	}
+	
+	entries := make([][]byte, len(values))
+	for i, value := range values {
+		entries[i] = []byte(value)
+	}
+	return entries, nil
+}
+
 // Exists checks if a key exists in Redis
 func (r *RedisClient) Exists(key string) (bool, error) {
 	if r.client == nil {
@@ -153,96 +379,237 @@ func (r *RedisClient) FlushDB() error {
 		return nil
 	}
 	
-	err := r.client.FlushDB(r.ctx).Err()
+	var err error
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		// Every master holds part of the keyspace
+		err = cluster.ForEachMaster(r.ctx, func(ctx context.Context, master *redis.Client) error {
+			return master.FlushDB(ctx).Err()
+		})
+	} else {
+		err = r.client.FlushDB(r.ctx).Err()
+	}
 	if err != nil {
 		return fmt.Errorf("error flushing Redis database: %w", err)
 	}
 	return nil
 }
 
+// postsWithUserKey is the cache key holding the latest page of posts with user information
+const postsWithUserKey = "posts_with_user"
+
+// postsWithUserStaleKey holds a long-lived copy of the latest page of posts,
+// served only as a last resort when the database is unavailable
+const postsWithUserStaleKey = "posts_with_user:stale"
+
+// postsSnapshot is the cached latest page of posts together with the total
+// number of posts when it was read (-1 if unknown), stored as one value so
+// the two always match
+type postsSnapshot struct {
+	Posts []*domain.PostWithUser `json:"posts"`
+	Total int                    `json:"total"`
+}
+
+// defaultStaleTTL is how long the stale copy of the posts is kept
+const defaultStaleTTL = 24 * time.Hour
+
+// defaultListMaxItems is the most posts kept in the cached list snapshot
+const defaultListMaxItems = 100
+
+// defaultListTTL is how long the list snapshot of posts is cached
+const defaultListTTL = 5 * time.Minute
+
+// defaultPostTTL is how long a single post is cached
+const defaultPostTTL = 5 * time.Minute
+
+// defaultTimelineTTL is how long the first page of a user's timeline is
+// cached
+const defaultTimelineTTL = time.Minute
+
+// defaultTTLJitterPercent is how far, in percent, cache TTLs are randomly
+// moved either way by default
+const defaultTTLJitterPercent = 10
+
+// maxTTLJitterPercent keeps jittered TTLs well above zero, which Redis would
+// treat as no expiry
+const maxTTLJitterPercent = 50
+
 // PostCache implements caching for posts
 type PostCache struct {
-	client RedisClientInterface
+	client       RedisClientInterface
+	staleTTL     time.Duration
+	listMaxItems int
+	listTTL      time.Duration
+	postTTL      time.Duration
+	timelineTTL  time.Duration
+	// ttlJitter is the fraction by which the list and post TTLs are randomly
+	// lengthened or shortened, so keys written together don't all expire at
+	// once
+	ttlJitter float64
+	random    func() float64
 }
 
-// NewPostCache creates a new post cache
+// NewPostCache creates a new post cache. The stale copy of the posts is kept
+// for STALE_CACHE_TTL_MS (default 24h, 0 disables it), and the cached list
+// snapshot holds at most CACHE_LIST_MAX_ITEMS posts (default 100, 0 means no
+// limit). Single posts are cached for POST_CACHE_TTL (default 5m), which
+// can be shorter than the list snapshot's for frequently edited posts; as
+// posts must expire, values <= 0 fall back to the default. The first
+// page of each user's timeline is cached for TIMELINE_CACHE_TTL (default 1m,
+// 0 disables it). The list, post and timeline TTLs vary randomly by up to
+// CACHE_TTL_JITTER_PERCENT (default 10, at most 50) either way to avoid
+// synchronized expiry.
 func NewPostCache(client RedisClientInterface) *PostCache {
+	jitter := config.GetEnvFloat("CACHE_TTL_JITTER_PERCENT", defaultTTLJitterPercent)
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > maxTTLJitterPercent {
+		jitter = maxTTLJitterPercent
+	}
+
+	postTTL := config.GetEnvDuration("POST_CACHE_TTL", defaultPostTTL)
+	if postTTL <= 0 {
+		log.Printf("Ignoring POST_CACHE_TTL=%v, posts must expire; using %v", postTTL, defaultPostTTL)
+		postTTL = defaultPostTTL
+	}
+
 	return &PostCache{
-		client: client,
+		client:       client,
+		staleTTL:     config.GetEnvMillis("STALE_CACHE_TTL_MS", defaultStaleTTL),
+		listMaxItems: config.GetEnvInt("CACHE_LIST_MAX_ITEMS", defaultListMaxItems),
+		listTTL:      defaultListTTL,
+		postTTL:      postTTL,
+		timelineTTL:  config.GetEnvDuration("TIMELINE_CACHE_TTL", defaultTimelineTTL),
+		ttlJitter:    jitter / 100,
+		random:       rand.Float64,
 	}
 }
 
-// GetPosts retrieves posts from the cache
-func (c *PostCache) GetPosts() ([]*domain.Post, error) {
-	// Get posts from Redis
-	data, err := c.client.Get("posts")
-	if err != nil {
-		return nil, err
+// jittered returns ttl moved randomly by up to the jitter fraction either way
+func (c *PostCache) jittered(ttl time.Duration) time.Duration {
+	if c.ttlJitter <= 0 || ttl <= 0 {
+		return ttl
 	}
-	
-	// Unmarshal posts
-	var posts []*domain.Post
-	err = json.Unmarshal(data, &posts)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling posts: %w", err)
+	factor := 1 + c.ttlJitter*(2*c.random()-1)
+	return time.Duration(float64(ttl) * factor)
+}
+
+// GetPostsWithUser retrieves posts with user information from the cache
+func (c *PostCache) GetPostsWithUser() ([]*domain.PostWithUser, error) {
+	return c.getPostsWithUser(postsWithUserKey)
+}
+
+// GetStalePostsWithUser retrieves the long-lived copy of the posts, which
+// survives invalidation and may be out of date
+func (c *PostCache) GetStalePostsWithUser() ([]*domain.PostWithUser, error) {
+	if c.staleTTL <= 0 {
+		return nil, ErrCacheMiss
 	}
-	
-	return posts, nil
+	return c.getPostsWithUser(postsWithUserStaleKey)
 }
 
-// SetPosts stores posts in the cache
-func (c *PostCache) SetPosts(posts []*domain.Post) error {
-	// Marshal posts
-	data, err := json.Marshal(posts)
+// getPostsWithUser retrieves posts with user information stored under key
+func (c *PostCache) getPostsWithUser(key string) ([]*domain.PostWithUser, error) {
+	snapshot, err := c.getPostsSnapshot(key)
 	if err != nil {
-		return fmt.Errorf("error marshaling posts: %w", err)
+		return nil, err
 	}
-	
-	// Set posts in Redis
-	return c.client.Set("posts", data, 5*time.Minute)
+	return snapshot.Posts, nil
 }
 
-// GetPostsWithUser retrieves posts with user information from the cache
-func (c *PostCache) GetPostsWithUser() ([]*domain.PostWithUser, error) {
+// getPostsSnapshot retrieves the posts snapshot stored under key
+func (c *PostCache) getPostsSnapshot(key string) (*postsSnapshot, error) {
 	// Get posts from Redis
-	data, err := c.client.Get("posts_with_user")
+	data, err := c.client.Get(key)
 	if err != nil {
 		return nil, err
 	}
 	
 	// Unmarshal posts
-	var posts []*domain.PostWithUser
-	err = json.Unmarshal(data, &posts)
+	var snapshot postsSnapshot
+	err = json.Unmarshal(data, &snapshot)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshaling posts with user: %w", err)
 	}
 	
-	return posts, nil
+	return &snapshot, nil
 }
 
 // SetPostsWithUser stores posts with user information in the cache
 func (c *PostCache) SetPostsWithUser(posts []*domain.PostWithUser) error {
+	return c.SetPostsWithUserAt(posts, time.Now())
+}
+
+// SetPostsWithUserAt stores posts with user information read from the
+// database at readAt, unless a snapshot read later has already been stored.
+// Concurrent cache fills can finish out of order; this keeps the newest.
+// Only the newest CACHE_LIST_MAX_ITEMS posts are stored.
+func (c *PostCache) SetPostsWithUserAt(posts []*domain.PostWithUser, readAt time.Time) error {
+	return c.setPostsSnapshot(posts, -1, readAt)
+}
+
+// setPostsSnapshot stores posts and the total (-1 if unknown) read at readAt
+// as one value, unless a snapshot read later has already been stored
+func (c *PostCache) setPostsSnapshot(posts []*domain.PostWithUser, total int, readAt time.Time) error {
+	// Bound the snapshot so a large page can't bloat Redis
+	if c.listMaxItems > 0 && len(posts) > c.listMaxItems {
+		posts = posts[:c.listMaxItems]
+	}
+
 	// Marshal posts
-	data, err := json.Marshal(posts)
+	data, err := json.Marshal(postsSnapshot{Posts: posts, Total: total})
 	if err != nil {
 		return fmt.Errorf("error marshaling posts with user: %w", err)
 	}
 	
 	// Set posts in Redis
-	return c.client.Set("posts_with_user", data, 5*time.Minute)
+	written, err := c.client.SetIfNewer(postsWithUserKey, data, readAt.UnixNano(), c.jittered(c.listTTL))
+	if err != nil {
+		return err
+	}
+	if !written {
+		return nil
+	}
+
+	// Keep a stale copy to fall back on if the database goes down
+	if c.staleTTL > 0 {
+		return c.client.Set(postsWithUserStaleKey, data, c.staleTTL)
+	}
+	return nil
 }
 
-// InvalidatePosts invalidates the posts cache
+// GetPostsTotal retrieves the total number of posts stored with the cached
+// snapshot. Once the snapshot is invalidated, it is the last known good total
+// kept with the stale copy, which may be out of date.
+func (c *PostCache) GetPostsTotal() (int, error) {
+	snapshot, err := c.getPostsSnapshot(postsWithUserKey)
+	if err == ErrCacheMiss && c.staleTTL > 0 {
+		snapshot, err = c.getPostsSnapshot(postsWithUserStaleKey)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if snapshot.Total < 0 {
+		return 0, ErrCacheMiss
+	}
+	return snapshot.Total, nil
+}
+
+// SetPostsWithUserAndTotalAt stores the first page of posts read at readAt
+// together with the true total, so readers can tell whether the snapshot,
+// which may have been truncated, holds every post
+func (c *PostCache) SetPostsWithUserAndTotalAt(posts []*domain.PostWithUser, total int, readAt time.Time) error {
+	return c.setPostsSnapshot(posts, total, readAt)
+}
+
+// InvalidatePosts invalidates the posts cache. Snapshots read before the
+// invalidation are rejected if they are written after it, as they may miss
+// the change that invalidated the cache.
 func (c *PostCache) InvalidatePosts() error {
 	// Delete posts from Redis
-	err1 := c.client.Delete("posts")
-	err2 := c.client.Delete("posts_with_user")
-	
-	if err1 != nil {
-		return fmt.Errorf("error deleting posts cache: %w", err1)
-	}
-	if err2 != nil {
-		return fmt.Errorf("error deleting posts with user cache: %w", err2)
+	err := c.client.DeleteIfNewer(postsWithUserKey, time.Now().UnixNano(), c.listTTL)
+	if err != nil {
+		return fmt.Errorf("error deleting posts with user cache: %w", err)
 	}
 	
 	return nil
@@ -277,7 +644,7 @@ func (c *PostCache) SetPost(post *domain.Post) error {
 	}
 	
 	// Set post in Redis
-	return c.client.Set(key, data, 5*time.Minute)
+	return c.client.Set(key, data, c.jittered(c.postTTL))
 }
 
 // InvalidatePost invalidates a post in the cache
@@ -292,6 +659,75 @@ func (c *PostCache) InvalidatePost(id string) error {
 	return nil
 }
 
+// InvalidatePostsByIDs invalidates the cached entries of many posts with a
+// single delete, for use after bulk changes
+func (c *PostCache) InvalidatePostsByIDs(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = fmt.Sprintf("post:%s", id)
+	}
+
+	if err := c.client.Delete(keys...); err != nil {
+		return fmt.Errorf("error deleting post caches: %w", err)
+	}
+
+	return nil
+}
+
+// cachedTimeline is the first page of a user's timeline and the total
+// number of posts in it
+type cachedTimeline struct {
+	Posts []*domain.PostWithUser `json:"posts"`
+	Total int                    `json:"total"`
+}
+
+// timelineKey returns the key of a user's cached timeline
+func timelineKey(userID string) string {
+	return fmt.Sprintf("timeline:%s", userID)
+}
+
+// GetTimeline retrieves the first page of a user's timeline and its total
+func (c *PostCache) GetTimeline(userID string) ([]*domain.PostWithUser, int, error) {
+	if c.timelineTTL <= 0 {
+		return nil, 0, ErrCacheMiss
+	}
+	data, err := c.client.Get(timelineKey(userID))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var timeline cachedTimeline
+	if err := json.Unmarshal(data, &timeline); err != nil {
+		return nil, 0, fmt.Errorf("error unmarshaling timeline: %w", err)
+	}
+	return timeline.Posts, timeline.Total, nil
+}
+
+// SetTimeline stores the first page of a user's timeline and its total
+func (c *PostCache) SetTimeline(userID string, posts []*domain.PostWithUser, total int) error {
+	if c.timelineTTL <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(cachedTimeline{Posts: posts, Total: total})
+	if err != nil {
+		return fmt.Errorf("error marshaling timeline: %w", err)
+	}
+	return c.client.Set(timelineKey(userID), data, c.jittered(c.timelineTTL))
+}
+
+// InvalidateTimeline invalidates a user's cached timeline, after they
+// follow or unfollow someone
+func (c *PostCache) InvalidateTimeline(userID string) error {
+	if err := c.client.Delete(timelineKey(userID)); err != nil {
+		return fmt.Errorf("error deleting timeline cache: %w", err)
+	}
+	return nil
+}
+
 // Ping checks if the cache connection is alive
 func (c *PostCache) Ping() error {
 	return c.client.Ping()
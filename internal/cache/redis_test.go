@@ -1,8 +1,13 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 // Using the MockRedisClient from post_cache_test.go
@@ -45,6 +50,156 @@ func TestNewRedisClient(t *testing.T) {
 	}
 }
 
+// fakeRedisPinger is a Redis pinger that returns a fixed error
+type fakeRedisPinger struct {
+	err error
+}
+
+func (f *fakeRedisPinger) Ping(ctx context.Context) *redis.StatusCmd {
+	return redis.NewStatusResult("PONG", f.err)
+}
+
+func TestPingRedis(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		expectAuth  bool
+		expectError bool
+	}{
+		{
+			name: "success",
+			err:  nil,
+		},
+		{
+			name:        "NOAUTH",
+			err:         errors.New("NOAUTH Authentication required."),
+			expectAuth:  true,
+			expectError: true,
+		},
+		{
+			name:        "WRONGPASS",
+			err:         errors.New("WRONGPASS invalid username-password pair or user is disabled."),
+			expectAuth:  true,
+			expectError: true,
+		},
+		{
+			name:        "connectivity failure",
+			err:         errors.New("dial tcp 127.0.0.1:6379: connect: connection refused"),
+			expectAuth:  false,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Test
+			err := pingRedis(context.Background(), &fakeRedisPinger{err: tc.err}, "localhost:6379")
+
+			// Assert
+			if (err != nil) != tc.expectError {
+				t.Fatalf("pingRedis() error = %v, expectError %v", err, tc.expectError)
+			}
+			if err == nil {
+				return
+			}
+			if errors.Is(err, ErrRedisAuth) != tc.expectAuth {
+				t.Errorf("errors.Is(err, ErrRedisAuth) = %v, want %v", errors.Is(err, ErrRedisAuth), tc.expectAuth)
+			}
+			if tc.expectAuth && !strings.Contains(err.Error(), "check REDIS_PASSWORD") {
+				t.Errorf("Expected actionable message, got %q", err.Error())
+			}
+			if !tc.expectAuth && !errors.Is(err, tc.err) {
+				t.Errorf("Expected connectivity error to wrap %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestNewUniversalClient(t *testing.T) {
+	// Record which constructor is used instead of connecting
+	var constructed string
+	var standalone *redis.Options
+	var failover *redis.FailoverOptions
+	var cluster *redis.ClusterOptions
+	origStandalone, origFailover, origCluster := newStandaloneClient, newFailoverClient, newClusterClient
+	defer func() {
+		newStandaloneClient, newFailoverClient, newClusterClient = origStandalone, origFailover, origCluster
+	}()
+	newStandaloneClient = func(opts *redis.Options) *redis.Client {
+		constructed, standalone = RedisModeStandalone, opts
+		return &redis.Client{}
+	}
+	newFailoverClient = func(opts *redis.FailoverOptions) *redis.Client {
+		constructed, failover = RedisModeSentinel, opts
+		return &redis.Client{}
+	}
+	newClusterClient = func(opts *redis.ClusterOptions) *redis.ClusterClient {
+		constructed, cluster = RedisModeCluster, opts
+		return &redis.ClusterClient{}
+	}
+
+	addrs := []string{"redis-1:6379", "redis-2:6379"}
+	testCases := []struct {
+		name        string
+		opts        RedisOptions
+		expected    string
+		expectError bool
+	}{
+		{name: "default", opts: RedisOptions{Addrs: addrs, DB: 2}, expected: RedisModeStandalone},
+		{name: "standalone", opts: RedisOptions{Mode: RedisModeStandalone, Addrs: addrs, DB: 2}, expected: RedisModeStandalone},
+		{name: "sentinel", opts: RedisOptions{Mode: RedisModeSentinel, Addrs: addrs, MasterName: "primary", DB: 2}, expected: RedisModeSentinel},
+		{name: "cluster", opts: RedisOptions{Mode: RedisModeCluster, Addrs: addrs}, expected: RedisModeCluster},
+		{name: "sentinel without master", opts: RedisOptions{Mode: RedisModeSentinel, Addrs: addrs}, expectError: true},
+		{name: "cluster with DB", opts: RedisOptions{Mode: RedisModeCluster, Addrs: addrs, DB: 2}, expectError: true},
+		{name: "no addresses", opts: RedisOptions{Mode: RedisModeStandalone}, expectError: true},
+		{name: "unknown mode", opts: RedisOptions{Mode: "ring", Addrs: addrs}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			constructed = ""
+
+			// Test
+			client, err := newUniversalClient(tc.opts)
+
+			// Assert
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("newUniversalClient() expected error, got client %T", client)
+				}
+				if constructed != "" {
+					t.Errorf("Expected no client to be constructed, got %s", constructed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newUniversalClient() returned error: %v", err)
+			}
+			if constructed != tc.expected {
+				t.Fatalf("Expected %s client, got %s", tc.expected, constructed)
+			}
+
+			switch constructed {
+			case RedisModeStandalone:
+				if standalone.Addr != addrs[0] || standalone.DB != 2 {
+					t.Errorf("Unexpected standalone options: %+v", standalone)
+				}
+			case RedisModeSentinel:
+				if failover.MasterName != "primary" || len(failover.SentinelAddrs) != 2 || failover.DB != 2 {
+					t.Errorf("Unexpected sentinel options: %+v", failover)
+				}
+			case RedisModeCluster:
+				if _, ok := client.(*redis.ClusterClient); !ok {
+					t.Errorf("Expected *redis.ClusterClient, got %T", client)
+				}
+				if len(cluster.Addrs) != 2 {
+					t.Errorf("Unexpected cluster options: %+v", cluster)
+				}
+			}
+		})
+	}
+}
+
 func TestMockRedisClientMethods(t *testing.T) {
 	// Setup
 	client := NewMockRedisClient()
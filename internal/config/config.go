@@ -9,16 +9,28 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Cache    CacheConfig    `json:"cache"`
+	Server   ServerConfig    `json:"server"`
+	Database DatabaseConfig  `json:"database"`
+	Cache    CacheConfig     `json:"cache"`
+	Auth     AuthCredentials `json:"auth"`
 }
 
 // ServerConfig represents the server configuration
 type ServerConfig struct {
-	Port    int    `json:"port"`
-	Host    string `json:"host"`
-	BaseURL string `json:"base_url"`
+	Port           int    `json:"port"`
+	Host           string `json:"host"`
+	BaseURL        string `json:"base_url"`
+	MaxHeaderBytes int    `json:"max_header_bytes"`
+	// RateLimitRPS is the requests per second allowed per client IP on the
+	// posts API (0 disables the limit)
+	RateLimitRPS float64 `json:"rate_limit_rps"`
+	// RateLimitBurst is the burst of requests allowed above the rate (0 uses
+	// the rate rounded up)
+	RateLimitBurst int `json:"rate_limit_burst"`
+	// TrustedProxies is a comma-separated list of the IPs or CIDR ranges of
+	// the proxies whose X-Forwarded-For is trusted by the rate limit; it is
+	// ignored from any other peer
+	TrustedProxies string `json:"trusted_proxies"`
 }
 
 // DatabaseConfig represents the database configuration
@@ -40,13 +52,21 @@ type CacheConfig struct {
 	DB       int    `json:"db"`
 }
 
+// AuthCredentials represents the authentication configuration
+type AuthCredentials struct {
+	// JWTSecret signs the bearer tokens issued at login; token login is
+	// disabled when it is empty
+	JWTSecret string `json:"jwt_secret"`
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:    8080,
-			Host:    "0.0.0.0",
-			BaseURL: "http://localhost:8080",
+			Port:           8080,
+			Host:           "0.0.0.0",
+			BaseURL:        "http://localhost:8080",
+			MaxHeaderBytes: 1 << 20,
 		},
 		Database: DatabaseConfig{
 			Host:     "localhost",
@@ -105,6 +125,10 @@ func LoadConfigFromEnv() *Config {
 	if baseURL := os.Getenv("TT_SERVER_BASE_URL"); baseURL != "" {
 		config.Server.BaseURL = baseURL
 	}
+	config.Server.MaxHeaderBytes = GetEnvInt("TT_SERVER_MAX_HEADER_BYTES", config.Server.MaxHeaderBytes)
+	config.Server.RateLimitRPS = GetEnvFloat("RATE_LIMIT_RPS", config.Server.RateLimitRPS)
+	config.Server.RateLimitBurst = GetEnvInt("RATE_LIMIT_BURST", config.Server.RateLimitBurst)
+	config.Server.TrustedProxies = GetEnv("TRUSTED_PROXIES", config.Server.TrustedProxies)
 
 	// Database config
 	if host := os.Getenv("TT_DB_HOST"); host != "" {
@@ -143,5 +167,10 @@ func LoadConfigFromEnv() *Config {
 		fmt.Sscanf(db, "%d", &config.Cache.DB)
 	}
 
+	// Auth config
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		config.Auth.JWTSecret = secret
+	}
+
 	return config
 }
@@ -20,6 +20,9 @@ func TestDefaultConfig(t *testing.T) {
 	if config.Server.BaseURL != "http://localhost:8080" {
 		t.Errorf("Default server base URL = %s, want %s", config.Server.BaseURL, "http://localhost:8080")
 	}
+	if config.Server.MaxHeaderBytes != 1<<20 {
+		t.Errorf("Default server max header bytes = %d, want %d", config.Server.MaxHeaderBytes, 1<<20)
+	}
 
 	// Verify default database config
 	if config.Database.Host != "localhost" {
@@ -219,9 +222,11 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	// Save original environment variables
 	origEnv := make(map[string]string)
 	envVars := []string{
-		"TT_SERVER_PORT", "TT_SERVER_HOST", "TT_SERVER_BASE_URL",
+		"TT_SERVER_PORT", "TT_SERVER_HOST", "TT_SERVER_BASE_URL", "TT_SERVER_MAX_HEADER_BYTES",
+		"RATE_LIMIT_RPS", "RATE_LIMIT_BURST",
 		"TT_DB_HOST", "TT_DB_PORT", "TT_DB_USER", "TT_DB_PASSWORD", "TT_DB_NAME", "TT_DB_SSL_MODE",
 		"TT_CACHE_ENABLED", "TT_CACHE_HOST", "TT_CACHE_PORT", "TT_CACHE_PASSWORD", "TT_CACHE_DB",
+		"JWT_SECRET",
 	}
 	for _, env := range envVars {
 		origEnv[env] = os.Getenv(env)
@@ -247,6 +252,9 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	os.Setenv("TT_SERVER_PORT", "9090")
 	os.Setenv("TT_SERVER_HOST", "127.0.0.1")
 	os.Setenv("TT_SERVER_BASE_URL", "http://example.com")
+	os.Setenv("TT_SERVER_MAX_HEADER_BYTES", "8192")
+	os.Setenv("RATE_LIMIT_RPS", "2.5")
+	os.Setenv("RATE_LIMIT_BURST", "5")
 	os.Setenv("TT_DB_HOST", "db.example.com")
 	os.Setenv("TT_DB_PORT", "5433")
 	os.Setenv("TT_DB_USER", "testuser")
@@ -258,6 +266,7 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	os.Setenv("TT_CACHE_PORT", "6380")
 	os.Setenv("TT_CACHE_PASSWORD", "cachepass")
 	os.Setenv("TT_CACHE_DB", "1")
+	os.Setenv("JWT_SECRET", "jwtsecret")
 
 	// Test
 	config := LoadConfigFromEnv()
@@ -272,6 +281,15 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	if config.Server.BaseURL != "http://example.com" {
 		t.Errorf("Server base URL = %s, want %s", config.Server.BaseURL, "http://example.com")
 	}
+	if config.Server.MaxHeaderBytes != 8192 {
+		t.Errorf("Server max header bytes = %d, want %d", config.Server.MaxHeaderBytes, 8192)
+	}
+	if config.Server.RateLimitRPS != 2.5 {
+		t.Errorf("Server rate limit RPS = %v, want %v", config.Server.RateLimitRPS, 2.5)
+	}
+	if config.Server.RateLimitBurst != 5 {
+		t.Errorf("Server rate limit burst = %d, want %d", config.Server.RateLimitBurst, 5)
+	}
 	if config.Database.Host != "db.example.com" {
 		t.Errorf("Database host = %s, want %s", config.Database.Host, "db.example.com")
 	}
@@ -305,6 +323,9 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	if config.Cache.DB != 1 {
 		t.Errorf("Cache DB = %d, want %d", config.Cache.DB, 1)
 	}
+	if config.Auth.JWTSecret != "jwtsecret" {
+		t.Errorf("Auth JWT secret = %s, want %s", config.Auth.JWTSecret, "jwtsecret")
+	}
 
 	// Test with invalid port values
 	os.Setenv("TT_SERVER_PORT", "invalid")
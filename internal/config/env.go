@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnv retrieves an environment variable or returns a default value if not set
+func GetEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvInt retrieves an integer environment variable or returns a default value
+// if it is not set or cannot be parsed
+func GetEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvFloat retrieves a floating-point environment variable or returns a
+// default value if it is not set or cannot be parsed
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvMillis retrieves a duration given in milliseconds from an environment
+// variable or returns a default value if it is not set or cannot be parsed
+func GetEnvMillis(key string, defaultValue time.Duration) time.Duration {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return time.Duration(value) * time.Millisecond
+}
+
+// GetEnvBool retrieves a boolean environment variable or returns a default
+// value if it is not set or cannot be parsed
+func GetEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvDuration retrieves a duration such as "30s" or "5m" from an environment
+// variable or returns a default value if it is not set or cannot be parsed
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetEnv(t *testing.T) {
+	os.Setenv("TT_TEST_ENV", "value")
+	defer os.Unsetenv("TT_TEST_ENV")
+
+	if value := GetEnv("TT_TEST_ENV", "default"); value != "value" {
+		t.Errorf("GetEnv() = %s, want %s", value, "value")
+	}
+	if value := GetEnv("TT_TEST_ENV_MISSING", "default"); value != "default" {
+		t.Errorf("GetEnv() = %s, want %s", value, "default")
+	}
+}
+
+func TestGetEnvInt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected int
+	}{
+		{name: "valid integer", value: "42", expected: 42},
+		{name: "unset", value: "", expected: 7},
+		{name: "invalid integer", value: "abc", expected: 7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Setenv("TT_TEST_ENV_INT", tc.value)
+			defer os.Unsetenv("TT_TEST_ENV_INT")
+
+			if value := GetEnvInt("TT_TEST_ENV_INT", 7); value != tc.expected {
+				t.Errorf("GetEnvInt() = %d, want %d", value, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvFloat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected float64
+	}{
+		{name: "valid float", value: "0.25", expected: 0.25},
+		{name: "unset", value: "", expected: 1},
+		{name: "invalid float", value: "half", expected: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Setenv("TT_TEST_ENV_FLOAT", tc.value)
+			defer os.Unsetenv("TT_TEST_ENV_FLOAT")
+
+			if value := GetEnvFloat("TT_TEST_ENV_FLOAT", 1); value != tc.expected {
+				t.Errorf("GetEnvFloat() = %v, want %v", value, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvMillis(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "valid milliseconds", value: "250", expected: 250 * time.Millisecond},
+		{name: "zero", value: "0", expected: 0},
+		{name: "unset", value: "", expected: time.Second},
+		{name: "invalid milliseconds", value: "1s", expected: time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Setenv("TT_TEST_ENV_MILLIS", tc.value)
+			defer os.Unsetenv("TT_TEST_ENV_MILLIS")
+
+			if value := GetEnvMillis("TT_TEST_ENV_MILLIS", time.Second); value != tc.expected {
+				t.Errorf("GetEnvMillis() = %v, want %v", value, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvBool(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected bool
+	}{
+		{name: "true", value: "true", expected: true},
+		{name: "false", value: "0", expected: false},
+		{name: "unset", value: "", expected: true},
+		{name: "invalid boolean", value: "maybe", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Setenv("TT_TEST_ENV_BOOL", tc.value)
+			defer os.Unsetenv("TT_TEST_ENV_BOOL")
+
+			if value := GetEnvBool("TT_TEST_ENV_BOOL", true); value != tc.expected {
+				t.Errorf("GetEnvBool() = %v, want %v", value, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "seconds", value: "30s", expected: 30 * time.Second},
+		{name: "minutes", value: "5m", expected: 5 * time.Minute},
+		{name: "unset", value: "", expected: time.Minute},
+		{name: "missing unit", value: "30", expected: time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Setenv("TT_TEST_ENV_DURATION", tc.value)
+			defer os.Unsetenv("TT_TEST_ENV_DURATION")
+
+			if value := GetEnvDuration("TT_TEST_ENV_DURATION", time.Minute); value != tc.expected {
+				t.Errorf("GetEnvDuration() = %v, want %v", value, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"encoding/json"
+)
+
+// RedactedPlaceholder replaces secret values in redacted output
+const RedactedPlaceholder = "[REDACTED]"
+
+// SensitiveString is a secret value that never appears in JSON or logs
+type SensitiveString string
+
+// MarshalJSON encodes the placeholder instead of the secret. An empty value
+// stays empty so operators can still see that a secret is unset.
+func (s SensitiveString) MarshalJSON() ([]byte, error) {
+	if s == "" {
+		return json.Marshal("")
+	}
+	return json.Marshal(RedactedPlaceholder)
+}
+
+// String returns the placeholder instead of the secret
+func (s SensitiveString) String() string {
+	if s == "" {
+		return ""
+	}
+	return RedactedPlaceholder
+}
+
+// RedactedConfig is a view of Config that is safe to expose
+type RedactedConfig struct {
+	Server   ServerConfig            `json:"server"`
+	Database RedactedDatabaseConfig  `json:"database"`
+	Cache    RedactedCacheConfig     `json:"cache"`
+	Auth     RedactedAuthCredentials `json:"auth"`
+}
+
+// RedactedDatabaseConfig is a view of DatabaseConfig with the password redacted
+type RedactedDatabaseConfig struct {
+	DatabaseConfig
+	Password SensitiveString `json:"password"`
+}
+
+// RedactedCacheConfig is a view of CacheConfig with the password redacted
+type RedactedCacheConfig struct {
+	CacheConfig
+	Password SensitiveString `json:"password"`
+}
+
+// RedactedAuthCredentials is a view of AuthCredentials with the JWT secret
+// redacted
+type RedactedAuthCredentials struct {
+	AuthCredentials
+	JWTSecret SensitiveString `json:"jwt_secret"`
+}
+
+// Redacted returns a view of the configuration with every secret redacted
+func (c *Config) Redacted() RedactedConfig {
+	return RedactedConfig{
+		Server: c.Server,
+		Database: RedactedDatabaseConfig{
+			DatabaseConfig: c.Database,
+			Password:       SensitiveString(c.Database.Password),
+		},
+		Cache: RedactedCacheConfig{
+			CacheConfig: c.Cache,
+			Password:    SensitiveString(c.Cache.Password),
+		},
+		Auth: RedactedAuthCredentials{
+			AuthCredentials: c.Auth,
+			JWTSecret:       SensitiveString(c.Auth.JWTSecret),
+		},
+	}
+}
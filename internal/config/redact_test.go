@@ -0,0 +1,55 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	// Setup
+	config := DefaultConfig()
+	config.Database.Password = "db-secret"
+	config.Cache.Password = ""
+	config.Auth.JWTSecret = "jwt-secret"
+
+	// Test
+	data, err := json.Marshal(config.Redacted())
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	// Assert
+	if strings.Contains(string(data), "db-secret") {
+		t.Errorf("Redacted config contains the database password: %s", data)
+	}
+	if strings.Contains(string(data), "jwt-secret") {
+		t.Errorf("Redacted config contains the JWT secret: %s", data)
+	}
+
+	var redacted Config
+	if err := json.Unmarshal(data, &redacted); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if redacted.Database.Password != RedactedPlaceholder {
+		t.Errorf("Database password = %s, want %s", redacted.Database.Password, RedactedPlaceholder)
+	}
+	if redacted.Cache.Password != "" {
+		t.Errorf("Cache password = %s, want empty", redacted.Cache.Password)
+	}
+	if redacted.Database.Host != config.Database.Host {
+		t.Errorf("Database host = %s, want %s", redacted.Database.Host, config.Database.Host)
+	}
+
+	// The original config is untouched
+	if config.Database.Password != "db-secret" {
+		t.Errorf("Database password = %s, want %s", config.Database.Password, "db-secret")
+	}
+}
+
+func TestSensitiveStringString(t *testing.T) {
+	if value := fmt.Sprintf("%v", SensitiveString("secret")); value != RedactedPlaceholder {
+		t.Errorf("SensitiveString formatted as %s, want %s", value, RedactedPlaceholder)
+	}
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+func TestCommentRepository_Queries(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewCommentRepository(NewPostgresDB(mockDB))
+	createdAt := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(`INSERT INTO comments \(id, post_id, user_id, content, created_at\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`).
+		WithArgs("comment_1", "post_1", "user_1", "Nice post", createdAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id, post_id, user_id, content, created_at\s+FROM comments\s+WHERE post_id = \$1\s+ORDER BY created_at ASC, id ASC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs("post_1", 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "user_id", "content", "created_at"}).
+			AddRow("comment_1", "post_1", "user_1", "Nice post", createdAt))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM comments WHERE post_id = \$1`).
+		WithArgs("post_1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+	mock.ExpectExec(`DELETE FROM comments WHERE id = \$1`).
+		WithArgs("comment_1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM comments WHERE id = \$1`).
+		WithArgs("comment_2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Test
+	if err := repo.Create(&domain.Comment{ID: "comment_1", PostID: "post_1", UserID: "user_1", Content: "Nice post", CreatedAt: createdAt}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	comments, err := repo.ListByPost("post_1", 20, 10)
+	if err != nil {
+		t.Fatalf("ListByPost() error = %v", err)
+	}
+	if len(comments) != 1 || comments[0].ID != "comment_1" || comments[0].Content != "Nice post" {
+		t.Errorf("ListByPost() = %+v, want comment_1", comments)
+	}
+	if count, err := repo.Count("post_1"); err != nil || count != 21 {
+		t.Errorf("Count() = %d, %v, want 21", count, err)
+	}
+	if err := repo.Delete("comment_1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete("comment_2"); !errors.Is(err, domain.ErrCommentNotFound) {
+		t.Errorf("Delete() error = %v, want %v", err, domain.ErrCommentNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCommentRepository_Stub(t *testing.T) {
+	repo := NewCommentRepository(NewPostgresStub())
+	createdAt := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+
+	for i, id := range []string{"comment_3", "comment_1", "comment_2"} {
+		repo.Create(&domain.Comment{ID: id, PostID: "post_stub_1", UserID: "user_1", Content: "Comment", CreatedAt: createdAt.Add(time.Duration(2-i) * time.Minute)})
+	}
+	repo.Create(&domain.Comment{ID: "comment_4", PostID: "post_stub_2", UserID: "user_1", Content: "Comment", CreatedAt: createdAt})
+
+	comments, _ := repo.ListByPost("post_stub_1", 1, 5)
+	if len(comments) != 2 || comments[0].ID != "comment_1" || comments[1].ID != "comment_3" {
+		t.Errorf("ListByPost() = %+v, want comment_1 and comment_3, oldest first", comments)
+	}
+	if count, _ := repo.Count("post_stub_1"); count != 3 {
+		t.Errorf("Count() = %d, want 3", count)
+	}
+
+	if err := repo.Delete("comment_1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete("comment_1"); !errors.Is(err, domain.ErrCommentNotFound) {
+		t.Errorf("Delete() error = %v, want %v", err, domain.ErrCommentNotFound)
+	}
+	if count, _ := repo.Count("post_stub_1"); count != 2 {
+		t.Errorf("Count() = %d, want 2", count)
+	}
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// CommentRepository implements domain.CommentRepository on the comments
+// table. On a stub connection comments are kept in memory.
+type CommentRepository struct {
+	db *PostgresDB
+
+	// stubComments holds the comments of a stub connection
+	mu           sync.Mutex
+	stubComments []*domain.Comment
+}
+
+// NewCommentRepository creates a new comment repository
+func NewCommentRepository(db *PostgresDB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create creates a new comment
+func (r *CommentRepository) Create(comment *domain.Comment) error {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		stored := *comment
+		r.stubComments = append(r.stubComments, &stored)
+		return nil
+	}
+
+	query := "INSERT INTO comments (id, post_id, user_id, content, created_at) VALUES ($1, $2, $3, $4, $5)"
+	_, err := r.db.ExecContext(context.Background(), query, comment.ID, comment.PostID, comment.UserID, comment.Content, comment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating comment: %w", err)
+	}
+	return nil
+}
+
+// ListByPost retrieves the comments on a post with pagination, oldest first
+func (r *CommentRepository) ListByPost(postID string, offset, limit int) ([]*domain.Comment, error) {
+	if r.db.isStub() {
+		return r.listByPostStub(postID, offset, limit), nil
+	}
+	if r.db.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	query := `
+		SELECT id, post_id, user_id, content, created_at
+		FROM comments
+		WHERE post_id = $1
+		ORDER BY created_at ASC, id ASC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.ReadQueryContext(context.Background(), query, postID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make([]*domain.Comment, 0)
+	for rows.Next() {
+		var comment domain.Comment
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning comment row: %w", err)
+		}
+		comments = append(comments, &comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comment rows: %w", err)
+	}
+
+	return comments, nil
+}
+
+// listByPostStub returns a page of the stub connection's comments on a post
+func (r *CommentRepository) listByPostStub(postID string, offset, limit int) []*domain.Comment {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comments := make([]*domain.Comment, 0)
+	for _, comment := range r.stubComments {
+		if comment.PostID == postID {
+			copied := *comment
+			comments = append(comments, &copied)
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+
+	start, end := stubPage(len(comments), offset, limit)
+	return comments[start:end]
+}
+
+// Count returns the number of comments on a post
+func (r *CommentRepository) Count(postID string) (int, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		count := 0
+		for _, comment := range r.stubComments {
+			if comment.PostID == postID {
+				count++
+			}
+		}
+		return count, nil
+	}
+	if r.db.db == nil {
+		return 0, fmt.Errorf("database connection not initialized")
+	}
+
+	query := "SELECT COUNT(*) FROM comments WHERE post_id = $1"
+	var count int
+	if err := r.db.ReadQueryRowContext(context.Background(), query, postID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting comments: %w", err)
+	}
+	return count, nil
+}
+
+// Delete deletes a comment
+func (r *CommentRepository) Delete(id string) error {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, comment := range r.stubComments {
+			if comment.ID == id {
+				r.stubComments = append(r.stubComments[:i], r.stubComments[i+1:]...)
+				return nil
+			}
+		}
+		return domain.ErrCommentNotFound
+	}
+
+	result, err := r.db.ExecContext(context.Background(), "DELETE FROM comments WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting comment: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return domain.ErrCommentNotFound
+	}
+	return nil
+}
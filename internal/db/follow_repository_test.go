@@ -0,0 +1,108 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFollowRepository_Queries(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewFollowRepository(NewPostgresDB(mockDB))
+	createdAt := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+
+	// A repeated follow is absorbed by the primary key instead of failing
+	mock.ExpectExec(`INSERT INTO follows \(follower_id, followee_id, created_at\)\s+VALUES \(\$1, \$2, \$3\)\s+ON CONFLICT \(follower_id, followee_id\) DO NOTHING`).
+		WithArgs("user_1", "user_2", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM follows WHERE follower_id = \$1 AND followee_id = \$2\)`).
+		WithArgs("user_1", "user_2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT follower_id, followee_id, created_at\s+FROM follows\s+WHERE followee_id = \$1\s+ORDER BY created_at DESC, follower_id ASC, followee_id ASC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs("user_2", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"follower_id", "followee_id", "created_at"}).AddRow("user_1", "user_2", createdAt))
+	mock.ExpectQuery(`SELECT follower_id, followee_id, created_at\s+FROM follows\s+WHERE follower_id = \$1`).
+		WithArgs("user_1", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"follower_id", "followee_id", "created_at"}).AddRow("user_1", "user_2", createdAt))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM follows WHERE followee_id = \$1`).
+		WithArgs("user_2").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM follows WHERE follower_id = \$1`).
+		WithArgs("user_2").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`DELETE FROM follows WHERE follower_id = \$1 AND followee_id = \$2`).
+		WithArgs("user_1", "user_2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Test
+	if changed, err := repo.Follow("user_1", "user_2"); err != nil || changed {
+		t.Fatalf("Follow() = %v, %v, want an unchanged repeated follow", changed, err)
+	}
+	if following, err := repo.IsFollowing("user_1", "user_2"); err != nil || !following {
+		t.Errorf("IsFollowing() = %v, %v, want true", following, err)
+	}
+	if followers, err := repo.ListFollowers("user_2", 0, 10); err != nil || len(followers) != 1 || followers[0].FollowerID != "user_1" {
+		t.Errorf("ListFollowers() = %+v, %v, want user_1", followers, err)
+	}
+	if following, err := repo.ListFollowing("user_1", 0, 10); err != nil || len(following) != 1 || following[0].FolloweeID != "user_2" {
+		t.Errorf("ListFollowing() = %+v, %v, want user_2", following, err)
+	}
+	if count, err := repo.CountFollowers("user_2"); err != nil || count != 1 {
+		t.Errorf("CountFollowers() = %d, %v, want 1", count, err)
+	}
+	if count, err := repo.CountFollowing("user_2"); err != nil || count != 0 {
+		t.Errorf("CountFollowing() = %d, %v, want 0", count, err)
+	}
+	if changed, err := repo.Unfollow("user_1", "user_2"); err != nil || !changed {
+		t.Fatalf("Unfollow() = %v, %v, want a removed follow", changed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFollowRepository_Stub(t *testing.T) {
+	repo := NewFollowRepository(NewPostgresStub())
+
+	for i := 0; i < 2; i++ {
+		changed, err := repo.Follow("user_1", "user_2")
+		if err != nil {
+			t.Fatalf("Follow() error = %v", err)
+		}
+		if changed != (i == 0) {
+			t.Errorf("Follow() #%d changed = %v, want only the first to change", i+1, changed)
+		}
+	}
+	repo.Follow("user_3", "user_2")
+	repo.Follow("user_2", "user_1")
+
+	if count, _ := repo.CountFollowers("user_2"); count != 2 {
+		t.Errorf("CountFollowers() = %d, want 2 after a repeated follow", count)
+	}
+	if count, _ := repo.CountFollowing("user_2"); count != 1 {
+		t.Errorf("CountFollowing() = %d, want 1", count)
+	}
+	if followers, _ := repo.ListFollowers("user_2", 1, 10); len(followers) != 1 {
+		t.Errorf("ListFollowers() = %+v, want the second follower only", followers)
+	}
+
+	if changed, _ := repo.Unfollow("user_1", "user_2"); !changed {
+		t.Errorf("Unfollow() changed = false, want true")
+	}
+	if changed, _ := repo.Unfollow("user_1", "user_2"); changed {
+		t.Errorf("Unfollow() changed = true for a missing follow, want false")
+	}
+	if following, _ := repo.IsFollowing("user_1", "user_2"); following {
+		t.Errorf("IsFollowing() = true after Unfollow, want false")
+	}
+	if followers, _ := repo.ListFollowers("user_2", 0, 10); len(followers) != 1 || followers[0].FollowerID != "user_3" {
+		t.Errorf("ListFollowers() = %+v, want user_3", followers)
+	}
+}
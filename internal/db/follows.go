@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// FollowRepository implements domain.FollowRepository on the follows table.
+// On a stub connection follows are kept in memory.
+type FollowRepository struct {
+	db *PostgresDB
+
+	// stubFollows holds the follows of a stub connection
+	mu          sync.Mutex
+	stubFollows []*domain.Follow
+}
+
+// NewFollowRepository creates a new follow repository
+func NewFollowRepository(db *PostgresDB) *FollowRepository {
+	return &FollowRepository{db: db}
+}
+
+// Follow records that followerID follows followeeID, reporting whether the
+// follow is new; following again is a no-op. The primary key decides between
+// concurrent follows, so only one of them is reported as new.
+func (r *FollowRepository) Follow(followerID, followeeID string) (bool, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.stubIndex(followerID, followeeID) >= 0 {
+			return false, nil
+		}
+		r.stubFollows = append(r.stubFollows, &domain.Follow{FollowerID: followerID, FolloweeID: followeeID, CreatedAt: time.Now()})
+		return true, nil
+	}
+
+	query := `
+		INSERT INTO follows (follower_id, followee_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (follower_id, followee_id) DO NOTHING
+	`
+	result, err := r.db.ExecContext(context.Background(), query, followerID, followeeID, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("error creating follow: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking created follow: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// Unfollow removes a follow, reporting whether there was one; removing a
+// missing follow is a no-op
+func (r *FollowRepository) Unfollow(followerID, followeeID string) (bool, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		i := r.stubIndex(followerID, followeeID)
+		if i < 0 {
+			return false, nil
+		}
+		r.stubFollows = append(r.stubFollows[:i], r.stubFollows[i+1:]...)
+		return true, nil
+	}
+
+	query := "DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2"
+	result, err := r.db.ExecContext(context.Background(), query, followerID, followeeID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting follow: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking deleted follow: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// IsFollowing reports whether followerID follows followeeID
+func (r *FollowRepository) IsFollowing(followerID, followeeID string) (bool, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.stubIndex(followerID, followeeID) >= 0, nil
+	}
+	if r.db.db == nil {
+		return false, fmt.Errorf("database connection not initialized")
+	}
+
+	query := "SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2)"
+	var exists bool
+	if err := r.db.ReadQueryRowContext(context.Background(), query, followerID, followeeID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error checking follow: %w", err)
+	}
+	return exists, nil
+}
+
+// ListFollowers retrieves the follows of a user with pagination, most recent
+// first
+func (r *FollowRepository) ListFollowers(userID string, offset, limit int) ([]*domain.Follow, error) {
+	return r.list("followee_id", userID, offset, limit)
+}
+
+// ListFollowing retrieves the follows by a user with pagination, most recent
+// first
+func (r *FollowRepository) ListFollowing(userID string, offset, limit int) ([]*domain.Follow, error) {
+	return r.list("follower_id", userID, offset, limit)
+}
+
+// CountFollowers returns the number of users following a user
+func (r *FollowRepository) CountFollowers(userID string) (int, error) {
+	return r.count("followee_id", userID)
+}
+
+// CountFollowing returns the number of users a user follows
+func (r *FollowRepository) CountFollowing(userID string) (int, error) {
+	return r.count("follower_id", userID)
+}
+
+// list retrieves a page of the follows whose column is userID; column is
+// follower_id or followee_id, never user input
+func (r *FollowRepository) list(column, userID string, offset, limit int) ([]*domain.Follow, error) {
+	if r.db.isStub() {
+		return r.listStub(column, userID, offset, limit), nil
+	}
+	if r.db.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	query := `
+		SELECT follower_id, followee_id, created_at
+		FROM follows
+		WHERE ` + column + ` = $1
+		ORDER BY created_at DESC, follower_id ASC, followee_id ASC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.ReadQueryContext(context.Background(), query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying follows: %w", err)
+	}
+	defer rows.Close()
+
+	follows := make([]*domain.Follow, 0)
+	for rows.Next() {
+		var follow domain.Follow
+		if err := rows.Scan(&follow.FollowerID, &follow.FolloweeID, &follow.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning follow row: %w", err)
+		}
+		follows = append(follows, &follow)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating follow rows: %w", err)
+	}
+
+	return follows, nil
+}
+
+// count returns the number of follows whose column is userID
+func (r *FollowRepository) count(column, userID string) (int, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.stubMatches(column, userID)), nil
+	}
+	if r.db.db == nil {
+		return 0, fmt.Errorf("database connection not initialized")
+	}
+
+	query := "SELECT COUNT(*) FROM follows WHERE " + column + " = $1"
+	var count int
+	if err := r.db.ReadQueryRowContext(context.Background(), query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting follows: %w", err)
+	}
+	return count, nil
+}
+
+// listStub returns a page of the stub connection's follows whose column is
+// userID, most recent first
+func (r *FollowRepository) listStub(column, userID string, offset, limit int) []*domain.Follow {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	follows := r.stubMatches(column, userID)
+	sort.SliceStable(follows, func(i, j int) bool {
+		return follows[i].CreatedAt.After(follows[j].CreatedAt)
+	})
+
+	start, end := stubPage(len(follows), offset, limit)
+	return follows[start:end]
+}
+
+// stubMatches returns copies of the stub follows whose column is userID;
+// the caller holds mu
+func (r *FollowRepository) stubMatches(column, userID string) []*domain.Follow {
+	follows := make([]*domain.Follow, 0)
+	for _, follow := range r.stubFollows {
+		if (column == "follower_id" && follow.FollowerID == userID) || (column == "followee_id" && follow.FolloweeID == userID) {
+			copied := *follow
+			follows = append(follows, &copied)
+		}
+	}
+	return follows
+}
+
+// stubIndex returns the index of a stub follow, or -1; the caller holds mu
+func (r *FollowRepository) stubIndex(followerID, followeeID string) int {
+	for i, follow := range r.stubFollows {
+		if follow.FollowerID == followerID && follow.FolloweeID == followeeID {
+			return i
+		}
+	}
+	return -1
+}
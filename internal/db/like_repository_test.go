@@ -0,0 +1,96 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+func TestLikeRepository_Queries(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewLikeRepository(NewPostgresDB(mockDB))
+	createdAt := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+
+	// A repeated like is absorbed by the primary key instead of failing
+	mock.ExpectExec(`INSERT INTO likes \(post_id, user_id, created_at\)\s+VALUES \(\$1, \$2, \$3\)\s+ON CONFLICT \(post_id, user_id\) DO NOTHING`).
+		WithArgs("post_1", "user_1", createdAt).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM likes WHERE user_id = \$1 AND post_id = \$2\)`).
+		WithArgs("user_1", "post_1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM likes WHERE post_id = \$1`).
+		WithArgs("post_1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`SELECT post_id, COUNT\(\*\) FROM likes WHERE post_id = ANY\(\$1\) GROUP BY post_id`).
+		WithArgs(pq.Array([]string{"post_1", "post_2"})).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "count"}).AddRow("post_1", 3))
+	mock.ExpectExec(`DELETE FROM likes WHERE post_id = \$1 AND user_id = \$2`).
+		WithArgs("post_1", "user_1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Test
+	if created, err := repo.Create(&domain.Like{PostID: "post_1", UserID: "user_1", CreatedAt: createdAt}); err != nil || created {
+		t.Fatalf("Create() = %v, %v, want an absorbed repeat", created, err)
+	}
+	if exists, err := repo.ExistsByUserAndPost("user_1", "post_1"); err != nil || !exists {
+		t.Errorf("ExistsByUserAndPost() = %v, %v, want true", exists, err)
+	}
+	if count, err := repo.CountByPost("post_1"); err != nil || count != 3 {
+		t.Errorf("CountByPost() = %d, %v, want 3", count, err)
+	}
+	counts, err := repo.CountByPosts([]string{"post_1", "post_2"})
+	if err != nil || len(counts) != 1 || counts["post_1"] != 3 {
+		t.Errorf("CountByPosts() = %v, %v, want post_1: 3", counts, err)
+	}
+	if deleted, err := repo.Delete("post_1", "user_1"); err != nil || !deleted {
+		t.Fatalf("Delete() = %v, %v, want a deleted like", deleted, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestLikeRepository_Stub(t *testing.T) {
+	repo := NewLikeRepository(NewPostgresStub())
+
+	for i := 0; i < 2; i++ {
+		created, err := repo.Create(&domain.Like{PostID: "post_stub_1", UserID: "user_1", CreatedAt: time.Now()})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if created != (i == 0) {
+			t.Errorf("Create() #%d = %v, want only the first like to be new", i+1, created)
+		}
+	}
+	repo.Create(&domain.Like{PostID: "post_stub_1", UserID: "user_2", CreatedAt: time.Now()})
+
+	if count, _ := repo.CountByPost("post_stub_1"); count != 2 {
+		t.Errorf("CountByPost() = %d, want 2 after a repeated like", count)
+	}
+	if exists, _ := repo.ExistsByUserAndPost("user_1", "post_stub_1"); !exists {
+		t.Errorf("ExistsByUserAndPost() = false, want true")
+	}
+
+	if deleted, _ := repo.Delete("post_stub_1", "user_1"); !deleted {
+		t.Errorf("Delete() = false, want true")
+	}
+	if deleted, _ := repo.Delete("post_stub_1", "user_1"); deleted {
+		t.Errorf("Delete() = true for a missing like, want false")
+	}
+	if exists, _ := repo.ExistsByUserAndPost("user_1", "post_stub_1"); exists {
+		t.Errorf("ExistsByUserAndPost() = true after Delete, want false")
+	}
+	if count, _ := repo.CountByPost("post_stub_1"); count != 1 {
+		t.Errorf("CountByPost() = %d, want 1", count)
+	}
+}
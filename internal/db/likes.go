@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/lib/pq"
+)
+
+// LikeRepository implements domain.LikeRepository on the likes table. On a
+// stub connection likes are kept in memory.
+type LikeRepository struct {
+	db *PostgresDB
+
+	// stubLikes holds the likes of a stub connection, by post and user
+	mu        sync.Mutex
+	stubLikes map[string]map[string]time.Time
+}
+
+// NewLikeRepository creates a new like repository
+func NewLikeRepository(db *PostgresDB) *LikeRepository {
+	return &LikeRepository{
+		db:        db,
+		stubLikes: make(map[string]map[string]time.Time),
+	}
+}
+
+// Create records a like, reporting whether it is new; liking a post again
+// is a no-op. The primary key decides between concurrent likes, so only one
+// of them is reported as new.
+func (r *LikeRepository) Create(like *domain.Like) (bool, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		users := r.stubLikes[like.PostID]
+		if users == nil {
+			users = make(map[string]time.Time)
+			r.stubLikes[like.PostID] = users
+		}
+		if _, ok := users[like.UserID]; ok {
+			return false, nil
+		}
+		users[like.UserID] = like.CreatedAt
+		return true, nil
+	}
+
+	query := `
+		INSERT INTO likes (post_id, user_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO NOTHING
+	`
+	result, err := r.db.ExecContext(context.Background(), query, like.PostID, like.UserID, like.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("error creating like: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking created like: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// Delete removes a user's like of a post, reporting whether there was one;
+// removing a missing like is a no-op
+func (r *LikeRepository) Delete(postID, userID string) (bool, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := r.stubLikes[postID][userID]; !ok {
+			return false, nil
+		}
+		delete(r.stubLikes[postID], userID)
+		return true, nil
+	}
+
+	query := "DELETE FROM likes WHERE post_id = $1 AND user_id = $2"
+	result, err := r.db.ExecContext(context.Background(), query, postID, userID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting like: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking deleted like: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// CountByPost returns the number of likes of a post
+func (r *LikeRepository) CountByPost(postID string) (int, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.stubLikes[postID]), nil
+	}
+	if r.db.db == nil {
+		return 0, fmt.Errorf("database connection not initialized")
+	}
+
+	query := "SELECT COUNT(*) FROM likes WHERE post_id = $1"
+	var count int
+	if err := r.db.ReadQueryRowContext(context.Background(), query, postID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting likes: %w", err)
+	}
+	return count, nil
+}
+
+// CountByPosts returns the number of likes of each of the posts with a
+// single query; posts without likes are left out
+func (r *LikeRepository) CountByPosts(postIDs []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, postID := range postIDs {
+			if count := len(r.stubLikes[postID]); count > 0 {
+				counts[postID] = count
+			}
+		}
+		return counts, nil
+	}
+	if r.db.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	query := "SELECT post_id, COUNT(*) FROM likes WHERE post_id = ANY($1) GROUP BY post_id"
+	rows, err := r.db.ReadQueryContext(context.Background(), query, pq.Array(postIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error counting likes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID string
+		var count int
+		if err := rows.Scan(&postID, &count); err != nil {
+			return nil, fmt.Errorf("error scanning like count: %w", err)
+		}
+		counts[postID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating like counts: %w", err)
+	}
+	return counts, nil
+}
+
+// ExistsByUserAndPost reports whether a user has liked a post
+func (r *LikeRepository) ExistsByUserAndPost(userID, postID string) (bool, error) {
+	if r.db.isStub() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		_, ok := r.stubLikes[postID][userID]
+		return ok, nil
+	}
+	if r.db.db == nil {
+		return false, fmt.Errorf("database connection not initialized")
+	}
+
+	query := "SELECT EXISTS (SELECT 1 FROM likes WHERE user_id = $1 AND post_id = $2)"
+	var exists bool
+	if err := r.db.ReadQueryRowContext(context.Background(), query, userID, postID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error checking like: %w", err)
+	}
+	return exists, nil
+}
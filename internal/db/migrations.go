@@ -0,0 +1,150 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Migration is a versioned schema change applied once, in version order
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// migrations lists the schema changes applied on top of the base tables
+// created by initializeDatabase. Append new migrations with increasing versions.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "index posts by creation time",
+		SQL:     "CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts (created_at)",
+	},
+	{
+		Version: 2,
+		Name:    "index posts by user",
+		SQL:     "CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts (user_id, created_at)",
+	},
+	{
+		Version: 3,
+		Name:    "index posts by modification time",
+		SQL:     "CREATE INDEX IF NOT EXISTS idx_posts_updated_at ON posts (updated_at, id)",
+	},
+	{
+		Version: 4,
+		Name:    "keep post revisions",
+		SQL: `CREATE TABLE IF NOT EXISTS post_revisions (
+			id BIGSERIAL PRIMARY KEY,
+			post_id VARCHAR(255) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			content TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_post_revisions_post_id ON post_revisions (post_id, id)`,
+	},
+	{
+		Version: 5,
+		Name:    "add comments",
+		SQL: `CREATE TABLE IF NOT EXISTS comments (
+			id VARCHAR(255) PRIMARY KEY,
+			post_id VARCHAR(255) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			content TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments (post_id, created_at, id)`,
+	},
+	{
+		Version: 6,
+		Name:    "store post tags and mentions",
+		SQL: `CREATE TABLE IF NOT EXISTS post_tags (
+			post_id VARCHAR(255) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			tag VARCHAR(255) NOT NULL,
+			PRIMARY KEY (post_id, tag)
+		);
+		CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags (tag);
+		CREATE TABLE IF NOT EXISTS post_mentions (
+			post_id VARCHAR(255) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			username VARCHAR(255) NOT NULL,
+			PRIMARY KEY (post_id, position)
+		)`,
+	},
+}
+
+// migrateMu serializes migration runs within the process
+var migrateMu sync.Mutex
+
+// Migrate applies every pending migration and returns the versions applied.
+// It is safe to call repeatedly: applied versions are recorded in
+// schema_migrations and skipped on later runs.
+func (p *PostgresDB) Migrate() ([]int, error) {
+	return p.migrate(migrations)
+}
+
+// migrate applies the pending migrations from the given list
+func (p *PostgresDB) migrate(migrations []Migration) ([]int, error) {
+	if p.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+
+	_, err := p.db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	applied := []int{}
+	for _, migration := range migrations {
+		ok, err := p.applyMigration(migration)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			log.Printf("Applied migration %d: %s", migration.Version, migration.Name)
+			applied = append(applied, migration.Version)
+		}
+	}
+
+	return applied, nil
+}
+
+// applyMigration applies a migration in a transaction unless it has already
+// been applied, reporting whether it ran
+func (p *PostgresDB) applyMigration(migration Migration) (bool, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("error starting migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", migration.Version).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking migration %d: %w", migration.Version, err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(migration.SQL); err != nil {
+		return false, fmt.Errorf("error applying migration %d: %w", migration.Version, err)
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", migration.Version); err != nil {
+		return false, fmt.Errorf("error recording migration %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing migration %d: %w", migration.Version, err)
+	}
+
+	return true, nil
+}
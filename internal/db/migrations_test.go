@@ -0,0 +1,78 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresDB_Migrate(t *testing.T) {
+	testMigrations := []Migration{
+		{Version: 1, Name: "applied", SQL: "CREATE INDEX IF NOT EXISTS idx_applied ON posts (id)"},
+		{Version: 2, Name: "pending", SQL: "CREATE INDEX IF NOT EXISTS idx_pending ON posts (id)"},
+	}
+
+	testCases := []struct {
+		name            string
+		appliedVersions map[int]bool
+		expectedApplied []int
+	}{
+		{
+			name:            "applies pending migration",
+			appliedVersions: map[int]bool{1: true},
+			expectedApplied: []int{2},
+		},
+		{
+			name:            "no pending migrations",
+			appliedVersions: map[int]bool{1: true, 2: true},
+			expectedApplied: []int{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			mockDB, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Error creating mock database: %v", err)
+			}
+			defer mockDB.Close()
+
+			mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+			for _, migration := range testMigrations {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT EXISTS").
+					WithArgs(migration.Version).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.appliedVersions[migration.Version]))
+				if tc.appliedVersions[migration.Version] {
+					mock.ExpectRollback()
+					continue
+				}
+				mock.ExpectExec("CREATE INDEX IF NOT EXISTS idx_pending").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO schema_migrations").
+					WithArgs(migration.Version).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			// Test
+			applied, err := (&PostgresDB{db: mockDB}).migrate(testMigrations)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("migrate() error = %v", err)
+			}
+			if len(applied) != len(tc.expectedApplied) {
+				t.Fatalf("migrate() = %v, want %v", applied, tc.expectedApplied)
+			}
+			for i := range applied {
+				if applied[i] != tc.expectedApplied[i] {
+					t.Errorf("migrate() = %v, want %v", applied, tc.expectedApplied)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -1,11 +1,27 @@
 package db
 
 import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/requestid"
 )
 
+// entityColumns are the columns of the query reading post tags and mentions
+var entityColumns = []string{"post_id", "kind", "value", "position"}
+
 // MockPostgresDB is a mock implementation of the PostgreSQL database for testing
 type MockPostgresDB struct {
 	posts map[string]*domain.Post
@@ -41,6 +57,279 @@ func TestPostRepository_Update(t *testing.T) {
 	t.Skip("Skipping test that requires a real database")
 }
 
+// TestPostRepository_UpdateRevisions tests that every edit keeps the
+// previous content as a revision and prunes all but the most recent
+// maxRevisions in the same transaction
+func TestPostRepository_UpdateRevisions(t *testing.T) {
+	testCases := []struct {
+		name         string
+		maxRevisions int
+	}{
+		{name: "Capped", maxRevisions: 3},
+		{name: "Unlimited", maxRevisions: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			mockDB, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Error creating mock database: %v", err)
+			}
+			defer mockDB.Close()
+
+			repo := NewPostRepository(&PostgresDB{db: mockDB})
+			repo.maxRevisions = tc.maxRevisions
+
+			// More edits than the cap; each one prunes back to the cap
+			edits := 5
+			for i := 0; i < edits; i++ {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO post_revisions").
+					WithArgs("post_1").
+					WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+				mock.ExpectExec("UPDATE posts SET content").
+					WithArgs(fmt.Sprintf("Edit %d", i), sqlmock.AnyArg(), "post_1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM post_tags").WithArgs("post_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM post_mentions").WithArgs("post_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				if tc.maxRevisions > 0 {
+					pruned := int64(0)
+					if i+1 > tc.maxRevisions {
+						pruned = 1
+					}
+					mock.ExpectExec("DELETE FROM post_revisions").
+						WithArgs("post_1", tc.maxRevisions).
+						WillReturnResult(sqlmock.NewResult(0, pruned))
+				}
+				mock.ExpectCommit()
+			}
+
+			// Test
+			for i := 0; i < edits; i++ {
+				post := &domain.Post{ID: "post_1", Content: fmt.Sprintf("Edit %d", i), UpdatedAt: time.Now()}
+				if err := repo.Update(post); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+			}
+
+			// Assert
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+// TestPostRepository_TagsAndMentions tests that the tags and mentions of a
+// post are stored with it and read back in order
+func TestPostRepository_TagsAndMentions(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(NewPostgresDB(mockDB))
+	now := time.Now()
+	post := &domain.Post{
+		ID:        "post_1",
+		UserID:    "user_1",
+		Content:   "#go and #redis with @alice",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Tags:      []string{"go", "redis"},
+		Mentions:  []string{"alice"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO posts").
+		WithArgs("post_1", "user_1", post.Content, now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO post_tags").
+		WithArgs("post_1", pq.Array([]string{"go", "redis"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO post_mentions").
+		WithArgs("post_1", pq.Array([]string{"alice"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery("SELECT id, user_id, content, created_at, updated_at FROM posts WHERE id").
+		WithArgs("post_1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at"}).
+			AddRow("post_1", "user_1", post.Content, now, now))
+	mock.ExpectQuery("FROM post_tags").
+		WithArgs(pq.Array([]string{"post_1"})).
+		WillReturnRows(sqlmock.NewRows(entityColumns).
+			AddRow("post_1", "tag", "go", 1).
+			AddRow("post_1", "mention", "alice", 1).
+			AddRow("post_1", "tag", "redis", 2))
+
+	mock.ExpectQuery("JOIN users").
+		WithArgs(10, 0, repo.fallbackUsername).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at", "username"}).
+			AddRow("post_2", "user_1", "Untagged", now, now, "testuser").
+			AddRow("post_1", "user_1", post.Content, now, now, "testuser"))
+	mock.ExpectQuery("FROM post_tags").
+		WithArgs(pq.Array([]string{"post_2", "post_1"})).
+		WillReturnRows(sqlmock.NewRows(entityColumns).
+			AddRow("post_1", "tag", "go", 1).
+			AddRow("post_1", "mention", "alice", 1).
+			AddRow("post_1", "tag", "redis", 2))
+
+	// Test
+	if err := repo.Create(post); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	stored, err := repo.GetByID("post_1")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	page, err := repo.List(0, 10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	// Assert
+	if strings.Join(stored.Tags, ",") != "go,redis" || strings.Join(stored.Mentions, ",") != "alice" {
+		t.Errorf("GetByID() tags = %v, mentions = %v, want [go redis] and [alice]", stored.Tags, stored.Mentions)
+	}
+	if len(page) != 2 {
+		t.Fatalf("len(List()) = %d, want 2", len(page))
+	}
+	if len(page[0].Tags) != 0 || len(page[0].Mentions) != 0 {
+		t.Errorf("List() untagged post tags = %v, mentions = %v, want none", page[0].Tags, page[0].Mentions)
+	}
+	if strings.Join(page[1].Tags, ",") != "go,redis" || strings.Join(page[1].Mentions, ",") != "alice" {
+		t.Errorf("List() tags = %v, mentions = %v, want [go redis] and [alice]", page[1].Tags, page[1].Mentions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestPostRepository_DuplicateTagsStoredOnce tests that a tag repeated in
+// different cases is stored as a single post_tags row
+func TestPostRepository_DuplicateTagsStoredOnce(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(NewPostgresDB(mockDB))
+	now := time.Now()
+	post := &domain.Post{ID: "post_1", UserID: "user_1", Content: "#go #go #Go", CreatedAt: now, UpdatedAt: now}
+	post.Tags = domain.ExtractTags(post.Content, 0)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO posts").
+		WithArgs("post_1", "user_1", "#go #go #Go", now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO post_tags").
+		WithArgs("post_1", pq.Array([]string{"go"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Test
+	if err := repo.Create(post); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Assert: sqlmock fails the insert unless exactly one tag is stored
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestPostRepository_CreateMany tests that posts are imported in one
+// transaction, which is rolled back when any insert fails
+func TestPostRepository_CreateMany(t *testing.T) {
+	posts := []*domain.Post{
+		{ID: "post_1", UserID: "user_1", Content: "First"},
+		{ID: "post_2", UserID: "user_1", Content: "Second"},
+	}
+
+	t.Run("Committed", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Error creating mock database: %v", err)
+		}
+		defer mockDB.Close()
+		repo := NewPostRepository(&PostgresDB{db: mockDB})
+
+		mock.ExpectBegin()
+		for _, post := range posts {
+			mock.ExpectExec("INSERT INTO posts").
+				WithArgs(post.ID, post.UserID, post.Content, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+
+		if err := repo.CreateMany(posts); err != nil {
+			t.Fatalf("CreateMany() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("Rolled back", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Error creating mock database: %v", err)
+		}
+		defer mockDB.Close()
+		repo := NewPostRepository(&PostgresDB{db: mockDB})
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO posts").
+			WithArgs("post_1", "user_1", "First", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO posts").
+			WithArgs("post_2", "user_1", "Second", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnError(fmt.Errorf("duplicate key"))
+		mock.ExpectRollback()
+
+		if err := repo.CreateMany(posts); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+// TestPostRepository_UpdateNotFound tests that updating a missing post rolls
+// back the revision it recorded
+func TestPostRepository_UpdateNotFound(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(&PostgresDB{db: mockDB})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO post_revisions").
+		WithArgs("post_404").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE posts SET content").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = repo.Update(&domain.Post{ID: "post_404", Content: "Edit", UpdatedAt: time.Now()})
+	if err != domain.ErrPostNotFound {
+		t.Errorf("Update() error = %v, want %v", err, domain.ErrPostNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestPostRepository_Delete(t *testing.T) {
 	// Skip this test in CI environments since we don't have a real database
 	t.Skip("Skipping test that requires a real database")
@@ -75,3 +364,575 @@ func TestPostRepository_CreatePost(t *testing.T) {
 	// Skip this test in CI environments since we don't have a real database
 	t.Skip("Skipping test that requires a real database")
 }
+
+func TestPostRepository_DeleteOlderThan(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(&PostgresDB{db: mockDB})
+	cutoff := time.Now().AddDate(0, 0, -30)
+
+	// A full batch is followed by another batch until one comes back short
+	query := "DELETE FROM posts WHERE id IN"
+	fullBatch := sqlmock.NewRows([]string{"id"})
+	for i := 0; i < retentionBatchSize; i++ {
+		fullBatch.AddRow(fmt.Sprintf("post_%d", i))
+	}
+	shortBatch := sqlmock.NewRows([]string{"id"})
+	for i := 0; i < 5; i++ {
+		shortBatch.AddRow(fmt.Sprintf("post_last_%d", i))
+	}
+	mock.ExpectQuery(query).
+		WithArgs(cutoff, retentionBatchSize).
+		WillReturnRows(fullBatch)
+	mock.ExpectQuery(query).
+		WithArgs(cutoff, retentionBatchSize).
+		WillReturnRows(shortBatch)
+
+	// Test
+	deleted, err := repo.DeleteOlderThan(cutoff)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("DeleteOlderThan() error = %v", err)
+	}
+	if len(deleted) != retentionBatchSize+5 {
+		t.Errorf("DeleteOlderThan() deleted %d posts, want %d", len(deleted), retentionBatchSize+5)
+	}
+	if deleted[len(deleted)-1] != "post_last_4" {
+		t.Errorf("Last deleted ID = %s, want %s", deleted[len(deleted)-1], "post_last_4")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostRepository_ReadReplicaRouting(t *testing.T) {
+	// Setup
+	primaryDB, primary, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer primaryDB.Close()
+	replicaDB, replica, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer replicaDB.Close()
+
+	postgres := NewPostgresDB(primaryDB)
+	postgres.SetReplica(replicaDB)
+	repo := NewPostRepository(postgres)
+	now := time.Now()
+
+	// Reads go to the replica
+	replica.ExpectQuery("SELECT id, user_id, content, created_at, updated_at FROM posts WHERE id").
+		WithArgs("post_1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at"}).
+			AddRow("post_1", "user_1", "hello", now, now))
+	replica.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+	replica.ExpectQuery("FROM posts p LEFT JOIN users u").
+		WithArgs(10, 0, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at", "username"}).
+			AddRow("post_1", "user_1", "hello", now, now, "testuser"))
+	replica.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+	replica.ExpectQuery(`SELECT COUNT\(\*\) FROM posts`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	// Writes go to the primary
+	primary.ExpectBegin()
+	primary.ExpectExec("INSERT INTO posts").
+		WithArgs("post_2", "user_1", "world", now, now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	primary.ExpectCommit()
+
+	// Test
+	if _, err := repo.GetByID("post_1"); err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if _, err := repo.List(0, 10); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if _, err := repo.Count(); err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	post := &domain.Post{ID: "post_2", UserID: "user_1", Content: "world", CreatedAt: now, UpdatedAt: now}
+	if err := repo.Create(post); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Assert: sqlmock fails any query that was not expected on its handle
+	if err := replica.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled replica expectations: %v", err)
+	}
+	if err := primary.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled primary expectations: %v", err)
+	}
+}
+
+func TestPostRepository_ReadsUsePrimaryWithoutReplica(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(NewPostgresDB(mockDB))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM posts`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	// Test
+	count, err := repo.Count()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 7 {
+		t.Errorf("Count() = %d, want 7", count)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostRepository_SlowQueryLogIncludesRequestID(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	// Every query counts as slow
+	repo := NewPostRepository(&PostgresDB{db: mockDB, slowQueryThreshold: time.Nanosecond})
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := repo.WithContext(r.Context()).Count(); err != nil {
+			t.Errorf("Count() error = %v", err)
+		}
+	}), true)
+
+	// Test
+	req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+	req.Header.Set(requestid.Header, "req-slow-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	line := logs.String()
+	if !strings.Contains(line, "Slow query") || !strings.Contains(line, "request_id=req-slow-123") {
+		t.Errorf("Expected slow query log with request ID, got %q", line)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStub_PostCount(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected int
+	}{
+		{name: "default", value: "", expected: 16},
+		{name: "configured", value: "40", expected: 40},
+		{name: "empty", value: "0", expected: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Setenv("STUB_POST_COUNT", tc.value)
+			defer os.Unsetenv("STUB_POST_COUNT")
+
+			repo := NewPostRepository(NewPostgresStub())
+
+			count, err := repo.Count()
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != tc.expected {
+				t.Errorf("Count() = %d, want %d", count, tc.expected)
+			}
+
+			posts, err := repo.List(0, 100)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(posts) != tc.expected {
+				t.Errorf("len(List()) = %d, want %d", len(posts), tc.expected)
+			}
+
+			all, err := repo.FetchAllPosts()
+			if err != nil {
+				t.Fatalf("FetchAllPosts() error = %v", err)
+			}
+			if len(all) != tc.expected {
+				t.Errorf("len(FetchAllPosts()) = %d, want %d", len(all), tc.expected)
+			}
+
+			// Pages past the end are empty
+			page, err := repo.List(tc.expected, 10)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(page) != 0 {
+				t.Errorf("Expected an empty page past the end, got %d posts", len(page))
+			}
+		})
+	}
+}
+
+func TestPostgresStub_ListOutOfRange(t *testing.T) {
+	repo := NewPostRepository(NewPostgresStub())
+
+	testCases := []struct {
+		name     string
+		offset   int
+		limit    int
+		expected int
+	}{
+		{name: "negative offset", offset: -5, limit: 3, expected: 3},
+		{name: "negative limit", offset: 0, limit: -1, expected: 0},
+		{name: "huge limit", offset: 2, limit: math.MaxInt, expected: 14},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			posts, err := repo.List(tc.offset, tc.limit)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(posts) != tc.expected {
+				t.Errorf("len(List()) = %d, want %d", len(posts), tc.expected)
+			}
+
+			matches, err := repo.Search("stub", tc.offset, tc.limit)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(matches) != tc.expected {
+				t.Errorf("len(Search()) = %d, want %d", len(matches), tc.expected)
+			}
+		})
+	}
+}
+
+func TestPostgresStub_SearchCount(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{name: "all posts", query: "stub", expected: 16},
+		{name: "every word must match", query: "POST 3", expected: 1},
+		{name: "no match", query: "missing", expected: 0},
+	}
+
+	repo := NewPostRepository(NewPostgresStub())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			count, err := repo.CountSearch(tc.query)
+			if err != nil {
+				t.Fatalf("CountSearch() error = %v", err)
+			}
+			if count != tc.expected {
+				t.Errorf("CountSearch() = %d, want %d", count, tc.expected)
+			}
+
+			// The count matches the results the search returns
+			posts, err := repo.Search(tc.query, 0, 100)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(posts) != count {
+				t.Errorf("len(Search()) = %d, want CountSearch() = %d", len(posts), count)
+			}
+		})
+	}
+}
+
+func TestPostRepository_CountSearch(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(NewPostgresDB(mockDB))
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM posts p WHERE to_tsvector\('english', p.content\) @@ plainto_tsquery\('english', \$1\)`).
+		WithArgs("hello world").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	// Test
+	count, err := repo.CountSearch("hello world")
+	if err != nil {
+		t.Fatalf("CountSearch() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("CountSearch() = %d, want 3", count)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostRepository_ListFallbackUsername(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	os.Setenv("FALLBACK_USERNAME", "[deleted user]")
+	defer os.Unsetenv("FALLBACK_USERNAME")
+	repo := NewPostRepository(&PostgresDB{db: mockDB})
+
+	// Posts whose user is gone are listed alongside the others, under the
+	// fallback username
+	now := time.Now()
+	mock.ExpectQuery(`LEFT JOIN users u ON p.user_id = u.id\s+ORDER BY p.created_at DESC, p.id DESC\s+LIMIT \$1 OFFSET \$2`).
+		WithArgs(10, 0, "[deleted user]").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at", "username"}).
+			AddRow("post_3", "user_1", "Post", now, now, "admin").
+			AddRow("post_2", "user_gone", "Orphaned post", now, now, "[deleted user]"))
+	mock.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+
+	// Test
+	posts, err := repo.List(0, 10)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("len(List()) = %d, want 2", len(posts))
+	}
+	if posts[0].Username != "admin" || posts[1].Username != "[deleted user]" {
+		t.Errorf("List() usernames = %q, %q, want admin and [deleted user]", posts[0].Username, posts[1].Username)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestPostRepository_ListOversizedPage tests that a listing query returning
+// more rows than its limit is truncated with a warning, or rejected when
+// FAIL_OVERSIZED_PAGES is set
+func TestPostRepository_ListOversizedPage(t *testing.T) {
+	testCases := []struct {
+		name          string
+		failOversized bool
+	}{
+		{name: "Truncated"},
+		{name: "Rejected", failOversized: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			mockDB, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Error creating mock database: %v", err)
+			}
+			defer mockDB.Close()
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			repo := NewPostRepository(&PostgresDB{db: mockDB})
+			repo.failOversizedPages = tc.failOversized
+
+			// The database ignores the limit of 2
+			now := time.Now()
+			rows := sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at", "username"})
+			for i := 1; i <= 5; i++ {
+				rows.AddRow(fmt.Sprintf("post_%d", i), "user_1", "Post", now, now, "admin")
+			}
+			mock.ExpectQuery("JOIN users").WithArgs(2, 0, sqlmock.AnyArg()).WillReturnRows(rows)
+			if !tc.failOversized {
+				mock.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+			}
+
+			// Test
+			posts, err := repo.List(0, 2)
+
+			// Assert
+			if tc.failOversized {
+				if err == nil {
+					t.Fatalf("List() = %d posts, want an error", len(posts))
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("List() error = %v", err)
+				}
+				if len(posts) != 2 || posts[0].ID != "post_1" || posts[1].ID != "post_2" {
+					t.Errorf("List() = %d posts, want post_1 and post_2", len(posts))
+				}
+				if !strings.Contains(logs.String(), "returned 5 posts, more than the limit of 2") {
+					t.Errorf("Expected a warning about the oversized page, got %q", logs.String())
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostRepository_ListModifiedSince(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(NewPostgresDB(mockDB))
+	since := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+	edited := since.Add(time.Minute)
+
+	mock.ExpectQuery(`WHERE \(p.updated_at, p.id\) > \(\$1, \$2\)\s+ORDER BY p.updated_at ASC, p.id ASC\s+LIMIT \$3`).
+		WithArgs(since, "post_1", 10, repo.fallbackUsername).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at", "username"}).
+			AddRow("post_2", "user_1", "Edited", since.Add(-time.Hour), edited, "testuser"))
+	mock.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+
+	// Test
+	posts, err := repo.ListModifiedSince(since, "post_1", 10)
+	if err != nil {
+		t.Fatalf("ListModifiedSince() error = %v", err)
+	}
+	if len(posts) != 1 || posts[0].ID != "post_2" || !posts[0].UpdatedAt.Equal(edited) {
+		t.Errorf("ListModifiedSince() = %v, want the edited post_2", posts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostRepository_ListByUsers(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(NewPostgresDB(mockDB))
+	created := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`WHERE p.user_id = ANY\(\$1\)\s+ORDER BY p.created_at DESC\s+LIMIT \$2`).
+		WithArgs(pq.Array([]string{"user_1", "user_2"}), 5, repo.fallbackUsername).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at", "username"}).
+			AddRow("post_3", "user_2", "Newest", created, created, "bob").
+			AddRow("post_2", "user_1", "Older", created.Add(-time.Minute), created.Add(-time.Minute), "alice"))
+	mock.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+
+	// Test
+	posts, err := repo.ListByUsers([]string{"user_1", "user_2"}, 5)
+	if err != nil {
+		t.Fatalf("ListByUsers() error = %v", err)
+	}
+	if len(posts) != 2 || posts[0].ID != "post_3" || posts[1].ID != "post_2" {
+		t.Errorf("ListByUsers() = %v, want post_3 then post_2", posts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostRepository_ListFollowedBy(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(NewPostgresDB(mockDB))
+	created := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`JOIN follows f ON f.followee_id = p.user_id\s+LEFT JOIN users u ON p.user_id = u.id\s+WHERE f.follower_id = \$1\s+ORDER BY p.created_at DESC, p.id DESC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs("user_1", 5, 10, repo.fallbackUsername).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at", "updated_at", "username"}).
+			AddRow("post_3", "user_2", "Followed", created, created, "bob"))
+	mock.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+	mock.ExpectQuery(`SELECT COUNT\(\*\)\s+FROM posts p\s+JOIN follows f ON f.followee_id = p.user_id\s+WHERE f.follower_id = \$1`).
+		WithArgs("user_1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+
+	// Test
+	posts, err := repo.ListFollowedBy("user_1", 10, 5)
+	if err != nil {
+		t.Fatalf("ListFollowedBy() error = %v", err)
+	}
+	if len(posts) != 1 || posts[0].ID != "post_3" || posts[0].Username != "bob" {
+		t.Errorf("ListFollowedBy() = %v, want post_3 by bob", posts)
+	}
+	if count, err := repo.CountFollowedBy("user_1"); err != nil || count != 11 {
+		t.Errorf("CountFollowedBy() = %d, %v, want 11", count, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostRepository_ListAfter(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewPostRepository(NewPostgresDB(mockDB))
+	createdAt := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "user_id", "content", "created_at", "updated_at", "username"}
+
+	// The first page has no position to seek past
+	mock.ExpectQuery(`LEFT JOIN users u ON p.user_id = u.id\s+ORDER BY p.created_at DESC, p.id DESC\s+LIMIT \$1`).
+		WithArgs(2, repo.fallbackUsername).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("post_3", "user_1", "Newest", createdAt, createdAt, "testuser").
+			AddRow("post_2", "user_1", "Older", createdAt, createdAt, "testuser"))
+	mock.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+	mock.ExpectQuery(`WHERE \(p.created_at, p.id\) < \(\$3, \$4\)\s+ORDER BY p.created_at DESC, p.id DESC\s+LIMIT \$1`).
+		WithArgs(2, repo.fallbackUsername, createdAt, "post_2").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("post_1", "user_1", "Oldest", createdAt.Add(-time.Hour), createdAt.Add(-time.Hour), "testuser"))
+	mock.ExpectQuery("FROM post_tags").WillReturnRows(sqlmock.NewRows(entityColumns))
+
+	// Test
+	first, err := repo.ListAfter(time.Time{}, "", 2)
+	if err != nil {
+		t.Fatalf("ListAfter() error = %v", err)
+	}
+	if len(first) != 2 || first[0].ID != "post_3" || first[1].ID != "post_2" {
+		t.Errorf("ListAfter() = %v, want post_3 and post_2", first)
+	}
+	rest, err := repo.ListAfter(createdAt, "post_2", 2)
+	if err != nil {
+		t.Fatalf("ListAfter() error = %v", err)
+	}
+	if len(rest) != 1 || rest[0].ID != "post_1" {
+		t.Errorf("ListAfter() = %v, want post_1", rest)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
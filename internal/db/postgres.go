@@ -1,31 +1,122 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/JoobyPM/tiger-tail-microblog/internal/auth"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/requestid"
+	"github.com/lib/pq"
 )
 
+// defaultSlowQueryThreshold is how long a query may run before it is logged as slow
+const defaultSlowQueryThreshold = 200 * time.Millisecond
+
 // PostgresDB represents a PostgreSQL database connection
 type PostgresDB struct {
 	db *sql.DB
+	// replica serves read-only queries when set; reads go to db otherwise
+	replica            *sql.DB
+	slowQueryThreshold time.Duration
+	// stubPosts are the canned posts served by a stub connection, newest first
+	stubPosts []*domain.PostWithUser
 }
 
-// NewPostgresStub creates a new stub PostgreSQL connection for testing
+// defaultStubPostCount is the number of canned posts served by the stub
+const defaultStubPostCount = 16
+
+// NewPostgresStub creates a new stub PostgreSQL connection for testing. It
+// serves STUB_POST_COUNT (default 16) canned posts from List, Count, Search
+// and FetchAllPosts.
 func NewPostgresStub() *PostgresDB {
-	log.Println("Creating PostgreSQL stub")
+	count := config.GetEnvInt("STUB_POST_COUNT", defaultStubPostCount)
+	log.Printf("Creating PostgreSQL stub with %d posts", count)
 	return &PostgresDB{
-		db: nil,
+		db:        nil,
+		stubPosts: newStubPosts(count),
+	}
+}
+
+// newStubPosts generates count canned posts by the default user, newest first
+func newStubPosts(count int) []*domain.PostWithUser {
+	if count < 0 {
+		count = 0
+	}
+
+	newest := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	posts := make([]*domain.PostWithUser, count)
+	for i := range posts {
+		createdAt := newest.Add(-time.Duration(i) * time.Minute)
+		posts[i] = &domain.PostWithUser{
+			Post: domain.Post{
+				ID:        fmt.Sprintf("post_stub_%d", count-i),
+				UserID:    "user_1",
+				Content:   fmt.Sprintf("Stub post %d", count-i),
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+			},
+			Username: "admin",
+		}
 	}
+	return posts
 }
 
-// NewPostgresConnection creates a new PostgreSQL connection
+// NewPostgresConnection creates a new PostgreSQL connection. When
+// DB_REPLICA_DSN is set, read-only queries are sent to that read replica.
 func NewPostgresConnection(dsn string) (*PostgresDB, error) {
-	log.Printf("Connecting to PostgreSQL with DSN: %s", dsn)
+	log.Printf("Connecting to PostgreSQL on host %s", DSNHost(dsn))
+	
+	db, err := openDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Initialize database
+	postgres := NewPostgresDB(db)
+	
+	if replicaDSN := config.GetEnv("DB_REPLICA_DSN", ""); replicaDSN != "" {
+		log.Printf("Connecting to PostgreSQL read replica on host %s", DSNHost(replicaDSN))
+		replica, err := openDB(replicaDSN)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("read replica: %w", err)
+		}
+		postgres.SetReplica(replica)
+	}
+	
+	if err := postgres.initializeDatabase(); err != nil {
+		log.Printf("Warning: Failed to initialize database: %v", err)
+	} else if _, err := postgres.Migrate(); err != nil {
+		log.Printf("Warning: Failed to apply migrations: %v", err)
+	}
 	
+	return postgres, nil
+}
+
+// DSNHost returns the host of a URL or key=value DSN, so connections can be
+// logged without their credentials
+func DSNHost(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	for _, field := range strings.Fields(dsn) {
+		if key, value, ok := strings.Cut(field, "="); ok && key == "host" {
+			return value
+		}
+	}
+	return "unknown"
+}
+
+// openDB opens and verifies a pooled connection to dsn
+func openDB(dsn string) (*sql.DB, error) {
 	// Connect to the database
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
@@ -39,19 +130,41 @@ func NewPostgresConnection(dsn string) (*PostgresDB, error) {
 	
 	// Verify connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 	
-	// Initialize database
-	postgres := &PostgresDB{
-		db: db,
+	return db, nil
+}
+
+// NewPostgresDB wraps an open database handle. Queries slower than
+// SLOW_QUERY_MS (default 200ms) are logged with the request ID from their context.
+func NewPostgresDB(db *sql.DB) *PostgresDB {
+	return &PostgresDB{
+		db:                 db,
+		slowQueryThreshold: config.GetEnvMillis("SLOW_QUERY_MS", defaultSlowQueryThreshold),
 	}
-	
-	if err := postgres.initializeDatabase(); err != nil {
-		log.Printf("Warning: Failed to initialize database: %v", err)
+}
+
+// SetReplica routes read-only queries to a read replica; writes keep going
+// to the primary handle
+func (p *PostgresDB) SetReplica(replica *sql.DB) {
+	p.replica = replica
+}
+
+// reader returns the handle read-only queries run on: the replica when one
+// is configured, the primary otherwise
+func (p *PostgresDB) reader() *sql.DB {
+	if p.replica != nil {
+		return p.replica
 	}
-	
-	return postgres, nil
+	return p.db
+}
+
+// isStub reports whether p is a stub connection, whose repositories keep
+// their rows in memory
+func (p *PostgresDB) isStub() bool {
+	return p.db == nil && p.stubPosts != nil
 }
 
 // initializeDatabase creates the necessary tables if they don't exist
@@ -93,20 +206,88 @@ func (p *PostgresDB) initializeDatabase() error {
 		return fmt.Errorf("error creating posts table: %w", err)
 	}
 	
+	// Create likes table; the primary key makes each user's like unique
+	likesTable := `
+	CREATE TABLE IF NOT EXISTS likes (
+		post_id VARCHAR(255) NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (post_id, user_id),
+		FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	)
+	`
+	
+	_, err = p.db.Exec(likesTable)
+	if err != nil {
+		return fmt.Errorf("error creating likes table: %w", err)
+	}
+	
+	// Create follows table; the primary key makes following idempotent and
+	// the followee index serves follower listings
+	followsTable := `
+	CREATE TABLE IF NOT EXISTS follows (
+		follower_id VARCHAR(255) NOT NULL,
+		followee_id VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (follower_id, followee_id),
+		CHECK (follower_id <> followee_id),
+		FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON follows (followee_id, created_at DESC)
+	`
+	
+	_, err = p.db.Exec(followsTable)
+	if err != nil {
+		return fmt.Errorf("error creating follows table: %w", err)
+	}
+	
+	// Production deployments create their own users instead of the
+	// well-known default
+	if SeedDefaultAdmin() {
+		if err := p.seedDefaultUser(); err != nil {
+			return err
+		}
+	} else {
+		log.Println("Skipping default user seed (SEED_DEFAULT_ADMIN=false)")
+	}
+	
+	log.Println("Database initialized successfully")
+	return nil
+}
+
+// SeedDefaultAdmin reports whether the default admin user is seeded on
+// startup: SEED_DEFAULT_ADMIN, which defaults to off when
+// ENVIRONMENT=production
+func SeedDefaultAdmin() bool {
+	return config.GetEnvBool("SEED_DEFAULT_ADMIN", config.GetEnv("ENVIRONMENT", "development") != "production")
+}
+
+// defaultAdminPassword is the password of the seeded admin user, stored
+// only as a bcrypt hash
+const defaultAdminPassword = "password"
+
+// seedDefaultUser creates the default admin user if it doesn't exist
+func (p *PostgresDB) seedDefaultUser() error {
 	// Check if default user exists
 	var count int
-	err = p.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = 'admin'").Scan(&count)
+	err := p.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = 'admin'").Scan(&count)
 	if err != nil {
 		return fmt.Errorf("error checking for default user: %w", err)
 	}
 	
 	// Create default user if it doesn't exist
 	if count == 0 {
+		hash, err := auth.HashPassword(defaultAdminPassword)
+		if err != nil {
+			return fmt.Errorf("error hashing default user password: %w", err)
+		}
 		_, err = p.db.Exec(
 			"INSERT INTO users (id, username, password, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)",
 			"user_1",
 			"admin",
-			"password", // In a real app, this would be hashed
+			hash,
 			time.Now(),
 			time.Now(),
 		)
@@ -116,7 +297,6 @@ func (p *PostgresDB) initializeDatabase() error {
 		log.Println("Created default user: admin")
 	}
 	
-	log.Println("Database initialized successfully")
 	return nil
 }
 
@@ -126,6 +306,13 @@ func (p *PostgresDB) Close() error {
 		return nil
 	}
 	
+	if p.replica != nil {
+		log.Println("Closing PostgreSQL read replica connection")
+		if err := p.replica.Close(); err != nil {
+			log.Printf("Error closing read replica connection: %v", err)
+		}
+	}
+	
 	log.Println("Closing PostgreSQL connection")
 	return p.db.Close()
 }
@@ -141,44 +328,141 @@ func (p *PostgresDB) Ping() error {
 
 // Exec executes a query without returning any rows
 func (p *PostgresDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query without returning any rows
+func (p *PostgresDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	if p.db == nil {
 		return nil, fmt.Errorf("database connection not initialized")
 	}
 	
-	return p.db.Exec(query, args...)
+	defer p.logSlowQuery(ctx, query, time.Now())
+	return p.db.ExecContext(ctx, query, args...)
 }
 
 // Query executes a query that returns rows
 func (p *PostgresDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return p.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns rows
+func (p *PostgresDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	if p.db == nil {
 		return nil, fmt.Errorf("database connection not initialized")
 	}
 	
-	return p.db.Query(query, args...)
+	defer p.logSlowQuery(ctx, query, time.Now())
+	return p.db.QueryContext(ctx, query, args...)
 }
 
 // QueryRow executes a query that returns a single row
 func (p *PostgresDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return p.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes a query that returns a single row
+func (p *PostgresDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if p.db == nil {
+		log.Printf("Error: database connection not initialized")
+		return nil
+	}
+	
+	defer p.logSlowQuery(ctx, query, time.Now())
+	return p.db.QueryRowContext(ctx, query, args...)
+}
+
+// ReadQueryContext executes a read-only query that returns rows on the read
+// replica, or the primary when no replica is configured
+func (p *PostgresDB) ReadQueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if p.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	
+	defer p.logSlowQuery(ctx, query, time.Now())
+	return p.reader().QueryContext(ctx, query, args...)
+}
+
+// ReadQueryRowContext executes a read-only query that returns a single row on
+// the read replica, or the primary when no replica is configured
+func (p *PostgresDB) ReadQueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	if p.db == nil {
 		log.Printf("Error: database connection not initialized")
 		return nil
 	}
 	
-	return p.db.QueryRow(query, args...)
+	defer p.logSlowQuery(ctx, query, time.Now())
+	return p.reader().QueryRowContext(ctx, query, args...)
 }
 
+// logSlowQuery logs query if it has run longer than the slow-query threshold,
+// tagged with the request ID from ctx so it can be traced to its request
+func (p *PostgresDB) logSlowQuery(ctx context.Context, query string, start time.Time) {
+	elapsed := time.Since(start)
+	if p.slowQueryThreshold <= 0 || elapsed < p.slowQueryThreshold {
+		return
+	}
+
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		id = "-"
+	}
+	log.Printf("Slow query (%s) request_id=%s: %s", elapsed, id, strings.Join(strings.Fields(query), " "))
+}
+
+// defaultFallbackUsername is shown for posts whose user cannot be found
+const defaultFallbackUsername = "unknown"
+
 // PostRepository implements the domain.PostRepository interface
 type PostRepository struct {
-	db *PostgresDB
+	db  *PostgresDB
+	ctx context.Context
+	// fallbackUsername is shown for posts listed without user information
+	fallbackUsername string
+	// maxRevisions is how many previous versions of a post are kept (0
+	// keeps them all)
+	maxRevisions int
+	// failOversizedPages rejects listings returning more rows than their
+	// limit instead of truncating them
+	failOversizedPages bool
 }
 
-// NewPostRepository creates a new post repository
+// defaultMaxRevisions is how many revisions are kept per post by default
+const defaultMaxRevisions = 10
+
+// NewPostRepository creates a new post repository keeping up to
+// MAX_REVISIONS_PER_POST (default 10) revisions of each post. Listings that
+// return more rows than their limit are truncated, or rejected with
+// FAIL_OVERSIZED_PAGES.
 func NewPostRepository(db *PostgresDB) *PostRepository {
 	return &PostRepository{
-		db: db,
+		db:                 db,
+		fallbackUsername:   config.GetEnv("FALLBACK_USERNAME", defaultFallbackUsername),
+		maxRevisions:       config.GetEnvInt("MAX_REVISIONS_PER_POST", defaultMaxRevisions),
+		failOversizedPages: config.GetEnvBool("FAIL_OVERSIZED_PAGES", false),
+	}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx,
+// so they are cancelled with the request and logged with its request ID
+func (r *PostRepository) WithContext(ctx context.Context) *PostRepository {
+	return &PostRepository{
+		db:               r.db,
+		ctx:              ctx,
+		fallbackUsername:   r.fallbackUsername,
+		maxRevisions:       r.maxRevisions,
+		failOversizedPages: r.failOversizedPages,
 	}
 }
 
+// context returns the context queries run with
+func (r *PostRepository) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
 // GetByID retrieves a post by ID
 func (r *PostRepository) GetByID(id string) (*domain.Post, error) {
 	if r.db.db == nil {
@@ -186,7 +470,7 @@ func (r *PostRepository) GetByID(id string) (*domain.Post, error) {
 	}
 	
 	query := "SELECT id, user_id, content, created_at, updated_at FROM posts WHERE id = $1"
-	row := r.db.QueryRow(query, id)
+	row := r.db.ReadQueryRowContext(r.context(), query, id)
 	
 	var post domain.Post
 	err := row.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt)
@@ -197,32 +481,177 @@ func (r *PostRepository) GetByID(id string) (*domain.Post, error) {
 		return nil, fmt.Errorf("error scanning post row: %w", err)
 	}
 	
+	if err := r.loadEntities(r.db.ReadQueryContext, []*domain.Post{&post}); err != nil {
+		return nil, err
+	}
+	
 	return &post, nil
 }
 
-// Create creates a new post
+// Create creates a new post together with its tags and mentions
 func (r *PostRepository) Create(post *domain.Post) error {
 	if r.db.db == nil {
 		return fmt.Errorf("database connection not initialized")
 	}
 	
-	query := "INSERT INTO posts (id, user_id, content, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)"
-	_, err := r.db.Exec(query, post.ID, post.UserID, post.Content, post.CreatedAt, post.UpdatedAt)
+	ctx := r.context()
+	tx, err := r.db.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("error starting post creation: %w", err)
+	}
+	defer tx.Rollback()
+	
+	query := "INSERT INTO posts (id, user_id, content, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)"
+	if _, err := tx.ExecContext(ctx, query, post.ID, post.UserID, post.Content, post.CreatedAt, post.UpdatedAt); err != nil {
 		return fmt.Errorf("error creating post: %w", err)
 	}
+	if err := insertEntities(ctx, tx, post); err != nil {
+		return err
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing post creation: %w", err)
+	}
 	
 	return nil
 }
 
-// Update updates an existing post
+// insertEntities stores the tags and mentions of a post in the order they
+// appear in it
+func insertEntities(ctx context.Context, tx *sql.Tx, post *domain.Post) error {
+	if len(post.Tags) > 0 {
+		query := "INSERT INTO post_tags (post_id, position, tag) SELECT $1, position, tag FROM unnest($2::text[]) WITH ORDINALITY AS t(tag, position)"
+		if _, err := tx.ExecContext(ctx, query, post.ID, pq.Array(post.Tags)); err != nil {
+			return fmt.Errorf("error storing post tags: %w", err)
+		}
+	}
+	if len(post.Mentions) > 0 {
+		query := "INSERT INTO post_mentions (post_id, position, username) SELECT $1, position, username FROM unnest($2::text[]) WITH ORDINALITY AS m(username, position)"
+		if _, err := tx.ExecContext(ctx, query, post.ID, pq.Array(post.Mentions)); err != nil {
+			return fmt.Errorf("error storing post mentions: %w", err)
+		}
+	}
+	return nil
+}
+
+// entitiesQuery reads the stored tags and mentions of the posts with the
+// given IDs, in the order they appear in each post
+const entitiesQuery = `
+	SELECT post_id, 'tag', tag, position FROM post_tags WHERE post_id = ANY($1)
+	UNION ALL
+	SELECT post_id, 'mention', username, position FROM post_mentions WHERE post_id = ANY($1)
+	ORDER BY 4
+`
+
+// queryFunc runs a query on the primary or the read replica, matching the
+// query that read the posts
+type queryFunc func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+
+// loadEntities fills in the stored tags and mentions of posts using query
+func (r *PostRepository) loadEntities(query queryFunc, posts []*domain.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	
+	byID := make(map[string]*domain.Post, len(posts))
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+		ids = append(ids, post.ID)
+	}
+	
+	rows, err := query(r.context(), entitiesQuery, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("error querying post tags and mentions: %w", err)
+	}
+	defer rows.Close()
+	
+	for rows.Next() {
+		var postID, kind, value string
+		var position int
+		if err := rows.Scan(&postID, &kind, &value, &position); err != nil {
+			return fmt.Errorf("error scanning post tag row: %w", err)
+		}
+		post, ok := byID[postID]
+		if !ok {
+			continue
+		}
+		if kind == "tag" {
+			post.Tags = append(post.Tags, value)
+		} else {
+			post.Mentions = append(post.Mentions, value)
+		}
+	}
+	
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating post tag rows: %w", err)
+	}
+	return nil
+}
+
+// loadEntitiesWithUser fills in the stored tags and mentions of a page of
+// posts
+func (r *PostRepository) loadEntitiesWithUser(query queryFunc, posts []*domain.PostWithUser) error {
+	plain := make([]*domain.Post, len(posts))
+	for i, post := range posts {
+		plain[i] = &post.Post
+	}
+	return r.loadEntities(query, plain)
+}
+
+// CreateMany creates posts in a single transaction, storing either all of
+// them or none
+func (r *PostRepository) CreateMany(posts []*domain.Post) error {
+	if r.db.db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+	
+	ctx := r.context()
+	tx, err := r.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting post import: %w", err)
+	}
+	defer tx.Rollback()
+	
+	query := "INSERT INTO posts (id, user_id, content, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)"
+	for _, post := range posts {
+		if _, err := tx.ExecContext(ctx, query, post.ID, post.UserID, post.Content, post.CreatedAt, post.UpdatedAt); err != nil {
+			return fmt.Errorf("error creating post %s: %w", post.ID, err)
+		}
+		if err := insertEntities(ctx, tx, post); err != nil {
+			return err
+		}
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing post import: %w", err)
+	}
+	
+	return nil
+}
+
+// Update updates an existing post. The previous content is kept as a
+// revision, and revisions beyond the most recent maxRevisions are pruned in
+// the same transaction.
 func (r *PostRepository) Update(post *domain.Post) error {
 	if r.db.db == nil {
 		return fmt.Errorf("database connection not initialized")
 	}
 	
+	ctx := r.context()
+	tx, err := r.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting post update: %w", err)
+	}
+	defer tx.Rollback()
+	
+	revisionQuery := "INSERT INTO post_revisions (post_id, content, created_at) SELECT id, content, updated_at FROM posts WHERE id = $1"
+	if _, err := tx.ExecContext(ctx, revisionQuery, post.ID); err != nil {
+		return fmt.Errorf("error saving post revision: %w", err)
+	}
+	
 	query := "UPDATE posts SET content = $1, updated_at = $2 WHERE id = $3"
-	result, err := r.db.Exec(query, post.Content, post.UpdatedAt, post.ID)
+	result, err := tx.ExecContext(ctx, query, post.Content, post.UpdatedAt, post.ID)
 	if err != nil {
 		return fmt.Errorf("error updating post: %w", err)
 	}
@@ -236,6 +665,33 @@ func (r *PostRepository) Update(post *domain.Post) error {
 		return domain.ErrPostNotFound
 	}
 	
+	// The edited content may tag and mention differently
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = $1", post.ID); err != nil {
+		return fmt.Errorf("error clearing post tags: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_mentions WHERE post_id = $1", post.ID); err != nil {
+		return fmt.Errorf("error clearing post mentions: %w", err)
+	}
+	if err := insertEntities(ctx, tx, post); err != nil {
+		return err
+	}
+	
+	if r.maxRevisions > 0 {
+		pruneQuery := `
+		DELETE FROM post_revisions
+		WHERE post_id = $1 AND id NOT IN (
+			SELECT id FROM post_revisions WHERE post_id = $1 ORDER BY id DESC LIMIT $2
+		)
+		`
+		if _, err := tx.ExecContext(ctx, pruneQuery, post.ID, r.maxRevisions); err != nil {
+			return fmt.Errorf("error pruning post revisions: %w", err)
+		}
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing post update: %w", err)
+	}
+	
 	return nil
 }
 
@@ -246,7 +702,7 @@ func (r *PostRepository) Delete(id string) error {
 	}
 	
 	query := "DELETE FROM posts WHERE id = $1"
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.ExecContext(r.context(), query, id)
 	if err != nil {
 		return fmt.Errorf("error deleting post: %w", err)
 	}
@@ -263,6 +719,55 @@ func (r *PostRepository) Delete(id string) error {
 	return nil
 }
 
+// retentionBatchSize is the number of posts removed per statement by DeleteOlderThan
+const retentionBatchSize = 1000
+
+// DeleteOlderThan hard-deletes posts created before cutoff, in bounded batches
+// so a large purge never holds locks on the whole table, and returns the IDs
+// of the posts deleted
+func (r *PostRepository) DeleteOlderThan(cutoff time.Time) ([]string, error) {
+	if r.db.db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	query := "DELETE FROM posts WHERE id IN (SELECT id FROM posts WHERE created_at < $1 LIMIT $2) RETURNING id"
+	var deleted []string
+	for {
+		batch, err := r.deleteBatch(query, cutoff)
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted = append(deleted, batch...)
+		if len(batch) < retentionBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// deleteBatch runs one batch of DeleteOlderThan and returns the deleted IDs
+func (r *PostRepository) deleteBatch(query string, cutoff time.Time) ([]string, error) {
+	rows, err := r.db.QueryContext(r.context(), query, cutoff, retentionBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting old posts: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return ids, fmt.Errorf("error scanning deleted post id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return ids, fmt.Errorf("error iterating deleted post ids: %w", err)
+	}
+
+	return ids, nil
+}
+
 // ListByUser retrieves posts by a specific user with pagination
 func (r *PostRepository) ListByUser(userID string, offset, limit int) ([]*domain.Post, error) {
 	if r.db.db == nil {
@@ -270,7 +775,7 @@ func (r *PostRepository) ListByUser(userID string, offset, limit int) ([]*domain
 	}
 	
 	query := "SELECT id, user_id, content, created_at, updated_at FROM posts WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3"
-	rows, err := r.db.Query(query, userID, limit, offset)
+	rows, err := r.db.QueryContext(r.context(), query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("error querying posts by user: %w", err)
 	}
@@ -290,28 +795,33 @@ func (r *PostRepository) ListByUser(userID string, offset, limit int) ([]*domain
 		return nil, fmt.Errorf("error iterating post rows: %w", err)
 	}
 	
+	if err := r.loadEntities(r.db.QueryContext, posts); err != nil {
+		return nil, err
+	}
+	
 	return posts, nil
 }
 
 // List retrieves a list of posts with pagination
 func (r *PostRepository) List(offset, limit int) ([]*domain.PostWithUser, error) {
 	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			return r.listStub(offset, limit), nil
+		}
 		return nil, fmt.Errorf("database connection not initialized")
 	}
 	
-	// First, try to get posts with user information
+	// Posts whose user is gone are shown under the fallback username
 	query := `
-		SELECT p.id, p.user_id, p.content, p.created_at, p.updated_at, u.username
+		SELECT p.id, p.user_id, p.content, p.created_at, p.updated_at, COALESCE(u.username, $3)
 		FROM posts p
-		JOIN users u ON p.user_id = u.id
-		ORDER BY p.created_at DESC
+		LEFT JOIN users u ON p.user_id = u.id
+		ORDER BY p.created_at DESC, p.id DESC
 		LIMIT $1 OFFSET $2
 	`
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.ReadQueryContext(r.context(), query, limit, offset, r.fallbackUsername)
 	if err != nil {
-		// If the query fails (e.g., no users table yet), fall back to just getting posts
-		log.Printf("Error querying posts with users: %v, falling back to posts-only query", err)
-		return r.listPostsOnly(offset, limit)
+		return nil, fmt.Errorf("error querying posts: %w", err)
 	}
 	defer rows.Close()
 	
@@ -329,42 +839,133 @@ func (r *PostRepository) List(offset, limit int) ([]*domain.PostWithUser, error)
 		return nil, fmt.Errorf("error iterating post rows: %w", err)
 	}
 	
-	// If no posts were found with the JOIN, try the fallback method
-	if len(posts) == 0 {
-		return r.listPostsOnly(offset, limit)
+	posts, err = r.capPage(posts, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadEntitiesWithUser(r.db.ReadQueryContext, posts); err != nil {
+		return nil, err
 	}
 	
 	return posts, nil
 }
 
-// listPostsOnly retrieves posts without user information
-func (r *PostRepository) listPostsOnly(offset, limit int) ([]*domain.PostWithUser, error) {
-	query := `
-		SELECT id, user_id, content, created_at, updated_at
-		FROM posts
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
-	rows, err := r.db.Query(query, limit, offset)
+// capPage guards against a listing query returning more rows than limit,
+// which only a query bug could cause. The page is truncated to limit with a
+// warning, or rejected when failOversizedPages is set.
+func (r *PostRepository) capPage(posts []*domain.PostWithUser, limit int) ([]*domain.PostWithUser, error) {
+	if limit < 0 || len(posts) <= limit {
+		return posts, nil
+	}
+	if r.failOversizedPages {
+		return nil, fmt.Errorf("query returned %d posts, more than the limit of %d", len(posts), limit)
+	}
+	
+	log.Printf("Warning: query returned %d posts, more than the limit of %d, truncating", len(posts), limit)
+	return posts[:limit], nil
+}
+
+// listStub returns a page of the stub connection's canned posts
+func (r *PostRepository) listStub(offset, limit int) []*domain.PostWithUser {
+	start, end := stubPage(len(r.db.stubPosts), offset, limit)
+	return r.db.stubPosts[start:end]
+}
+
+// stubPage returns the bounds of the page at offset of a stub connection's
+// n canned rows. A negative offset is treated as 0 and a negative limit as
+// an empty page, so out-of-range requests cannot panic.
+func stubPage(n, offset, limit int) (start, end int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	if offset >= n {
+		return n, n
+	}
+	end = offset + limit
+	if end > n || end < offset {
+		end = n
+	}
+	return offset, end
+}
+
+// CountByUser returns the total number of posts by a specific user
+func (r *PostRepository) CountByUser(userID string) (int, error) {
+	if r.db.db == nil {
+		return 0, fmt.Errorf("database connection not initialized")
+	}
+	
+	query := "SELECT COUNT(*) FROM posts WHERE user_id = $1"
+	var count int
+	err := r.db.QueryRowContext(r.context(), query, userID).Scan(&count)
 	if err != nil {
-		// If this also fails, there might be no posts table yet
+		return 0, fmt.Errorf("error counting posts by user: %w", err)
+	}
+	
+	return count, nil
+}
+
+// Count returns the total number of posts
+func (r *PostRepository) Count() (int, error) {
+	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			return len(r.db.stubPosts), nil
+		}
+		return 0, fmt.Errorf("database connection not initialized")
+	}
+	
+	query := "SELECT COUNT(*) FROM posts"
+	var count int
+	err := r.db.ReadQueryRowContext(r.context(), query).Scan(&count)
+	if err != nil {
+		// If the table doesn't exist yet, return 0 instead of an error
 		if err.Error() == `pq: relation "posts" does not exist` {
-			// Return empty result instead of error for better UX
-			return []*domain.PostWithUser{}, nil
+			return 0, nil
 		}
-		return nil, fmt.Errorf("error querying posts: %w", err)
+		return 0, fmt.Errorf("error counting posts: %w", err)
+	}
+	
+	return count, nil
+}
+
+// searchCondition matches posts whose content contains every word of the
+// query; plainto_tsquery ignores query syntax so user input cannot fail to parse
+const searchCondition = "to_tsvector('english', p.content) @@ plainto_tsquery('english', $1)"
+
+// Search retrieves posts matching a full-text query with pagination
+func (r *PostRepository) Search(query string, offset, limit int) ([]*domain.PostWithUser, error) {
+	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			matches := r.searchStub(query)
+			start, end := stubPage(len(matches), offset, limit)
+			return matches[start:end], nil
+		}
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	
+	sqlQuery := `
+		SELECT p.id, p.user_id, p.content, p.created_at, p.updated_at, COALESCE(u.username, $4)
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		WHERE ` + searchCondition + `
+		ORDER BY p.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.QueryContext(r.context(), sqlQuery, query, limit, offset, r.fallbackUsername)
+	if err != nil {
+		return nil, fmt.Errorf("error searching posts: %w", err)
 	}
 	defer rows.Close()
 	
 	posts := make([]*domain.PostWithUser, 0)
 	for rows.Next() {
 		var post domain.PostWithUser
-		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt)
+		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.Username)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning post row: %w", err)
 		}
-		// Set a default username since we don't have user information
-		post.Username = "unknown"
 		posts = append(posts, &post)
 	}
 	
@@ -372,62 +973,271 @@ func (r *PostRepository) listPostsOnly(offset, limit int) ([]*domain.PostWithUse
 		return nil, fmt.Errorf("error iterating post rows: %w", err)
 	}
 	
+	if err := r.loadEntitiesWithUser(r.db.QueryContext, posts); err != nil {
+		return nil, err
+	}
+	
 	return posts, nil
 }
 
-// CountByUser returns the total number of posts by a specific user
-func (r *PostRepository) CountByUser(userID string) (int, error) {
+// CountSearch returns the number of posts matching a full-text query
+func (r *PostRepository) CountSearch(query string) (int, error) {
 	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			return len(r.searchStub(query)), nil
+		}
 		return 0, fmt.Errorf("database connection not initialized")
 	}
 	
-	query := "SELECT COUNT(*) FROM posts WHERE user_id = $1"
+	sqlQuery := "SELECT COUNT(*) FROM posts p WHERE " + searchCondition
 	var count int
-	err := r.db.QueryRow(query, userID).Scan(&count)
+	err := r.db.QueryRowContext(r.context(), sqlQuery, query).Scan(&count)
 	if err != nil {
-		return 0, fmt.Errorf("error counting posts by user: %w", err)
+		return 0, fmt.Errorf("error counting matching posts: %w", err)
 	}
 	
 	return count, nil
 }
 
-// Count returns the total number of posts
-func (r *PostRepository) Count() (int, error) {
+// searchStub returns the stub connection's canned posts containing every word
+// of the query, ignoring case
+func (r *PostRepository) searchStub(query string) []*domain.PostWithUser {
+	terms := strings.Fields(strings.ToLower(query))
+	matches := make([]*domain.PostWithUser, 0)
+	for _, post := range r.db.stubPosts {
+		words := make(map[string]bool)
+		for _, word := range strings.Fields(strings.ToLower(post.Content)) {
+			words[word] = true
+		}
+		matched := len(terms) > 0
+		for _, term := range terms {
+			if !words[term] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, post)
+		}
+	}
+	return matches
+}
+
+// ListAfter retrieves up to limit posts older than the (createdAt, afterID)
+// position in listing order, newest first; a zero createdAt starts from the
+// newest post. Seeking on (created_at, id) keeps deep pages as cheap as the
+// first one, unlike OFFSET.
+func (r *PostRepository) ListAfter(createdAt time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
 	if r.db.db == nil {
-		return 0, fmt.Errorf("database connection not initialized")
+		if r.db.stubPosts != nil {
+			return r.listAfterStub(createdAt, afterID, limit), nil
+		}
+		return nil, fmt.Errorf("database connection not initialized")
 	}
 	
-	query := "SELECT COUNT(*) FROM posts"
-	var count int
-	err := r.db.QueryRow(query).Scan(&count)
+	args := []interface{}{limit, r.fallbackUsername}
+	where := ""
+	if !createdAt.IsZero() {
+		where = "WHERE (p.created_at, p.id) < ($3, $4)"
+		args = append(args, createdAt, afterID)
+	}
+	query := `
+		SELECT p.id, p.user_id, p.content, p.created_at, p.updated_at, COALESCE(u.username, $2)
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		` + where + `
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $1
+	`
+	rows, err := r.db.ReadQueryContext(r.context(), query, args...)
 	if err != nil {
-		// If the table doesn't exist yet, return 0 instead of an error
-		if err.Error() == `pq: relation "posts" does not exist` {
-			return 0, nil
+		return nil, fmt.Errorf("error querying posts: %w", err)
+	}
+	defer rows.Close()
+	
+	posts := make([]*domain.PostWithUser, 0)
+	for rows.Next() {
+		var post domain.PostWithUser
+		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.Username)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning post row: %w", err)
 		}
-		return 0, fmt.Errorf("error counting posts: %w", err)
+		posts = append(posts, &post)
 	}
 	
-	return count, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post rows: %w", err)
+	}
+	
+	if err := r.loadEntitiesWithUser(r.db.ReadQueryContext, posts); err != nil {
+		return nil, err
+	}
+	
+	return posts, nil
 }
 
-// FetchAllPosts retrieves all posts from the database
-func (r *PostRepository) FetchAllPosts() ([]*domain.Post, error) {
+// listAfterStub returns the stub connection's canned posts following the
+// (createdAt, afterID) position, in listing order
+func (r *PostRepository) listAfterStub(createdAt time.Time, afterID string, limit int) []*domain.PostWithUser {
+	posts := make([]*domain.PostWithUser, 0)
+	for _, post := range r.db.stubPosts {
+		if createdAt.IsZero() || post.CreatedAt.Before(createdAt) || (post.CreatedAt.Equal(createdAt) && post.ID < afterID) {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts
+}
+
+// ListModifiedSince retrieves up to limit posts created or edited after the
+// (since, afterID) position, ordered by updated_at then ID so posts sharing a
+// timestamp are neither skipped nor repeated across pages
+func (r *PostRepository) ListModifiedSince(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
 	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			return r.listModifiedStub(since, afterID, limit), nil
+		}
 		return nil, fmt.Errorf("database connection not initialized")
 	}
 	
-	query := "SELECT id, user_id, content, created_at, updated_at FROM posts ORDER BY created_at DESC"
-	rows, err := r.db.Query(query)
+	query := `
+		SELECT p.id, p.user_id, p.content, p.created_at, p.updated_at, COALESCE(u.username, $4)
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		WHERE (p.updated_at, p.id) > ($1, $2)
+		ORDER BY p.updated_at ASC, p.id ASC
+		LIMIT $3
+	`
+	rows, err := r.db.ReadQueryContext(r.context(), query, since, afterID, limit, r.fallbackUsername)
 	if err != nil {
-		return nil, fmt.Errorf("error querying all posts: %w", err)
+		return nil, fmt.Errorf("error querying modified posts: %w", err)
 	}
 	defer rows.Close()
 	
-	posts := make([]*domain.Post, 0)
+	posts := make([]*domain.PostWithUser, 0)
 	for rows.Next() {
-		var post domain.Post
-		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt)
+		var post domain.PostWithUser
+		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.Username)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning post row: %w", err)
+		}
+		posts = append(posts, &post)
+	}
+	
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post rows: %w", err)
+	}
+	
+	if err := r.loadEntitiesWithUser(r.db.ReadQueryContext, posts); err != nil {
+		return nil, err
+	}
+	
+	return posts, nil
+}
+
+// listModifiedStub returns the stub connection's canned posts modified after
+// the (since, afterID) position, in sync order
+func (r *PostRepository) listModifiedStub(since time.Time, afterID string, limit int) []*domain.PostWithUser {
+	posts := make([]*domain.PostWithUser, 0)
+	for _, post := range r.db.stubPosts {
+		if post.UpdatedAt.After(since) || (post.UpdatedAt.Equal(since) && post.ID > afterID) {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].UpdatedAt.Equal(posts[j].UpdatedAt) {
+			return posts[i].UpdatedAt.Before(posts[j].UpdatedAt)
+		}
+		return posts[i].ID < posts[j].ID
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts
+}
+
+// ListByUsers retrieves the limit most recent posts by any of the given
+// users in a single query, newest first
+func (r *PostRepository) ListByUsers(userIDs []string, limit int) ([]*domain.PostWithUser, error) {
+	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			return r.listByUsersStub(userIDs, limit), nil
+		}
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	
+	query := `
+		SELECT p.id, p.user_id, p.content, p.created_at, p.updated_at, COALESCE(u.username, $3)
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		WHERE p.user_id = ANY($1)
+		ORDER BY p.created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.ReadQueryContext(r.context(), query, pq.Array(userIDs), limit, r.fallbackUsername)
+	if err != nil {
+		return nil, fmt.Errorf("error querying posts by users: %w", err)
+	}
+	defer rows.Close()
+	
+	posts := make([]*domain.PostWithUser, 0)
+	for rows.Next() {
+		var post domain.PostWithUser
+		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.Username)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning post row: %w", err)
+		}
+		posts = append(posts, &post)
+	}
+	
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post rows: %w", err)
+	}
+	
+	if err := r.loadEntitiesWithUser(r.db.ReadQueryContext, posts); err != nil {
+		return nil, err
+	}
+	
+	return posts, nil
+}
+
+// ListFollowedBy retrieves the posts by the users a user follows with
+// pagination, newest first
+func (r *PostRepository) ListFollowedBy(userID string, offset, limit int) ([]*domain.PostWithUser, error) {
+	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			return r.listFollowedByStub(userID, offset, limit), nil
+		}
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	
+	query := `
+		SELECT p.id, p.user_id, p.content, p.created_at, p.updated_at, COALESCE(u.username, $4)
+		FROM posts p
+		JOIN follows f ON f.followee_id = p.user_id
+		LEFT JOIN users u ON p.user_id = u.id
+		WHERE f.follower_id = $1
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.ReadQueryContext(r.context(), query, userID, limit, offset, r.fallbackUsername)
+	if err != nil {
+		return nil, fmt.Errorf("error querying followed posts: %w", err)
+	}
+	defer rows.Close()
+	
+	posts := make([]*domain.PostWithUser, 0)
+	for rows.Next() {
+		var post domain.PostWithUser
+		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.Username)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning post row: %w", err)
 		}
@@ -438,9 +1248,130 @@ func (r *PostRepository) FetchAllPosts() ([]*domain.Post, error) {
 		return nil, fmt.Errorf("error iterating post rows: %w", err)
 	}
 	
+	posts, err = r.capPage(posts, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadEntitiesWithUser(r.db.ReadQueryContext, posts); err != nil {
+		return nil, err
+	}
+	
 	return posts, nil
 }
 
+// CountFollowedBy returns the number of posts by the users a user follows
+func (r *PostRepository) CountFollowedBy(userID string) (int, error) {
+	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			return len(r.listFollowedByStub(userID, 0, len(r.db.stubPosts))), nil
+		}
+		return 0, fmt.Errorf("database connection not initialized")
+	}
+	
+	query := `
+		SELECT COUNT(*)
+		FROM posts p
+		JOIN follows f ON f.followee_id = p.user_id
+		WHERE f.follower_id = $1
+	`
+	var count int
+	if err := r.db.ReadQueryRowContext(r.context(), query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting followed posts: %w", err)
+	}
+	
+	return count, nil
+}
+
+// listFollowedByStub returns a page of the stub connection's canned posts
+// as a timeline; the stub has no follows, so it holds every post not by the
+// user
+func (r *PostRepository) listFollowedByStub(userID string, offset, limit int) []*domain.PostWithUser {
+	posts := make([]*domain.PostWithUser, 0)
+	for _, post := range r.db.stubPosts {
+		if post.UserID != userID {
+			posts = append(posts, post)
+		}
+	}
+	start, end := stubPage(len(posts), offset, limit)
+	return posts[start:end]
+}
+
+// listByUsersStub returns the stub connection's canned posts by any of the
+// given users, newest first
+func (r *PostRepository) listByUsersStub(userIDs []string, limit int) []*domain.PostWithUser {
+	wanted := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+	
+	posts := make([]*domain.PostWithUser, 0)
+	for _, post := range r.db.stubPosts {
+		if wanted[post.UserID] {
+			posts = append(posts, post)
+		}
+		if len(posts) == limit {
+			break
+		}
+	}
+	return posts
+}
+
+// FetchAllPosts retrieves all posts from the database
+func (r *PostRepository) FetchAllPosts() ([]*domain.Post, error) {
+	posts := make([]*domain.Post, 0)
+	err := r.EachPost(func(post *domain.Post) error {
+		posts = append(posts, post)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// EachPost calls fn for every post in the database, newest first, one row at a
+// time so callers can stream large result sets without holding them in memory.
+// Iteration stops at the first error returned by fn.
+func (r *PostRepository) EachPost(fn func(*domain.Post) error) error {
+	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			for _, post := range r.db.stubPosts {
+				copied := post.Post
+				if err := fn(&copied); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	query := "SELECT id, user_id, content, created_at, updated_at FROM posts ORDER BY created_at DESC"
+	rows, err := r.db.QueryContext(r.context(), query)
+	if err != nil {
+		return fmt.Errorf("error querying all posts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var post domain.Post
+		err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("error scanning post row: %w", err)
+		}
+		if err := fn(&post); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating post rows: %w", err)
+	}
+
+	return nil
+}
+
 // CreatePost creates a new post in the database
 func (r *PostRepository) CreatePost(post *domain.Post) error {
 	return r.Create(post) // Reuse the Create method
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestUserRepository_GetByUsernames(t *testing.T) {
+	// Setup
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	repo := NewUserRepository(NewPostgresDB(mockDB))
+	createdAt := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+	usernames := []string{"alice", "nobody"}
+
+	mock.ExpectQuery(`SELECT id, username, created_at, updated_at FROM users WHERE username = ANY\(\$1\)`).
+		WithArgs(pq.Array(usernames)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "created_at", "updated_at"}).
+			AddRow("user_2", "alice", createdAt, createdAt))
+
+	// Test
+	users, err := repo.GetByUsernames(usernames)
+	if err != nil {
+		t.Fatalf("GetByUsernames() error = %v", err)
+	}
+	if len(users) != 1 || users[0].ID != "user_2" || users[0].Username != "alice" {
+		t.Errorf("GetByUsernames() = %v, want alice only", users)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/lib/pq"
+)
+
+// stubUser is the user a stub connection knows, the author of its canned
+// posts
+var stubUser = domain.User{ID: "user_1", Username: "admin"}
+
+// UserRepository looks users up in the users table
+type UserRepository struct {
+	db *PostgresDB
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(db *PostgresDB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// GetByUsernames retrieves the users with any of the given usernames in a
+// single query; usernames without a user are left out
+func (r *UserRepository) GetByUsernames(usernames []string) ([]*domain.User, error) {
+	if r.db.db == nil {
+		if r.db.stubPosts != nil {
+			return r.getByUsernamesStub(usernames), nil
+		}
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	query := "SELECT id, username, created_at, updated_at FROM users WHERE username = ANY($1)"
+	rows, err := r.db.ReadQueryContext(context.Background(), query, pq.Array(usernames))
+	if err != nil {
+		return nil, fmt.Errorf("error querying users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*domain.User, 0, len(usernames))
+	for rows.Next() {
+		var user domain.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning user row: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user rows: %w", err)
+	}
+
+	return users, nil
+}
+
+// getByUsernamesStub returns the stub connection's user if it was asked for
+func (r *UserRepository) getByUsernamesStub(usernames []string) []*domain.User {
+	users := make([]*domain.User, 0, 1)
+	for _, username := range usernames {
+		if username == stubUser.Username {
+			user := stubUser
+			users = append(users, &user)
+			break
+		}
+	}
+	return users
+}
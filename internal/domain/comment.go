@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCommentNotFound is returned when a comment does not exist
+var ErrCommentNotFound = errors.New("comment not found")
+
+// Comment is a user's comment on a post
+type Comment struct {
+	ID        string    `json:"id"`
+	PostID    string    `json:"post_id"`
+	UserID    string    `json:"user_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CommentRepository defines the interface for comment data access
+type CommentRepository interface {
+	// Create creates a new comment
+	Create(comment *Comment) error
+	
+	// ListByPost retrieves the comments on a post with pagination, oldest
+	// first so a thread reads in order
+	ListByPost(postID string, offset, limit int) ([]*Comment, error)
+	
+	// Count returns the number of comments on a post
+	Count(postID string) (int, error)
+	
+	// Delete deletes a comment
+	Delete(id string) error
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Follow is a user following another user; a user follows another at most
+// once and never themselves
+type Follow struct {
+	FollowerID string    `json:"follower_id"`
+	FolloweeID string    `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FollowRepository defines the interface for the social graph
+type FollowRepository interface {
+	// Follow records that followerID follows followeeID, reporting whether
+	// the follow is new; following again is a no-op
+	Follow(followerID, followeeID string) (bool, error)
+	
+	// Unfollow removes a follow, reporting whether there was one; removing a
+	// missing follow is a no-op
+	Unfollow(followerID, followeeID string) (bool, error)
+	
+	// IsFollowing reports whether followerID follows followeeID
+	IsFollowing(followerID, followeeID string) (bool, error)
+	
+	// ListFollowers retrieves the follows of a user with pagination, most
+	// recent first
+	ListFollowers(userID string, offset, limit int) ([]*Follow, error)
+	
+	// ListFollowing retrieves the follows by a user with pagination, most
+	// recent first
+	ListFollowing(userID string, offset, limit int) ([]*Follow, error)
+	
+	// CountFollowers returns the number of users following a user
+	CountFollowers(userID string) (int, error)
+	
+	// CountFollowing returns the number of users a user follows
+	CountFollowing(userID string) (int, error)
+}
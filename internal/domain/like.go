@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// Like is a user's like of a post; a user likes a post at most once
+type Like struct {
+	PostID    string    `json:"post_id"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LikeRepository defines the interface for like data access
+type LikeRepository interface {
+	// Create records a like, reporting whether it is new; liking a post
+	// again is a no-op
+	Create(like *Like) (bool, error)
+	
+	// Delete removes a user's like of a post, reporting whether there was
+	// one; removing a missing like is a no-op
+	Delete(postID, userID string) (bool, error)
+	
+	// CountByPost returns the number of likes of a post
+	CountByPost(postID string) (int, error)
+	
+	// CountByPosts returns the number of likes of each of the posts; posts
+	// without likes are left out
+	CountByPosts(postIDs []string) (map[string]int, error)
+	
+	// ExistsByUserAndPost reports whether a user has liked a post
+	ExistsByUserAndPost(userID, postID string) (bool, error)
+}
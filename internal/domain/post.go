@@ -10,6 +10,9 @@ var (
 	ErrPostNotFound      = errors.New("post not found")
 	ErrInvalidPostID     = errors.New("invalid post ID")
 	ErrInvalidPostContent = errors.New("invalid post content")
+	ErrPostContentTooLong = errors.New("post content too long")
+	ErrDuplicatePost     = errors.New("duplicate post")
+	ErrInvalidTimestamp  = errors.New("invalid timestamp")
 )
 
 // Post represents a microblog post
@@ -17,6 +20,8 @@ type Post struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"`
 	Content   string    `json:"content"`
+	Tags      []string  `json:"tags,omitempty"`
+	Mentions  []string  `json:"mentions,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -24,7 +29,8 @@ type Post struct {
 // PostWithUser represents a post with user information
 type PostWithUser struct {
 	Post
-	Username string `json:"username"`
+	Username  string `json:"username"`
+	LikeCount int    `json:"like_count"`
 }
 
 // PostRepository defines the interface for post data access
@@ -35,6 +41,10 @@ type PostRepository interface {
 	// Create creates a new post
 	Create(post *Post) error
 	
+	// CreateMany creates posts in a single transaction, storing either all
+	// of them or none
+	CreateMany(posts []*Post) error
+	
 	// Update updates an existing post
 	Update(post *Post) error
 	
@@ -52,6 +62,32 @@ type PostRepository interface {
 	
 	// Count returns the total number of posts
 	Count() (int, error)
+	
+	// Search retrieves posts matching a full-text query with pagination
+	Search(query string, offset, limit int) ([]*PostWithUser, error)
+	
+	// CountSearch returns the number of posts matching a full-text query
+	CountSearch(query string) (int, error)
+	
+	// ListAfter retrieves up to limit posts older than the (createdAt,
+	// afterID) position, newest first; a zero createdAt starts from the
+	// newest post
+	ListAfter(createdAt time.Time, afterID string, limit int) ([]*PostWithUser, error)
+	
+	// ListModifiedSince retrieves up to limit posts created or edited after
+	// the (since, afterID) position, ordered by updated_at then ID
+	ListModifiedSince(since time.Time, afterID string, limit int) ([]*PostWithUser, error)
+	
+	// ListByUsers retrieves the limit most recent posts by any of the users
+	ListByUsers(userIDs []string, limit int) ([]*PostWithUser, error)
+	
+	// ListFollowedBy retrieves the posts by the users a user follows with
+	// pagination, newest first
+	ListFollowedBy(userID string, offset, limit int) ([]*PostWithUser, error)
+	
+	// CountFollowedBy returns the number of posts by the users a user
+	// follows
+	CountFollowedBy(userID string) (int, error)
 }
 
 // PostService defines the interface for post business logic
@@ -73,4 +109,30 @@ type PostService interface {
 	
 	// List retrieves a list of posts with pagination
 	List(page, limit int) ([]*PostWithUser, int, error)
+	
+	// Search retrieves posts matching a full-text query with pagination
+	Search(query string, page, limit int) ([]*PostWithUser, int, error)
+	
+	// CountSearch returns the number of posts matching a full-text query
+	CountSearch(query string) (int, error)
+	
+	// ListAfter retrieves the page of posts following the (createdAt,
+	// afterID) position, newest first, for cursor pagination
+	ListAfter(createdAt time.Time, afterID string, limit int) ([]*PostWithUser, error)
+	
+	// ListModifiedSince retrieves posts created or edited after the
+	// (since, afterID) position, oldest change first, for sync clients
+	ListModifiedSince(since time.Time, afterID string, limit int) ([]*PostWithUser, error)
+	
+	// ListByUsers retrieves the most recent posts across a set of users,
+	// newest first
+	ListByUsers(userIDs []string, limit int) ([]*PostWithUser, error)
+	
+	// ListFollowedBy retrieves the timeline of a user, the posts by the
+	// users they follow, with pagination, newest first
+	ListFollowedBy(userID string, page, limit int) ([]*PostWithUser, int, error)
+	
+	// CreateMany imports posts in bulk, keeping their timestamps when
+	// preserveTimestamps is set
+	CreateMany(posts []*Post, preserveTimestamps bool) ([]*Post, error)
 }
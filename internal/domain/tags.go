@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	tagPattern     = regexp.MustCompile(`(?:^|\s)#(\w+)`)
+	mentionPattern = regexp.MustCompile(`(?:^|\s)@(\w+)`)
+)
+
+// ExtractTags returns the distinct #tags found in content, lowercased, in
+// order of first appearance, so "#go #go #Go" yields the single tag "go".
+// At most max tags are returned; a max of 0 or less means no limit.
+func ExtractTags(content string, max int) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, match := range tagPattern.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(match[1])
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if max > 0 && len(tags) == max {
+			break
+		}
+	}
+	return tags
+}
+
+// ExtractMentions returns the @mentions found in content, in order of appearance.
+// At most max mentions are returned; a max of 0 or less means no limit.
+func ExtractMentions(content string, max int) []string {
+	return extract(mentionPattern, content, max)
+}
+
+// extract collects the first capture group of every match, stopping at max
+func extract(pattern *regexp.Regexp, content string, max int) []string {
+	n := -1
+	if max > 0 {
+		n = max
+	}
+
+	matches := pattern.FindAllStringSubmatch(content, n)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(matches))
+	for _, match := range matches {
+		values = append(values, match[1])
+	}
+	return values
+}
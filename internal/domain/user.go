@@ -13,6 +13,7 @@ var (
 	ErrInvalidUsername   = errors.New("invalid username")
 	ErrInvalidEmail      = errors.New("invalid email")
 	ErrInvalidPassword   = errors.New("invalid password")
+	ErrInvalidBio        = errors.New("invalid bio")
 )
 
 // User represents a user in the system
@@ -26,6 +27,13 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// FailedLogin is a rejected authentication attempt
+type FailedLogin struct {
+	Time     time.Time `json:"time"`
+	Username string    `json:"username"`
+	IP       string    `json:"ip"`
+}
+
 // UserRepository defines the interface for user data access
 type UserRepository interface {
 	// GetByID retrieves a user by ID
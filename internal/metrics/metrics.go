@@ -0,0 +1,80 @@
+// Package metrics collects request and cache metrics for Prometheus.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics are the application metrics: HTTP requests by route, method and
+// status, their latencies and response sizes, and posts cache hits and
+// misses. A nil *Metrics
+// records nothing, so handlers can run without metrics.
+type Metrics struct {
+	requests    *prometheus.CounterVec
+	durations   *prometheus.HistogramVec
+	sizes       *prometheus.HistogramVec
+	cacheHits   prometheus.Counter
+	cacheMisses prometheus.Counter
+}
+
+// New registers the application metrics with registerer. Tests pass their
+// own registry so metrics don't leak between them.
+func New(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total HTTP requests by route, method and status.",
+		}, []string{"path", "method", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latencies in seconds by route and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"path", "method"}),
+		sizes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "HTTP response body sizes in bytes by route and method.",
+			Buckets: prometheus.ExponentialBuckets(100, 10, 6),
+		}, []string{"path", "method"}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Post listings served from the cache.",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Post listings not found in the cache.",
+		}),
+	}
+	registerer.MustRegister(m.requests, m.durations, m.sizes, m.cacheHits, m.cacheMisses)
+	return m
+}
+
+// ObserveRequest records a completed request and the size of its response
+// body. path should be the matched route pattern rather than the request
+// path, so IDs don't each become a series.
+func (m *Metrics) ObserveRequest(path, method string, status int, duration time.Duration, bytes int64) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(path, method, strconv.Itoa(status)).Inc()
+	m.durations.WithLabelValues(path, method).Observe(duration.Seconds())
+	m.sizes.WithLabelValues(path, method).Observe(float64(bytes))
+}
+
+// CacheHit records a post listing served from the cache
+func (m *Metrics) CacheHit() {
+	if m == nil {
+		return
+	}
+	m.cacheHits.Inc()
+}
+
+// CacheMiss records a post listing that wasn't in the cache
+func (m *Metrics) CacheMiss() {
+	if m == nil {
+		return
+	}
+	m.cacheMisses.Inc()
+}
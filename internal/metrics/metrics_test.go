@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrape returns the exposition of registry
+func scrape(t *testing.T, registry *prometheus.Registry) string {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	body, _ := io.ReadAll(rr.Body)
+	return string(body)
+}
+
+// TestMetrics tests that the application metrics are registered and that
+// a nil *Metrics records nothing
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := New(registry)
+
+	m.ObserveRequest("/api/posts", http.MethodGet, http.StatusOK, 20*time.Millisecond, 2048)
+	m.CacheHit()
+	m.CacheMiss()
+	m.CacheMiss()
+
+	body := scrape(t, registry)
+	for _, line := range []string{
+		`http_requests_total{method="GET",path="/api/posts",status="200"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",path="/api/posts",le="0.025"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",path="/api/posts",le="0.01"} 0`,
+		`http_response_size_bytes_bucket{method="GET",path="/api/posts",le="10000"} 1`,
+		`http_response_size_bytes_bucket{method="GET",path="/api/posts",le="1000"} 0`,
+		"cache_hits_total 1",
+		"cache_misses_total 2",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, body)
+		}
+	}
+
+	var none *Metrics
+	none.ObserveRequest("/api/posts", http.MethodGet, http.StatusOK, time.Millisecond, 0)
+	none.CacheHit()
+	none.CacheMiss()
+}
+
+// TestMetricsDuplicate tests that registering the metrics twice with one
+// registry panics, since that is a programming error
+func TestMetricsDuplicate(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	New(registry)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic registering the metrics twice")
+		}
+	}()
+	New(registry)
+}
@@ -0,0 +1,63 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNew(t *testing.T) {
+	id := New()
+	if !uuidPattern.MatchString(id) {
+		t.Errorf("New() = %q, want a version 4 UUID", id)
+	}
+	if New() == id {
+		t.Error("New() returned the same ID twice")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	testCases := []struct {
+		name        string
+		header      string
+		trustHeader bool
+		expectSame  bool
+	}{
+		{name: "generated", header: "", trustHeader: true, expectSame: false},
+		{name: "trusted header", header: "abc-123", trustHeader: true, expectSame: true},
+		{name: "untrusted header", header: "abc-123", trustHeader: false, expectSame: false},
+		{name: "malformed header", header: "has space", trustHeader: true, expectSame: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var seen string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = FromContext(r.Context())
+			}), tc.trustHeader)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(Header, tc.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if seen == "" {
+				t.Fatal("Expected a request ID in the context")
+			}
+			if rr.Header().Get(Header) != seen {
+				t.Errorf("Response header = %q, want %q", rr.Header().Get(Header), seen)
+			}
+			if (seen == tc.header) != tc.expectSame {
+				t.Errorf("Request ID = %q, header %q, expectSame %v", seen, tc.header, tc.expectSame)
+			}
+			if !tc.expectSame && !uuidPattern.MatchString(seen) {
+				t.Errorf("Request ID = %q, want a generated UUID", seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/auth"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/webhook"
+)
+
+// adminUserID is the ID of the seeded administrator account
+const adminUserID = "user_1"
+
+// Migrator applies pending schema migrations, returning the versions applied
+type Migrator interface {
+	Migrate() ([]int, error)
+}
+
+// WebhookDeadLetters exposes webhook deliveries that exhausted their retries
+// so they can be inspected and replayed
+type WebhookDeadLetters interface {
+	Failures() ([]webhook.FailedDelivery, error)
+	Replay(id string) error
+}
+
+// AdminHandler handles administrative requests
+type AdminHandler struct {
+	postService domain.PostService
+	postCache   PostCache
+	posts       PostStreamer
+	users       domain.UserRepository
+	migrator    Migrator
+	failures    FailedLoginLog
+	webhooks    WebhookDeadLetters
+	inFlight    *inFlightGauge
+	auth        *authenticator
+	appConfig   *config.Config
+	warmer      *cacheWarmer
+	options     Options
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(postService domain.PostService, postCache PostCache, posts PostStreamer, users domain.UserRepository) *AdminHandler {
+	options := LoadOptionsFromEnv()
+	return &AdminHandler{
+		postService: postService,
+		postCache:   postCache,
+		posts:       posts,
+		users:       users,
+		auth:        newAuthenticator(users, options.AuthCacheTTL),
+		warmer:      newCacheWarmer(options.MaxConcurrentCacheWarms),
+		options:     options,
+	}
+}
+
+// ExportPostsHandler handles GET /api/admin/posts/export requests
+func (h *AdminHandler) ExportPostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		if h.posts == nil {
+			respondError(w, http.StatusServiceUnavailable, "Post export is not available")
+			return
+		}
+
+		// Stream posts straight from the repository so the export never
+		// holds the full table in memory
+		respondJSONStream(w, http.StatusOK, "posts", func(emit func(interface{}) error) error {
+			return h.posts.EachPost(func(post *domain.Post) error {
+				return emit(post)
+			})
+		})
+	}
+}
+
+// maxImportPosts is the most posts accepted by one import request
+const maxImportPosts = 1000
+
+// importMaxBodyBytes caps the size of an import request body at
+// maxImportPosts posts of the size a create request may have (0 means no
+// limit)
+func (h *AdminHandler) importMaxBodyBytes() int64 {
+	return h.options.PostMaxBodyBytes * maxImportPosts
+}
+
+// ImportPostsHandler handles POST /api/admin/posts/import requests with a
+// body of the form {"posts": [...], "preserve_timestamps": false}. Imported
+// posts get the server time unless preserve_timestamps is set, in which case
+// their created_at and updated_at are kept; future timestamps are rejected.
+// Bodies larger than maxImportPosts create requests are rejected with 413.
+func (h *AdminHandler) ImportPostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		if limit := h.importMaxBodyBytes(); limit > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+
+		var requestBody struct {
+			Posts              []*domain.Post `json:"posts"`
+			PreserveTimestamps bool           `json:"preserve_timestamps"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+			} else {
+				respondError(w, http.StatusBadRequest, "Invalid request body")
+			}
+			return
+		}
+		if len(requestBody.Posts) == 0 {
+			respondError(w, http.StatusBadRequest, "At least one post is required")
+			return
+		}
+		if len(requestBody.Posts) > maxImportPosts {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d posts can be imported at once", maxImportPosts))
+			return
+		}
+
+		posts, err := h.postService.CreateMany(requestBody.Posts, requestBody.PreserveTimestamps)
+		if err != nil {
+			switch {
+			case errors.Is(err, domain.ErrInvalidTimestamp):
+				respondDomainError(w, http.StatusBadRequest, err, "Invalid timestamp: "+err.Error())
+			case errors.Is(err, domain.ErrInvalidUserID):
+				respondDomainError(w, http.StatusBadRequest, err, "User ID is required")
+			case errors.Is(err, domain.ErrUserNotFound):
+				respondDomainError(w, http.StatusBadRequest, domain.ErrUserNotFound, "User not found")
+			case errors.Is(err, domain.ErrInvalidPostContent):
+				respondDomainError(w, http.StatusBadRequest, err, "Content is required")
+			case errors.Is(err, domain.ErrPostContentTooLong):
+				respondDomainError(w, http.StatusBadRequest, err, "Invalid content: "+err.Error())
+			default:
+				log.Printf("Error importing posts: %v", err)
+				respondError(w, http.StatusInternalServerError, "Failed to import posts")
+			}
+			return
+		}
+
+		if err := h.postCache.InvalidatePosts(); err != nil {
+			log.Printf("Error invalidating posts cache after import: %v", err)
+		}
+
+		respondJSON(w, http.StatusCreated, map[string]interface{}{
+			"imported": len(posts),
+			"posts":    posts,
+		})
+	}
+}
+
+// RebuildCacheHandler handles POST /api/admin/cache/rebuild requests
+func (h *AdminHandler) RebuildCacheHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		cached, err := h.warmer.warm(h.postService, h.postCache)
+		if err == errWarmInProgress {
+			respondJSON(w, http.StatusAccepted, map[string]interface{}{
+				"message": "Cache rebuild already in progress",
+			})
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to rebuild cache")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Cache rebuilt successfully",
+			"cached":  cached,
+		})
+	}
+}
+
+// MigrateHandler handles POST /api/admin/migrate requests, applying any
+// pending migrations. Calling it when the schema is current is a no-op.
+func (h *AdminHandler) MigrateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		if h.migrator == nil {
+			respondError(w, http.StatusServiceUnavailable, "Migrations are not available")
+			return
+		}
+
+		applied, err := h.migrator.Migrate()
+		if err != nil {
+			log.Printf("Error applying migrations: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to apply migrations")
+			return
+		}
+
+		message := fmt.Sprintf("Applied %d migrations", len(applied))
+		if len(applied) == 0 {
+			message = "No pending migrations"
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": message,
+			"applied": applied,
+		})
+	}
+}
+
+// FailedLoginsHandler handles GET /api/admin/security/failed-logins requests,
+// returning the most recent failed authentication attempts, newest first
+func (h *AdminHandler) FailedLoginsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		if h.failures == nil {
+			respondError(w, http.StatusServiceUnavailable, "Failed login tracking is not available")
+			return
+		}
+
+		attempts, err := h.failures.RecentFailedLogins()
+		if err != nil {
+			log.Printf("Error reading failed logins: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to get failed logins")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"failed_logins": attempts,
+		})
+	}
+}
+
+// WebhookFailuresHandler handles GET /api/admin/webhooks/failures requests,
+// returning the webhook deliveries that exhausted their retries, newest first
+func (h *AdminHandler) WebhookFailuresHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		if h.webhooks == nil {
+			respondError(w, http.StatusServiceUnavailable, "Webhooks are not available")
+			return
+		}
+
+		failures, err := h.webhooks.Failures()
+		if err != nil {
+			log.Printf("Error reading failed webhook deliveries: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to get failed webhook deliveries")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"failures": failures,
+		})
+	}
+}
+
+// ReplayWebhookHandler handles POST /api/admin/webhooks/replay requests,
+// retrying a failed delivery given as {"id": "..."}. A successful replay
+// removes it from the failures.
+func (h *AdminHandler) ReplayWebhookHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		if h.webhooks == nil {
+			respondError(w, http.StatusServiceUnavailable, "Webhooks are not available")
+			return
+		}
+
+		var requestBody struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if requestBody.ID == "" {
+			respondError(w, http.StatusBadRequest, "Failed delivery ID is required")
+			return
+		}
+
+		err := h.webhooks.Replay(requestBody.ID)
+		if errors.Is(err, webhook.ErrFailureNotFound) {
+			respondError(w, http.StatusNotFound, "Failed delivery not found")
+			return
+		}
+		if err != nil {
+			log.Printf("Error replaying webhook delivery %s: %v", requestBody.ID, err)
+			respondError(w, http.StatusBadGateway, "Webhook replay failed")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Webhook delivered",
+			"id":      requestBody.ID,
+		})
+	}
+}
+
+// MetricsHandler handles GET /api/admin/metrics requests, returning the
+// number of requests in flight and the saturation high-water mark
+func (h *AdminHandler) MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		if h.inFlight == nil {
+			respondError(w, http.StatusServiceUnavailable, "Metrics are not available")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"in_flight_requests":   h.inFlight.Count(),
+			"in_flight_high_water": h.options.InFlightHighWater,
+		})
+	}
+}
+
+// ConfigHandler handles GET /api/admin/config requests, returning the
+// effective configuration with secrets redacted
+func (h *AdminHandler) ConfigHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		appConfig := h.appConfig
+		if appConfig == nil {
+			appConfig = config.LoadConfigFromEnv()
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"config":  appConfig.Redacted(),
+			"options": h.options.Redacted(),
+		})
+	}
+}
+
+// RotateCredentialsHandler handles POST /api/admin/rotate-credentials requests
+func (h *AdminHandler) RotateCredentialsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		if h.users == nil {
+			respondError(w, http.StatusServiceUnavailable, "Credential rotation is not available")
+			return
+		}
+
+		// Parse request body
+		var requestBody struct {
+			CurrentPassword string `json:"current_password"`
+			NewPassword     string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if requestBody.CurrentPassword == "" || requestBody.NewPassword == "" {
+			respondError(w, http.StatusBadRequest, "Current and new password are required")
+			return
+		}
+
+		admin, err := h.users.GetByID(adminUserID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to load admin user")
+			return
+		}
+
+		if !auth.CheckPassword(admin.Password, requestBody.CurrentPassword) {
+			respondError(w, http.StatusForbidden, "Invalid current password")
+			return
+		}
+
+		hash, err := auth.HashPassword(requestBody.NewPassword)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to hash password")
+			return
+		}
+
+		admin.Password = hash
+		admin.UpdatedAt = time.Now()
+		if err := h.users.Update(admin); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to update credentials")
+			return
+		}
+		h.auth.invalidateUser(admin.ID)
+
+		respondJSON(w, http.StatusOK, map[string]string{
+			"message": "Credentials rotated successfully",
+		})
+	}
+}
@@ -0,0 +1,823 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/auth"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/webhook"
+)
+
+// mockPostStreamer is a mock implementation of PostStreamer for testing
+type mockPostStreamer struct {
+	count int
+	err   error
+}
+
+func (m *mockPostStreamer) EachPost(fn func(*domain.Post) error) error {
+	for i := 0; i < m.count; i++ {
+		post := &domain.Post{
+			ID:        fmt.Sprintf("post_%d", i),
+			UserID:    "user_1",
+			Content:   fmt.Sprintf("Post \"%d\" with <markup>", i),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
+
+// TestExportPostsHandler tests the ExportPostsHandler method
+func TestExportPostsHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		count          int
+		authenticate   bool
+		expectedStatus int
+	}{
+		{
+			name:           "Many posts",
+			count:          5000,
+			authenticate:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "No posts",
+			count:          0,
+			authenticate:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unauthorized",
+			count:          1,
+			authenticate:   false,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewAdminHandler(nil, nil, &mockPostStreamer{count: tc.count}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/posts/export", nil)
+			if tc.authenticate {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ExportPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Posts []*domain.Post `json:"posts"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Streamed output is not valid JSON: %v", err)
+			}
+			if len(response.Posts) != tc.count {
+				t.Errorf("Expected %d posts, got %d", tc.count, len(response.Posts))
+			}
+			if tc.count > 0 && response.Posts[tc.count-1].ID != fmt.Sprintf("post_%d", tc.count-1) {
+				t.Errorf("Expected last post ID post_%d, got %s", tc.count-1, response.Posts[tc.count-1].ID)
+			}
+		})
+	}
+}
+
+// TestExportPostsHandlerStreamError tests that a failed stream is not a valid document
+func TestExportPostsHandlerStreamError(t *testing.T) {
+	handler := NewAdminHandler(nil, nil, &mockPostStreamer{count: 3, err: errors.New("connection reset")}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/posts/export", nil)
+	req.SetBasicAuth("admin", "password")
+	rr := httptest.NewRecorder()
+
+	handler.ExportPostsHandler()(rr, req)
+
+	if strings.HasSuffix(strings.TrimSpace(rr.Body.String()), "]}") {
+		t.Error("Expected truncated output when streaming fails")
+	}
+	if json.Valid(rr.Body.Bytes()) {
+		t.Error("Expected invalid JSON when streaming fails")
+	}
+}
+
+// mockUserRepository is a mock implementation of domain.UserRepository for testing
+type mockUserRepository struct {
+	users           map[string]*domain.User
+	usernameLookups int
+}
+
+func newMockUserRepository(users ...*domain.User) *mockUserRepository {
+	repo := &mockUserRepository{users: make(map[string]*domain.User)}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+	return repo
+}
+
+func (m *mockUserRepository) GetByID(id string) (*domain.User, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (m *mockUserRepository) GetByUsername(username string) (*domain.User, error) {
+	m.usernameLookups++
+	for _, user := range m.users {
+		if user.Username == username {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) GetByUsernames(usernames []string) ([]*domain.User, error) {
+	m.usernameLookups++
+	users := make([]*domain.User, 0, len(usernames))
+	for _, username := range usernames {
+		for _, user := range m.users {
+			if user.Username == username {
+				copied := *user
+				users = append(users, &copied)
+			}
+		}
+	}
+	return users, nil
+}
+
+func (m *mockUserRepository) GetByEmail(email string) (*domain.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) Create(user *domain.User) error {
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *mockUserRepository) Update(user *domain.User) error {
+	if _, ok := m.users[user.ID]; !ok {
+		return domain.ErrUserNotFound
+	}
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *mockUserRepository) Delete(id string) error {
+	delete(m.users, id)
+	return nil
+}
+
+func (m *mockUserRepository) List(offset, limit int) ([]*domain.User, error) {
+	users := make([]*domain.User, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (m *mockUserRepository) Count() (int, error) {
+	return len(m.users), nil
+}
+
+// TestRotateCredentialsHandler tests the RotateCredentialsHandler method
+func TestRotateCredentialsHandler(t *testing.T) {
+	testCases := []struct {
+		name            string
+		currentPassword string
+		expectedStatus  int
+		expectRotated   bool
+	}{
+		{
+			name:            "Success",
+			currentPassword: "password",
+			expectedStatus:  http.StatusOK,
+			expectRotated:   true,
+		},
+		{
+			name:            "Wrong current password",
+			currentPassword: "not-the-password",
+			expectedStatus:  http.StatusForbidden,
+			expectRotated:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := newMockUserRepository(&domain.User{
+				ID:       adminUserID,
+				Username: "admin",
+				Password: "password",
+			})
+			handler := NewAdminHandler(nil, nil, nil, users)
+
+			body, _ := json.Marshal(map[string]string{
+				"current_password": tc.currentPassword,
+				"new_password":     "s3cure-passphrase",
+			})
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/rotate-credentials", bytes.NewReader(body))
+			req.SetBasicAuth("admin", "password")
+			rr := httptest.NewRecorder()
+
+			handler.RotateCredentialsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+
+			stored := users.users[adminUserID].Password
+			if !tc.expectRotated {
+				if stored != "password" {
+					t.Errorf("Expected password to be unchanged, got %q", stored)
+				}
+				return
+			}
+
+			if stored == "s3cure-passphrase" {
+				t.Error("Expected new password to be stored hashed")
+			}
+			if !auth.CheckPassword(stored, "s3cure-passphrase") {
+				t.Error("Expected stored hash to match the new password")
+			}
+
+			// The old password must no longer authenticate
+			req = httptest.NewRequest(http.MethodGet, "/api/admin/posts/export", nil)
+			req.SetBasicAuth("admin", "password")
+			if _, err := handler.auth.authenticate(req); err == nil {
+				t.Error("Expected old password to be rejected after rotation")
+			}
+		})
+	}
+}
+
+// TestRebuildCacheHandler tests the RebuildCacheHandler method
+func TestRebuildCacheHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		authenticate   bool
+		expectedStatus int
+		expectedSource string
+	}{
+		{
+			name:           "Authorized rebuild",
+			authenticate:   true,
+			expectedStatus: http.StatusOK,
+			expectedSource: "cache",
+		},
+		{
+			name:           "Unauthorized",
+			authenticate:   false,
+			expectedStatus: http.StatusUnauthorized,
+			expectedSource: "database",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			postService := &mockPostService{
+				listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+					return []*domain.PostWithUser{
+						{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "First"}, Username: "admin"},
+						{Post: domain.Post{ID: "post_2", UserID: "user_1", Content: "Second"}, Username: "admin"},
+					}, 2, nil
+				},
+			}
+
+			var cached []*domain.PostWithUser
+			postCache := &mockPostCache{
+				getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+					if cached == nil {
+						return nil, errors.New("cache miss")
+					}
+					return cached, nil
+				},
+				setPostsWithUserFunc: func(posts []*domain.PostWithUser) error {
+					if tc.expectedStatus == http.StatusOK {
+						cached = posts
+					}
+					return nil
+				},
+			}
+
+			handler := NewAdminHandler(postService, postCache, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/cache/rebuild", nil)
+			if tc.authenticate {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.RebuildCacheHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+
+			if tc.expectedStatus == http.StatusOK {
+				var response map[string]interface{}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to parse response body: %v", err)
+				}
+				if response["cached"] != float64(2) {
+					t.Errorf("Expected 2 cached posts, got %v", response["cached"])
+				}
+			}
+
+			// A subsequent listing should be served from the warm cache
+			postHandler := NewPostHandler(postService, postCache)
+			req = httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+			rr = httptest.NewRecorder()
+			postHandler.GetPostsHandler()(rr, req)
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response["source"] != tc.expectedSource {
+				t.Errorf("Expected source %q, got %v", tc.expectedSource, response["source"])
+			}
+		})
+	}
+}
+
+// TestRebuildCacheHandlerConcurrent tests that concurrent rebuilds are
+// skipped and share a single re-run of the running rebuild
+func TestRebuildCacheHandlerConcurrent(t *testing.T) {
+	const requests = 5
+
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	postService := &mockPostService{
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(started)
+			}
+			<-release
+			return []*domain.PostWithUser{}, 0, nil
+		},
+	}
+	handler := NewAdminHandler(postService, &mockPostCache{}, nil, nil)
+	handler.warmer = newCacheWarmer(1)
+
+	rebuild := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/cache/rebuild", nil)
+		req.SetBasicAuth("admin", "password")
+		rr := httptest.NewRecorder()
+		handler.RebuildCacheHandler()(rr, req)
+		return rr.Code
+	}
+
+	// The first rebuild holds the only slot until released
+	first := make(chan int)
+	go func() { first <- rebuild() }()
+	<-started
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- rebuild()
+		}()
+	}
+	wg.Wait()
+	close(release)
+	close(statuses)
+
+	if status := <-first; status != http.StatusOK {
+		t.Errorf("Expected the running rebuild to succeed, got status %d", status)
+	}
+	for status := range statuses {
+		if status != http.StatusAccepted {
+			t.Errorf("Expected concurrent rebuilds to be skipped with status %d, got %d", http.StatusAccepted, status)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected List to be called twice, got %d", calls)
+	}
+}
+
+// TestConfigHandler tests that the effective configuration is returned redacted
+func TestConfigHandler(t *testing.T) {
+	appConfig := config.DefaultConfig()
+	appConfig.Database.Password = "db-super-secret"
+	appConfig.Cache.Password = "cache-super-secret"
+
+	handler := NewAdminHandler(nil, nil, nil, nil)
+	handler.appConfig = appConfig
+	handler.options.PostIDSecret = "post-id-super-secret"
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	req.SetBasicAuth("admin", "password")
+	rr := httptest.NewRecorder()
+	handler.ConfigHandler()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	body := rr.Body.String()
+	for _, secret := range []string{"db-super-secret", "cache-super-secret", "post-id-super-secret"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("Response contains secret %q: %s", secret, body)
+		}
+	}
+
+	var response struct {
+		Config config.Config `json:"config"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if response.Config.Database.Password != config.RedactedPlaceholder {
+		t.Errorf("Expected database password %q, got %q", config.RedactedPlaceholder, response.Config.Database.Password)
+	}
+	if response.Config.Cache.Password != config.RedactedPlaceholder {
+		t.Errorf("Expected cache password %q, got %q", config.RedactedPlaceholder, response.Config.Cache.Password)
+	}
+	if response.Config.Server.Port != appConfig.Server.Port {
+		t.Errorf("Expected server port %d, got %d", appConfig.Server.Port, response.Config.Server.Port)
+	}
+
+	// Non-admins are rejected
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	rr = httptest.NewRecorder()
+	handler.ConfigHandler()(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
+
+// mockMigrator is a mock implementation of Migrator for testing
+type mockMigrator struct {
+	pending []int
+	calls   int
+}
+
+func (m *mockMigrator) Migrate() ([]int, error) {
+	m.calls++
+	applied := append([]int{}, m.pending...)
+	m.pending = nil
+	return applied, nil
+}
+
+// TestMigrateHandler tests that pending migrations are applied and reported
+func TestMigrateHandler(t *testing.T) {
+	testCases := []struct {
+		name            string
+		pending         []int
+		authenticate    bool
+		expectedStatus  int
+		expectedMessage string
+		expectedApplied int
+	}{
+		{
+			name:            "Pending migration",
+			pending:         []int{3},
+			authenticate:    true,
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Applied 1 migrations",
+			expectedApplied: 1,
+		},
+		{
+			name:            "Up to date",
+			pending:         nil,
+			authenticate:    true,
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "No pending migrations",
+			expectedApplied: 0,
+		},
+		{
+			name:           "Unauthorized",
+			pending:        []int{3},
+			authenticate:   false,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			migrator := &mockMigrator{pending: tc.pending}
+			handler := NewAdminHandler(&mockPostService{}, &mockPostCache{}, nil, nil)
+			handler.migrator = migrator
+
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/migrate", nil)
+			if tc.authenticate {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.MigrateHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				if migrator.calls != 0 {
+					t.Errorf("Expected no migration run, got %d", migrator.calls)
+				}
+				return
+			}
+
+			var response struct {
+				Message string `json:"message"`
+				Applied []int  `json:"applied"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response.Message != tc.expectedMessage {
+				t.Errorf("Expected message %q, got %q", tc.expectedMessage, response.Message)
+			}
+			if len(response.Applied) != tc.expectedApplied {
+				t.Errorf("Expected %d applied migrations, got %v", tc.expectedApplied, response.Applied)
+			}
+
+			// A second run finds nothing left to apply
+			rr = httptest.NewRecorder()
+			handler.MigrateHandler()(rr, req)
+			if !strings.Contains(rr.Body.String(), "No pending migrations") {
+				t.Errorf("Expected no pending migrations on rerun, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+// mockFailedLoginLog is an in-memory FailedLoginLog keeping the newest attempt first
+type mockFailedLoginLog struct {
+	attempts []domain.FailedLogin
+}
+
+func (m *mockFailedLoginLog) RecordFailedLogin(attempt domain.FailedLogin) error {
+	m.attempts = append([]domain.FailedLogin{attempt}, m.attempts...)
+	return nil
+}
+
+func (m *mockFailedLoginLog) RecentFailedLogins() ([]domain.FailedLogin, error) {
+	return m.attempts, nil
+}
+
+// TestFailedLoginsHandler tests that a rejected login shows up in the
+// failed logins endpoint and requests without credentials do not
+func TestFailedLoginsHandler(t *testing.T) {
+	failures := &mockFailedLoginLog{}
+	handler := NewAdminHandler(&mockPostService{}, &mockPostCache{}, nil, nil)
+	handler.failures = failures
+	handler.auth.failures = failures
+
+	// A wrong password and a request without credentials are both rejected
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/migrate", nil)
+	req.SetBasicAuth("mallory", "guess")
+	rr := httptest.NewRecorder()
+	handler.MigrateHandler()(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/admin/migrate", nil)
+	handler.MigrateHandler()(httptest.NewRecorder(), req)
+
+	// Non-admins cannot read the failed logins
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/security/failed-logins", nil)
+	rr = httptest.NewRecorder()
+	handler.FailedLoginsHandler()(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/security/failed-logins", nil)
+	req.SetBasicAuth("admin", "password")
+	rr = httptest.NewRecorder()
+	handler.FailedLoginsHandler()(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		FailedLogins []domain.FailedLogin `json:"failed_logins"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if len(response.FailedLogins) != 1 {
+		t.Fatalf("Expected 1 failed login, got %+v", response.FailedLogins)
+	}
+	attempt := response.FailedLogins[0]
+	if attempt.Username != "mallory" || attempt.IP != "192.0.2.1" || attempt.Time.IsZero() {
+		t.Errorf("Unexpected failed login %+v", attempt)
+	}
+}
+
+// TestWebhookFailuresHandlers tests that a permanently failing webhook
+// delivery is listed by the failures endpoint and can be replayed once the
+// receiver recovers
+func TestWebhookFailuresHandlers(t *testing.T) {
+	var healthy atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	dispatcher := webhook.NewDispatcherWithOptions(webhook.NewClientWithOptions(time.Second, 1024), webhook.NewMemoryDeadLetterStore(), 2, time.Millisecond)
+	if err := dispatcher.Dispatch(webhook.Delivery{URL: receiver.URL, PostID: "post_1", Payload: []byte(`{"event":"post.created"}`)}); err == nil {
+		t.Fatal("Expected the delivery to fail")
+	}
+
+	handler := NewAdminHandler(&mockPostService{}, &mockPostCache{}, nil, nil)
+	handler.webhooks = dispatcher
+
+	listFailures := func() []webhook.FailedDelivery {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/webhooks/failures", nil)
+		req.SetBasicAuth("admin", "password")
+		rr := httptest.NewRecorder()
+		handler.WebhookFailuresHandler()(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		var response struct {
+			Failures []webhook.FailedDelivery `json:"failures"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response body: %v", err)
+		}
+		return response.Failures
+	}
+
+	failures := listFailures()
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 failed delivery, got %+v", failures)
+	}
+	if failures[0].PostID != "post_1" || failures[0].LastStatus != http.StatusServiceUnavailable || failures[0].Attempts != 2 {
+		t.Errorf("Unexpected failed delivery %+v", failures[0])
+	}
+
+	replay := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/webhooks/replay", strings.NewReader(body))
+		req.SetBasicAuth("admin", "password")
+		rr := httptest.NewRecorder()
+		handler.ReplayWebhookHandler()(rr, req)
+		return rr
+	}
+
+	// The receiver is still down
+	if rr := replay(`{"id":"` + failures[0].ID + `"}`); rr.Code != http.StatusBadGateway {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadGateway, rr.Code)
+	}
+
+	healthy.Store(true)
+	if rr := replay(`{"id":"` + failures[0].ID + `"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if failures := listFailures(); len(failures) != 0 {
+		t.Errorf("Expected no failed deliveries after the replay, got %+v", failures)
+	}
+
+	if rr := replay(`{"id":"` + failures[0].ID + `"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	if rr := replay(`{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	// Non-admins cannot list failures
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/webhooks/failures", nil)
+	rr := httptest.NewRecorder()
+	handler.WebhookFailuresHandler()(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	// Without a dispatcher the endpoints are unavailable
+	handler.webhooks = nil
+	if rr := replay(`{"id":"dl_1"}`); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
+
+// TestImportPostsHandler tests that the admin import passes supplied
+// timestamps and the preserve flag to the service, rejects invalid
+// timestamps with 400 and bodies over the import cap with 413
+func TestImportPostsHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		maxBodyBytes   int64
+		authenticate   bool
+		importErr      error
+		expectedStatus int
+		expectedFlag   bool
+	}{
+		{
+			name:           "Preserved timestamps",
+			body:           `{"preserve_timestamps": true, "posts": [{"user_id": "user_1", "content": "Old", "created_at": "2019-05-01T10:00:00Z", "updated_at": "2019-05-01T11:00:00Z"}]}`,
+			authenticate:   true,
+			expectedStatus: http.StatusCreated,
+			expectedFlag:   true,
+		},
+		{
+			name:           "Server timestamps",
+			body:           `{"posts": [{"user_id": "user_1", "content": "Old", "created_at": "2019-05-01T10:00:00Z"}]}`,
+			authenticate:   true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Future timestamp",
+			body:           `{"preserve_timestamps": true, "posts": [{"user_id": "user_1", "content": "Later", "created_at": "2999-01-01T00:00:00Z"}]}`,
+			authenticate:   true,
+			importErr:      fmt.Errorf("%w: timestamps may not be in the future", domain.ErrInvalidTimestamp),
+			expectedStatus: http.StatusBadRequest,
+			expectedFlag:   true,
+		},
+		{
+			name:           "No posts",
+			body:           `{"posts": []}`,
+			authenticate:   true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Body too large",
+			body:           `{"posts": [{"user_id": "user_1", "content": "` + strings.Repeat("x", 2000) + `"}]}`,
+			maxBodyBytes:   1,
+			authenticate:   true,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "Unauthorized",
+			body:           `{"posts": [{"user_id": "user_1", "content": "Old"}]}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var received []*domain.Post
+			var flag bool
+			postService := &mockPostService{
+				createManyFunc: func(posts []*domain.Post, preserveTimestamps bool) ([]*domain.Post, error) {
+					received, flag = posts, preserveTimestamps
+					if tc.importErr != nil {
+						return nil, tc.importErr
+					}
+					return posts, nil
+				},
+			}
+			handler := NewAdminHandler(postService, &mockPostCache{}, nil, nil)
+			if tc.maxBodyBytes > 0 {
+				handler.options.PostMaxBodyBytes = tc.maxBodyBytes
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/posts/import", strings.NewReader(tc.body))
+			if tc.authenticate {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.ImportPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if received == nil {
+				return
+			}
+			if flag != tc.expectedFlag {
+				t.Errorf("preserveTimestamps = %v, want %v", flag, tc.expectedFlag)
+			}
+			if want := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC); tc.expectedStatus == http.StatusCreated && !received[0].CreatedAt.Equal(want) {
+				t.Errorf("Expected created_at %v to reach the service, got %v", want, received[0].CreatedAt)
+			}
+		})
+	}
+}
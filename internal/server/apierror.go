@@ -0,0 +1,98 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// APIError is an error response: a stable machine-readable code clients
+// can branch on, a human-readable message and the HTTP status it is sent
+// with
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the message of the error
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// domainErrorCodes are the codes of the domain errors handlers report
+var domainErrorCodes = []struct {
+	err  error
+	code string
+}{
+	{domain.ErrPostNotFound, "post_not_found"},
+	{domain.ErrInvalidPostID, "invalid_post_id"},
+	{domain.ErrInvalidPostContent, "invalid_post_content"},
+	{domain.ErrPostContentTooLong, "post_content_too_long"},
+	{domain.ErrDuplicatePost, "duplicate_post"},
+	{domain.ErrInvalidTimestamp, "invalid_timestamp"},
+	{domain.ErrUserNotFound, "user_not_found"},
+	{domain.ErrUserAlreadyExists, "user_already_exists"},
+	{domain.ErrInvalidUserID, "invalid_user_id"},
+	{domain.ErrInvalidUsername, "invalid_username"},
+	{domain.ErrInvalidEmail, "invalid_email"},
+	{domain.ErrInvalidPassword, "invalid_password"},
+	{domain.ErrInvalidBio, "invalid_bio"},
+	{domain.ErrCommentNotFound, "comment_not_found"},
+}
+
+// errorCode returns the code of err, which may wrap a domain error, falling
+// back to the code of status
+func errorCode(err error, status int) string {
+	for _, known := range domainErrorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+	return statusCode(status)
+}
+
+// statusCode returns the generic code of an HTTP status, its status text in
+// snake case, e.g. not_found for 404
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// writeAPIError responds with apiErr, as problem details when the response
+// goes through ProblemErrors. The message is also sent as error, the field
+// clients read before codes were added.
+func writeAPIError(w http.ResponseWriter, apiErr *APIError) {
+	if instance, ok := problemInstance(w); ok {
+		respondProblem(w, apiErr, instance)
+		return
+	}
+	respondJSON(w, apiErr.Status, map[string]string{
+		"code":    apiErr.Code,
+		"message": apiErr.Message,
+		"error":   apiErr.Message,
+	})
+}
+
+// respondDomainError responds with status and message, coded by the domain
+// error err
+func respondDomainError(w http.ResponseWriter, status int, err error, message string) {
+	writeAPIError(w, &APIError{Status: status, Code: errorCode(err, status), Message: message})
+}
+
+// RespondError responds with status and message, coded by status, in the
+// same shape as the API handlers; for handlers outside this package
+func RespondError(w http.ResponseWriter, status int, message string) {
+	respondError(w, status, message)
+}
+
+// RespondDomainError responds like RespondError, coded by the domain error
+// err when it has a code
+func RespondDomainError(w http.ResponseWriter, status int, err error, message string) {
+	respondDomainError(w, status, err, message)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestErrorCode tests that domain errors, also when wrapped, get their own
+// codes and other errors the code of their status
+func TestErrorCode(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		status       int
+		expectedCode string
+	}{
+		{
+			name:         "Domain error",
+			err:          domain.ErrPostNotFound,
+			status:       http.StatusNotFound,
+			expectedCode: "post_not_found",
+		},
+		{
+			name:         "Wrapped domain error",
+			err:          fmt.Errorf("%w: 300 characters, more than the limit of 280", domain.ErrPostContentTooLong),
+			status:       http.StatusBadRequest,
+			expectedCode: "post_content_too_long",
+		},
+		{
+			name:         "Other error",
+			err:          errors.New("database error"),
+			status:       http.StatusInternalServerError,
+			expectedCode: "internal_server_error",
+		},
+		{
+			name:         "Multi-word status",
+			status:       http.StatusMethodNotAllowed,
+			expectedCode: "method_not_allowed",
+		},
+		{
+			name:         "Unknown status",
+			status:       599,
+			expectedCode: "error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := errorCode(tc.err, tc.status); code != tc.expectedCode {
+				t.Errorf("errorCode() = %q, want %q", code, tc.expectedCode)
+			}
+		})
+	}
+}
+
+// TestRespondDomainError tests that handlers report domain errors with
+// their code, keeping the message for humans
+func TestRespondDomainError(t *testing.T) {
+	mockPostService := &mockPostService{
+		getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+			return nil, domain.ErrPostNotFound
+		},
+	}
+	mockPostCache := &mockPostCache{
+		getPostFunc: func(id string) (*domain.Post, error) {
+			return nil, errors.New("cache miss")
+		},
+	}
+	handler := NewPostHandler(mockPostService, mockPostCache)
+
+	rr := httptest.NewRecorder()
+	handler.GetPostHandler()(rr, httptest.NewRequest(http.MethodGet, "/api/posts/post_404", nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if body["code"] != "post_not_found" {
+		t.Errorf("Expected code %q, got %q", "post_not_found", body["code"])
+	}
+	if body["message"] != "Post not found" || body["error"] != "Post not found" {
+		t.Errorf("Expected message %q, got %v", "Post not found", body)
+	}
+}
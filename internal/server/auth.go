@@ -0,0 +1,310 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/auth"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// Errors for requests whose Authorization header carries no usable Basic
+// credentials
+var (
+	errMissingCredentials   = errors.New("missing credentials")
+	errMalformedCredentials = errors.New("malformed Authorization header")
+)
+
+// authChallenge is sent with 401 responses so browsers prompt for credentials
+const authChallenge = `Basic realm="tigertail", charset="UTF-8"`
+
+// maxAuthCacheEntries bounds the auth cache; expired entries are swept when
+// it fills up
+const maxAuthCacheEntries = 10000
+
+// authCacheEntry is a cached successful authentication
+type authCacheEntry struct {
+	userID    string
+	expiresAt time.Time
+}
+
+// FailedLoginLog records failed authentication attempts for security monitoring
+type FailedLoginLog interface {
+	RecordFailedLogin(attempt domain.FailedLogin) error
+	RecentFailedLogins() ([]domain.FailedLogin, error)
+}
+
+// authenticator authenticates requests against the user repository, falling
+// back to the environment credentials when no repository is configured.
+// Successful lookups are cached for ttl so repeated requests skip the
+// repository and password check. Rejected credentials are recorded in
+// failures when it is set. Bearer tokens are accepted when tokens is set.
+type authenticator struct {
+	users    domain.UserRepository
+	ttl      time.Duration
+	now      func() time.Time
+	failures FailedLoginLog
+	tokens   *tokenIssuer
+
+	mu    sync.Mutex
+	cache map[string]authCacheEntry
+}
+
+// newAuthenticator creates a new authenticator caching results for ttl
+// (0 disables caching)
+func newAuthenticator(users domain.UserRepository, ttl time.Duration) *authenticator {
+	return &authenticator{
+		users: users,
+		ttl:   ttl,
+		now:   time.Now,
+		cache: make(map[string]authCacheEntry),
+	}
+}
+
+// authenticate authenticates a request using Basic Auth or a bearer token and
+// returns the user ID
+func (a *authenticator) authenticate(r *http.Request) (string, error) {
+	userID, err := a.check(r)
+	if err != nil {
+		a.recordFailure(r)
+	}
+	return userID, err
+}
+
+// check verifies the bearer token or Basic Auth credentials of a request
+func (a *authenticator) check(r *http.Request) (string, error) {
+	if token, ok := bearerToken(r); ok && a != nil && a.tokens != nil {
+		return a.checkToken(token)
+	}
+
+	if a == nil || a.users == nil {
+		return authenticateRequest(r)
+	}
+
+	username, password, err := basicAuth(r)
+	if err != nil {
+		return "", err
+	}
+
+	key := credentialsKey(username, password)
+	if userID, ok := a.cached(key); ok {
+		return userID, nil
+	}
+
+	user, err := a.users.GetByUsername(username)
+	if err != nil {
+		return "", domain.ErrUserNotFound
+	}
+
+	if !auth.CheckPassword(user.Password, password) {
+		return "", domain.ErrUserNotFound
+	}
+
+	a.store(key, user.ID)
+	return user.ID, nil
+}
+
+// checkToken verifies a bearer token and that it was issued for the user's
+// current credentials, so rotating or changing a password revokes every
+// token issued before
+func (a *authenticator) checkToken(token string) (string, error) {
+	claims, err := a.tokens.verify(token)
+	if err != nil {
+		return "", err
+	}
+
+	// Without a user store tokens are issued for the environment credentials
+	if a.users == nil {
+		_, password := envCredentials()
+		if claims.Version != a.tokens.credentialsVersion(password) {
+			return "", errInvalidToken
+		}
+		return claims.Subject, nil
+	}
+
+	key := credentialsKey(claims.Subject, "\x00token\x00"+token)
+	if userID, ok := a.cached(key); ok {
+		return userID, nil
+	}
+
+	user, err := a.users.GetByID(claims.Subject)
+	if err != nil || claims.Version != a.tokens.credentialsVersion(user.Password) {
+		return "", errInvalidToken
+	}
+
+	a.store(key, user.ID)
+	return user.ID, nil
+}
+
+// recordFailure records a request whose credentials were rejected; requests
+// without credentials are not login attempts and are not recorded
+func (a *authenticator) recordFailure(r *http.Request) {
+	username, _, ok := r.BasicAuth()
+	if !ok {
+		return
+	}
+	a.recordFailedLogin(r, username)
+}
+
+// recordFailedLogin records a rejected login attempt for username
+func (a *authenticator) recordFailedLogin(r *http.Request, username string) {
+	if a == nil || a.failures == nil {
+		return
+	}
+
+	attempt := domain.FailedLogin{
+		Time:     a.now().UTC(),
+		Username: username,
+		IP:       clientIP(r),
+	}
+	if err := a.failures.RecordFailedLogin(attempt); err != nil {
+		log.Printf("Error recording failed login: %v", err)
+	}
+}
+
+// basicAuth returns the Basic Auth credentials of a request. A missing
+// header, or one using another scheme, gives errMissingCredentials; a Basic
+// header that cannot be decoded gives errMalformedCredentials.
+func basicAuth(r *http.Request) (string, string, error) {
+	header := r.Header.Get("Authorization")
+	scheme, _, _ := strings.Cut(header, " ")
+	if header == "" || !strings.EqualFold(scheme, "Basic") {
+		return "", "", errMissingCredentials
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", "", errMalformedCredentials
+	}
+	return username, password, nil
+}
+
+// respondAuthError writes the response for a failed authentication: 400 for
+// a malformed Authorization header, 401 with a Bearer challenge for a
+// rejected token, otherwise 401 with a Basic challenge
+func respondAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errMalformedCredentials):
+		respondError(w, http.StatusBadRequest, "Malformed Authorization header")
+		return
+	case errors.Is(err, errExpiredToken):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="token expired"`)
+		respondError(w, http.StatusUnauthorized, "Token expired")
+		return
+	case errors.Is(err, errInvalidToken):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		respondError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", authChallenge)
+	respondError(w, http.StatusUnauthorized, "Unauthorized")
+}
+
+// credentialsKey hashes credentials so the cache never holds plaintext passwords
+func credentialsKey(username, password string) string {
+	sum := sha256.Sum256([]byte(username + "\x00" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// cached returns the user ID cached for key if it has not expired
+func (a *authenticator) cached(key string) (string, bool) {
+	if a.ttl <= 0 {
+		return "", false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.cache[key]
+	if !ok {
+		return "", false
+	}
+	if !a.now().Before(entry.expiresAt) {
+		delete(a.cache, key)
+		return "", false
+	}
+	return entry.userID, true
+}
+
+// store caches a successful authentication
+func (a *authenticator) store(key, userID string) {
+	if a.ttl <= 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if len(a.cache) >= maxAuthCacheEntries {
+		for k, entry := range a.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(a.cache, k)
+			}
+		}
+	}
+	if len(a.cache) >= maxAuthCacheEntries {
+		return
+	}
+
+	a.cache[key] = authCacheEntry{userID: userID, expiresAt: now.Add(a.ttl)}
+}
+
+// invalidateUser drops every cached authentication of userID, so a changed
+// password takes effect immediately
+func (a *authenticator) invalidateUser(userID string) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for key, entry := range a.cache {
+		if entry.userID == userID {
+			delete(a.cache, key)
+		}
+	}
+}
+
+// requireAdmin authenticates the request and checks that it belongs to the
+// administrator, writing an error response and returning false otherwise
+func (a *authenticator) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	userID, err := a.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return false
+	}
+
+	if userID != adminUserID {
+		respondError(w, http.StatusForbidden, "Forbidden")
+		return false
+	}
+
+	return true
+}
+
+// adminOnly wraps next so that it is only served to the administrator
+func (a *authenticator) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.requireAdmin(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin wraps next so that it is only served to requests carrying
+// the AUTH_USERNAME and AUTH_PASSWORD Basic Auth credentials, for binaries
+// that run without a user store
+func RequireAdmin(next http.Handler) http.Handler {
+	var a *authenticator
+	return a.adminOnly(next)
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/auth"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/service"
+)
+
+// TestAuthenticatorCache tests that successful authentications are cached for the TTL
+func TestAuthenticatorCache(t *testing.T) {
+	testCases := []struct {
+		name            string
+		ttl             time.Duration
+		elapsed         time.Duration
+		expectedLookups int
+	}{
+		{
+			name:            "Second request within TTL",
+			ttl:             30 * time.Second,
+			elapsed:         10 * time.Second,
+			expectedLookups: 1,
+		},
+		{
+			name:            "Second request after TTL",
+			ttl:             30 * time.Second,
+			elapsed:         30 * time.Second,
+			expectedLookups: 2,
+		},
+		{
+			name:            "Caching disabled",
+			ttl:             0,
+			elapsed:         0,
+			expectedLookups: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := newMockUserRepository(&domain.User{
+				ID:       adminUserID,
+				Username: "admin",
+				Password: "password",
+			})
+			now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+			auth := newAuthenticator(users, tc.ttl)
+			auth.now = func() time.Time { return now }
+
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/api/posts/mine", nil)
+				req.SetBasicAuth("admin", "password")
+				userID, err := auth.authenticate(req)
+				if err != nil {
+					t.Fatalf("authenticate() error = %v", err)
+				}
+				if userID != adminUserID {
+					t.Errorf("authenticate() = %s, want %s", userID, adminUserID)
+				}
+				now = now.Add(tc.elapsed)
+			}
+
+			if users.usernameLookups != tc.expectedLookups {
+				t.Errorf("Expected %d repository lookups, got %d", tc.expectedLookups, users.usernameLookups)
+			}
+		})
+	}
+}
+
+// TestAuthenticatorCacheRejectsOtherPasswords tests that a cached result is
+// only reused for the exact credentials and is dropped on invalidation
+func TestAuthenticatorCacheRejectsOtherPasswords(t *testing.T) {
+	users := newMockUserRepository(&domain.User{
+		ID:       adminUserID,
+		Username: "admin",
+		Password: "password",
+	})
+	auth := newAuthenticator(users, time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts/mine", nil)
+	req.SetBasicAuth("admin", "password")
+	if _, err := auth.authenticate(req); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+
+	wrong := httptest.NewRequest(http.MethodGet, "/api/posts/mine", nil)
+	wrong.SetBasicAuth("admin", "wrong")
+	if _, err := auth.authenticate(wrong); err == nil {
+		t.Error("Expected wrong password to be rejected")
+	}
+
+	auth.invalidateUser(adminUserID)
+	users.users[adminUserID].Password = "changed"
+	if _, err := auth.authenticate(req); err == nil {
+		t.Error("Expected old password to be rejected after invalidation")
+	}
+}
+
+// TestAuthorizationHeaderErrors tests that missing credentials are challenged
+// with 401 while malformed Basic headers are rejected with 400
+func TestAuthorizationHeaderErrors(t *testing.T) {
+	testCases := []struct {
+		name              string
+		authorization     string
+		expectedStatus    int
+		expectedChallenge bool
+	}{
+		{
+			name:              "Missing header",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: true,
+		},
+		{
+			name:              "Other scheme",
+			authorization:     "Bearer token",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: true,
+		},
+		{
+			name:           "Invalid base64",
+			authorization:  "Basic not-base64!",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "No colon separator",
+			authorization:  "Basic " + base64.StdEncoding.EncodeToString([]byte("admin")),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:              "Wrong password",
+			authorization:     "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:wrong")),
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: true,
+		},
+		{
+			name:           "Valid credentials",
+			authorization:  "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:password")),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				listByUserFunc: func(userID string, page, limit int) ([]*domain.Post, int, error) {
+					return []*domain.Post{}, 0, nil
+				},
+			}
+			handler := NewPostHandler(mockPostService, &mockPostCache{})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/mine", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.MyPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			challenge := rr.Header().Get("WWW-Authenticate")
+			if tc.expectedChallenge && !strings.HasPrefix(challenge, "Basic ") {
+				t.Errorf("Expected a Basic WWW-Authenticate challenge, got %q", challenge)
+			}
+			if !tc.expectedChallenge && challenge != "" {
+				t.Errorf("Did not expect a WWW-Authenticate challenge, got %q", challenge)
+			}
+		})
+	}
+}
+
+// TestPasswordChangeInvalidatesAuthCache tests that a password changed
+// through the user service stops the old password authenticating at once,
+// rather than once its cached result expires
+func TestPasswordChangeInvalidatesAuthCache(t *testing.T) {
+	hash, err := auth.HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	users := newMockUserRepository(&domain.User{ID: adminUserID, Username: "admin", Password: hash})
+	userService := service.NewUserService(users)
+	server := New(Config{Host: "localhost", Port: 8080}, &MockPostService{}, &MockPostCache{}, &MockDBPinger{}, &MockPostCache{},
+		WithUserRepository(users),
+		WithUserService(userService))
+	server.options.AuthCacheTTL = time.Minute
+	server.registerRoutes()
+
+	getConfig := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+		req.SetBasicAuth("admin", "password")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := getConfig(); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+
+	if err := userService.ChangePassword(adminUserID, "password", "new-password"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if code := getConfig(); code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d with the old password, got %d", http.StatusUnauthorized, code)
+	}
+}
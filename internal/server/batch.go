@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// errTooManyIDs is returned when a batch request names more IDs than allowed
+var errTooManyIDs = errors.New("too many ids")
+
+// BatchGetPostsHandler handles POST /posts/batch requests with a body of the
+// form {"ids": ["post_1", "post_2"]}, returning the posts that exist. IDs
+// are decoded one at a time and the request is rejected as soon as the
+// array grows past the configured maximum, so oversized bodies are never
+// buffered in full.
+func (h *PostHandler) BatchGetPostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if h.options.PostMaxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, h.options.PostMaxBodyBytes)
+		}
+
+		ids, err := decodeBatchIDs(json.NewDecoder(r.Body), h.options.MaxBatchIDs)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.Is(err, errTooManyIDs) {
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d ids are allowed", h.options.MaxBatchIDs))
+			} else if errors.As(err, &tooLarge) {
+				respondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+			} else {
+				respondError(w, http.StatusBadRequest, "Invalid request body")
+			}
+			return
+		}
+
+		posts := make([]*domain.PostWithUser, 0, len(ids))
+		for _, id := range ids {
+			// Clients may use either the raw or the opaque form of the ID
+			id, err := h.postIDs().decode(id)
+			if err != nil {
+				continue
+			}
+			post, err := h.postService.GetByID(id)
+			if err != nil {
+				if err == domain.ErrPostNotFound {
+					continue
+				}
+				respondError(w, http.StatusInternalServerError, "Failed to get posts")
+				return
+			}
+			if post != nil {
+				posts = append(posts, post)
+			}
+		}
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"posts": posts,
+		})
+	}
+}
+
+// decodeBatchIDs reads the ids array from a batch request body token by
+// token, failing with errTooManyIDs once more than max IDs are seen (0 means
+// no limit). Unknown fields are skipped.
+func decodeBatchIDs(dec *json.Decoder, max int) ([]string, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key, _ := token.(string); key != "ids" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return nil, err
+		}
+		for dec.More() {
+			if max > 0 && len(ids) >= max {
+				return nil, errTooManyIDs
+			}
+			var id string
+			if err := dec.Decode(&id); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// expectDelim reads the next token and checks it is the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %q, got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// idArrayReader generates a {"ids": [...]} body with n elements on demand
+// and counts the bytes read from it
+type idArrayReader struct {
+	n, emitted int
+	pending    string
+	read       int
+}
+
+func (r *idArrayReader) Read(p []byte) (int, error) {
+	for r.pending == "" {
+		switch {
+		case r.emitted == 0:
+			r.pending = `{"ids":["post_0"`
+		case r.emitted < r.n:
+			r.pending = `,"post_x"`
+		case r.emitted == r.n:
+			r.pending = `]}`
+		default:
+			return 0, io.EOF
+		}
+		r.emitted++
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.read += n
+	return n, nil
+}
+
+// TestBatchGetPostsHandler tests that existing posts are returned and
+// missing IDs are skipped
+func TestBatchGetPostsHandler(t *testing.T) {
+	mockPostService := &mockPostService{
+		getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+			if id == "post_missing" {
+				return nil, domain.ErrPostNotFound
+			}
+			return &domain.PostWithUser{Post: domain.Post{ID: id}}, nil
+		},
+	}
+
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	body := `{"ids": ["post_1", "post_missing", "post_2"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/posts/batch", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.BatchGetPostsHandler()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		Posts []domain.PostWithUser `json:"posts"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Posts) != 2 || response.Posts[0].ID != "post_1" || response.Posts[1].ID != "post_2" {
+		t.Errorf("Expected posts post_1 and post_2, got %+v", response.Posts)
+	}
+}
+
+// TestBatchGetPostsHandler_TooManyIDs tests that an oversized ids array is
+// rejected once the cap is passed, without reading the rest of the body
+func TestBatchGetPostsHandler_TooManyIDs(t *testing.T) {
+	lookups := 0
+	mockPostService := &mockPostService{
+		getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+			lookups++
+			return &domain.PostWithUser{Post: domain.Post{ID: id}}, nil
+		},
+	}
+
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	handler.options.MaxBatchIDs = 10
+
+	body := &idArrayReader{n: 1000000}
+	req := httptest.NewRequest(http.MethodPost, "/api/posts/batch", body)
+	rr := httptest.NewRecorder()
+	handler.BatchGetPostsHandler()(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if lookups != 0 {
+		t.Errorf("Expected no post lookups, got %d", lookups)
+	}
+	// The decoder reads ahead in small chunks, so only a tiny prefix of the
+	// ~9MB body should have been consumed
+	if body.read > 64*1024 {
+		t.Errorf("Expected early rejection, but %d bytes were read", body.read)
+	}
+}
+
+// TestBatchGetPostsHandler_BodyTooLarge tests that a body over the size
+// limit is rejected even when the ids array stays short, e.g. because of a
+// large unknown field
+func TestBatchGetPostsHandler_BodyTooLarge(t *testing.T) {
+	handler := NewPostHandler(&mockPostService{}, &mockPostCache{})
+	handler.options.PostMaxBodyBytes = 64
+
+	body := `{"padding": "` + strings.Repeat("x", 128) + `", "ids": ["post_1"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/posts/batch", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.BatchGetPostsHandler()(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxByUsersLimit is the most posts returned by a by-users request
+const maxByUsersLimit = 100
+
+// PostsByUsersHandler handles POST /posts/by-users requests with a body of
+// the form {"user_ids": ["user_1", "user_2"], "limit": 20}, returning the
+// most recent posts across those users for clients building their own
+// timeline. The number of user IDs and the limit are capped.
+func (h *PostHandler) PostsByUsersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var requestBody struct {
+			UserIDs []string `json:"user_ids"`
+			Limit   int      `json:"limit"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if len(requestBody.UserIDs) == 0 {
+			respondError(w, http.StatusBadRequest, "At least one user ID is required")
+			return
+		}
+		if h.options.MaxByUsersIDs > 0 && len(requestBody.UserIDs) > h.options.MaxByUsersIDs {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d user_ids are allowed", h.options.MaxByUsersIDs))
+			return
+		}
+
+		limit := requestBody.Limit
+		if limit == 0 {
+			limit = h.options.PostsDefaultLimit
+		}
+		if limit < 1 || limit > maxByUsersLimit {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", maxByUsersLimit))
+			return
+		}
+
+		posts, err := h.postService.ListByUsers(requestBody.UserIDs, limit)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to get posts")
+			return
+		}
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"posts": posts,
+			"limit": limit,
+		})
+	}
+}
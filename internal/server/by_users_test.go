@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestPostsByUsersHandler tests that the most recent posts across several
+// users are returned
+func TestPostsByUsersHandler(t *testing.T) {
+	var requestedIDs []string
+	var requestedLimit int
+	mockPostService := &mockPostService{
+		listByUsersFunc: func(userIDs []string, limit int) ([]*domain.PostWithUser, error) {
+			requestedIDs, requestedLimit = userIDs, limit
+			return []*domain.PostWithUser{
+				{Post: domain.Post{ID: "post_3", UserID: "user_2"}, Username: "bob"},
+				{Post: domain.Post{ID: "post_2", UserID: "user_1"}, Username: "alice"},
+			}, nil
+		},
+	}
+
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	body := `{"user_ids": ["user_1", "user_2"], "limit": 5}`
+	req := httptest.NewRequest(http.MethodPost, "/api/posts/by-users", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.PostsByUsersHandler()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if !reflect.DeepEqual(requestedIDs, []string{"user_1", "user_2"}) || requestedLimit != 5 {
+		t.Errorf("Expected users [user_1 user_2] with limit 5, got %v with limit %d", requestedIDs, requestedLimit)
+	}
+
+	var response struct {
+		Posts []domain.PostWithUser `json:"posts"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if len(response.Posts) != 2 || response.Posts[0].ID != "post_3" || response.Posts[1].ID != "post_2" {
+		t.Errorf("Expected posts post_3 and post_2, got %+v", response.Posts)
+	}
+}
+
+// TestPostsByUsersHandlerValidation tests that empty user lists and requests
+// over the caps are rejected
+func TestPostsByUsersHandlerValidation(t *testing.T) {
+	testCases := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name:            "Empty user list",
+			body:            `{"user_ids": []}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "At least one user ID is required",
+		},
+		{
+			name:            "Missing user list",
+			body:            `{"limit": 5}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "At least one user ID is required",
+		},
+		{
+			name:            "Too many users",
+			body:            `{"user_ids": ["user_1", "user_2", "user_3"]}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "At most 2 user_ids are allowed",
+		},
+		{
+			name:            "Limit over the cap",
+			body:            `{"user_ids": ["user_1"], "limit": 101}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "Limit must be between 1 and 100",
+		},
+		{
+			name:            "Negative limit",
+			body:            `{"user_ids": ["user_1"], "limit": -1}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "Limit must be between 1 and 100",
+		},
+		{
+			name:           "Users at the cap",
+			body:           `{"user_ids": ["user_1", "user_2"], "limit": 100}`,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewPostHandler(&mockPostService{}, &mockPostCache{})
+			handler.options.MaxByUsersIDs = 2
+
+			req := httptest.NewRequest(http.MethodPost, "/api/posts/by-users", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			handler.PostsByUsersHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedMessage != "" {
+				var response map[string]string
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to parse response body: %v", err)
+				}
+				if response["error"] != tc.expectedMessage {
+					t.Errorf("Expected error %q, got %q", tc.expectedMessage, response["error"])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// AsyncCacheWrites runs cache writes in the background and tracks them, so
+// a shutdown can wait for them instead of leaving the cache half-updated
+type AsyncCacheWrites struct {
+	wg sync.WaitGroup
+}
+
+// Go runs write in a new goroutine
+func (c *AsyncCacheWrites) Go(write func()) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		write()
+	}()
+}
+
+// Wait blocks until every write started so far has finished, or returns the
+// context's error if it is done first
+func (c *AsyncCacheWrites) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestStopWaitsForCacheWrites tests that stopping the server waits for a
+// cache write a handler started after responding
+func TestStopWaitsForCacheWrites(t *testing.T) {
+	var written atomic.Bool
+	started := make(chan struct{})
+	release := make(chan struct{})
+	postService := &mockPostService{
+		getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+			return &domain.PostWithUser{Post: domain.Post{ID: id, Content: "Hello"}}, nil
+		},
+	}
+	postCache := &mockPostCache{
+		setPostFunc: func(post *domain.Post) error {
+			close(started)
+			<-release
+			written.Store(true)
+			return nil
+		},
+	}
+	server := New(Config{Host: "localhost", Port: 8080}, postService, postCache, &mockDBPinger{}, &mockCachePinger{})
+	server.registerRoutes()
+
+	// A cache miss responds right away and writes the cache in the background
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/posts/post_1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	<-started
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Stop(ctx); err != nil {
+		t.Fatalf("Error stopping server: %v", err)
+	}
+	if !written.Load() {
+		t.Error("Expected the cache write to complete before Stop returned")
+	}
+}
+
+// TestAsyncCacheWritesWaitTimeout tests that waiting gives up when the
+// context is done before the writes finish
+func TestAsyncCacheWritesWaitTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	writes := &AsyncCacheWrites{}
+	writes.Go(func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := writes.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// toCamelCase maps a response onto a generic JSON value with every object key
+// converted from snake_case to camelCase
+func toCamelCase(data interface{}) (interface{}, error) {
+	value, err := toJSONValue(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return camelizeKeys(value), nil
+}
+
+// toJSONValue maps a response onto a generic JSON value so it can be rewritten
+func toJSONValue(data interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// camelizeKeys recursively renames the keys of decoded JSON objects
+func camelizeKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[camelCaseKey(key)] = camelizeKeys(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = camelizeKeys(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// camelCaseKey converts a snake_case key to camelCase
+func camelCaseKey(key string) string {
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
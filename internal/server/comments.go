@@ -0,0 +1,132 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// CommentsHandler handles GET and POST /posts/:id/comments requests,
+// listing the comments on a post oldest first and commenting as the caller
+func (h *PostHandler) CommentsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET and POST methods
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication before anything else when commenting
+		var userID string
+		if r.Method == http.MethodPost {
+			var err error
+			userID, err = h.auth.authenticate(r)
+			if err != nil {
+				respondAuthError(w, err)
+				return
+			}
+		}
+
+		if h.comments == nil {
+			respondError(w, http.StatusServiceUnavailable, "Comments are not available")
+			return
+		}
+
+		// Extract post ID from URL, /api/posts/{id}/comments; clients may use
+		// either the raw or the opaque form of the ID
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		id, err := h.postIDs().decode(parts[len(parts)-2])
+		if err != nil || id == "" {
+			respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			return
+		}
+
+		if _, err := h.postService.GetByID(id); err != nil {
+			if errors.Is(err, domain.ErrPostNotFound) {
+				respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			} else {
+				respondError(w, http.StatusInternalServerError, "Failed to get post")
+			}
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			h.createComment(w, r, id, userID)
+			return
+		}
+		h.listComments(w, r, id)
+	}
+}
+
+// listComments responds with a page of the comments on a post
+func (h *PostHandler) listComments(w http.ResponseWriter, r *http.Request, postID string) {
+	page, limit, ok := h.parsePaginationParams(w, r, h.options.PostsDefaultLimit)
+	if !ok {
+		return
+	}
+
+	comments, err := h.comments.ListByPost(postID, (page-1)*limit, limit)
+	if err != nil {
+		log.Printf("Error listing comments: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get comments")
+		return
+	}
+	total, err := h.comments.Count(postID)
+	if err != nil {
+		log.Printf("Error counting comments: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get comments")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"comments": comments,
+		"page":     page,
+		"limit":    limit,
+		"total":    total,
+	})
+}
+
+// createComment adds the caller's comment to a post, validated like post
+// content
+func (h *PostHandler) createComment(w http.ResponseWriter, r *http.Request, postID, userID string) {
+	content, ok := h.decodePostContent(w, r, h.options.CommentMaxLength)
+	if !ok {
+		return
+	}
+	if strings.TrimSpace(content) == "" {
+		respondError(w, http.StatusBadRequest, "Content is required")
+		return
+	}
+
+	comment := &domain.Comment{
+		ID:        newCommentID(),
+		PostID:    postID,
+		UserID:    userID,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+	if err := h.comments.Create(comment); err != nil {
+		log.Printf("Error creating comment: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to create comment")
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"comment": comment,
+	})
+}
+
+// newCommentID generates a random comment ID
+func newCommentID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "comment_" + time.Now().Format("20060102150405.000000000")
+	}
+	return "comment_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// mockCommentRepository is an in-memory domain.CommentRepository
+type mockCommentRepository struct {
+	mu       sync.Mutex
+	comments []*domain.Comment
+}
+
+func (m *mockCommentRepository) Create(comment *domain.Comment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.comments = append(m.comments, comment)
+	return nil
+}
+
+func (m *mockCommentRepository) ListByPost(postID string, offset, limit int) ([]*domain.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	comments := make([]*domain.Comment, 0)
+	for _, comment := range m.comments {
+		if comment.PostID == postID {
+			comments = append(comments, comment)
+		}
+	}
+	if offset >= len(comments) {
+		return []*domain.Comment{}, nil
+	}
+	if offset+limit < len(comments) {
+		comments = comments[:offset+limit]
+	}
+	return comments[offset:], nil
+}
+
+func (m *mockCommentRepository) Count(postID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, comment := range m.comments {
+		if comment.PostID == postID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockCommentRepository) Delete(id string) error {
+	return nil
+}
+
+// TestCommentsHandler tests commenting on a post and listing its comments
+// page by page
+func TestCommentsHandler(t *testing.T) {
+	mockPostService := &mockPostService{
+		getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+			if id != "post_1" {
+				return nil, domain.ErrPostNotFound
+			}
+			return &domain.PostWithUser{Post: domain.Post{ID: id, UserID: "user_2"}}, nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	handler.comments = &mockCommentRepository{}
+
+	// Steps run in order against the same comments
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		authenticated  bool
+		expectedStatus int
+		expectedIDs    int
+		expectedTotal  int
+	}{
+		{
+			name:           "First comment",
+			method:         http.MethodPost,
+			path:           "/api/posts/post_1/comments",
+			body:           `{"content":"First"}`,
+			authenticated:  true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Second comment",
+			method:         http.MethodPost,
+			path:           "/api/posts/post_1/comments",
+			body:           `{"content":"Second"}`,
+			authenticated:  true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Blank comment",
+			method:         http.MethodPost,
+			path:           "/api/posts/post_1/comments",
+			body:           `{"content":"   "}`,
+			authenticated:  true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unauthenticated",
+			method:         http.MethodPost,
+			path:           "/api/posts/post_1/comments",
+			body:           `{"content":"Anonymous"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Comment on missing post",
+			method:         http.MethodPost,
+			path:           "/api/posts/post_9/comments",
+			body:           `{"content":"Hello"}`,
+			authenticated:  true,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "First page",
+			method:         http.MethodGet,
+			path:           "/api/posts/post_1/comments?limit=1",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    1,
+			expectedTotal:  2,
+		},
+		{
+			name:           "Past the last page",
+			method:         http.MethodGet,
+			path:           "/api/posts/post_1/comments?page=3&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedTotal:  2,
+		},
+		{
+			name:           "Invalid page",
+			method:         http.MethodGet,
+			path:           "/api/posts/post_1/comments?page=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "List missing post",
+			method:         http.MethodGet,
+			path:           "/api/posts/post_9/comments",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodDelete,
+			path:           "/api/posts/post_1/comments",
+			authenticated:  true,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+			if step.authenticated {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.CommentsHandler()(rr, req)
+
+			if rr.Code != step.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", step.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			switch rr.Code {
+			case http.StatusCreated:
+				var response struct {
+					Comment domain.Comment `json:"comment"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to parse response: %v", err)
+				}
+				if response.Comment.ID == "" || response.Comment.PostID != "post_1" || response.Comment.UserID != "user_1" {
+					t.Errorf("Expected a comment by user_1 on post_1, got %+v", response.Comment)
+				}
+			case http.StatusOK:
+				var response struct {
+					Comments []domain.Comment `json:"comments"`
+					Total    int              `json:"total"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to parse response: %v", err)
+				}
+				if len(response.Comments) != step.expectedIDs || response.Total != step.expectedTotal {
+					t.Errorf("Expected %d comments of %d, got %d of %d", step.expectedIDs, step.expectedTotal, len(response.Comments), response.Total)
+				}
+				if step.expectedIDs > 0 && response.Comments[0].Content != "First" {
+					t.Errorf("Expected the oldest comment first, got %q", response.Comments[0].Content)
+				}
+			}
+		})
+	}
+}
+
+// TestCommentsRoute tests that comment requests for a post are dispatched
+// to the comments handler, which is unavailable without a comment repository
+func TestCommentsRoute(t *testing.T) {
+	testCases := []struct {
+		name           string
+		opts           []ServerOption
+		expectedStatus int
+	}{
+		{
+			name:           "Comments",
+			opts:           []ServerOption{WithCommentRepository(&mockCommentRepository{})},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "No comment repository",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := New(Config{Host: "localhost", Port: 8080}, &MockPostService{}, &MockPostCache{}, &MockDBPinger{}, &MockPostCache{}, tc.opts...)
+			server.registerRoutes()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/post_1/comments", nil)
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// compressiblePayload returns text drawn from a small vocabulary, which
+// compresses noticeably better at higher levels
+func compressiblePayload() string {
+	words := []string{"tiger", "tail", "post", "user", "cache", "redis", "hello", "world", "stream", "feed"}
+	random := rand.New(rand.NewSource(1))
+	var b strings.Builder
+	for i := 0; i < 20000; i++ {
+		b.WriteString(words[random.Intn(len(words))])
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// gzipResponse serves payload through the gzip middleware at level
+func gzipResponse(t *testing.T, level int, payload string, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, payload)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rr := httptest.NewRecorder()
+	Gzip(next, level).ServeHTTP(rr, req)
+	return rr
+}
+
+// TestGzipLevel tests that the configured level is applied and the body
+// decompresses to the original payload
+func TestGzipLevel(t *testing.T) {
+	payload := compressiblePayload()
+
+	sizes := make(map[int]int)
+	for _, level := range []int{1, 9} {
+		rr := gzipResponse(t, level, payload, "gzip, deflate")
+
+		if rr.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("Level %d: expected gzip Content-Encoding, got %q", level, rr.Header().Get("Content-Encoding"))
+		}
+		sizes[level] = rr.Body.Len()
+
+		reader, err := gzip.NewReader(rr.Body)
+		if err != nil {
+			t.Fatalf("Level %d: failed to read gzip body: %v", level, err)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Level %d: failed to decompress body: %v", level, err)
+		}
+		if string(body) != payload {
+			t.Errorf("Level %d: decompressed body does not match payload", level)
+		}
+	}
+
+	if sizes[9] >= sizes[1] {
+		t.Errorf("Expected level 9 (%d bytes) to compress smaller than level 1 (%d bytes)", sizes[9], sizes[1])
+	}
+}
+
+// TestGzipSkipped tests that responses are left uncompressed when the client
+// does not accept gzip or compression is disabled
+func TestGzipSkipped(t *testing.T) {
+	testCases := []struct {
+		name           string
+		level          int
+		acceptEncoding string
+	}{
+		{name: "No Accept-Encoding", level: 6, acceptEncoding: ""},
+		{name: "Other encodings only", level: 6, acceptEncoding: "br, deflate"},
+		{name: "Gzip refused", level: 6, acceptEncoding: "gzip;q=0"},
+		{name: "Disabled", level: 0, acceptEncoding: "gzip"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := gzipResponse(t, tc.level, "hello", tc.acceptEncoding)
+
+			if rr.Header().Get("Content-Encoding") != "" {
+				t.Errorf("Expected no Content-Encoding, got %q", rr.Header().Get("Content-Encoding"))
+			}
+			if rr.Body.String() != "hello" {
+				t.Errorf("Expected plain body, got %q", rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// csvHeader is the header row of the CSV posts export
+var csvHeader = []string{"id", "user_id", "username", "content", "created_at"}
+
+// CSVPostsHandler handles GET /posts.csv requests, returning a page of posts
+// as CSV. Pages are capped like the JSON listing; set CSV_REQUIRE_ADMIN to
+// restrict the export to the administrator. Rendered pages are cached for
+// FEED_CACHE_TTL.
+//
+// With FEED_CURSORS set, responses carry the time of their newest post as
+// Last-Modified and a Link to the next poll. Its since cursor returns only
+// the posts created or edited after that post, oldest first, or 304 Not
+// Modified when there are none, so polling clients never fetch a post twice.
+func (h *PostHandler) CSVPostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if h.options.CSVRequireAdmin && !h.auth.requireAdmin(w, r) {
+			return
+		}
+
+		since := r.URL.Query().Get("since")
+		if h.options.FeedCursors && since != "" {
+			h.respondPostsCSVSince(w, r, since)
+			return
+		}
+
+		page, limit, ok := h.parsePaginationParams(w, r, h.options.PostsDefaultLimit)
+		if !ok {
+			return
+		}
+
+		// Bots tend to poll the same page together, so rendered pages are
+		// shared for a short while
+		rendered, err := h.feeds.get(fmt.Sprintf("csv|%d|%d", page, limit), func() (feed, error) {
+			posts, _, err := h.postService.List(page, limit)
+			if err != nil {
+				return feed{}, err
+			}
+			return h.renderPostsCSV(posts)
+		})
+		if err != nil {
+			log.Printf("Error rendering posts CSV: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to get posts")
+			return
+		}
+
+		h.writePostsCSV(w, r, rendered)
+	}
+}
+
+// respondPostsCSVSince serves the posts created or edited after the since
+// cursor as CSV, or 304 when there are none. Polls are not cached, since
+// each client polls from its own position.
+func (h *PostHandler) respondPostsCSVSince(w http.ResponseWriter, r *http.Request, since string) {
+	at, afterID, err := h.decodeCursor(since)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid since parameter")
+		return
+	}
+
+	limit, ok := parseLimitParam(w, r, h.options.PostsDefaultLimit)
+	if !ok {
+		return
+	}
+
+	posts, err := h.postService.ListModifiedSince(at, afterID, limit)
+	if err != nil {
+		log.Printf("Error getting posts since cursor: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get posts")
+		return
+	}
+
+	// Nothing newer; the client keeps polling from the same position
+	if len(posts) == 0 {
+		h.setFeedLink(w, r, since)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rendered, err := h.renderPostsCSV(posts)
+	if err != nil {
+		log.Printf("Error rendering posts CSV: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get posts")
+		return
+	}
+
+	h.writePostsCSV(w, r, rendered)
+}
+
+// writePostsCSV writes a rendered CSV feed, with its polling cursor when
+// feed cursors are enabled
+func (h *PostHandler) writePostsCSV(w http.ResponseWriter, r *http.Request, rendered feed) {
+	if h.options.FeedCursors && rendered.cursor != "" {
+		w.Header().Set("Last-Modified", rendered.lastModified.UTC().Format(http.TimeFormat))
+		h.setFeedLink(w, r, rendered.cursor)
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="posts.csv"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(rendered.body)
+}
+
+// setFeedLink sets the Link to the next poll of the feed from cursor,
+// keeping the requested limit
+func (h *PostHandler) setFeedLink(w http.ResponseWriter, r *http.Request, cursor string) {
+	query := url.Values{}
+	query.Set("since", cursor)
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		query.Set("limit", limit)
+	}
+	w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, query.Encode()))
+}
+
+// csvSafe neutralizes a user-supplied cell that a spreadsheet would run as
+// a formula, by prefixing it with a quote so it is shown as text
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// renderPostsCSV renders posts as CSV, along with the cursor of the most
+// recently created or edited one
+func (h *PostHandler) renderPostsCSV(posts []*domain.PostWithUser) (feed, error) {
+	// encoding/csv quotes fields containing commas, quotes or newlines
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(csvHeader)
+	var newest *domain.PostWithUser
+	for _, post := range posts {
+		writer.Write([]string{
+			h.exposedPostID(post.ID),
+			post.UserID,
+			csvSafe(post.Username),
+			csvSafe(post.Content),
+			post.CreatedAt.UTC().Format(time.RFC3339),
+		})
+		if newest == nil || post.UpdatedAt.After(newest.UpdatedAt) || (post.UpdatedAt.Equal(newest.UpdatedAt) && post.ID > newest.ID) {
+			newest = post
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return feed{}, err
+	}
+
+	rendered := feed{body: buf.Bytes()}
+	if newest != nil {
+		rendered.cursor = h.encodeCursor(newest.UpdatedAt, newest.ID)
+		rendered.lastModified = newest.UpdatedAt
+	}
+	return rendered, nil
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestCSVPostsHandler tests the CSV header row and quoting of content
+func TestCSVPostsHandler(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	mockPostService := &mockPostService{
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			return []*domain.PostWithUser{
+				{
+					Post: domain.Post{
+						ID:        "post_1",
+						UserID:    "user_1",
+						Content:   "Hello, world\nsecond \"line\"",
+						CreatedAt: createdAt,
+					},
+					Username: "testuser",
+				},
+			}, 1, nil
+		},
+	}
+
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	req := httptest.NewRequest(http.MethodGet, "/api/posts.csv", nil)
+	rr := httptest.NewRecorder()
+	handler.CSVPostsHandler()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("Expected CSV content type, got %q", rr.Header().Get("Content-Type"))
+	}
+
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, "id,user_id,username,content,created_at\n") {
+		t.Errorf("Expected CSV header row, got %q", body)
+	}
+	if !strings.Contains(body, `"Hello, world`+"\n"+`second ""line"""`) {
+		t.Errorf("Expected content to be quoted, got %q", body)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	expected := []string{"post_1", "user_1", "testuser", "Hello, world\nsecond \"line\"", "2024-06-01T12:00:00Z"}
+	for i, field := range expected {
+		if records[1][i] != field {
+			t.Errorf("Field %d = %q, want %q", i, records[1][i], field)
+		}
+	}
+}
+
+// TestCSVSafe tests that cells a spreadsheet would run as formulas are
+// prefixed with a quote and other cells are left alone
+func TestCSVSafe(t *testing.T) {
+	testCases := []struct {
+		cell     string
+		expected string
+	}{
+		{cell: "=SUM(A1:A2)", expected: "'=SUM(A1:A2)"},
+		{cell: "+1", expected: "'+1"},
+		{cell: "-1+2", expected: "'-1+2"},
+		{cell: "@cmd", expected: "'@cmd"},
+		{cell: "\t=1", expected: "'\t=1"},
+		{cell: "Hello = world", expected: "Hello = world"},
+		{cell: "", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		if got := csvSafe(tc.cell); got != tc.expected {
+			t.Errorf("csvSafe(%q) = %q, want %q", tc.cell, got, tc.expected)
+		}
+	}
+}
+
+// TestCSVPostsHandlerRequireAdmin tests the optional admin restriction and limit cap
+func TestCSVPostsHandlerRequireAdmin(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		authenticate   bool
+		expectedStatus int
+	}{
+		{
+			name:           "Unauthenticated",
+			authenticate:   false,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Admin",
+			authenticate:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Limit over cap",
+			query:          "?limit=1000",
+			authenticate:   true,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+					return []*domain.PostWithUser{}, 0, nil
+				},
+			}
+			handler := NewPostHandler(mockPostService, &mockPostCache{})
+			handler.options.CSVRequireAdmin = true
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts.csv"+tc.query, nil)
+			if tc.authenticate {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.CSVPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+// TestCSVPostsHandlerSince tests that polling the feed with the cursor from
+// its Link header returns only newer posts, and 304 when there are none
+func TestCSVPostsHandlerSince(t *testing.T) {
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	posts := []*domain.PostWithUser{
+		{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "First", CreatedAt: base, UpdatedAt: base}},
+		{Post: domain.Post{ID: "post_2", UserID: "user_1", Content: "Second", CreatedAt: base.Add(time.Minute), UpdatedAt: base.Add(time.Minute)}},
+	}
+	mockPostService := &mockPostService{
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			return []*domain.PostWithUser{posts[1], posts[0]}, 2, nil
+		},
+		listModifiedSinceFunc: func(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+			newer := make([]*domain.PostWithUser, 0)
+			for _, post := range posts {
+				if post.UpdatedAt.After(since) || (post.UpdatedAt.Equal(since) && post.ID > afterID) {
+					newer = append(newer, post)
+				}
+			}
+			sort.Slice(newer, func(i, j int) bool {
+				return newer[i].UpdatedAt.Before(newer[j].UpdatedAt)
+			})
+			return newer, nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	handler.options.FeedCursors = true
+
+	poll := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		handler.CSVPostsHandler()(rr, req)
+		return rr
+	}
+	nextPoll := func(rr *httptest.ResponseRecorder) string {
+		link := rr.Header().Get("Link")
+		target, ok := strings.CutSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+		if !ok {
+			t.Fatalf("Expected a next Link header, got %q", link)
+		}
+		return target
+	}
+
+	// The first fetch points past its newest post
+	rr := poll("/api/posts.csv?limit=5")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if lastModified := rr.Header().Get("Last-Modified"); lastModified != "Sat, 01 Jun 2024 12:01:00 GMT" {
+		t.Errorf("Expected Last-Modified of the newest post, got %q", lastModified)
+	}
+	target := nextPoll(rr)
+	if !strings.Contains(target, "limit=5") {
+		t.Errorf("Expected the next poll to keep the limit, got %q", target)
+	}
+
+	// Nothing newer yet
+	rr = poll(target)
+	if rr.Code != http.StatusNotModified {
+		t.Fatalf("Expected status code %d, got %d", http.StatusNotModified, rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected an empty body, got %q", rr.Body.String())
+	}
+	if nextPoll(rr) != target {
+		t.Errorf("Expected the next poll to stay at %q, got %q", target, nextPoll(rr))
+	}
+
+	// A new post and an edit arrive, oldest change first
+	posts = append(posts, &domain.PostWithUser{Post: domain.Post{ID: "post_3", UserID: "user_1", Content: "Third", CreatedAt: base.Add(2 * time.Minute), UpdatedAt: base.Add(2 * time.Minute)}})
+	posts[0].UpdatedAt = base.Add(3 * time.Minute)
+	rr = poll(target)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	records, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 3 || records[1][0] != "post_3" || records[2][0] != "post_1" {
+		t.Fatalf("Expected post_3 then the edited post_1, got %v", records)
+	}
+
+	if rr = poll(nextPoll(rr)); rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status code %d after catching up, got %d", http.StatusNotModified, rr.Code)
+	}
+
+	if rr = poll("/api/posts.csv?since=not-a-cursor"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid cursor, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
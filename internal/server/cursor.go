@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// errInvalidCursor is returned for cursors that were not issued by
+// encodeCursor
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursorSeparator separates the timestamp from the post ID in a cursor
+const cursorSeparator = "|"
+
+// encodeCursor returns the opaque cursor for the (at, id) position of a
+// listing: created_at for the posts listing, updated_at for the feed. The
+// post ID is carried in its exposed form, so opaque IDs stay opaque inside
+// cursors.
+func (h *PostHandler) encodeCursor(at time.Time, id string) string {
+	position := at.UTC().Format(time.RFC3339Nano) + cursorSeparator + h.exposedPostID(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+// decodeCursor returns the timestamp and raw post ID of a cursor issued by
+// encodeCursor
+func (h *PostHandler) decodeCursor(cursor string) (time.Time, string, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+
+	timestamp, id, ok := strings.Cut(string(position), cursorSeparator)
+	if !ok || id == "" {
+		return time.Time{}, "", errInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil || createdAt.IsZero() {
+		return time.Time{}, "", errInvalidCursor
+	}
+	rawID, err := h.postIDs().decode(id)
+	if err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+
+	return createdAt, rawID, nil
+}
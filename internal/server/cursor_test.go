@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// cursorTestPosts returns a mock post service listing posts after a cursor
+// position, newest first, the way the repository does
+func cursorTestPosts(posts []*domain.PostWithUser) *mockPostService {
+	return &mockPostService{
+		listAfterFunc: func(createdAt time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+			page := make([]*domain.PostWithUser, 0)
+			for _, post := range posts {
+				if createdAt.IsZero() || post.CreatedAt.Before(createdAt) || (post.CreatedAt.Equal(createdAt) && post.ID < afterID) {
+					page = append(page, post)
+				}
+			}
+			sort.Slice(page, func(i, j int) bool {
+				if !page[i].CreatedAt.Equal(page[j].CreatedAt) {
+					return page[i].CreatedAt.After(page[j].CreatedAt)
+				}
+				return page[i].ID > page[j].ID
+			})
+			if len(page) > limit {
+				page = page[:limit]
+			}
+			return page, nil
+		},
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			return nil, 0, errors.New("offset listing used with a cursor")
+		},
+	}
+}
+
+// TestGetPostsHandlerCursor tests that following next_cursor walks every
+// post exactly once, including posts created at the same instant, and ends
+// with a null cursor
+func TestGetPostsHandlerCursor(t *testing.T) {
+	createdAt := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+	var posts []*domain.PostWithUser
+	for i, id := range []string{"post_1", "post_2", "post_3", "post_4", "post_5"} {
+		// post_2 to post_4 share a timestamp, so the ID breaks the tie
+		offset := time.Duration(i)
+		if i >= 1 && i <= 3 {
+			offset = 1
+		}
+		posts = append(posts, &domain.PostWithUser{Post: domain.Post{ID: id, Content: "Post " + id, CreatedAt: createdAt.Add(offset * time.Minute)}})
+	}
+
+	for _, format := range []string{PostIDFormatRaw, PostIDFormatOpaque} {
+		t.Run(format, func(t *testing.T) {
+			handler := NewPostHandler(cursorTestPosts(posts), &mockPostCache{})
+			handler.options.PostIDFormat = format
+			handler.options.PostIDSecret = "secret"
+
+			var seen []string
+			cursor := ""
+			for pages := 0; pages < 5; pages++ {
+				// page is ignored once a cursor is given
+				query := url.Values{"cursor": {cursor}, "limit": {"2"}, "page": {"100000"}}
+				req := httptest.NewRequest(http.MethodGet, "/api/posts?"+query.Encode(), nil)
+				rr := httptest.NewRecorder()
+				handler.GetPostsHandler()(rr, req)
+				if rr.Code != http.StatusOK {
+					t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+				}
+
+				var response struct {
+					Posts []struct {
+						ID string `json:"id"`
+					} `json:"posts"`
+					NextCursor *string `json:"next_cursor"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to parse response: %v", err)
+				}
+				for _, post := range response.Posts {
+					id, err := handler.postIDs().decode(post.ID)
+					if err != nil {
+						t.Fatalf("Failed to decode post ID %q: %v", post.ID, err)
+					}
+					seen = append(seen, id)
+				}
+				if response.NextCursor == nil {
+					break
+				}
+				cursor = *response.NextCursor
+
+				position, err := base64.RawURLEncoding.DecodeString(cursor)
+				if err != nil {
+					t.Fatalf("Failed to decode cursor %q: %v", cursor, err)
+				}
+				if format == PostIDFormatOpaque && strings.Contains(string(position), rawPostIDPrefix) {
+					t.Errorf("Expected the cursor to carry an opaque post ID, got %q", position)
+				}
+			}
+
+			expected := "post_5,post_4,post_3,post_2,post_1"
+			if got := strings.Join(seen, ","); got != expected {
+				t.Errorf("Expected posts %s, got %s", expected, got)
+			}
+		})
+	}
+}
+
+// TestGetPostsHandlerInvalidCursor tests that cursors not issued by the
+// server are rejected
+func TestGetPostsHandlerInvalidCursor(t *testing.T) {
+	handler := NewPostHandler(cursorTestPosts(nil), &mockPostCache{})
+
+	for _, cursor := range []string{
+		"not a cursor",
+		base64.RawURLEncoding.EncodeToString([]byte("yesterday|post_1")),
+		base64.RawURLEncoding.EncodeToString([]byte("2025-03-18T12:00:00Z")),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/posts?"+url.Values{"cursor": {cursor}}.Encode(), nil)
+		rr := httptest.NewRecorder()
+		handler.GetPostsHandler()(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for cursor %q, got %d", http.StatusBadRequest, cursor, rr.Code)
+		}
+	}
+}
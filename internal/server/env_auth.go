@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// EnvAuth authenticates requests for binaries that run without a user
+// store: with Basic Auth against the AUTH_USERNAME and AUTH_PASSWORD
+// credentials, or with a bearer token issued by its LoginHandler. Tokens
+// are tied to the password they were issued for, so changing AUTH_PASSWORD
+// revokes them.
+type EnvAuth struct {
+	auth *authenticator
+}
+
+// NewEnvAuth creates an EnvAuth issuing tokens signed with secret and valid
+// for ttl; token login is unavailable when secret is empty
+func NewEnvAuth(secret string, ttl time.Duration) *EnvAuth {
+	auth := newAuthenticator(nil, 0)
+	auth.tokens = newTokenIssuer(secret, ttl)
+	return &EnvAuth{auth: auth}
+}
+
+// Authenticate authenticates a request using Basic Auth or a bearer token
+// and returns the user ID. Otherwise it writes the error response and
+// returns false.
+func (e *EnvAuth) Authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, err := e.auth.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return "", false
+	}
+	return userID, true
+}
+
+// LoginHandler handles POST /api/login requests with a body of the form
+// {"username": "...", "password": "..."}, returning a signed bearer token
+// for the environment credentials. It is unavailable (503) unless a JWT
+// secret is configured.
+func (e *EnvAuth) LoginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if e.auth.tokens == nil {
+			respondError(w, http.StatusServiceUnavailable, "Token login is not available")
+			return
+		}
+
+		username, password, ok := decodeLoginRequest(w, r)
+		if !ok {
+			return
+		}
+
+		expectedUsername, expectedPassword := envCredentials()
+		if username != expectedUsername || password != expectedPassword {
+			respondError(w, http.StatusUnauthorized, "Invalid credentials")
+			return
+		}
+
+		respondToken(w, e.auth.tokens, adminUserID, e.auth.tokens.credentialsVersion(expectedPassword))
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxFeedCacheEntries bounds the feed cache; expired feeds are swept when it
+// fills up
+const maxFeedCacheEntries = 100
+
+// feed is a rendered feed page
+type feed struct {
+	body []byte
+	// cursor is the position of the newest post in the page, which polling
+	// clients pass back as since; empty for an empty page
+	cursor string
+	// lastModified is when the newest post in the page was created or
+	// edited
+	lastModified time.Time
+}
+
+// renderedFeed is a cached feed
+type renderedFeed struct {
+	feed
+	expiresAt time.Time
+}
+
+// feedRender is a feed generation other requests for the same feed wait on
+type feedRender struct {
+	done chan struct{}
+	feed feed
+	err  error
+}
+
+// feedCache keeps rendered feeds for a short TTL, keyed by format and page,
+// so bots polling the same feed don't each query the database. Concurrent
+// requests for a feed that isn't cached share a single generation.
+type feedCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	feeds     map[string]renderedFeed
+	rendering map[string]*feedRender
+}
+
+// newFeedCache creates a feed cache keeping feeds for ttl (0 disables
+// caching, but concurrent generations are still shared)
+func newFeedCache(ttl time.Duration) *feedCache {
+	return &feedCache{
+		ttl:       ttl,
+		now:       time.Now,
+		feeds:     make(map[string]renderedFeed),
+		rendering: make(map[string]*feedRender),
+	}
+}
+
+// get returns the feed for key, calling render when it isn't cached. Only
+// one render per key runs at a time; callers arriving meanwhile get its
+// result. Failed renders are not cached, and a render that panics fails
+// with an error so waiting callers and later requests aren't blocked.
+func (c *feedCache) get(key string, render func() (feed, error)) (feed, error) {
+	c.mu.Lock()
+	if cached, ok := c.feeds[key]; ok && c.now().Before(cached.expiresAt) {
+		c.mu.Unlock()
+		return cached.feed, nil
+	}
+	if call, ok := c.rendering[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.feed, call.err
+	}
+	call := &feedRender{done: make(chan struct{})}
+	c.rendering[key] = call
+	c.mu.Unlock()
+
+	c.render(key, call, render)
+	return call.feed, call.err
+}
+
+// render runs render for the call registered under key, then stores its
+// result and releases the callers waiting on it, even if render panics
+func (c *feedCache) render(key string, call *feedRender, render func() (feed, error)) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("Feed render for %s panicked: %v", key, recovered)
+			call.feed, call.err = feed{}, fmt.Errorf("feed render panicked: %v", recovered)
+		}
+
+		c.mu.Lock()
+		delete(c.rendering, key)
+		if call.err == nil {
+			c.store(key, call.feed)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.feed, call.err = render()
+}
+
+// store caches a rendered feed; the caller holds mu
+func (c *feedCache) store(key string, rendered feed) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	now := c.now()
+	if len(c.feeds) >= maxFeedCacheEntries {
+		for k, cached := range c.feeds {
+			if !now.Before(cached.expiresAt) {
+				delete(c.feeds, k)
+			}
+		}
+	}
+	if len(c.feeds) >= maxFeedCacheEntries {
+		return
+	}
+
+	c.feeds[key] = renderedFeed{feed: rendered, expiresAt: now.Add(c.ttl)}
+}
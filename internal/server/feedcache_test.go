@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestCSVPostsHandlerSharesGeneration tests that concurrent requests for the
+// same feed page trigger a single post fetch
+func TestCSVPostsHandlerSharesGeneration(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	mockPostService := &mockPostService{
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			atomic.AddInt32(&fetches, 1)
+			<-release
+			return []*domain.PostWithUser{{Post: domain.Post{ID: "post_1", Content: "Hello"}}}, 1, nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	handler.feeds = newFeedCache(time.Minute)
+
+	const requests = 20
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/api/posts.csv?limit=5", nil)
+			rr := httptest.NewRecorder()
+			handler.CSVPostsHandler()(rr, req)
+			codes <- rr.Code
+		}()
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, code)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expected 1 post fetch, got %d", fetches)
+	}
+}
+
+// TestFeedCacheTTL tests that rendered feeds are reused until they expire,
+// per key, and that failed renders are not cached
+func TestFeedCacheTTL(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	feeds := newFeedCache(5 * time.Second)
+	feeds.now = func() time.Time { return now }
+
+	renders := 0
+	render := func() (feed, error) {
+		renders++
+		return feed{body: []byte("feed")}, nil
+	}
+
+	feeds.get("csv|1|10", render)
+	now = now.Add(4 * time.Second)
+	feeds.get("csv|1|10", render)
+	if renders != 1 {
+		t.Errorf("Expected 1 render within the TTL, got %d", renders)
+	}
+
+	feeds.get("csv|2|10", render)
+	if renders != 2 {
+		t.Errorf("Expected another page to render separately, got %d renders", renders)
+	}
+
+	now = now.Add(time.Second)
+	feeds.get("csv|1|10", render)
+	if renders != 3 {
+		t.Errorf("Expected an expired feed to render again, got %d renders", renders)
+	}
+
+	failing := func() (feed, error) { return feed{}, errors.New("database down") }
+	if _, err := feeds.get("csv|3|10", failing); err == nil {
+		t.Fatal("Expected render error, got nil")
+	}
+	if rendered, err := feeds.get("csv|3|10", render); err != nil || string(rendered.body) != "feed" {
+		t.Errorf("Expected a failed render not to be cached, got %q, %v", rendered.body, err)
+	}
+}
+
+// TestFeedCachePanickingRender tests that a render that panics fails its
+// callers instead of blocking later requests for the feed
+func TestFeedCachePanickingRender(t *testing.T) {
+	feeds := newFeedCache(5 * time.Second)
+
+	panicking := func() (feed, error) { panic("template broken") }
+	if _, err := feeds.get("csv|1|10", panicking); err == nil {
+		t.Fatal("Expected an error from a panicking render, got nil")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := feeds.get("csv|1|10", func() (feed, error) { return feed{body: []byte("feed")}, nil })
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the next render to succeed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Next request for the feed blocked after a panicking render")
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// postFields are the post fields a client can select with the fields
+// parameter, in response order
+var postFields = []string{"id", "user_id", "username", "content", "tags", "mentions", "like_count", "created_at", "updated_at"}
+
+// userFieldPrefix prefixes the fields of a post's author, which are returned
+// nested under a user object, e.g. fields=id,user.username
+const userFieldPrefix = "user."
+
+// userFields are the author fields a client can select with the user.
+// prefix
+var userFields = []string{"id", "username"}
+
+// parseFieldsParam splits a comma-separated fields parameter into the known
+// post fields and the unknown ones, dropping blanks and duplicates
+func parseFieldsParam(value string) (fields, unknown []string) {
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		seen[field] = true
+		if isPostField(field) {
+			fields = append(fields, field)
+		} else {
+			unknown = append(unknown, field)
+		}
+	}
+	return fields, unknown
+}
+
+// isPostField reports whether field can be selected with the fields parameter
+func isPostField(field string) bool {
+	known := postFields
+	if strings.HasPrefix(field, userFieldPrefix) {
+		field = strings.TrimPrefix(field, userFieldPrefix)
+		known = userFields
+	}
+	for _, known := range known {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// createPostsResponse returns the posts reduced to the view's selected
+// fields, with their URLs added when requested, or the posts unchanged for
+// the default view. Fields must already have been checked with
+// parseFieldsParam.
+func createPostsResponse(posts []*domain.PostWithUser, view postView) interface{} {
+	fields := view.fields
+	if len(fields) == 0 {
+		if view.postURL == nil {
+			return posts
+		}
+		linked := make([]interface{}, 0, len(posts))
+		for _, post := range posts {
+			linked = append(linked, withPostURL(post, view.postURL(post.ID)))
+		}
+		return linked
+	}
+
+	partial := make([]map[string]interface{}, 0, len(posts))
+	for _, post := range posts {
+		item := make(map[string]interface{}, len(fields)+1)
+		if view.postURL != nil {
+			item["url"] = view.postURL(post.ID)
+		}
+		for _, field := range fields {
+			if strings.HasPrefix(field, userFieldPrefix) {
+				user, _ := item["user"].(map[string]interface{})
+				if user == nil {
+					user = make(map[string]interface{}, len(userFields))
+					item["user"] = user
+				}
+				switch name := strings.TrimPrefix(field, userFieldPrefix); name {
+				case "id":
+					user[name] = post.UserID
+				case "username":
+					user[name] = post.Username
+				}
+				continue
+			}
+			switch field {
+			case "id":
+				item[field] = post.ID
+			case "user_id":
+				item[field] = post.UserID
+			case "username":
+				item[field] = post.Username
+			case "content":
+				item[field] = post.Content
+			case "tags":
+				item[field] = post.Tags
+			case "mentions":
+				item[field] = post.Mentions
+			case "like_count":
+				item[field] = post.LikeCount
+			case "created_at":
+				item[field] = post.CreatedAt
+			case "updated_at":
+				item[field] = post.UpdatedAt
+			}
+		}
+		partial = append(partial, item)
+	}
+	return partial
+}
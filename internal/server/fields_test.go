@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestGetPostsHandlerFields tests that the fields parameter selects post
+// fields, including nested user fields, and that unknown fields are rejected
+// by name
+func TestGetPostsHandlerFields(t *testing.T) {
+	testCases := []struct {
+		name           string
+		fields         string
+		expectedStatus int
+		expectedKeys   []string
+		expectedUser   map[string]interface{}
+		expectedError  string
+	}{
+		{
+			name:           "Valid fields",
+			fields:         "id, content",
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"content", "id"},
+		},
+		{
+			name:           "Mix of valid and invalid fields",
+			fields:         "id,contnet,username,likes",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Unknown fields: contnet, likes",
+		},
+		{
+			name:           "Only invalid fields",
+			fields:         "title",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Unknown fields: title",
+		},
+		{
+			name:           "Nested user field with post fields",
+			fields:         "id,user.username,content",
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"content", "id", "user"},
+			expectedUser:   map[string]interface{}{"username": "testuser"},
+		},
+		{
+			name:           "All nested user fields",
+			fields:         "user.id,user.username",
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"user"},
+			expectedUser:   map[string]interface{}{"id": "user_1", "username": "testuser"},
+		},
+		{
+			name:           "Unknown nested user field",
+			fields:         "id,user.email,user",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Unknown fields: user.email, user",
+		},
+		{
+			name:           "No fields",
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"content", "created_at", "id", "like_count", "updated_at", "user_id", "username"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostCache := &mockPostCache{
+				getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+					return []*domain.PostWithUser{
+						{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "Hello"}, Username: "testuser"},
+					}, nil
+				},
+			}
+			handler := NewPostHandler(&mockPostService{}, mockPostCache)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts?fields="+url.QueryEscape(tc.fields), nil)
+			rr := httptest.NewRecorder()
+			handler.GetPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+
+			if tc.expectedError != "" {
+				var response map[string]string
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to parse response body: %v", err)
+				}
+				if response["error"] != tc.expectedError {
+					t.Errorf("Expected error %q, got %q", tc.expectedError, response["error"])
+				}
+				return
+			}
+
+			var response struct {
+				Posts []map[string]interface{} `json:"posts"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if len(response.Posts) != 1 {
+				t.Fatalf("Expected 1 post, got %d", len(response.Posts))
+			}
+
+			keys := make([]string, 0, len(response.Posts[0]))
+			for key := range response.Posts[0] {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tc.expectedKeys) {
+				t.Errorf("Expected post keys %v, got %v", tc.expectedKeys, keys)
+			}
+			if tc.expectedUser != nil && !reflect.DeepEqual(response.Posts[0]["user"], tc.expectedUser) {
+				t.Errorf("Expected user %v, got %v", tc.expectedUser, response.Posts[0]["user"])
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// FollowHandler handles POST and DELETE /users/:id/follow requests,
+// following and unfollowing a user as the caller. Both are idempotent:
+// following a user again or unfollowing a user who isn't followed changes
+// nothing and returns 200. A new follow returns 201. Users cannot follow
+// themselves.
+func (h *UserHandler) FollowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST and DELETE methods
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication
+		followerID, err := h.auth.authenticate(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		if h.follows == nil || h.users == nil {
+			respondError(w, http.StatusServiceUnavailable, "Follows are not available")
+			return
+		}
+
+		followeeID, ok := h.followedUser(w, r)
+		if !ok {
+			return
+		}
+		if followeeID == followerID {
+			respondError(w, http.StatusBadRequest, "Cannot follow yourself")
+			return
+		}
+
+		// The repository reports whether the follow changed, so concurrent
+		// requests agree on which of them created or removed it
+		var changed bool
+		if r.Method == http.MethodPost {
+			changed, err = h.follows.Follow(followerID, followeeID)
+		} else {
+			changed, err = h.follows.Unfollow(followerID, followeeID)
+		}
+		if err != nil {
+			log.Printf("Error updating follow: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to update follow")
+			return
+		}
+
+		status := http.StatusOK
+		if changed && r.Method == http.MethodPost {
+			status = http.StatusCreated
+		}
+
+		// The caller's cached timeline no longer matches who they follow
+		if changed && h.timelines != nil {
+			if err := h.timelines.InvalidateTimeline(followerID); err != nil {
+				log.Printf("Failed to invalidate timeline: %v", err)
+			}
+		}
+
+		count, err := h.follows.CountFollowers(followeeID)
+		if err != nil {
+			log.Printf("Error counting followers: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to update follow")
+			return
+		}
+
+		respondJSON(w, status, map[string]interface{}{
+			"user_id":        followeeID,
+			"following":      r.Method == http.MethodPost,
+			"follower_count": count,
+		})
+	}
+}
+
+// FollowersHandler handles GET /users/:id/followers requests, listing the
+// users following a user, most recent follow first
+func (h *UserHandler) FollowersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if h.follows == nil || h.users == nil {
+			respondError(w, http.StatusServiceUnavailable, "Follows are not available")
+			return
+		}
+
+		userID, ok := h.followedUser(w, r)
+		if !ok {
+			return
+		}
+
+		page, limit, ok := parsePageParams(w, r, defaultPageLimit, h.options.MaxOffset)
+		if !ok {
+			return
+		}
+
+		followers, err := h.follows.ListFollowers(userID, (page-1)*limit, limit)
+		if err != nil {
+			log.Printf("Error listing followers: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to get followers")
+			return
+		}
+		total, err := h.follows.CountFollowers(userID)
+		if err != nil {
+			log.Printf("Error counting followers: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to get followers")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"followers": followers,
+			"page":      page,
+			"limit":     limit,
+			"total":     total,
+		})
+	}
+}
+
+// followedUser returns the user ID of a /api/users/{id}/... request,
+// writing a 404 response and returning false if no such user exists
+func (h *UserHandler) followedUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[2] == "" {
+		respondDomainError(w, http.StatusNotFound, domain.ErrUserNotFound, "User not found")
+		return "", false
+	}
+
+	user, err := h.users.GetByID(parts[2])
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			respondDomainError(w, http.StatusNotFound, domain.ErrUserNotFound, "User not found")
+		} else {
+			respondError(w, http.StatusInternalServerError, "Failed to get user")
+		}
+		return "", false
+	}
+	return user.ID, true
+}
@@ -0,0 +1,363 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/service"
+)
+
+// mockFollowRepository is an in-memory domain.FollowRepository
+type mockFollowRepository struct {
+	mu      sync.Mutex
+	follows []*domain.Follow
+}
+
+func (m *mockFollowRepository) Follow(followerID, followeeID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, follow := range m.follows {
+		if follow.FollowerID == followerID && follow.FolloweeID == followeeID {
+			return false, nil
+		}
+	}
+	m.follows = append(m.follows, &domain.Follow{FollowerID: followerID, FolloweeID: followeeID, CreatedAt: time.Now()})
+	return true, nil
+}
+
+func (m *mockFollowRepository) Unfollow(followerID, followeeID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, follow := range m.follows {
+		if follow.FollowerID == followerID && follow.FolloweeID == followeeID {
+			m.follows = append(m.follows[:i], m.follows[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockFollowRepository) IsFollowing(followerID, followeeID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, follow := range m.follows {
+		if follow.FollowerID == followerID && follow.FolloweeID == followeeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockFollowRepository) ListFollowers(userID string, offset, limit int) ([]*domain.Follow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	followers := make([]*domain.Follow, 0)
+	for _, follow := range m.follows {
+		if follow.FolloweeID == userID {
+			followers = append(followers, follow)
+		}
+	}
+	if offset >= len(followers) {
+		return []*domain.Follow{}, nil
+	}
+	if offset+limit < len(followers) {
+		followers = followers[:offset+limit]
+	}
+	return followers[offset:], nil
+}
+
+func (m *mockFollowRepository) ListFollowing(userID string, offset, limit int) ([]*domain.Follow, error) {
+	return nil, nil
+}
+
+func (m *mockFollowRepository) CountFollowers(userID string) (int, error) {
+	followers, _ := m.ListFollowers(userID, 0, 100)
+	return len(followers), nil
+}
+
+func (m *mockFollowRepository) CountFollowing(userID string) (int, error) {
+	return 0, nil
+}
+
+// TestFollowHandler tests that following and unfollowing are idempotent
+// and that users cannot follow themselves
+func TestFollowHandler(t *testing.T) {
+	users := newMockUserRepository(
+		&domain.User{ID: "user_1", Username: "admin"},
+		&domain.User{ID: "user_2", Username: "alice"},
+	)
+	handler := NewUserHandler(service.NewUserService(users))
+	handler.follows = &mockFollowRepository{}
+
+	// Steps run in order against the same follows
+	steps := []struct {
+		name              string
+		method            string
+		path              string
+		authenticated     bool
+		expectedStatus    int
+		expectedFollowing bool
+		expectedCount     int
+	}{
+		{
+			name:              "Follow",
+			method:            http.MethodPost,
+			path:              "/api/users/user_2/follow",
+			authenticated:     true,
+			expectedStatus:    http.StatusCreated,
+			expectedFollowing: true,
+			expectedCount:     1,
+		},
+		{
+			name:              "Repeated follow",
+			method:            http.MethodPost,
+			path:              "/api/users/user_2/follow",
+			authenticated:     true,
+			expectedStatus:    http.StatusOK,
+			expectedFollowing: true,
+			expectedCount:     1,
+		},
+		{
+			name:           "Unfollow",
+			method:         http.MethodDelete,
+			path:           "/api/users/user_2/follow",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Repeated unfollow",
+			method:         http.MethodDelete,
+			path:           "/api/users/user_2/follow",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Self follow",
+			method:         http.MethodPost,
+			path:           "/api/users/user_1/follow",
+			authenticated:  true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "User not found",
+			method:         http.MethodPost,
+			path:           "/api/users/user_9/follow",
+			authenticated:  true,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Unauthenticated",
+			method:         http.MethodPost,
+			path:           "/api/users/user_2/follow",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodGet,
+			path:           "/api/users/user_2/follow",
+			authenticated:  true,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			req := httptest.NewRequest(step.method, step.path, nil)
+			if step.authenticated {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.FollowHandler()(rr, req)
+
+			if rr.Code != step.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", step.expectedStatus, rr.Code)
+			}
+			if rr.Code != http.StatusOK && rr.Code != http.StatusCreated {
+				return
+			}
+
+			var response struct {
+				UserID        string `json:"user_id"`
+				Following     bool   `json:"following"`
+				FollowerCount int    `json:"follower_count"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.UserID != "user_2" || response.Following != step.expectedFollowing || response.FollowerCount != step.expectedCount {
+				t.Errorf("Expected user_2 followed %t by %d users, got %+v", step.expectedFollowing, step.expectedCount, response)
+			}
+		})
+	}
+}
+
+// TestFollowHandlerConcurrent tests that of concurrent follows of the same
+// user, only one is reported as a new follow
+func TestFollowHandlerConcurrent(t *testing.T) {
+	users := newMockUserRepository(
+		&domain.User{ID: "user_1", Username: "admin"},
+		&domain.User{ID: "user_2", Username: "alice"},
+	)
+	handler := NewUserHandler(service.NewUserService(users))
+	handler.follows = &mockFollowRepository{}
+
+	const requests = 10
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/api/users/user_2/follow", nil)
+			req.SetBasicAuth("admin", "password")
+			rr := httptest.NewRecorder()
+			handler.FollowHandler()(rr, req)
+			codes <- rr.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusOK:
+		default:
+			t.Errorf("Expected status code %d or %d, got %d", http.StatusCreated, http.StatusOK, code)
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected exactly one new follow, got %d", created)
+	}
+}
+
+// TestFollowersHandler tests the paginated follower listing
+func TestFollowersHandler(t *testing.T) {
+	users := newMockUserRepository(
+		&domain.User{ID: "user_1", Username: "admin"},
+		&domain.User{ID: "user_2", Username: "alice"},
+		&domain.User{ID: "user_3", Username: "bob"},
+	)
+	follows := &mockFollowRepository{}
+	follows.Follow("user_1", "user_3")
+	follows.Follow("user_2", "user_3")
+	handler := NewUserHandler(service.NewUserService(users))
+	handler.follows = follows
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{
+			name:           "First page",
+			path:           "/api/users/user_3/followers?limit=1",
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		{
+			name:           "No followers",
+			path:           "/api/users/user_1/followers",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "User not found",
+			path:           "/api/users/user_9/followers",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid limit",
+			path:           "/api/users/user_3/followers?limit=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			handler.FollowersHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Followers []domain.Follow `json:"followers"`
+				Total     int             `json:"total"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if len(response.Followers) != tc.expectedCount {
+				t.Errorf("Expected %d followers, got %d", tc.expectedCount, len(response.Followers))
+			}
+			if tc.expectedCount > 0 && response.Total != 2 {
+				t.Errorf("Expected a total of 2 followers, got %d", response.Total)
+			}
+		})
+	}
+}
+
+// TestFollowRoutes tests that follow requests are dispatched to the follow
+// handlers while user posts keep their route
+func TestFollowRoutes(t *testing.T) {
+	users := newMockUserRepository(
+		&domain.User{ID: "user_1", Username: "admin"},
+		&domain.User{ID: "user_2", Username: "alice"},
+	)
+	server := New(Config{Host: "localhost", Port: 8080}, &MockPostService{}, &MockPostCache{}, &MockDBPinger{}, &MockPostCache{},
+		WithUserService(service.NewUserService(users)),
+		WithFollowRepository(&mockFollowRepository{}))
+	server.registerRoutes()
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{
+			name:           "Follow",
+			method:         http.MethodPost,
+			path:           "/api/users/user_2/follow",
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Followers",
+			method:         http.MethodGet,
+			path:           "/api/users/user_2/followers",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "User posts",
+			method:         http.MethodGet,
+			path:           "/api/users/user_2/posts",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.SetBasicAuth("admin", "password")
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
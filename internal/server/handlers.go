@@ -2,12 +2,19 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/metrics"
 )
 
 // LivezHandler handles liveness probe requests
@@ -22,41 +29,116 @@ func LivezHandler() http.HandlerFunc {
 // ReadyzHandler handles readiness probe requests
 func ReadyzHandler(db DBPinger, cache CachePinger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Check database connection
-		dbStatus := "up"
-		if err := db.Ping(); err != nil {
-			dbStatus = "down"
-		}
+		respondReadiness(w, checkReadiness(db, cache, nil))
+	}
+}
 
-		// Check cache connection
-		cacheStatus := "up"
-		if err := cache.Ping(); err != nil {
-			cacheStatus = "down"
+// CachedReadyzHandler handles readiness probe requests, sharing one
+// dependency check between all probes that arrive within ttl of it.
+// A dependency going down is therefore reported at most ttl late. When disk
+// is not nil its writability is checked too.
+func CachedReadyzHandler(db DBPinger, cache CachePinger, disk DiskChecker, ttl time.Duration) http.HandlerFunc {
+	if ttl <= 0 {
+		return func(w http.ResponseWriter, r *http.Request) {
+			respondReadiness(w, checkReadiness(db, cache, disk))
 		}
+	}
 
-		// Determine overall status
-		status := http.StatusOK
-		if dbStatus == "down" || cacheStatus == "down" {
-			status = http.StatusServiceUnavailable
-		}
+	readiness := &readinessCache{ttl: ttl, now: time.Now}
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondReadiness(w, readiness.get(db, cache, disk))
+	}
+}
 
-		// Determine status message
-		statusMsg := "ready"
-		if status != http.StatusOK {
-			statusMsg = "not ready"
+// readinessResult holds the outcome of a readiness check
+type readinessResult struct {
+	dbStatus    string
+	cacheStatus string
+	// diskStatus is empty when the disk is not checked
+	diskStatus string
+}
+
+// ready reports whether all dependencies are up
+func (r readinessResult) ready() bool {
+	return r.dbStatus == "up" && r.cacheStatus == "up" && (r.diskStatus == "" || r.diskStatus == "up")
+}
+
+// readinessCache caches the latest readiness result for a short window
+type readinessCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	result    readinessResult
+	checkedAt time.Time
+}
+
+// get returns the cached result, checking the dependencies if it has expired.
+// The lock is held during the check so concurrent probes wait for it rather
+// than each pinging the dependencies.
+func (c *readinessCache) get(db DBPinger, cache CachePinger, disk DiskChecker) readinessResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.checkedAt.IsZero() || now.Sub(c.checkedAt) >= c.ttl {
+		c.result = checkReadiness(db, cache, disk)
+		c.checkedAt = now
+	}
+
+	return c.result
+}
+
+// checkReadiness pings the database and cache, and checks the disk unless it
+// is nil
+func checkReadiness(db DBPinger, cache CachePinger, disk DiskChecker) readinessResult {
+	result := readinessResult{dbStatus: "up", cacheStatus: "up"}
+
+	// Check database connection
+	if err := db.Ping(); err != nil {
+		result.dbStatus = "down"
+	}
+
+	// Check cache connection
+	if err := cache.Ping(); err != nil {
+		result.cacheStatus = "down"
+	}
+
+	// Check the disk is writable
+	if disk != nil {
+		result.diskStatus = "up"
+		if err := disk.Check(); err != nil {
+			log.Printf("Readiness disk check failed: %v", err)
+			result.diskStatus = "not writable"
 		}
+	}
 
-		// Respond with status
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": statusMsg,
-			"checks": map[string]string{
-				"database": dbStatus,
-				"cache":    cacheStatus,
-			},
-		})
+	return result
+}
+
+// respondReadiness writes a readiness result
+func respondReadiness(w http.ResponseWriter, result readinessResult) {
+	// Determine overall status
+	status := http.StatusOK
+	statusMsg := "ready"
+	if !result.ready() {
+		status = http.StatusServiceUnavailable
+		statusMsg = "not ready"
+	}
+
+	// Respond with status
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	checks := map[string]string{
+		"database": result.dbStatus,
+		"cache":    result.cacheStatus,
 	}
+	if result.diskStatus != "" {
+		checks["disk"] = result.diskStatus
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": statusMsg,
+		"checks": checks,
+	})
 }
 
 // DBPinger defines the interface for database ping operations
@@ -69,17 +151,68 @@ type CachePinger interface {
 	Ping() error
 }
 
+// DiskChecker defines the interface for disk readiness checks
+type DiskChecker interface {
+	Check() error
+}
+
+// WritableDir checks that files can be created in a directory, for
+// deployments that write temporary files or exports to disk
+type WritableDir string
+
+// Check creates and removes a file in the directory
+func (d WritableDir) Check() error {
+	file, err := os.CreateTemp(string(d), ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", string(d), err)
+	}
+	name := file.Name()
+	file.Close()
+	return os.Remove(name)
+}
+
+// defaultPageLimit is the number of posts returned per page when no limit is
+// given and no endpoint default is configured
+const defaultPageLimit = 10
+
 // PostHandler handles post-related requests
 type PostHandler struct {
 	postService domain.PostService
 	postCache   PostCache
+	auth        *authenticator
+	events      *postBroadcaster
+	warmer      *cacheWarmer
+	cacheWrites *AsyncCacheWrites
+	feeds       *feedCache
+	options     Options
+	// likes records likes of posts; the like endpoints respond with 503
+	// without it
+	likes domain.LikeRepository
+	// comments records comments on posts; the comment endpoints respond
+	// with 503 without it
+	comments domain.CommentRepository
+	// timelines caches the first page of each user's timeline; timelines
+	// are read from the database every time without it
+	timelines TimelineCache
+	// metrics counts posts cache hits and misses; nothing is counted
+	// without it
+	metrics *metrics.Metrics
+	// baseURL prefixes the post URLs included on request; the request's
+	// host is used when empty
+	baseURL string
 }
 
 // NewPostHandler creates a new post handler
 func NewPostHandler(postService domain.PostService, postCache PostCache) *PostHandler {
+	options := LoadOptionsFromEnv()
 	return &PostHandler{
 		postService: postService,
 		postCache:   postCache,
+		events:      newPostBroadcaster(),
+		warmer:      newCacheWarmer(options.MaxConcurrentCacheWarms),
+		cacheWrites: &AsyncCacheWrites{},
+		feeds:       newFeedCache(options.FeedCacheTTL),
+		options:     options,
 	}
 }
 
@@ -92,68 +225,284 @@ func (h *PostHandler) GetPostsHandler() http.HandlerFunc {
 			return
 		}
 
-		// Parse query parameters
-		query := r.URL.Query()
-		pageStr := query.Get("page")
-		limitStr := query.Get("limit")
-
-		// Set default values
-		page := 1
-		limit := 10
+		view, ok := h.parsePostView(w, r)
+		if !ok {
+			return
+		}
 
-		// Parse page parameter
-		if pageStr != "" {
-			pageInt, err := strconv.Atoi(pageStr)
-			if err != nil || pageInt < 1 {
-				respondError(w, http.StatusBadRequest, "Invalid page parameter")
+		// A cursor, even an empty one for the first page, selects cursor
+		// pagination; page is then ignored
+		if r.URL.Query().Has("cursor") {
+			limit, ok := parseLimitParam(w, r, h.options.PostsDefaultLimit)
+			if !ok {
 				return
 			}
-			page = pageInt
+			h.respondPostsAfterCursor(w, r.URL.Query().Get("cursor"), limit, view)
+			return
 		}
 
-		// Parse limit parameter
-		if limitStr != "" {
-			limitInt, err := strconv.Atoi(limitStr)
-			if err != nil || limitInt < 1 || limitInt > 100 {
-				respondError(w, http.StatusBadRequest, "Invalid limit parameter")
-				return
-			}
-			limit = limitInt
+		page, limit, ok := h.parsePaginationParams(w, r, h.options.PostsDefaultLimit)
+		if !ok {
+			return
+		}
+
+		if h.options.ReadStrategy == ReadStrategyDBFirst {
+			h.respondPostsDBFirst(w, page, limit, view)
+			return
 		}
 
 		// Try to get posts from cache
 		posts, err := h.postCache.GetPostsWithUser()
+		if err == nil && !validCachedPosts(posts) {
+			// A corrupt snapshot is dropped and treated as a miss
+			log.Printf("Discarding malformed posts cache snapshot")
+			if err := h.postCache.InvalidatePosts(); err != nil {
+				log.Printf("Failed to invalidate posts cache: %v", err)
+			}
+			err = errMalformedCache
+		}
 		if err == nil {
-			// Cache hit
-			respondJSON(w, http.StatusOK, map[string]interface{}{
-				"posts":  posts,
-				"page":   page,
-				"limit":  limit,
-				"total":  len(posts),
-				"source": "cache",
-			})
-			return
+			// The snapshot is capped, so the recorded total tells whether it
+			// holds every post (-1 when unknown)
+			total, totalErr := h.postCache.GetPostsTotal()
+			if totalErr != nil {
+				total = -1
+			}
+
+			// Cache hit, as long as the page lies within the cached window
+			if window, ok := cachedPage(posts, total, page, limit); ok {
+				if total < len(posts) {
+					total = len(posts)
+				}
+				h.metrics.CacheHit()
+				h.respondPosts(w, window, page, limit, total, SourceCache, view)
+				return
+			}
 		}
 
 		// Cache miss, get posts from service
+		h.metrics.CacheMiss()
+		readAt := time.Now()
 		posts, total, err := h.postService.List(page, limit)
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "Failed to get posts")
+			log.Printf("Failed to get posts from database: %v", err)
+			h.respondStalePosts(w, page, limit, view)
 			return
 		}
 
-		// Set posts in cache
-		go h.postCache.SetPostsWithUser(posts)
+		// Only the first page is cached, so the cache always holds the newest
+		// posts; don't overwrite it with a deeper page. A newer read that has
+		// already been cached is kept.
+		if page == 1 {
+			h.cacheWrites.Go(func() { h.postCache.SetPostsWithUserAndTotalAt(posts, total, readAt) })
+		}
 
-		// Respond with posts
-		respondJSON(w, http.StatusOK, map[string]interface{}{
-			"posts":  posts,
-			"page":   page,
-			"limit":  limit,
-			"total":  total,
-			"source": "database",
-		})
+		h.respondPosts(w, posts, page, limit, total, SourceDatabase, view)
+	}
+}
+
+// errMalformedCache marks a cached posts snapshot holding malformed entries
+var errMalformedCache = errors.New("malformed posts cache")
+
+// validCachedPosts reports whether every entry of a cached posts snapshot is
+// usable; a nil entry or one without an ID means the snapshot is corrupt
+func validCachedPosts(posts []*domain.PostWithUser) bool {
+	for _, post := range posts {
+		if post == nil || post.ID == "" {
+			return false
+		}
 	}
+	return true
+}
+
+// cachedPage returns the requested page from the cached newest posts. The
+// cache is filled from the first page only and may be truncated, so a short
+// first page is served only when total (-1 if unknown) doesn't show posts
+// missing from it. Pages the cache can't fully cover are reported as missing
+// so the caller falls back to the database instead of serving a short or
+// empty page.
+func cachedPage(posts []*domain.PostWithUser, total, page, limit int) ([]*domain.PostWithUser, bool) {
+	offset := (page - 1) * limit
+	if offset+limit <= len(posts) {
+		return posts[offset : offset+limit], true
+	}
+	if page == 1 && total <= len(posts) {
+		return posts, true
+	}
+	return nil, false
+}
+
+// CachedPage returns the requested page from the cached newest posts like
+// the posts handler does; for handlers outside this package
+func CachedPage(posts []*domain.PostWithUser, total, page, limit int) ([]*domain.PostWithUser, bool) {
+	return cachedPage(posts, total, page, limit)
+}
+
+// respondPostsAfterCursor serves the page of posts following cursor from the
+// database, with the cursor of the next page, or null after the last one
+func (h *PostHandler) respondPostsAfterCursor(w http.ResponseWriter, cursor string, limit int, view postView) {
+	var createdAt time.Time
+	var afterID string
+	if cursor != "" {
+		var err error
+		createdAt, afterID, err = h.decodeCursor(cursor)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid cursor parameter")
+			return
+		}
+	}
+
+	// One extra post tells whether another page follows
+	posts, err := h.postService.ListAfter(createdAt, afterID, limit+1)
+	if err != nil {
+		log.Printf("Failed to get posts from database: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get posts")
+		return
+	}
+
+	var nextCursor interface{}
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[len(posts)-1]
+		nextCursor = h.encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"posts":       createPostsResponse(posts, view),
+		"limit":       limit,
+		"next_cursor": nextCursor,
+		"source":      SourceDatabase,
+	})
+}
+
+// respondPostsDBFirst serves posts from the database, falling back to the
+// cache only when the database query fails
+func (h *PostHandler) respondPostsDBFirst(w http.ResponseWriter, page, limit int, view postView) {
+	readAt := time.Now()
+	posts, total, err := h.postService.List(page, limit)
+	if err == nil {
+		if page == 1 {
+			h.cacheWrites.Go(func() { h.postCache.SetPostsWithUserAndTotalAt(posts, total, readAt) })
+		}
+		h.respondPosts(w, posts, page, limit, total, SourceDatabase, view)
+		return
+	}
+
+	log.Printf("Failed to get posts from database, falling back to cache: %v", err)
+	posts, cacheErr := h.postCache.GetPostsWithUser()
+	if cacheErr != nil || !validCachedPosts(posts) {
+		h.respondStalePosts(w, page, limit, view)
+		return
+	}
+
+	h.respondPosts(w, posts, page, limit, h.cachedPostsTotal(posts), SourceCache, view)
+}
+
+// cachedPostsTotal returns the total number of posts stored with the cached
+// snapshot. The snapshot may hold only the newest posts, so its length is
+// used only when no total is stored or the stored one is behind.
+func (h *PostHandler) cachedPostsTotal(posts []*domain.PostWithUser) int {
+	if total, err := h.postCache.GetPostsTotal(); err == nil && total > len(posts) {
+		return total
+	}
+	return len(posts)
+}
+
+// respondStalePosts serves the stale copy of the posts as a last resort when
+// the database has failed, marking the response as stale, or responds with
+// 500 if there is no stale copy either
+func (h *PostHandler) respondStalePosts(w http.ResponseWriter, page, limit int, view postView) {
+	posts, err := h.postCache.GetStalePostsWithUser()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get posts")
+		return
+	}
+
+	w.Header().Set("X-Cache-Stale", "true")
+	w.Header().Set("Warning", `110 - "Response is Stale"`)
+	h.respondPosts(w, posts, page, limit, h.cachedPostsTotal(posts), SourceStaleCache, view)
+}
+
+// Sources reported in the "source" field of post responses, telling clients
+// and dashboards which path served the request
+const (
+	// SourceCache is a response served from the posts cache
+	SourceCache = "cache"
+	// SourceDatabase is a response read from the database
+	SourceDatabase = "database"
+	// SourceStaleCache is the stale copy of the posts, served as a last
+	// resort while the database is failing
+	SourceStaleCache = "stale_cache"
+)
+
+// respondPosts writes a page of posts, shaped by the view, along with where
+// they were read from
+func (h *PostHandler) respondPosts(w http.ResponseWriter, posts []*domain.PostWithUser, page, limit, total int, source string, view postView) {
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"posts":  createPostsResponse(posts, view),
+		"page":   page,
+		"limit":  limit,
+		"total":  total,
+		"source": source,
+	})
+}
+
+// parsePaginationParams parses the page and limit query parameters, using
+// defaultLimit when no limit is given, writing a 400 response and returning
+// false if they are invalid or reach past the maximum offset
+func (h *PostHandler) parsePaginationParams(w http.ResponseWriter, r *http.Request, defaultLimit int) (int, int, bool) {
+	return parsePageParams(w, r, defaultLimit, h.options.MaxOffset)
+}
+
+// parsePageParams parses the page and limit query parameters like
+// parsePaginationParams, for handlers other than PostHandler; maxOffset of
+// 0 means no maximum
+func parsePageParams(w http.ResponseWriter, r *http.Request, defaultLimit, maxOffset int) (int, int, bool) {
+	// Parse page parameter
+	page := 1
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		pageInt, err := strconv.Atoi(pageStr)
+		if err != nil || pageInt < 1 {
+			respondError(w, http.StatusBadRequest, "Invalid page parameter")
+			return 0, 0, false
+		}
+		page = pageInt
+	}
+
+	limit, ok := parseLimitParam(w, r, defaultLimit)
+	if !ok {
+		return 0, 0, false
+	}
+
+	// Deep offsets are expensive for the database; clients paging this far
+	// should use cursor pagination instead
+	if maxOffset > 0 && (page-1) > maxOffset/limit {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Offset exceeds maximum of %d, use cursor pagination", maxOffset))
+		return 0, 0, false
+	}
+
+	return page, limit, true
+}
+
+// parseLimitParam parses the limit query parameter, using defaultLimit when
+// no limit is given, writing a 400 response and returning false if it is
+// invalid
+func parseLimitParam(w http.ResponseWriter, r *http.Request, defaultLimit int) (int, bool) {
+	limit := defaultLimit
+	if limit < 1 || limit > 100 {
+		limit = defaultPageLimit
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limitInt, err := strconv.Atoi(limitStr)
+		if err != nil || limitInt < 1 || limitInt > 100 {
+			respondError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return 0, false
+		}
+		limit = limitInt
+	}
+
+	return limit, true
 }
 
 // GetPostHandler handles GET /posts/:id requests
@@ -172,16 +521,33 @@ func (h *PostHandler) GetPostHandler() http.HandlerFunc {
 			respondError(w, http.StatusBadRequest, "Invalid URL")
 			return
 		}
-		id := parts[len(parts)-1]
+		// Clients may use either the raw or the opaque form of the ID
+		id, err := h.postIDs().decode(parts[len(parts)-1])
+		if err != nil {
+			respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			return
+		}
+
+		includeURL, unknown := parseIncludeParam(r.URL.Query().Get("include"))
+		if len(unknown) > 0 {
+			respondError(w, http.StatusBadRequest, "Unknown include: "+strings.Join(unknown, ", "))
+			return
+		}
+		respondPost := func(post interface{}, source string) {
+			if includeURL {
+				post = withPostURL(post, h.postURLs(r)(id))
+			}
+			h.respondJSON(w, http.StatusOK, map[string]interface{}{
+				"post":   post,
+				"source": source,
+			})
+		}
 
 		// Try to get post from cache
 		cachedPost, err := h.postCache.GetPost(id)
 		if err == nil {
 			// Cache hit
-			respondJSON(w, http.StatusOK, map[string]interface{}{
-				"post":   cachedPost,
-				"source": "cache",
-			})
+			respondPost(cachedPost, SourceCache)
 			return
 		}
 
@@ -189,7 +555,7 @@ func (h *PostHandler) GetPostHandler() http.HandlerFunc {
 		postWithUser, err := h.postService.GetByID(id)
 		if err != nil {
 			if err == domain.ErrPostNotFound {
-				respondError(w, http.StatusNotFound, "Post not found")
+				respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
 			} else {
 				respondError(w, http.StatusInternalServerError, "Failed to get post")
 			}
@@ -197,73 +563,263 @@ func (h *PostHandler) GetPostHandler() http.HandlerFunc {
 		}
 
 		// Set post in cache (we only cache the Post part, not the PostWithUser)
-		go h.postCache.SetPost(&postWithUser.Post)
+		h.cacheWrites.Go(func() { h.postCache.SetPost(&postWithUser.Post) })
 
 		// Respond with post
-		respondJSON(w, http.StatusOK, map[string]interface{}{
-			"post":   postWithUser,
-			"source": "database",
+		respondPost(postWithUser, SourceDatabase)
+	}
+}
+
+// DeletePostHandler handles DELETE /posts/:id requests, deleting a post owned
+// by the caller. Posts owned by someone else are reported as not found.
+func (h *PostHandler) DeletePostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow DELETE method
+		if r.Method != http.MethodDelete {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication
+		userID, err := h.auth.authenticate(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		// Extract post ID from URL; clients may use either the raw or the
+		// opaque form of the ID
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		id, err := h.postIDs().decode(parts[len(parts)-1])
+		if err != nil || id == "" {
+			respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			return
+		}
+
+		if err := h.postService.Delete(id, userID); err != nil {
+			if errors.Is(err, domain.ErrPostNotFound) {
+				respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			} else {
+				respondError(w, http.StatusInternalServerError, "Failed to delete post")
+			}
+			return
+		}
+
+		// Neither the post nor the listings holding it may be served again
+		h.cacheWrites.Go(func() {
+			h.postCache.InvalidatePost(id)
+			h.postCache.InvalidatePosts()
 		})
+
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-// CreatePostHandler handles POST /posts requests
-func (h *PostHandler) CreatePostHandler() http.HandlerFunc {
+// refreshPostsCache updates the posts cache after a post is created,
+// according to the configured create refresh mode
+func (h *PostHandler) refreshPostsCache() {
+	if h.options.CreateRefreshMode == CreateRefreshRefresh {
+		// A warm already running re-runs to pick up this post
+		_, err := h.warmer.warm(h.postService, h.postCache)
+		if err != nil && err != errWarmInProgress {
+			log.Printf("Error refreshing posts cache: %v", err)
+			h.postCache.InvalidatePosts()
+		}
+		return
+	}
+
+	h.postCache.InvalidatePosts()
+}
+
+// MyPostsHandler handles GET /posts/mine requests, returning the
+// authenticated user's own posts
+func (h *PostHandler) MyPostsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Only allow POST method
-		if r.Method != http.MethodPost {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
 			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		// Check authentication
-		userID, err := authenticateRequest(r)
+		userID, err := h.auth.authenticate(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		page, limit, ok := h.parsePaginationParams(w, r, h.options.FeedDefaultLimit)
+		if !ok {
+			return
+		}
+
+		// Per-user listings are never cached, so always read from the service
+		posts, total, err := h.postService.ListByUser(userID, page, limit)
 		if err != nil {
-			respondError(w, http.StatusUnauthorized, "Unauthorized")
+			respondError(w, http.StatusInternalServerError, "Failed to get posts")
+			return
+		}
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"posts": posts,
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// UserPostsHandler handles GET /users/:id/posts requests, listing a user's
+// posts publicly. To deter scraping only the most recent posts, up to the
+// public user posts cap, can be paged through; owners can page through all of
+// their posts with /posts/mine.
+func (h *PostHandler) UserPostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
 			return
 		}
 
-		// Parse request body
-		var requestBody struct {
-			Content string `json:"content"`
+		// Extract user ID from URL
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 4 || parts[3] != "posts" || parts[2] == "" {
+			respondError(w, http.StatusNotFound, "Not found")
+			return
+		}
+		userID := parts[2]
+
+		page, limit, ok := h.parsePaginationParams(w, r, h.options.UserPostsDefaultLimit)
+		if !ok {
+			return
+		}
+
+		if h.options.PublicUserPostsCap > 0 && page*limit > h.options.PublicUserPostsCap {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Only the most recent %d posts of a user are available", h.options.PublicUserPostsCap))
+			return
+		}
+
+		posts, total, err := h.postService.ListByUser(userID, page, limit)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to get posts")
+			return
+		}
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"posts": posts,
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// CreatePostHandler handles POST /posts requests
+func (h *PostHandler) CreatePostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
 		}
-		err = json.NewDecoder(r.Body).Decode(&requestBody)
+
+		// Check authentication
+		userID, err := h.auth.authenticate(r)
 		if err != nil {
-			respondError(w, http.StatusBadRequest, "Invalid request body")
+			respondAuthError(w, err)
 			return
 		}
 
-		// Validate content
-		if requestBody.Content == "" {
-			respondError(w, http.StatusBadRequest, "Content is required")
+		// Parse and validate request body
+		content, ok := h.decodePostContent(w, r, 0)
+		if !ok {
 			return
 		}
 
 		// Create post
-		post, err := h.postService.Create(userID, requestBody.Content)
+		post, err := h.postService.Create(userID, content)
 		if err != nil {
+			if err == domain.ErrDuplicatePost {
+				respondDomainError(w, http.StatusConflict, err, "Duplicate post")
+				return
+			}
+			if err == domain.ErrInvalidPostContent {
+				respondDomainError(w, http.StatusBadRequest, err, "Content is required")
+				return
+			}
+			if errors.Is(err, domain.ErrPostContentTooLong) {
+				respondDomainError(w, http.StatusBadRequest, err, "Invalid content: "+err.Error())
+				return
+			}
 			respondError(w, http.StatusInternalServerError, "Failed to create post")
 			return
 		}
 
-		// Invalidate cache
-		go h.postCache.InvalidatePosts()
+		h.cacheWrites.Go(h.refreshPostsCache)
+
+		// Notify stream subscribers
+		h.events.publish(post)
 
 		// Respond with created post
-		respondJSON(w, http.StatusCreated, map[string]interface{}{
+		h.respondJSON(w, http.StatusCreated, map[string]interface{}{
 			"post":    post,
 			"message": "Post created successfully",
 		})
 	}
 }
 
+// respondJSON responds with JSON using the configured key casing
+func (h *PostHandler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	opaque := h.options.PostIDFormat == PostIDFormatOpaque
+	camel := h.options.JSONCase == JSONCaseCamel
+	if opaque || camel {
+		value, err := toJSONValue(data)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to encode response")
+			return
+		}
+		if opaque {
+			value = h.postIDs().exposePostIDs(value)
+		}
+		if camel {
+			value = camelizeKeys(value)
+		}
+		data = value
+	}
+
+	respondJSON(w, status, data)
+}
+
+// postIDs returns the codec for opaque post IDs
+func (h *PostHandler) postIDs() postIDCodec {
+	return newPostIDCodec(h.options.PostIDSecret)
+}
+
+// exposedPostID returns a post ID in the format exposed to clients
+func (h *PostHandler) exposedPostID(id string) string {
+	if h.options.PostIDFormat != PostIDFormatOpaque {
+		return id
+	}
+	return h.postIDs().encode(id)
+}
+
 // PostCache defines the interface for post caching
 type PostCache interface {
 	GetPost(id string) (*domain.Post, error)
 	SetPost(post *domain.Post) error
 	GetPostsWithUser() ([]*domain.PostWithUser, error)
+	GetStalePostsWithUser() ([]*domain.PostWithUser, error)
 	SetPostsWithUser(posts []*domain.PostWithUser) error
+	SetPostsWithUserAt(posts []*domain.PostWithUser, readAt time.Time) error
+	SetPostsWithUserAndTotalAt(posts []*domain.PostWithUser, total int, readAt time.Time) error
+	GetPostsTotal() (int, error)
 	InvalidatePosts() error
+	InvalidatePost(id string) error
+}
+
+// PostStreamer defines the interface for iterating over every stored post
+type PostStreamer interface {
+	EachPost(fn func(*domain.Post) error) error
 }
 
 // respondJSON responds with JSON
@@ -273,19 +829,84 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-// respondError responds with an error
+// respondJSONStream responds with a JSON object of the form {"<key>": [...]},
+// encoding items one at a time as each emits them instead of building the
+// whole list in memory. Once streaming has started the status can no longer
+// change, so an error from each is logged and the response is cut short,
+// leaving the client with invalid JSON rather than a silently partial list.
+func respondJSONStream(w http.ResponseWriter, status int, key string, each func(emit func(item interface{}) error) error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	name, err := json.Marshal(key)
+	if err != nil {
+		log.Printf("Error encoding stream key: %v", err)
+		return
+	}
+
+	if _, err := w.Write(append(append([]byte("{"), name...), ':')); err != nil {
+		return
+	}
+
+	if err := writeJSONArray(w, each); err != nil {
+		log.Printf("Error streaming %s: %v", key, err)
+		return
+	}
+
+	w.Write([]byte("}\n"))
+}
+
+// writeJSONArray writes a JSON array of the items each emits, encoding them
+// one at a time
+func writeJSONArray(w io.Writer, each func(emit func(item interface{}) error) error) error {
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	first := true
+	err := each(func(item interface{}) error {
+		if !first {
+			if _, err := w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		first = false
+		return encoder.Encode(item)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte("]"))
+	return err
+}
+
+// respondError responds with an error coded by its status
 func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{"error": message})
+	writeAPIError(w, &APIError{Status: status, Code: statusCode(status), Message: message})
 }
 
 // authenticateRequest authenticates a request using Basic Auth
 func authenticateRequest(r *http.Request) (string, error) {
 	// Get username and password from Basic Auth
-	username, password, ok := r.BasicAuth()
-	if !ok {
-		return "", domain.ErrUserNotFound
+	username, password, err := basicAuth(r)
+	if err != nil {
+		return "", err
+	}
+
+	// Check if username and password are valid
+	expectedUsername, expectedPassword := envCredentials()
+	if username == expectedUsername && password == expectedPassword {
+		return adminUserID, nil
 	}
 
+	return "", domain.ErrUserNotFound
+}
+
+// envCredentials returns the administrator credentials from AUTH_USERNAME
+// and AUTH_PASSWORD, used when there is no user store
+func envCredentials() (string, string) {
 	// Get expected username and password from environment variables
 	expectedUsername := os.Getenv("AUTH_USERNAME")
 	if expectedUsername == "" {
@@ -297,10 +918,5 @@ func authenticateRequest(r *http.Request) (string, error) {
 		expectedPassword = "password" // Default if not set
 	}
 
-	// Check if username and password are valid
-	if username == expectedUsername && password == expectedPassword {
-		return "user_1", nil
-	}
-
-	return "", domain.ErrUserNotFound
+	return expectedUsername, expectedPassword
 }
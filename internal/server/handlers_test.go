@@ -5,7 +5,11 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestLivezHandler tests the LivezHandler function
@@ -156,6 +160,154 @@ func TestReadyzHandler(t *testing.T) {
 	}
 }
 
+// TestCachedReadyzHandler tests that a burst of probes shares a single check
+func TestCachedReadyzHandler(t *testing.T) {
+	db := &countingPinger{}
+	cache := &countingPinger{}
+	handler := CachedReadyzHandler(db, cache, nil, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := db.count(); calls != 1 {
+		t.Errorf("Expected database to be pinged once, got %d", calls)
+	}
+	if calls := cache.count(); calls != 1 {
+		t.Errorf("Expected cache to be pinged once, got %d", calls)
+	}
+}
+
+// TestReadyzDiskCheck tests that the optional disk check reports a directory
+// that cannot be written to as not writable and the service as not ready
+func TestReadyzDiskCheck(t *testing.T) {
+	writable := t.TempDir()
+	readOnly := t.TempDir()
+	if err := os.Chmod(readOnly, 0o555); err != nil {
+		t.Fatalf("Error making directory read-only: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnly, 0o755) })
+
+	testCases := []struct {
+		name           string
+		disk           DiskChecker
+		skipAsRoot     bool
+		expectedStatus int
+		expectedDisk   interface{}
+	}{
+		{name: "Not checked", expectedStatus: http.StatusOK},
+		{name: "Writable", disk: WritableDir(writable), expectedStatus: http.StatusOK, expectedDisk: "up"},
+		{
+			// Root can write to read-only directories
+			name:           "Read-only",
+			disk:           WritableDir(readOnly),
+			skipAsRoot:     true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedDisk:   "not writable",
+		},
+		{
+			name:           "Missing",
+			disk:           WritableDir(filepath.Join(writable, "missing")),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedDisk:   "not writable",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skipAsRoot && os.Geteuid() == 0 {
+				t.Skip("Skipping read-only directory check when running as root")
+			}
+
+			handler := CachedReadyzHandler(&mockDBPinger{}, &mockCachePinger{}, tc.disk, 0)
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tc.expectedStatus)
+			}
+			var response struct {
+				Checks map[string]interface{} `json:"checks"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Error parsing response body: %v", err)
+			}
+			if response.Checks["disk"] != tc.expectedDisk {
+				t.Errorf("handler returned wrong disk status: got %v want %v", response.Checks["disk"], tc.expectedDisk)
+			}
+		})
+	}
+
+	// The check leaves nothing behind
+	if entries, _ := os.ReadDir(writable); len(entries) != 0 {
+		t.Errorf("Expected the disk check to clean up, found %d entries", len(entries))
+	}
+}
+
+// TestReadinessCacheDetectsDown tests that a dependency going down is seen once the window expires
+func TestReadinessCacheDetectsDown(t *testing.T) {
+	now := time.Now()
+	db := &countingPinger{}
+	cache := &countingPinger{}
+	readiness := &readinessCache{ttl: time.Second, now: func() time.Time { return now }}
+
+	if !readiness.get(db, cache, nil).ready() {
+		t.Fatal("Expected dependencies to be ready")
+	}
+
+	db.setError(errors.New("database connection error"))
+
+	now = now.Add(500 * time.Millisecond)
+	if !readiness.get(db, cache, nil).ready() {
+		t.Error("Expected cached ready result within the window")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if readiness.get(db, cache, nil).ready() {
+		t.Error("Expected not ready once the window expired")
+	}
+	if calls := db.count(); calls != 2 {
+		t.Errorf("Expected database to be pinged twice, got %d", calls)
+	}
+}
+
+// countingPinger is a pinger that counts calls for testing
+type countingPinger struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (p *countingPinger) Ping() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return p.err
+}
+
+func (p *countingPinger) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *countingPinger) setError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
 // mockDBPinger is a mock implementation of DBPinger for testing
 type mockDBPinger struct {
 	shouldError bool
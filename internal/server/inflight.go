@@ -0,0 +1,77 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// inFlightGauge counts the requests being served and warns when the count
+// stays above a high-water mark, signalling saturation before requests
+// start failing
+type inFlightGauge struct {
+	mu         sync.Mutex
+	count      int
+	highWater  int
+	sustain    time.Duration
+	aboveSince time.Time
+	warned     bool
+	now        func() time.Time
+	logf       func(format string, args ...interface{})
+}
+
+// newInFlightGauge creates a gauge warning once the count has been above
+// highWater (0 disables the warning) for at least sustain
+func newInFlightGauge(highWater int, sustain time.Duration) *inFlightGauge {
+	return &inFlightGauge{
+		highWater: highWater,
+		sustain:   sustain,
+		now:       time.Now,
+		logf:      log.Printf,
+	}
+}
+
+// Middleware returns middleware counting the requests served by next
+func (g *inFlightGauge) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.add(1)
+		defer g.add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Count returns the number of requests being served
+func (g *inFlightGauge) Count() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.count
+}
+
+// add changes the count and checks it against the high-water mark. The
+// warning is logged once per saturated period and re-armed when the count
+// drops back to the mark.
+func (g *inFlightGauge) add(delta int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.count += delta
+	if g.highWater <= 0 {
+		return
+	}
+
+	if g.count <= g.highWater {
+		g.aboveSince = time.Time{}
+		g.warned = false
+		return
+	}
+
+	now := g.now()
+	if g.aboveSince.IsZero() {
+		g.aboveSince = now
+	}
+	if !g.warned && now.Sub(g.aboveSince) >= g.sustain {
+		g.warned = true
+		g.logf("Warning: %d requests in flight, above the high-water mark of %d for %s", g.count, g.highWater, now.Sub(g.aboveSince))
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestInFlightGauge tests that concurrent slow requests are counted while
+// they are served and a saturation warning is logged once the count passes
+// the high-water mark
+func TestInFlightGauge(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	gauge := newInFlightGauge(2, 0)
+	gauge.logf = func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	warnings := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), lines...)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := gauge.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	serve := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/posts", nil))
+		}()
+		<-started
+	}
+
+	// At the high-water mark nothing is logged
+	serve()
+	serve()
+	if count := gauge.Count(); count != 2 {
+		t.Fatalf("Expected 2 requests in flight, got %d", count)
+	}
+	if logs := warnings(); len(logs) != 0 {
+		t.Fatalf("Expected no warning at the high-water mark, got %v", logs)
+	}
+
+	// Past it the warning fires once
+	serve()
+	serve()
+	if count := gauge.Count(); count != 4 {
+		t.Fatalf("Expected 4 requests in flight, got %d", count)
+	}
+	logs := warnings()
+	if len(logs) != 1 || !strings.Contains(logs[0], "3 requests in flight") {
+		t.Fatalf("Expected one saturation warning, got %v", logs)
+	}
+
+	close(release)
+	wg.Wait()
+	if count := gauge.Count(); count != 0 {
+		t.Errorf("Expected no requests in flight, got %d", count)
+	}
+}
+
+// TestInFlightGaugeSustain tests that the warning waits until the count has
+// stayed above the high-water mark for the sustain period
+func TestInFlightGaugeSustain(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var logs []string
+	gauge := newInFlightGauge(1, 10*time.Second)
+	gauge.now = func() time.Time { return now }
+	gauge.logf = func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	gauge.add(1)
+	gauge.add(1)
+	now = now.Add(5 * time.Second)
+	gauge.add(1)
+	if len(logs) != 0 {
+		t.Fatalf("Expected no warning before the sustain period, got %v", logs)
+	}
+
+	now = now.Add(5 * time.Second)
+	gauge.add(-1)
+	if len(logs) != 1 {
+		t.Fatalf("Expected a warning after the sustain period, got %v", logs)
+	}
+
+	// Dropping back to the mark re-arms the warning
+	gauge.add(-1)
+	gauge.add(1)
+	if len(logs) != 1 {
+		t.Errorf("Expected the sustain period to restart, got %v", logs)
+	}
+}
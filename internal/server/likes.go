@@ -0,0 +1,92 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// LikePostHandler handles POST and DELETE /posts/:id/like requests, liking
+// and unliking a post as the caller. Both are idempotent: liking a post
+// again or unliking a post that isn't liked changes nothing and returns 200.
+// A new like returns 201.
+func (h *PostHandler) LikePostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST and DELETE methods
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication
+		userID, err := h.auth.authenticate(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		if h.likes == nil {
+			respondError(w, http.StatusServiceUnavailable, "Likes are not available")
+			return
+		}
+
+		// Extract post ID from URL, /api/posts/{id}/like; clients may use
+		// either the raw or the opaque form of the ID
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		id, err := h.postIDs().decode(parts[len(parts)-2])
+		if err != nil || id == "" {
+			respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			return
+		}
+
+		if _, err := h.postService.GetByID(id); err != nil {
+			if errors.Is(err, domain.ErrPostNotFound) {
+				respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			} else {
+				respondError(w, http.StatusInternalServerError, "Failed to get post")
+			}
+			return
+		}
+
+		// The repository reports whether the like changed, so concurrent
+		// likes of the same post can't both be reported as new
+		var changed bool
+		if r.Method == http.MethodPost {
+			changed, err = h.likes.Create(&domain.Like{PostID: id, UserID: userID, CreatedAt: time.Now()})
+		} else {
+			changed, err = h.likes.Delete(id, userID)
+		}
+		if err != nil {
+			log.Printf("Error updating like: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to update like")
+			return
+		}
+
+		count, err := h.likes.CountByPost(id)
+		if err != nil {
+			log.Printf("Error counting likes: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to count likes")
+			return
+		}
+
+		// The cached listings carry like counts; the single post cache
+		// doesn't, so it stays valid
+		if changed {
+			h.cacheWrites.Go(func() { h.postCache.InvalidatePosts() })
+		}
+
+		status := http.StatusOK
+		if r.Method == http.MethodPost && changed {
+			status = http.StatusCreated
+		}
+		h.respondJSON(w, status, map[string]interface{}{
+			"post_id":    id,
+			"liked":      r.Method == http.MethodPost,
+			"like_count": count,
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// mockLikeRepository is an in-memory domain.LikeRepository
+type mockLikeRepository struct {
+	mu    sync.Mutex
+	likes map[string]map[string]bool
+}
+
+func newMockLikeRepository() *mockLikeRepository {
+	return &mockLikeRepository{likes: make(map[string]map[string]bool)}
+}
+
+func (m *mockLikeRepository) Create(like *domain.Like) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.likes[like.PostID] == nil {
+		m.likes[like.PostID] = make(map[string]bool)
+	}
+	if m.likes[like.PostID][like.UserID] {
+		return false, nil
+	}
+	m.likes[like.PostID][like.UserID] = true
+	return true, nil
+}
+
+func (m *mockLikeRepository) Delete(postID, userID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.likes[postID][userID] {
+		return false, nil
+	}
+	delete(m.likes[postID], userID)
+	return true, nil
+}
+
+func (m *mockLikeRepository) CountByPost(postID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.likes[postID]), nil
+}
+
+func (m *mockLikeRepository) CountByPosts(postIDs []string) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[string]int)
+	for _, postID := range postIDs {
+		if count := len(m.likes[postID]); count > 0 {
+			counts[postID] = count
+		}
+	}
+	return counts, nil
+}
+
+func (m *mockLikeRepository) ExistsByUserAndPost(userID, postID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.likes[postID][userID], nil
+}
+
+// TestLikePostHandler tests that liking and unliking are idempotent, and
+// that the cached listings are dropped only when a like changes
+func TestLikePostHandler(t *testing.T) {
+	mockPostService := &mockPostService{
+		getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+			if id != "post_1" {
+				return nil, domain.ErrPostNotFound
+			}
+			return &domain.PostWithUser{Post: domain.Post{ID: id, UserID: "user_2"}}, nil
+		},
+	}
+	invalidations := 0
+	mockPostCache := &mockPostCache{
+		invalidatePostsFunc: func() error {
+			invalidations++
+			return nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, mockPostCache)
+	handler.likes = newMockLikeRepository()
+
+	// Steps run in order against the same likes
+	steps := []struct {
+		name              string
+		method            string
+		path              string
+		authenticated     bool
+		expectedStatus    int
+		expectedLiked     bool
+		expectedCount     int
+		expectInvalidated bool
+	}{
+		{
+			name:              "Like",
+			method:            http.MethodPost,
+			path:              "/api/posts/post_1/like",
+			authenticated:     true,
+			expectedStatus:    http.StatusCreated,
+			expectedLiked:     true,
+			expectedCount:     1,
+			expectInvalidated: true,
+		},
+		{
+			name:           "Repeated like",
+			method:         http.MethodPost,
+			path:           "/api/posts/post_1/like",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			expectedLiked:  true,
+			expectedCount:  1,
+		},
+		{
+			name:              "Unlike",
+			method:            http.MethodDelete,
+			path:              "/api/posts/post_1/like",
+			authenticated:     true,
+			expectedStatus:    http.StatusOK,
+			expectedCount:     0,
+			expectInvalidated: true,
+		},
+		{
+			name:           "Repeated unlike",
+			method:         http.MethodDelete,
+			path:           "/api/posts/post_1/like",
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			expectedCount:  0,
+		},
+		{
+			name:           "Post not found",
+			method:         http.MethodPost,
+			path:           "/api/posts/post_9/like",
+			authenticated:  true,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Unauthenticated",
+			method:         http.MethodPost,
+			path:           "/api/posts/post_1/like",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodGet,
+			path:           "/api/posts/post_1/like",
+			authenticated:  true,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			before := invalidations
+
+			req := httptest.NewRequest(step.method, step.path, nil)
+			if step.authenticated {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.LikePostHandler()(rr, req)
+			handler.cacheWrites.Wait(context.Background())
+
+			if rr.Code != step.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", step.expectedStatus, rr.Code)
+			}
+			if invalidated := invalidations > before; invalidated != step.expectInvalidated {
+				t.Errorf("Expected posts cache invalidated = %t, got %t", step.expectInvalidated, invalidated)
+			}
+			if rr.Code != http.StatusOK && rr.Code != http.StatusCreated {
+				return
+			}
+
+			var response struct {
+				PostID    string `json:"post_id"`
+				Liked     bool   `json:"liked"`
+				LikeCount int    `json:"like_count"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.PostID != "post_1" || response.Liked != step.expectedLiked || response.LikeCount != step.expectedCount {
+				t.Errorf("Expected post_1 liked %t with %d likes, got %+v", step.expectedLiked, step.expectedCount, response)
+			}
+		})
+	}
+}
+
+// TestLikePostHandlerConcurrent tests that of concurrent likes of a post by
+// the same user, only one is reported as new
+func TestLikePostHandlerConcurrent(t *testing.T) {
+	mockPostService := &mockPostService{
+		getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+			return &domain.PostWithUser{Post: domain.Post{ID: id, UserID: "user_2"}}, nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	handler.likes = newMockLikeRepository()
+
+	const requests = 10
+	var wg sync.WaitGroup
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/api/posts/post_1/like", nil)
+			req.SetBasicAuth("admin", "password")
+			rr := httptest.NewRecorder()
+			handler.LikePostHandler()(rr, req)
+			codes <- rr.Code
+		}()
+	}
+	wg.Wait()
+	handler.cacheWrites.Wait(context.Background())
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		if code == http.StatusCreated {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected exactly one 201 Created, got %d", created)
+	}
+}
+
+// TestLikePostRoute tests that like requests for a post are dispatched to
+// the like handler, which is unavailable without a like repository
+func TestLikePostRoute(t *testing.T) {
+	testCases := []struct {
+		name           string
+		opts           []ServerOption
+		expectedStatus int
+	}{
+		{
+			name:           "Likes",
+			opts:           []ServerOption{WithLikeRepository(newMockLikeRepository())},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "No like repository",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := New(Config{Host: "localhost", Port: 8080}, &MockPostService{}, &MockPostCache{}, &MockDBPinger{}, &MockPostCache{}, tc.opts...)
+			server.registerRoutes()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/posts/post_1/like", nil)
+			req.SetBasicAuth("admin", "password")
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// postView is how posts are shaped in a response
+type postView struct {
+	// fields are the selected post fields; all fields when empty
+	fields []string
+	// postURL returns the absolute URL of a post; nil leaves URLs out
+	postURL func(id string) string
+}
+
+// parseIncludeParam splits a comma-separated include parameter, reporting
+// whether post URLs were requested and which values are unknown
+func parseIncludeParam(value string) (includeURL bool, unknown []string) {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		switch item {
+		case "":
+		case "url":
+			includeURL = true
+		default:
+			unknown = append(unknown, item)
+		}
+	}
+	return includeURL, unknown
+}
+
+// parsePostView reads the fields and include parameters of a request,
+// writing a 400 response and returning false when either names something
+// unknown
+func (h *PostHandler) parsePostView(w http.ResponseWriter, r *http.Request) (postView, bool) {
+	query := r.URL.Query()
+
+	// Reject misspelled fields rather than returning empty partial posts
+	fields, unknown := parseFieldsParam(query.Get("fields"))
+	if len(unknown) > 0 {
+		respondError(w, http.StatusBadRequest, "Unknown fields: "+strings.Join(unknown, ", "))
+		return postView{}, false
+	}
+
+	includeURL, unknown := parseIncludeParam(query.Get("include"))
+	if len(unknown) > 0 {
+		respondError(w, http.StatusBadRequest, "Unknown include: "+strings.Join(unknown, ", "))
+		return postView{}, false
+	}
+
+	view := postView{fields: fields}
+	if includeURL {
+		view.postURL = h.postURLs(r)
+	}
+	return view, true
+}
+
+// postURLs returns a function building absolute post URLs from the
+// configured base URL, or from the request's own scheme and host when no
+// base URL is configured
+func (h *PostHandler) postURLs(r *http.Request) func(id string) string {
+	base := strings.TrimSuffix(h.baseURL, "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return func(id string) string {
+		return base + "/api/posts/" + h.exposedPostID(id)
+	}
+}
+
+// withPostURL returns post as a JSON object with its url added
+func withPostURL(post interface{}, url string) interface{} {
+	value, err := toJSONValue(post)
+	if err != nil {
+		return post
+	}
+	if item, ok := value.(map[string]interface{}); ok {
+		item["url"] = url
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestPostURLsFromBaseURL tests that include=url adds each post's absolute
+// URL, composed from the configured base URL
+func TestPostURLsFromBaseURL(t *testing.T) {
+	mockPostCache := &MockPostCache{}
+	server := New(Config{Host: "localhost", Port: 8080, BaseURL: "https://tigertail.example/"}, &MockPostService{}, mockPostCache, &MockDBPinger{}, mockPostCache)
+	server.registerRoutes()
+
+	testCases := []struct {
+		path string
+		get  func(body []byte) (string, error)
+	}{
+		{
+			path: "/api/posts?include=url",
+			get: func(body []byte) (string, error) {
+				var response struct {
+					Posts []map[string]interface{} `json:"posts"`
+				}
+				err := json.Unmarshal(body, &response)
+				if err != nil || len(response.Posts) == 0 {
+					return "", err
+				}
+				url, _ := response.Posts[0]["url"].(string)
+				return url, nil
+			},
+		},
+		{
+			path: "/api/posts/post_1?include=url",
+			get: func(body []byte) (string, error) {
+				var response struct {
+					Post map[string]interface{} `json:"post"`
+				}
+				err := json.Unmarshal(body, &response)
+				url, _ := response.Post["url"].(string)
+				return url, err
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+			url, err := tc.get(rr.Body.Bytes())
+			if err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if url != "https://tigertail.example/api/posts/post_1" {
+				t.Errorf("Expected url %q, got %q", "https://tigertail.example/api/posts/post_1", url)
+			}
+		})
+	}
+}
+
+// TestPostURLsOptIn tests that URLs are left out unless requested, combine
+// with field selection and fall back to the request's host
+func TestPostURLsOptIn(t *testing.T) {
+	mockPostService := &mockPostService{
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			return []*domain.PostWithUser{{Post: domain.Post{ID: "post_7", Content: "Hello"}}}, 1, nil
+		},
+	}
+	mockPostCache := &mockPostCache{
+		getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+			return nil, domain.ErrPostNotFound
+		},
+	}
+	handler := NewPostHandler(mockPostService, mockPostCache)
+
+	testCases := []struct {
+		query          string
+		expectedStatus int
+		expectedPost   map[string]interface{}
+	}{
+		{
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedPost:   map[string]interface{}{"id": "post_7", "content": "Hello"},
+		},
+		{
+			query:          "?fields=id&include=url",
+			expectedStatus: http.StatusOK,
+			expectedPost:   map[string]interface{}{"id": "post_7", "url": "http://example.com/api/posts/post_7"},
+		},
+		{
+			query:          "?include=links",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.GetPostsHandler()(rr, httptest.NewRequest(http.MethodGet, "/api/posts"+tc.query, nil))
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedPost == nil {
+				return
+			}
+
+			var response struct {
+				Posts []map[string]interface{} `json:"posts"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if len(response.Posts) != 1 {
+				t.Fatalf("Expected 1 post, got %d", len(response.Posts))
+			}
+			post := response.Posts[0]
+			if _, ok := tc.expectedPost["url"]; !ok {
+				if _, ok := post["url"]; ok {
+					t.Errorf("Expected no url unless requested, got %v", post["url"])
+				}
+			}
+			for key, want := range tc.expectedPost {
+				if post[key] != want {
+					t.Errorf("Expected %s %v, got %v", key, want, post[key])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// TestServerMetrics tests that requests are counted by matched route,
+// method and status, that posts cache misses are counted, and that the
+// metrics are served at /metrics from the injected registry to the admin only
+func TestServerMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mockPostCache := &MockPostCache{}
+	server := New(Config{Host: "localhost", Port: 8080}, &MockPostService{}, mockPostCache, &MockDBPinger{}, mockPostCache, WithMetricsRegistry(registry))
+	server.registerRoutes()
+
+	testServer := httptest.NewServer(server.httpServer.Handler)
+	defer testServer.Close()
+
+	for _, path := range []string{"/api/posts", "/api/posts?page=2", "/health"} {
+		resp, err := http.Get(testServer.URL + path)
+		if err != nil {
+			t.Fatalf("Error making request to %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(testServer.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Error making request to /metrics: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d without credentials, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL+"/metrics", nil)
+	req.SetBasicAuth("admin", "password")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error making request to /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, line := range []string{
+		`http_requests_total{method="GET",path="/api/posts",status="200"} 2`,
+		`http_requests_total{method="GET",path="/health",status="200"} 1`,
+		`http_requests_total{method="GET",path="/metrics",status="401"} 1`,
+		`http_request_duration_seconds_count{method="GET",path="/api/posts"} 2`,
+		"cache_hits_total 0",
+		"cache_misses_total 2",
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, body)
+		}
+	}
+}
+
+// TestInstrumentRequests tests that requests matching no route are
+// labelled unmatched, that error statuses are recorded and that response
+// sizes are observed
+func TestInstrumentRequests(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/posts/", func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, http.StatusNotFound, "Post not found")
+	})
+	handler := InstrumentRequests(mux, mux, metrics.New(registry))
+
+	errorResponse := httptest.NewRecorder()
+	respondError(errorResponse, http.StatusNotFound, "Post not found")
+	errorBytes := errorResponse.Body.Len()
+
+	for _, path := range []string{"/api/posts/post_1", "/api/posts/post_2", "/elsewhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{
+		`http_requests_total{method="GET",path="/api/posts/",status="404"} 2`,
+		`http_requests_total{method="GET",path="unmatched",status="404"} 1`,
+		fmt.Sprintf(`http_response_size_bytes_sum{method="GET",path="/api/posts/"} %d`, 2*errorBytes),
+		`http_response_size_bytes_count{method="GET",path="/api/posts/"} 2`,
+	} {
+		if !strings.Contains(rr.Body.String(), line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, rr.Body.String())
+		}
+	}
+}
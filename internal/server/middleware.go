@@ -0,0 +1,145 @@
+package server
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/metrics"
+)
+
+// statusRecorder wraps a ResponseWriter to capture the response status code
+// and the number of body bytes written
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the status code before writing it
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the body bytes before writing them
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush passes flushes through so streamed responses still reach the client
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// requestLogger logs completed requests, sampling successful ones
+type requestLogger struct {
+	next               http.Handler
+	sampleRate         float64
+	largeResponseBytes int64
+	random             func() float64
+	logf               func(format string, args ...interface{})
+}
+
+// RequestLogger returns middleware that logs requests handled by next.
+// Server errors (status >= 500) are always logged; other requests are logged
+// with probability sampleRate, clamped to the range 0.0–1.0. Every response
+// larger than largeResponseBytes is logged as a warning (0 disables this).
+func RequestLogger(next http.Handler, sampleRate float64, largeResponseBytes int64) http.Handler {
+	return newRequestLogger(next, sampleRate, largeResponseBytes, rand.Float64, log.Printf)
+}
+
+// newRequestLogger creates a request logger with the given random source and log function
+func newRequestLogger(next http.Handler, sampleRate float64, largeResponseBytes int64, random func() float64, logf func(string, ...interface{})) *requestLogger {
+	if sampleRate < 0 {
+		sampleRate = 0
+	}
+	if sampleRate > 1 {
+		sampleRate = 1
+	}
+
+	return &requestLogger{
+		next:               next,
+		sampleRate:         sampleRate,
+		largeResponseBytes: largeResponseBytes,
+		random:             random,
+		logf:               logf,
+	}
+}
+
+// ServeHTTP serves the request and logs it if it is an error or sampled
+func (l *requestLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	l.next.ServeHTTP(recorder, r)
+
+	if l.largeResponseBytes > 0 && recorder.bytes > l.largeResponseBytes {
+		l.logf("Warning: large response %s %s %d bytes exceeds %d bytes", r.Method, r.URL.Path, recorder.bytes, l.largeResponseBytes)
+	}
+
+	if recorder.status < http.StatusInternalServerError && !l.sampled() {
+		return
+	}
+
+	l.logf("%s %s %d %s %d bytes", r.Method, r.URL.Path, recorder.status, time.Since(start), recorder.bytes)
+}
+
+// sampled reports whether a successful request should be logged
+func (l *requestLogger) sampled() bool {
+	switch {
+	case l.sampleRate >= 1:
+		return true
+	case l.sampleRate <= 0:
+		return false
+	default:
+		return l.random() < l.sampleRate
+	}
+}
+
+// MatchedRoute returns middleware that names the route pattern serving each
+// request in an X-Matched-Route header, e.g. "GET /api/posts/", to help debug
+// routing. When disabled, mux is returned unchanged.
+func MatchedRoute(mux *http.ServeMux, enabled bool) http.Handler {
+	if !enabled {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			w.Header().Set("X-Matched-Route", r.Method+" "+pattern)
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// InstrumentRequests returns middleware that records the count, status and
+// latency of requests handled by next in m, labelled with the mux route
+// they match rather than their path so post IDs don't each become a series.
+// Requests matching no route are labelled unmatched.
+func InstrumentRequests(next http.Handler, mux *http.ServeMux, m *metrics.Metrics) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		route := "unmatched"
+		if _, pattern := mux.Handler(r); pattern != "" {
+			route = pattern
+		}
+		m.ObserveRequest(route, r.Method, recorder.status, time.Since(start), recorder.bytes)
+	})
+}
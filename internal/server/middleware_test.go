@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRequestLoggerSampling tests that successes are sampled and errors are always logged
+func TestRequestLoggerSampling(t *testing.T) {
+	const requests = 10000
+	const sampleRate = 0.25
+
+	testCases := []struct {
+		name     string
+		status   int
+		minCount int
+		maxCount int
+	}{
+		{
+			name:     "Successes are sampled",
+			status:   http.StatusOK,
+			minCount: int(requests * (sampleRate - 0.05)),
+			maxCount: int(requests * (sampleRate + 0.05)),
+		},
+		{
+			name:     "Errors are always logged",
+			status:   http.StatusInternalServerError,
+			minCount: requests,
+			maxCount: requests,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			})
+
+			logged := 0
+			logf := func(format string, args ...interface{}) {
+				logged++
+			}
+
+			random := rand.New(rand.NewSource(1)).Float64
+			handler := newRequestLogger(next, sampleRate, 0, random, logf)
+
+			for i := 0; i < requests; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			if logged < tc.minCount || logged > tc.maxCount {
+				t.Errorf("Expected between %d and %d logged requests, got %d", tc.minCount, tc.maxCount, logged)
+			}
+		})
+	}
+}
+
+// TestRequestLoggerClampsRate tests that out-of-range sample rates are clamped
+func TestRequestLoggerClampsRate(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	logf := func(format string, args ...interface{}) {}
+
+	if l := newRequestLogger(next, 2, 0, rand.Float64, logf); l.sampleRate != 1 {
+		t.Errorf("Expected sample rate 1, got %v", l.sampleRate)
+	}
+	if l := newRequestLogger(next, -1, 0, rand.Float64, logf); l.sampleRate != 0 {
+		t.Errorf("Expected sample rate 0, got %v", l.sampleRate)
+	}
+}
+
+// TestRequestLoggerLargeResponse tests that every written byte is counted
+// and oversized responses are logged as warnings
+func TestRequestLoggerLargeResponse(t *testing.T) {
+	const threshold = 1024
+
+	testCases := []struct {
+		name          string
+		bodySize      int
+		expectWarning bool
+	}{
+		{
+			name:          "Small response",
+			bodySize:      threshold,
+			expectWarning: false,
+		},
+		{
+			name:          "Large response",
+			bodySize:      threshold * 4,
+			expectWarning: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Write in chunks to check that every write is counted
+				chunk := []byte(strings.Repeat("x", tc.bodySize/4))
+				for i := 0; i < 4; i++ {
+					w.Write(chunk)
+				}
+			})
+
+			var lines []string
+			logf := func(format string, args ...interface{}) {
+				lines = append(lines, fmt.Sprintf(format, args...))
+			}
+
+			handler := newRequestLogger(next, 1, threshold, rand.Float64, logf)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/admin/posts/export", nil))
+
+			if rr.Body.Len() != tc.bodySize {
+				t.Errorf("Expected %d bytes to reach the client, got %d", tc.bodySize, rr.Body.Len())
+			}
+
+			warned := false
+			for _, line := range lines {
+				if strings.Contains(line, "large response") {
+					warned = true
+				}
+			}
+			if warned != tc.expectWarning {
+				t.Errorf("Expected warning %v, got log lines %q", tc.expectWarning, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+)
+
+// JSON key casing styles for responses
+const (
+	JSONCaseSnake = "snake"
+	JSONCaseCamel = "camel"
+)
+
+// Read strategies for post listings
+const (
+	ReadStrategyCacheFirst = "cache_first"
+	ReadStrategyDBFirst    = "db_first"
+)
+
+// Cache refresh modes applied after a post is created
+const (
+	CreateRefreshInvalidate = "invalidate"
+	CreateRefreshRefresh    = "refresh"
+)
+
+// Options holds the tunable behaviour of the server
+type Options struct {
+	// LogSampleRate is the fraction (0.0–1.0) of successful requests that are
+	// logged; server errors are always logged
+	LogSampleRate float64
+	// JSONCase is the key casing used in post responses (snake or camel)
+	JSONCase string
+	// ReadyzCacheTTL is how long a readiness check result is shared between
+	// probes (0 disables caching)
+	ReadyzCacheTTL time.Duration
+	// MaxOffset is the largest pagination offset accepted (0 means no limit)
+	MaxOffset int
+	// RouteRateLimits maps a route to the requests per second allowed per
+	// client IP, e.g. RATE_LIMIT_ROUTES=/api/auth/login=1,/api/posts=20
+	RouteRateLimits map[string]float64
+	// TrustRequestID reuses a caller-supplied X-Request-ID instead of always
+	// generating a new one
+	TrustRequestID bool
+	// ReadStrategy is cache_first (serve from the cache when possible) or
+	// db_first (query the database and fall back to the cache on failure)
+	ReadStrategy string
+	// LargeResponseBytes is the response body size above which a warning is
+	// logged (0 disables the warning)
+	LargeResponseBytes int64
+	// AuthCacheTTL is how long a successful authentication is cached, e.g.
+	// AUTH_CACHE_TTL=30s (0 disables caching)
+	AuthCacheTTL time.Duration
+	// TokenTTL is how long a bearer token issued at login stays valid; as
+	// tokens must expire, values <= 0 fall back to the default
+	TokenTTL time.Duration
+	// LoginRateLimit is the token logins per second allowed per client IP,
+	// unless RATE_LIMIT_ROUTES sets a limit for /api/login (0 disables it)
+	LoginRateLimit float64
+	// MaxStreamDuration is how long a post stream connection stays open
+	// before it is closed so the client reconnects (0 means no limit)
+	MaxStreamDuration time.Duration
+	// MaxStreamClients caps the post stream connections open at once; more
+	// are rejected with 503 (0 means no limit)
+	MaxStreamClients int
+	// CreateRefreshMode is what happens to the posts cache after a create:
+	// invalidate drops it, refresh reloads it in the background. Neither
+	// delays the create response. Unknown modes fall back to invalidate.
+	// Only this package's create handler applies it; the tigertail binary
+	// always invalidates.
+	CreateRefreshMode string
+	// CSVRequireAdmin restricts the CSV posts export to the administrator
+	CSVRequireAdmin bool
+	// MaxBatchIDs is the largest number of IDs accepted by the batch get
+	// endpoint (0 means no limit)
+	MaxBatchIDs int
+	// GzipLevel is the gzip compression level of responses, from 1 (fastest)
+	// to 9 (smallest); 0 disables compression
+	GzipLevel int
+	// PublicUserPostsCap is how many of a user's most recent posts can be
+	// paged through on the public user posts endpoint (0 means no limit)
+	PublicUserPostsCap int
+	// PostIDFormat is how post IDs appear in responses: raw (post_17) or
+	// opaque, which hides their ordering. Both forms are accepted on input.
+	PostIDFormat string
+	// PostIDSecret keys the opaque post ID encoding; changing it invalidates
+	// previously issued opaque IDs. When opaque IDs are on and no secret is
+	// set, a random one is generated at startup.
+	PostIDSecret string
+	// DebugEchoRoute adds an X-Matched-Route header naming the route that
+	// served each request
+	DebugEchoRoute bool
+	// PostsDefaultLimit is the page size of the post listing, search and CSV
+	// export when no limit is given
+	PostsDefaultLimit int
+	// FeedDefaultLimit is the page size of the caller's own posts when no
+	// limit is given
+	FeedDefaultLimit int
+	// UserPostsDefaultLimit is the page size of the public user posts
+	// listing when no limit is given
+	UserPostsDefaultLimit int
+	// APIIndexVerbose lists the available endpoints in the /api index
+	APIIndexVerbose bool
+	// CommentMaxLength is the most characters a comment may have (0 means
+	// no limit). It shares MAX_POST_LENGTH with the limit the post service
+	// enforces on posts.
+	CommentMaxLength int
+	// PostMaxBodyBytes caps the size of create, update and batch get request
+	// bodies, and of imports at that size per post (0 means no limit)
+	PostMaxBodyBytes int64
+	// MaxConcurrentCacheWarms caps the posts cache warms (admin rebuilds and
+	// refreshes after creates) running at once; further warms are skipped
+	MaxConcurrentCacheWarms int
+	// FeedCacheTTL is how long a rendered feed page (the CSV export) is
+	// served to other requests before it is generated again (0 disables
+	// caching; concurrent generations are shared either way)
+	FeedCacheTTL time.Duration
+	// FeedCursors embeds a polling cursor in the CSV feed (Last-Modified
+	// and a Link to the next poll) and accepts it back as since, answering
+	// 304 when no post is newer
+	FeedCursors bool
+	// MaxByUsersIDs is the largest number of user IDs accepted by the posts
+	// by users endpoint (0 means no limit)
+	MaxByUsersIDs int
+	// MaxResolveUsernames is the largest number of usernames accepted by the
+	// username resolve endpoint (0 means no limit)
+	MaxResolveUsernames int
+	// InFlightHighWater is the number of requests in flight above which a
+	// saturation warning is logged (0 disables the warning)
+	InFlightHighWater int
+	// InFlightSustain is how long the in-flight requests must stay above
+	// InFlightHighWater before the warning is logged
+	InFlightSustain time.Duration
+	// SearchEmptyNotFound makes searches without results respond with 404
+	// instead of 200 and an empty list, on the count endpoint too
+	SearchEmptyNotFound bool
+	// ReadyzCheckDisk adds a check that ReadyzDiskDir is writable to the
+	// readiness probe, for deployments that write to disk
+	ReadyzCheckDisk bool
+	// ReadyzDiskDir is the directory checked by ReadyzCheckDisk (default:
+	// the system temp directory)
+	ReadyzDiskDir string
+	// IncludeUnfilteredTotal makes filtered listings such as search report
+	// the overall number of posts as total and the number of matches as
+	// filtered_total
+	IncludeUnfilteredTotal bool
+	// RetryAfterFormat is how Retry-After is sent on 429 and 503 responses:
+	// seconds (delta-seconds) or date (an HTTP-date)
+	RetryAfterFormat string
+	// ErrorFormat is how error responses are sent: simple ({"code": "...",
+	// "message": "..."}) or problem (RFC 7807 application/problem+json)
+	ErrorFormat string
+}
+
+// RedactedOptions is a view of Options that is safe to expose
+type RedactedOptions struct {
+	Options
+	PostIDSecret config.SensitiveString
+}
+
+// Redacted returns a view of the options with the post ID secret redacted
+func (o Options) Redacted() RedactedOptions {
+	return RedactedOptions{
+		Options:      o,
+		PostIDSecret: config.SensitiveString(o.PostIDSecret),
+	}
+}
+
+// DefaultOptions returns the default server options
+func DefaultOptions() Options {
+	return Options{
+		LogSampleRate:           1,
+		JSONCase:                JSONCaseSnake,
+		ReadyzCacheTTL:          time.Second,
+		MaxOffset:               10000,
+		TrustRequestID:          true,
+		ReadStrategy:            ReadStrategyCacheFirst,
+		LargeResponseBytes:      5 << 20,
+		AuthCacheTTL:            30 * time.Second,
+		TokenTTL:                time.Hour,
+		LoginRateLimit:          1,
+		MaxStreamDuration:       5 * time.Minute,
+		MaxStreamClients:        1000,
+		CreateRefreshMode:       CreateRefreshInvalidate,
+		MaxBatchIDs:             100,
+		GzipLevel:               6,
+		PublicUserPostsCap:      1000,
+		PostIDFormat:            PostIDFormatRaw,
+		PostsDefaultLimit:       defaultPageLimit,
+		FeedDefaultLimit:        defaultPageLimit,
+		UserPostsDefaultLimit:   defaultPageLimit,
+		CommentMaxLength:        280,
+		PostMaxBodyBytes:        1 << 20,
+		MaxConcurrentCacheWarms: 1,
+		FeedCacheTTL:            5 * time.Second,
+		MaxByUsersIDs:           50,
+		MaxResolveUsernames:     100,
+		InFlightSustain:         10 * time.Second,
+		ReadyzDiskDir:           os.TempDir(),
+		RetryAfterFormat:        RetryAfterSeconds,
+		ErrorFormat:             ErrorFormatSimple,
+	}
+}
+
+// LoadOptionsFromEnv loads the server options from environment variables
+func LoadOptionsFromEnv() Options {
+	options := DefaultOptions()
+	options.LogSampleRate = config.GetEnvFloat("LOG_SAMPLE_RATE", options.LogSampleRate)
+	options.JSONCase = config.GetEnv("JSON_CASE", options.JSONCase)
+	options.ReadyzCacheTTL = config.GetEnvMillis("READYZ_CACHE_MS", options.ReadyzCacheTTL)
+	options.MaxOffset = config.GetEnvInt("MAX_OFFSET", options.MaxOffset)
+	options.TrustRequestID = config.GetEnvBool("TRUST_REQUEST_ID", options.TrustRequestID)
+	options.ReadStrategy = config.GetEnv("READ_STRATEGY", options.ReadStrategy)
+	options.LargeResponseBytes = int64(config.GetEnvInt("LARGE_RESPONSE_BYTES", int(options.LargeResponseBytes)))
+	options.AuthCacheTTL = config.GetEnvDuration("AUTH_CACHE_TTL", options.AuthCacheTTL)
+	if ttl := config.GetEnvDuration("JWT_TTL", options.TokenTTL); ttl > 0 {
+		options.TokenTTL = ttl
+	} else {
+		log.Printf("Ignoring JWT_TTL=%v, tokens must expire; using %v", ttl, options.TokenTTL)
+	}
+	options.LoginRateLimit = config.GetEnvFloat("LOGIN_RATE_LIMIT", options.LoginRateLimit)
+	options.MaxStreamDuration = config.GetEnvDuration("MAX_STREAM_DURATION", options.MaxStreamDuration)
+	options.MaxStreamClients = config.GetEnvInt("MAX_STREAM_CLIENTS", options.MaxStreamClients)
+	switch mode := config.GetEnv("CREATE_REFRESH_MODE", options.CreateRefreshMode); mode {
+	case CreateRefreshInvalidate, CreateRefreshRefresh:
+		options.CreateRefreshMode = mode
+	default:
+		log.Printf("Ignoring unknown CREATE_REFRESH_MODE=%q; using %q", mode, options.CreateRefreshMode)
+	}
+	options.CSVRequireAdmin = config.GetEnvBool("CSV_REQUIRE_ADMIN", options.CSVRequireAdmin)
+	options.MaxBatchIDs = config.GetEnvInt("MAX_BATCH_IDS", options.MaxBatchIDs)
+	options.GzipLevel = config.GetEnvInt("GZIP_LEVEL", options.GzipLevel)
+	options.PublicUserPostsCap = config.GetEnvInt("PUBLIC_USER_POSTS_CAP", options.PublicUserPostsCap)
+	options.PostIDFormat = config.GetEnv("POST_ID_FORMAT", options.PostIDFormat)
+	options.PostIDSecret = config.GetEnv("POST_ID_SECRET", options.PostIDSecret)
+	if options.PostIDFormat == PostIDFormatOpaque && options.PostIDSecret == "" {
+		log.Printf("POST_ID_SECRET is not set; opaque post IDs will change on restart")
+		options.PostIDSecret = randomPostIDSecret()
+	}
+	options.DebugEchoRoute = config.GetEnvBool("DEBUG_ECHO_ROUTE", options.DebugEchoRoute)
+	options.PostsDefaultLimit = config.GetEnvInt("POSTS_DEFAULT_LIMIT", options.PostsDefaultLimit)
+	options.FeedDefaultLimit = config.GetEnvInt("FEED_DEFAULT_LIMIT", options.FeedDefaultLimit)
+	options.UserPostsDefaultLimit = config.GetEnvInt("USER_POSTS_DEFAULT_LIMIT", options.UserPostsDefaultLimit)
+	options.APIIndexVerbose = config.GetEnvBool("API_INDEX_VERBOSE", options.APIIndexVerbose)
+	options.CommentMaxLength = config.GetEnvInt("MAX_POST_LENGTH", options.CommentMaxLength)
+	options.PostMaxBodyBytes = int64(config.GetEnvInt("POST_MAX_BODY_BYTES", int(options.PostMaxBodyBytes)))
+	options.MaxConcurrentCacheWarms = config.GetEnvInt("CACHE_WARM_CONCURRENCY", options.MaxConcurrentCacheWarms)
+	options.FeedCacheTTL = config.GetEnvDuration("FEED_CACHE_TTL", options.FeedCacheTTL)
+	options.FeedCursors = config.GetEnvBool("FEED_CURSORS", options.FeedCursors)
+	options.MaxByUsersIDs = config.GetEnvInt("POSTS_BY_USERS_MAX_IDS", options.MaxByUsersIDs)
+	options.MaxResolveUsernames = config.GetEnvInt("RESOLVE_USERNAMES_MAX", options.MaxResolveUsernames)
+	options.InFlightHighWater = config.GetEnvInt("IN_FLIGHT_HIGH_WATER", options.InFlightHighWater)
+	options.InFlightSustain = config.GetEnvDuration("IN_FLIGHT_SUSTAIN", options.InFlightSustain)
+	options.SearchEmptyNotFound = config.GetEnvBool("SEARCH_EMPTY_NOT_FOUND", options.SearchEmptyNotFound)
+	options.ReadyzCheckDisk = config.GetEnvBool("READYZ_CHECK_DISK", options.ReadyzCheckDisk)
+	options.ReadyzDiskDir = config.GetEnv("READYZ_DISK_DIR", options.ReadyzDiskDir)
+	options.IncludeUnfilteredTotal = config.GetEnvBool("INCLUDE_UNFILTERED_TOTAL", options.IncludeUnfilteredTotal)
+	options.RetryAfterFormat = config.GetEnv("RETRY_AFTER_FORMAT", options.RetryAfterFormat)
+	options.ErrorFormat = config.GetEnv("ERROR_FORMAT", options.ErrorFormat)
+	if routes := config.GetEnv("RATE_LIMIT_ROUTES", ""); routes != "" {
+		options.RouteRateLimits = parseRouteRates(routes)
+	}
+	return options
+}
+
+// loginRoute is the route exchanging credentials for a bearer token
+const loginRoute = "/api/login"
+
+// routeRateLimits returns the per-route rate limits, adding LoginRateLimit
+// for the login route unless a limit is configured for it already
+func (o Options) routeRateLimits() map[string]float64 {
+	if o.LoginRateLimit <= 0 {
+		return o.RouteRateLimits
+	}
+	if _, ok := o.RouteRateLimits[loginRoute]; ok {
+		return o.RouteRateLimits
+	}
+
+	routes := make(map[string]float64, len(o.RouteRateLimits)+1)
+	for route, rps := range o.RouteRateLimits {
+		routes[route] = rps
+	}
+	routes[loginRoute] = o.LoginRateLimit
+	return routes
+}
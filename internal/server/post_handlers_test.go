@@ -2,16 +2,23 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/JoobyPM/tiger-tail-microblog/internal/cache"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
 )
 
+// The Redis posts cache must keep implementing the handlers' PostCache
+// interface, totals included
+var _ PostCache = (*cache.PostCache)(nil)
+
 // TestGetPostsHandler tests the GetPostsHandler method
 func TestGetPostsHandler(t *testing.T) {
 	testCases := []struct {
@@ -315,6 +322,22 @@ func TestCreatePostHandler(t *testing.T) {
 			serviceError:   nil,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:           "Content empty after sanitization",
+			method:         "POST",
+			auth:           true,
+			content:        "<script>alert(1)</script>",
+			serviceError:   domain.ErrInvalidPostContent,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Content too long",
+			method:         "POST",
+			auth:           true,
+			content:        "Test post content",
+			serviceError:   fmt.Errorf("%w: 300 characters, more than the limit of 280", domain.ErrPostContentTooLong),
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "Service error",
 			method:         "POST",
@@ -410,6 +433,134 @@ func TestCreatePostHandler(t *testing.T) {
 	}
 }
 
+// TestCreatePostHandlerRefreshMode tests that the create response never waits
+// on the list query, and that refresh mode reloads the cache in the background
+func TestCreatePostHandlerRefreshMode(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mode          string
+		expectRefresh bool
+	}{
+		{
+			name:          "Invalidate mode",
+			mode:          CreateRefreshInvalidate,
+			expectRefresh: false,
+		},
+		{
+			name:          "Refresh mode",
+			mode:          CreateRefreshRefresh,
+			expectRefresh: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			release := make(chan struct{})
+			listed := make(chan struct{}, 1)
+			cached := make(chan []*domain.PostWithUser, 1)
+			invalidated := make(chan struct{}, 1)
+
+			mockPostService := &mockPostService{
+				createFunc: func(userID, content string) (*domain.Post, error) {
+					return &domain.Post{ID: "post_123", UserID: userID, Content: content}, nil
+				},
+				// The list query blocks until the test releases it
+				listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+					listed <- struct{}{}
+					<-release
+					return []*domain.PostWithUser{{Post: domain.Post{ID: "post_123"}}}, 1, nil
+				},
+			}
+			mockPostCache := &mockPostCache{
+				setPostsWithUserFunc: func(posts []*domain.PostWithUser) error {
+					cached <- posts
+					return nil
+				},
+				invalidatePostsFunc: func() error {
+					invalidated <- struct{}{}
+					return nil
+				},
+			}
+
+			handler := NewPostHandler(mockPostService, mockPostCache)
+			handler.options.CreateRefreshMode = tc.mode
+
+			body, _ := json.Marshal(map[string]string{"content": "Test post content"})
+			req := httptest.NewRequest(http.MethodPost, "/api/posts/create", bytes.NewReader(body))
+			req.SetBasicAuth("admin", "password")
+			rr := httptest.NewRecorder()
+
+			done := make(chan struct{})
+			go func() {
+				handler.CreatePostHandler()(rr, req)
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				close(release)
+				t.Fatal("Create response blocked on the list query")
+			}
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rr.Code)
+			}
+
+			if !tc.expectRefresh {
+				close(release)
+				select {
+				case <-invalidated:
+				case <-time.After(time.Second):
+					t.Fatal("Expected the posts cache to be invalidated")
+				}
+				select {
+				case <-listed:
+					t.Error("Did not expect a list query in invalidate mode")
+				default:
+				}
+				return
+			}
+
+			select {
+			case <-listed:
+			case <-time.After(time.Second):
+				t.Fatal("Expected a background list query in refresh mode")
+			}
+			close(release)
+			select {
+			case posts := <-cached:
+				if len(posts) != 1 || posts[0].ID != "post_123" {
+					t.Errorf("Expected the refreshed posts to be cached, got %v", posts)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Expected the posts cache to be refreshed")
+			}
+		})
+	}
+}
+
+// TestCreateRefreshModeFromEnv tests that an unknown CREATE_REFRESH_MODE
+// falls back to invalidating the cache
+func TestCreateRefreshModeFromEnv(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{value: CreateRefreshRefresh, expected: CreateRefreshRefresh},
+		{value: CreateRefreshInvalidate, expected: CreateRefreshInvalidate},
+		{value: "reload", expected: CreateRefreshInvalidate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("CREATE_REFRESH_MODE", tc.value)
+			if mode := LoadOptionsFromEnv().CreateRefreshMode; mode != tc.expected {
+				t.Errorf("CreateRefreshMode = %q, want %q", mode, tc.expected)
+			}
+		})
+	}
+}
+
 // TestAuthenticateRequest tests the authenticateRequest function
 func TestAuthenticateRequest(t *testing.T) {
 	testCases := []struct {
@@ -471,86 +622,1047 @@ func TestAuthenticateRequest(t *testing.T) {
 	}
 }
 
-// mockPostService is a mock implementation of domain.PostService for testing
-type mockPostService struct {
-	getByIDFunc func(id string) (*domain.PostWithUser, error)
-	createFunc  func(userID, content string) (*domain.Post, error)
-	listFunc    func(page, limit int) ([]*domain.PostWithUser, int, error)
-}
+// TestGetPostsHandlerMaxOffset tests that offsets beyond MaxOffset are rejected
+func TestGetPostsHandlerMaxOffset(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "At max offset",
+			query:          "?page=101&limit=100",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Over max offset",
+			query:          "?page=102&limit=100",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Over max offset with small limit",
+			query:          "?page=10002&limit=1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
 
-func (m *mockPostService) GetByID(id string) (*domain.PostWithUser, error) {
-	if m.getByIDFunc != nil {
-		return m.getByIDFunc(id)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+					return []*domain.PostWithUser{}, 0, nil
+				},
+			}
+			mockPostCache := &mockPostCache{
+				getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+					return nil, errors.New("cache miss")
+				},
+				setPostsWithUserFunc: func(posts []*domain.PostWithUser) error {
+					return nil
+				},
+			}
+
+			handler := NewPostHandler(mockPostService, mockPostCache)
+			handler.options.MaxOffset = 10000
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.GetPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+		})
 	}
-	return nil, nil
 }
 
-func (m *mockPostService) Create(userID, content string) (*domain.Post, error) {
-	if m.createFunc != nil {
-		return m.createFunc(userID, content)
+// TestGetPostsHandlerReadStrategy tests that the reported source follows the
+// configured read strategy
+func TestGetPostsHandlerReadStrategy(t *testing.T) {
+	testCases := []struct {
+		name           string
+		readStrategy   string
+		dbErr          error
+		expectedSource string
+	}{
+		{
+			name:           "Cache first with healthy cache",
+			readStrategy:   ReadStrategyCacheFirst,
+			expectedSource: "cache",
+		},
+		{
+			name:           "DB first with healthy database",
+			readStrategy:   ReadStrategyDBFirst,
+			expectedSource: "database",
+		},
+		{
+			name:           "DB first falls back to cache on database failure",
+			readStrategy:   ReadStrategyDBFirst,
+			dbErr:          errors.New("database error"),
+			expectedSource: "cache",
+		},
 	}
-	return nil, nil
-}
 
-func (m *mockPostService) Update(id, userID, content string) (*domain.Post, error) {
-	return nil, nil
-}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			posts := []*domain.PostWithUser{
+				{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "Test post"}, Username: "testuser"},
+			}
+			mockPostService := &mockPostService{
+				listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+					if tc.dbErr != nil {
+						return nil, 0, tc.dbErr
+					}
+					return posts, len(posts), nil
+				},
+			}
+			mockPostCache := &mockPostCache{
+				getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+					return posts, nil
+				},
+			}
 
-func (m *mockPostService) Delete(id, userID string) error {
-	return nil
-}
+			handler := NewPostHandler(mockPostService, mockPostCache)
+			handler.options.ReadStrategy = tc.readStrategy
 
-func (m *mockPostService) ListByUser(userID string, page, limit int) ([]*domain.Post, int, error) {
-	return nil, 0, nil
-}
+			req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+			rr := httptest.NewRecorder()
+			handler.GetPostsHandler()(rr, req)
 
-func (m *mockPostService) List(page, limit int) ([]*domain.PostWithUser, int, error) {
-	if m.listFunc != nil {
-		return m.listFunc(page, limit)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response["source"] != tc.expectedSource {
+				t.Errorf("Expected source %q, got %v", tc.expectedSource, response["source"])
+			}
+		})
 	}
-	return nil, 0, nil
 }
 
-// mockPostCache is a mock implementation of PostCache for testing
-type mockPostCache struct {
-	getPostFunc          func(id string) (*domain.Post, error)
-	setPostFunc          func(post *domain.Post) error
-	getPostsWithUserFunc func() ([]*domain.PostWithUser, error)
-	setPostsWithUserFunc func(posts []*domain.PostWithUser) error
-	invalidatePostsFunc  func() error
-}
+// TestGetPostsHandlerCacheWindow tests that a page beyond the cached window is
+// served from the database rather than as an empty cached page
+func TestGetPostsHandlerCacheWindow(t *testing.T) {
+	firstPage := []*domain.PostWithUser{
+		{Post: domain.Post{ID: "post_4", UserID: "user_1", Content: "Fourth"}, Username: "testuser"},
+		{Post: domain.Post{ID: "post_3", UserID: "user_1", Content: "Third"}, Username: "testuser"},
+	}
+	secondPage := []*domain.PostWithUser{
+		{Post: domain.Post{ID: "post_2", UserID: "user_1", Content: "Second"}, Username: "testuser"},
+		{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "First"}, Username: "testuser"},
+	}
+	mockPostService := &mockPostService{
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			if page != 2 || limit != 2 {
+				t.Errorf("Expected database read of page 2 with limit 2, got page %d limit %d", page, limit)
+			}
+			return secondPage, 4, nil
+		},
+	}
+	mockPostCache := &mockPostCache{
+		getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+			return firstPage, nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, mockPostCache)
 
-func (m *mockPostCache) GetPost(id string) (*domain.Post, error) {
-	if m.getPostFunc != nil {
-		return m.getPostFunc(id)
+	testCases := []struct {
+		query          string
+		expectedSource string
+		expectedIDs    []string
+	}{
+		{query: "page=1&limit=2", expectedSource: "cache", expectedIDs: []string{"post_4", "post_3"}},
+		{query: "page=2&limit=2", expectedSource: "database", expectedIDs: []string{"post_2", "post_1"}},
 	}
-	return nil, errors.New("cache miss")
-}
 
-func (m *mockPostCache) SetPost(post *domain.Post) error {
-	if m.setPostFunc != nil {
-		return m.setPostFunc(post)
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/posts?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.GetPostsHandler()(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var response struct {
+				Posts  []domain.PostWithUser `json:"posts"`
+				Source string                `json:"source"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response.Source != tc.expectedSource {
+				t.Errorf("Expected source %q, got %q", tc.expectedSource, response.Source)
+			}
+			if len(response.Posts) != len(tc.expectedIDs) {
+				t.Fatalf("Expected %d posts, got %d", len(tc.expectedIDs), len(response.Posts))
+			}
+			for i, id := range tc.expectedIDs {
+				if response.Posts[i].ID != id {
+					t.Errorf("Expected post %d to be %s, got %s", i, id, response.Posts[i].ID)
+				}
+			}
+		})
 	}
-	return nil
 }
 
-func (m *mockPostCache) GetPostsWithUser() ([]*domain.PostWithUser, error) {
-	if m.getPostsWithUserFunc != nil {
-		return m.getPostsWithUserFunc()
+// TestGetPostsHandlerTruncatedCache tests that a first page longer than a
+// truncated cache snapshot is read from the database, while a snapshot
+// holding every post is still served
+func TestGetPostsHandlerTruncatedCache(t *testing.T) {
+	cached := []*domain.PostWithUser{
+		{Post: domain.Post{ID: "post_3", UserID: "user_1", Content: "Third"}, Username: "testuser"},
+		{Post: domain.Post{ID: "post_2", UserID: "user_1", Content: "Second"}, Username: "testuser"},
+	}
+	testCases := []struct {
+		name           string
+		total          int
+		expectedSource string
+		expectedTotal  int
+	}{
+		{name: "truncated", total: 3, expectedSource: "database", expectedTotal: 3},
+		{name: "complete", total: 2, expectedSource: "cache", expectedTotal: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+					return append(cached, &domain.PostWithUser{Post: domain.Post{ID: "post_1"}}), 3, nil
+				},
+			}
+			mockPostCache := &mockPostCache{
+				getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+					return cached, nil
+				},
+				getPostsTotalFunc: func() (int, error) {
+					return tc.total, nil
+				},
+			}
+			handler := NewPostHandler(mockPostService, mockPostCache)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts?page=1&limit=5", nil)
+			rr := httptest.NewRecorder()
+			handler.GetPostsHandler()(rr, req)
+
+			var response struct {
+				Source string `json:"source"`
+				Total  int    `json:"total"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response.Source != tc.expectedSource {
+				t.Errorf("Expected source %q, got %q", tc.expectedSource, response.Source)
+			}
+			if response.Total != tc.expectedTotal {
+				t.Errorf("Expected total %d, got %d", tc.expectedTotal, response.Total)
+			}
+		})
 	}
-	return nil, errors.New("cache miss")
 }
 
-func (m *mockPostCache) SetPostsWithUser(posts []*domain.PostWithUser) error {
-	if m.setPostsWithUserFunc != nil {
-		return m.setPostsWithUserFunc(posts)
+// TestGetPostsHandlerCachedTotal tests that a page served from the cache
+// reports the total recorded with the snapshot, not the number of cached posts
+func TestGetPostsHandlerCachedTotal(t *testing.T) {
+	mockPostCache := &mockPostCache{
+		getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+			return []*domain.PostWithUser{
+				{Post: domain.Post{ID: "post_57", UserID: "user_1", Content: "Newest"}, Username: "testuser"},
+				{Post: domain.Post{ID: "post_56", UserID: "user_1", Content: "Older"}, Username: "testuser"},
+			}, nil
+		},
+		getPostsTotalFunc: func() (int, error) {
+			return 57, nil
+		},
+	}
+	handler := NewPostHandler(&mockPostService{}, mockPostCache)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts?page=1&limit=2", nil)
+	rr := httptest.NewRecorder()
+	handler.GetPostsHandler()(rr, req)
+
+	var response struct {
+		Posts  []domain.PostWithUser `json:"posts"`
+		Source string                `json:"source"`
+		Total  int                   `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if response.Source != "cache" {
+		t.Errorf("Expected source %q, got %q", "cache", response.Source)
+	}
+	if len(response.Posts) != 2 {
+		t.Errorf("Expected 2 posts, got %d", len(response.Posts))
+	}
+	if response.Total != 57 {
+		t.Errorf("Expected total 57, got %d", response.Total)
 	}
-	return nil
 }
 
-func (m *mockPostCache) InvalidatePosts() error {
-	if m.invalidatePostsFunc != nil {
-		return m.invalidatePostsFunc()
+// TestDeletePostHandler tests that the caller's posts are deleted and
+// dropped from the cache, while other requests are rejected
+func TestDeletePostHandler(t *testing.T) {
+	testCases := []struct {
+		name              string
+		method            string
+		authenticated     bool
+		deleteErr         error
+		expectedStatus    int
+		expectInvalidated bool
+	}{
+		{
+			name:              "Deleted",
+			method:            http.MethodDelete,
+			authenticated:     true,
+			expectedStatus:    http.StatusNoContent,
+			expectInvalidated: true,
+		},
+		{
+			name:           "Not found",
+			method:         http.MethodDelete,
+			authenticated:  true,
+			deleteErr:      domain.ErrPostNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Service error",
+			method:         http.MethodDelete,
+			authenticated:  true,
+			deleteErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "Unauthenticated",
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodPost,
+			authenticated:  true,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var deletedID, deletedBy string
+			mockPostService := &mockPostService{
+				deleteFunc: func(id, userID string) error {
+					deletedID, deletedBy = id, userID
+					return tc.deleteErr
+				},
+			}
+			var invalidatedPost string
+			invalidatedPosts := false
+			mockPostCache := &mockPostCache{
+				invalidatePostFunc: func(id string) error {
+					invalidatedPost = id
+					return nil
+				},
+				invalidatePostsFunc: func() error {
+					invalidatedPosts = true
+					return nil
+				},
+			}
+			handler := NewPostHandler(mockPostService, mockPostCache)
+
+			req := httptest.NewRequest(tc.method, "/api/posts/post_1", nil)
+			if tc.authenticated {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.DeletePostHandler()(rr, req)
+			handler.cacheWrites.Wait(context.Background())
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus == http.StatusNoContent && (deletedID != "post_1" || deletedBy != "user_1") {
+				t.Errorf("Expected post_1 to be deleted by user_1, got %q by %q", deletedID, deletedBy)
+			}
+			if invalidated := invalidatedPost == "post_1" && invalidatedPosts; invalidated != tc.expectInvalidated {
+				t.Errorf("Expected cache invalidated = %t, got post %q, posts %t", tc.expectInvalidated, invalidatedPost, invalidatedPosts)
+			}
+		})
+	}
+}
+
+// TestDeletePostRoute tests that DELETE requests for a post are dispatched
+// to the delete handler
+func TestDeletePostRoute(t *testing.T) {
+	server := New(Config{Host: "localhost", Port: 8080}, &MockPostService{}, &MockPostCache{}, &MockDBPinger{}, &MockPostCache{})
+	server.registerRoutes()
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/posts/post_1", nil)
+	req.SetBasicAuth("admin", "password")
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, rr.Code)
+	}
+}
+
+// TestPostSources tests that each serving path reports its source
+func TestPostSources(t *testing.T) {
+	posts := []*domain.PostWithUser{
+		{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "First"}, Username: "testuser"},
+	}
+	fromCache := func() ([]*domain.PostWithUser, error) { return posts, nil }
+	cacheMiss := func() ([]*domain.PostWithUser, error) { return nil, errors.New("cache miss") }
+	fromDatabase := func(page, limit int) ([]*domain.PostWithUser, int, error) { return posts, 1, nil }
+	databaseDown := func(page, limit int) ([]*domain.PostWithUser, int, error) {
+		return nil, 0, errors.New("database down")
+	}
+
+	testCases := []struct {
+		name           string
+		path           string
+		readStrategy   string
+		cached         func() ([]*domain.PostWithUser, error)
+		stale          func() ([]*domain.PostWithUser, error)
+		list           func(page, limit int) ([]*domain.PostWithUser, int, error)
+		cachedPost     bool
+		expectedSource string
+	}{
+		{name: "Cache hit", path: "/api/posts", cached: fromCache, list: fromDatabase, expectedSource: SourceCache},
+		{name: "Cache miss", path: "/api/posts", cached: cacheMiss, list: fromDatabase, expectedSource: SourceDatabase},
+		{name: "Database first", path: "/api/posts", readStrategy: ReadStrategyDBFirst, cached: fromCache, list: fromDatabase, expectedSource: SourceDatabase},
+		{name: "Database first falls back to the cache", path: "/api/posts", readStrategy: ReadStrategyDBFirst, cached: fromCache, list: databaseDown, expectedSource: SourceCache},
+		{name: "Stale cache", path: "/api/posts", readStrategy: ReadStrategyDBFirst, cached: cacheMiss, stale: fromCache, list: databaseDown, expectedSource: SourceStaleCache},
+		{name: "Single post cache hit", path: "/api/posts/post_1", cachedPost: true, expectedSource: SourceCache},
+		{name: "Single post cache miss", path: "/api/posts/post_1", expectedSource: SourceDatabase},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				listFunc: tc.list,
+				getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+					return posts[0], nil
+				},
+			}
+			mockPostCache := &mockPostCache{
+				getPostsWithUserFunc: tc.cached,
+				getStalePostsFunc:    tc.stale,
+				getPostsTotalFunc:    func() (int, error) { return 1, nil },
+			}
+			if tc.cachedPost {
+				mockPostCache.getPostFunc = func(id string) (*domain.Post, error) {
+					return &posts[0].Post, nil
+				}
+			}
+			handler := NewPostHandler(mockPostService, mockPostCache)
+			if tc.readStrategy != "" {
+				handler.options.ReadStrategy = tc.readStrategy
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			if tc.path == "/api/posts" {
+				handler.GetPostsHandler()(rr, req)
+			} else {
+				handler.GetPostHandler()(rr, req)
+			}
+
+			var response struct {
+				Source string `json:"source"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response.Source != tc.expectedSource {
+				t.Errorf("Expected source %q, got %q", tc.expectedSource, response.Source)
+			}
+		})
+	}
+}
+
+// TestGetPostsHandlerMalformedCache tests that a cached snapshot holding an
+// entry without an ID is discarded and the posts are read from the database
+func TestGetPostsHandlerMalformedCache(t *testing.T) {
+	invalidated := false
+	mockPostService := &mockPostService{
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			return []*domain.PostWithUser{
+				{Post: domain.Post{ID: "post_2", UserID: "user_1", Content: "Second"}, Username: "testuser"},
+				{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "First"}, Username: "testuser"},
+			}, 2, nil
+		},
+	}
+	mockPostCache := &mockPostCache{
+		getPostsWithUserFunc: func() ([]*domain.PostWithUser, error) {
+			return []*domain.PostWithUser{
+				{Post: domain.Post{ID: "post_2", UserID: "user_1", Content: "Second"}, Username: "testuser"},
+				{Post: domain.Post{ID: "", Content: "garbage"}},
+			}, nil
+		},
+		invalidatePostsFunc: func() error {
+			invalidated = true
+			return nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, mockPostCache)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+	rr := httptest.NewRecorder()
+	handler.GetPostsHandler()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	var response struct {
+		Posts  []domain.PostWithUser `json:"posts"`
+		Source string                `json:"source"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if response.Source != "database" {
+		t.Errorf("Expected source %q, got %q", "database", response.Source)
+	}
+	if len(response.Posts) != 2 || response.Posts[1].ID != "post_1" {
+		t.Errorf("Expected the posts from the database, got %+v", response.Posts)
+	}
+	if !invalidated {
+		t.Error("Expected the malformed snapshot to be invalidated")
+	}
+}
+
+// TestDefaultPageLimits tests that each listing uses its own default page
+// size when no limit is given
+func TestDefaultPageLimits(t *testing.T) {
+	var requestedLimit int
+	mockPostService := &mockPostService{
+		listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+			requestedLimit = limit
+			return []*domain.PostWithUser{}, 0, nil
+		},
+		listByUserFunc: func(userID string, page, limit int) ([]*domain.Post, int, error) {
+			requestedLimit = limit
+			return []*domain.Post{}, 0, nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	handler.options.PostsDefaultLimit = 20
+	handler.options.FeedDefaultLimit = 30
+	handler.options.UserPostsDefaultLimit = 40
+
+	testCases := []struct {
+		name          string
+		path          string
+		handler       http.HandlerFunc
+		expectedLimit int
+	}{
+		{name: "Posts", path: "/api/posts", handler: handler.GetPostsHandler(), expectedLimit: 20},
+		{name: "Feed", path: "/api/posts/mine", handler: handler.MyPostsHandler(), expectedLimit: 30},
+		{name: "User posts", path: "/api/users/user_1/posts", handler: handler.UserPostsHandler(), expectedLimit: 40},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requestedLimit = 0
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.SetBasicAuth("admin", "password")
+			rr := httptest.NewRecorder()
+			tc.handler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+			if requestedLimit != tc.expectedLimit {
+				t.Errorf("Expected limit %d, got %d", tc.expectedLimit, requestedLimit)
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response["limit"] != float64(tc.expectedLimit) {
+				t.Errorf("Expected reported limit %d, got %v", tc.expectedLimit, response["limit"])
+			}
+		})
+	}
+}
+
+// TestGetPostsHandlerStaleFallback tests that a stale cached copy is served
+// with the stored total when the database fails and the fresh cache entry
+// is gone
+func TestGetPostsHandlerStaleFallback(t *testing.T) {
+	testCases := []struct {
+		name           string
+		readStrategy   string
+		staleAvailable bool
+		expectedStatus int
+	}{
+		{
+			name:           "Cache first serves stale copy",
+			readStrategy:   ReadStrategyCacheFirst,
+			staleAvailable: true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "DB first serves stale copy",
+			readStrategy:   ReadStrategyDBFirst,
+			staleAvailable: true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "No stale copy",
+			readStrategy:   ReadStrategyCacheFirst,
+			staleAvailable: false,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stale := []*domain.PostWithUser{
+				{Post: domain.Post{ID: "post_1", UserID: "user_1", Content: "Old post"}, Username: "testuser"},
+			}
+			mockPostService := &mockPostService{
+				listFunc: func(page, limit int) ([]*domain.PostWithUser, int, error) {
+					return nil, 0, errors.New("database error")
+				},
+			}
+			mockPostCache := &mockPostCache{
+				getStalePostsFunc: func() ([]*domain.PostWithUser, error) {
+					if !tc.staleAvailable {
+						return nil, errors.New("cache miss")
+					}
+					return stale, nil
+				},
+				getPostsTotalFunc: func() (int, error) {
+					return 42, nil
+				},
+			}
+
+			handler := NewPostHandler(mockPostService, mockPostCache)
+			handler.options.ReadStrategy = tc.readStrategy
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+			rr := httptest.NewRecorder()
+			handler.GetPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				if rr.Header().Get("X-Cache-Stale") != "" {
+					t.Errorf("Did not expect a stale header on an error response")
+				}
+				return
+			}
+
+			if rr.Header().Get("X-Cache-Stale") != "true" {
+				t.Errorf("Expected X-Cache-Stale header, got %q", rr.Header().Get("X-Cache-Stale"))
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response["source"] != "stale_cache" {
+				t.Errorf("Expected source %q, got %v", "stale_cache", response["source"])
+			}
+			if response["total"] != float64(42) {
+				t.Errorf("Expected the stored total 42, got %v", response["total"])
+			}
+		})
+	}
+}
+
+// TestMyPostsHandler tests the MyPostsHandler method
+func TestMyPostsHandler(t *testing.T) {
+	allPosts := []*domain.Post{
+		{ID: "post_1", UserID: "user_1", Content: "Mine"},
+		{ID: "post_2", UserID: "user_2", Content: "Someone else's"},
+		{ID: "post_3", UserID: "user_1", Content: "Also mine"},
+	}
+
+	testCases := []struct {
+		name           string
+		authenticate   bool
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{
+			name:           "Authenticated",
+			authenticate:   true,
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []string{"post_1", "post_3"},
+		},
+		{
+			name:           "Unauthenticated",
+			authenticate:   false,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				listByUserFunc: func(userID string, page, limit int) ([]*domain.Post, int, error) {
+					posts := make([]*domain.Post, 0)
+					for _, post := range allPosts {
+						if post.UserID == userID {
+							posts = append(posts, post)
+						}
+					}
+					return posts, len(posts), nil
+				},
+			}
+
+			handler := NewPostHandler(mockPostService, &mockPostCache{})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/mine", nil)
+			if tc.authenticate {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.MyPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Posts []*domain.Post `json:"posts"`
+				Total int            `json:"total"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+
+			if len(response.Posts) != len(tc.expectedIDs) || response.Total != len(tc.expectedIDs) {
+				t.Fatalf("Expected %d posts, got %d (total %d)", len(tc.expectedIDs), len(response.Posts), response.Total)
+			}
+			for i, post := range response.Posts {
+				if post.ID != tc.expectedIDs[i] {
+					t.Errorf("Expected post %s, got %s", tc.expectedIDs[i], post.ID)
+				}
+				if post.UserID != "user_1" {
+					t.Errorf("Expected only the caller's posts, got one by %s", post.UserID)
+				}
+			}
+		})
+	}
+}
+
+// TestUserPostsHandlerCap tests that public paging stops at the cap while
+// the owner can page past it through /posts/mine
+func TestUserPostsHandlerCap(t *testing.T) {
+	mockPostService := &mockPostService{
+		listByUserFunc: func(userID string, page, limit int) ([]*domain.Post, int, error) {
+			return []*domain.Post{{ID: "post_1", UserID: userID}}, 50, nil
+		},
+	}
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	handler.options.PublicUserPostsCap = 10
+	handler.options.MaxOffset = 0
+
+	testCases := []struct {
+		name           string
+		path           string
+		owner          bool
+		expectedStatus int
+	}{
+		{name: "Public within cap", path: "/api/users/user_1/posts?page=2&limit=5", expectedStatus: http.StatusOK},
+		{name: "Public beyond cap", path: "/api/users/user_1/posts?page=3&limit=5", expectedStatus: http.StatusBadRequest},
+		{name: "Public limit beyond cap", path: "/api/users/user_1/posts?limit=20", expectedStatus: http.StatusBadRequest},
+		{name: "Owner beyond cap", path: "/api/posts/mine?page=3&limit=5", owner: true, expectedStatus: http.StatusOK},
+		{name: "Missing user", path: "/api/users//posts", expectedStatus: http.StatusNotFound},
+		{name: "Unknown subresource", path: "/api/users/user_1/likes", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+			if tc.owner {
+				req.SetBasicAuth("admin", "password")
+				handler.MyPostsHandler()(rr, req)
+			} else {
+				handler.UserPostsHandler()(rr, req)
+			}
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestGetPostHandlerJSONCase tests the configurable response key casing
+func TestGetPostHandlerJSONCase(t *testing.T) {
+	testCases := []struct {
+		name        string
+		jsonCase    string
+		expectedKey string
+		missingKey  string
+	}{
+		{
+			name:        "Default snake case",
+			jsonCase:    JSONCaseSnake,
+			expectedKey: "created_at",
+			missingKey:  "createdAt",
+		},
+		{
+			name:        "Camel case",
+			jsonCase:    JSONCaseCamel,
+			expectedKey: "createdAt",
+			missingKey:  "created_at",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+					return &domain.PostWithUser{
+						Post: domain.Post{
+							ID:        id,
+							UserID:    "user_1",
+							Content:   "Test post content",
+							CreatedAt: time.Now(),
+							UpdatedAt: time.Now(),
+						},
+						Username: "testuser",
+					}, nil
+				},
+			}
+			mockPostCache := &mockPostCache{
+				getPostFunc: func(id string) (*domain.Post, error) {
+					return nil, errors.New("cache miss")
+				},
+				setPostFunc: func(post *domain.Post) error {
+					return nil
+				},
+			}
+
+			handler := NewPostHandler(mockPostService, mockPostCache)
+			handler.options.JSONCase = tc.jsonCase
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/post_1", nil)
+			rr := httptest.NewRecorder()
+			handler.GetPostHandler()(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var response struct {
+				Post map[string]interface{} `json:"post"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+
+			post := response.Post
+			if _, ok := post[tc.expectedKey]; !ok {
+				t.Errorf("Expected key %q in post, got %v", tc.expectedKey, post)
+			}
+			if _, ok := post[tc.missingKey]; ok {
+				t.Errorf("Did not expect key %q in post", tc.missingKey)
+			}
+		})
+	}
+}
+
+// mockPostService is a mock implementation of domain.PostService for testing
+type mockPostService struct {
+	getByIDFunc func(id string) (*domain.PostWithUser, error)
+	createFunc  func(userID, content string) (*domain.Post, error)
+	updateFunc  func(id, userID, content string) (*domain.Post, error)
+	deleteFunc  func(id, userID string) error
+	listFunc    func(page, limit int) ([]*domain.PostWithUser, int, error)
+
+	listByUserFunc func(userID string, page, limit int) ([]*domain.Post, int, error)
+
+	searchFunc      func(query string, page, limit int) ([]*domain.PostWithUser, int, error)
+	countSearchFunc func(query string) (int, error)
+
+	listAfterFunc         func(createdAt time.Time, afterID string, limit int) ([]*domain.PostWithUser, error)
+	listModifiedSinceFunc func(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error)
+	listByUsersFunc       func(userIDs []string, limit int) ([]*domain.PostWithUser, error)
+	listFollowedByFunc    func(userID string, page, limit int) ([]*domain.PostWithUser, int, error)
+	createManyFunc        func(posts []*domain.Post, preserveTimestamps bool) ([]*domain.Post, error)
+}
+
+func (m *mockPostService) GetByID(id string) (*domain.PostWithUser, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *mockPostService) Create(userID, content string) (*domain.Post, error) {
+	if m.createFunc != nil {
+		return m.createFunc(userID, content)
+	}
+	return nil, nil
+}
+
+func (m *mockPostService) Update(id, userID, content string) (*domain.Post, error) {
+	if m.updateFunc != nil {
+		return m.updateFunc(id, userID, content)
+	}
+	return nil, nil
+}
+
+func (m *mockPostService) Delete(id, userID string) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(id, userID)
+	}
+	return nil
+}
+
+func (m *mockPostService) ListByUser(userID string, page, limit int) ([]*domain.Post, int, error) {
+	if m.listByUserFunc != nil {
+		return m.listByUserFunc(userID, page, limit)
+	}
+	return nil, 0, nil
+}
+
+func (m *mockPostService) List(page, limit int) ([]*domain.PostWithUser, int, error) {
+	if m.listFunc != nil {
+		return m.listFunc(page, limit)
+	}
+	return nil, 0, nil
+}
+
+func (m *mockPostService) Search(query string, page, limit int) ([]*domain.PostWithUser, int, error) {
+	if m.searchFunc != nil {
+		return m.searchFunc(query, page, limit)
+	}
+	return nil, 0, nil
+}
+
+func (m *mockPostService) CountSearch(query string) (int, error) {
+	if m.countSearchFunc != nil {
+		return m.countSearchFunc(query)
+	}
+	return 0, nil
+}
+
+func (m *mockPostService) ListAfter(createdAt time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	if m.listAfterFunc != nil {
+		return m.listAfterFunc(createdAt, afterID, limit)
+	}
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *mockPostService) ListModifiedSince(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	if m.listModifiedSinceFunc != nil {
+		return m.listModifiedSinceFunc(since, afterID, limit)
+	}
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *mockPostService) ListByUsers(userIDs []string, limit int) ([]*domain.PostWithUser, error) {
+	if m.listByUsersFunc != nil {
+		return m.listByUsersFunc(userIDs, limit)
+	}
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *mockPostService) ListFollowedBy(userID string, page, limit int) ([]*domain.PostWithUser, int, error) {
+	if m.listFollowedByFunc != nil {
+		return m.listFollowedByFunc(userID, page, limit)
+	}
+	return []*domain.PostWithUser{}, 0, nil
+}
+
+func (m *mockPostService) CreateMany(posts []*domain.Post, preserveTimestamps bool) ([]*domain.Post, error) {
+	if m.createManyFunc != nil {
+		return m.createManyFunc(posts, preserveTimestamps)
+	}
+	return posts, nil
+}
+
+// mockPostCache is a mock implementation of PostCache for testing
+type mockPostCache struct {
+	getPostFunc          func(id string) (*domain.Post, error)
+	setPostFunc          func(post *domain.Post) error
+	getPostsWithUserFunc func() ([]*domain.PostWithUser, error)
+	getStalePostsFunc    func() ([]*domain.PostWithUser, error)
+	setPostsWithUserFunc func(posts []*domain.PostWithUser) error
+	getPostsTotalFunc    func() (int, error)
+	invalidatePostsFunc  func() error
+	invalidatePostFunc   func(id string) error
+}
+
+func (m *mockPostCache) GetPost(id string) (*domain.Post, error) {
+	if m.getPostFunc != nil {
+		return m.getPostFunc(id)
+	}
+	return nil, errors.New("cache miss")
+}
+
+func (m *mockPostCache) SetPost(post *domain.Post) error {
+	if m.setPostFunc != nil {
+		return m.setPostFunc(post)
+	}
+	return nil
+}
+
+func (m *mockPostCache) GetPostsWithUser() ([]*domain.PostWithUser, error) {
+	if m.getPostsWithUserFunc != nil {
+		return m.getPostsWithUserFunc()
+	}
+	return nil, errors.New("cache miss")
+}
+
+func (m *mockPostCache) GetStalePostsWithUser() ([]*domain.PostWithUser, error) {
+	if m.getStalePostsFunc != nil {
+		return m.getStalePostsFunc()
+	}
+	return nil, errors.New("cache miss")
+}
+
+func (m *mockPostCache) SetPostsWithUser(posts []*domain.PostWithUser) error {
+	if m.setPostsWithUserFunc != nil {
+		return m.setPostsWithUserFunc(posts)
+	}
+	return nil
+}
+
+func (m *mockPostCache) SetPostsWithUserAt(posts []*domain.PostWithUser, readAt time.Time) error {
+	return m.SetPostsWithUser(posts)
+}
+
+func (m *mockPostCache) SetPostsWithUserAndTotalAt(posts []*domain.PostWithUser, total int, readAt time.Time) error {
+	return m.SetPostsWithUser(posts)
+}
+
+func (m *mockPostCache) GetPostsTotal() (int, error) {
+	if m.getPostsTotalFunc != nil {
+		return m.getPostsTotalFunc()
+	}
+	return 0, errors.New("cache miss")
+}
+
+func (m *mockPostCache) InvalidatePosts() error {
+	if m.invalidatePostsFunc != nil {
+		return m.invalidatePostsFunc()
+	}
+	return nil
+}
+
+func (m *mockPostCache) InvalidatePost(id string) error {
+	if m.invalidatePostFunc != nil {
+		return m.invalidatePostFunc(id)
 	}
 	return nil
 }
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Post ID formats exposed in responses
+const (
+	PostIDFormatRaw    = "raw"
+	PostIDFormatOpaque = "opaque"
+)
+
+// opaquePostIDPrefix marks opaque post IDs so they can be told apart from
+// raw IDs on input
+const opaquePostIDPrefix = "p_"
+
+// rawPostIDPrefix is the prefix of every raw post ID
+const rawPostIDPrefix = "post_"
+
+// postIDTagSize is the length of the tag that both seeds the keystream and
+// authenticates an opaque ID
+const postIDTagSize = 8
+
+// errInvalidOpaqueID is returned for opaque IDs that were not issued with the
+// current secret
+var errInvalidOpaqueID = errors.New("invalid opaque post ID")
+
+// postIDCodec converts raw post IDs to opaque ones and back. An opaque ID is
+// the raw ID encrypted with a keystream derived from a keyed hash of the raw
+// ID itself, so the same raw ID always maps to the same opaque ID while
+// consecutive raw IDs map to unrelated ones.
+type postIDCodec struct {
+	key []byte
+}
+
+// randomPostIDSecret returns a random secret for when none is configured
+func randomPostIDSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("post ID secret: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// newPostIDCodec creates a codec keyed with secret
+func newPostIDCodec(secret string) postIDCodec {
+	key := sha256.Sum256([]byte(secret))
+	return postIDCodec{key: key[:]}
+}
+
+// encode returns the opaque form of a raw post ID
+func (c postIDCodec) encode(raw string) string {
+	tag := c.mac([]byte(raw))[:postIDTagSize]
+	sealed := append(tag, c.xorKeystream(tag, []byte(raw))...)
+	return opaquePostIDPrefix + base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+// decode returns the raw post ID for an opaque or raw ID; raw IDs are
+// returned unchanged so clients may use either form
+func (c postIDCodec) decode(id string) (string, error) {
+	encoded, ok := strings.CutPrefix(id, opaquePostIDPrefix)
+	if !ok {
+		return id, nil
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) <= postIDTagSize {
+		return "", errInvalidOpaqueID
+	}
+
+	tag := sealed[:postIDTagSize]
+	raw := c.xorKeystream(tag, sealed[postIDTagSize:])
+	if !hmac.Equal(tag, c.mac(raw)[:postIDTagSize]) {
+		return "", errInvalidOpaqueID
+	}
+	return string(raw), nil
+}
+
+// mac returns the keyed hash of data
+func (c postIDCodec) mac(data []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// xorKeystream XORs data with a keystream derived from tag
+func (c postIDCodec) xorKeystream(tag, data []byte) []byte {
+	out := make([]byte, len(data))
+	var block []byte
+	for i := range data {
+		if i%sha256.Size == 0 {
+			counter := make([]byte, 4)
+			binary.BigEndian.PutUint32(counter, uint32(i/sha256.Size))
+			block = c.mac(append(append([]byte{}, tag...), counter...))
+		}
+		out[i] = data[i] ^ block[i%sha256.Size]
+	}
+	return out
+}
+
+// exposePostIDs replaces raw post IDs in a decoded JSON response with their
+// opaque form. Post IDs are the "id" and "post_id" fields holding raw IDs.
+func (c postIDCodec) exposePostIDs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if id, ok := item.(string); ok && (key == "id" || key == "post_id") && strings.HasPrefix(id, rawPostIDPrefix) {
+				v[key] = c.encode(id)
+				continue
+			}
+			v[key] = c.exposePostIDs(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = c.exposePostIDs(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestPostIDCodecRoundTrip tests that opaque IDs decode back to the raw ID and
+// raw IDs are accepted unchanged
+func TestPostIDCodecRoundTrip(t *testing.T) {
+	codec := newPostIDCodec("secret")
+
+	for _, raw := range []string{"post_1", "post_17", "post_20240101120000", "post_stub_16"} {
+		opaque := codec.encode(raw)
+		if !strings.HasPrefix(opaque, opaquePostIDPrefix) {
+			t.Errorf("encode(%q) = %q, want prefix %q", raw, opaque, opaquePostIDPrefix)
+		}
+		if opaque != codec.encode(raw) {
+			t.Errorf("encode(%q) is not deterministic", raw)
+		}
+
+		decoded, err := codec.decode(opaque)
+		if err != nil || decoded != raw {
+			t.Errorf("decode(%q) = %q, %v, want %q", opaque, decoded, err, raw)
+		}
+
+		decoded, err = codec.decode(raw)
+		if err != nil || decoded != raw {
+			t.Errorf("decode(%q) = %q, %v, want the raw ID unchanged", raw, decoded, err)
+		}
+	}
+}
+
+// TestPostIDCodecHidesSequence tests that consecutive raw IDs produce
+// unrelated opaque IDs
+func TestPostIDCodecHidesSequence(t *testing.T) {
+	codec := newPostIDCodec("secret")
+
+	previous := codec.encode("post_100")
+	for i := 101; i < 120; i++ {
+		opaque := codec.encode(fmt.Sprintf("post_%d", i))
+		if strings.Contains(opaque, fmt.Sprint(i)) {
+			t.Errorf("Opaque ID %q reveals its sequence number %d", opaque, i)
+		}
+
+		// Beyond the shared prefix, consecutive IDs should differ from the start
+		body, prevBody := opaque[len(opaquePostIDPrefix):], previous[len(opaquePostIDPrefix):]
+		if body[:4] == prevBody[:4] {
+			t.Errorf("Opaque IDs %q and %q share a leading run", previous, opaque)
+		}
+		previous = opaque
+	}
+}
+
+// TestPostIDCodecRejectsForgedIDs tests that tampered opaque IDs and IDs
+// issued with another secret are rejected
+func TestPostIDCodecRejectsForgedIDs(t *testing.T) {
+	codec := newPostIDCodec("secret")
+	opaque := codec.encode("post_17")
+
+	forged := []string{
+		newPostIDCodec("other").encode("post_17"),
+		opaque[:len(opaque)-1] + "A",
+		opaquePostIDPrefix + "not base64!",
+		opaquePostIDPrefix,
+	}
+	for _, id := range forged {
+		if id == opaque {
+			continue
+		}
+		if raw, err := codec.decode(id); err == nil {
+			t.Errorf("decode(%q) = %q, want an error", id, raw)
+		}
+	}
+}
+
+// TestGetPostHandlerOpaqueIDs tests that responses expose opaque IDs and that
+// opaque IDs are accepted for lookups
+func TestGetPostHandlerOpaqueIDs(t *testing.T) {
+	var requested string
+	mockPostService := &mockPostService{
+		getByIDFunc: func(id string) (*domain.PostWithUser, error) {
+			requested = id
+			return &domain.PostWithUser{
+				Post:     domain.Post{ID: id, UserID: "user_1", Content: "Hello"},
+				Username: "testuser",
+			}, nil
+		},
+	}
+	mockCache := &mockPostCache{
+		getPostFunc: func(id string) (*domain.Post, error) {
+			return nil, fmt.Errorf("cache miss")
+		},
+	}
+
+	handler := NewPostHandler(mockPostService, mockCache)
+	handler.options.PostIDFormat = PostIDFormatOpaque
+	opaque := handler.postIDs().encode("post_17")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts/"+opaque, nil)
+	rr := httptest.NewRecorder()
+	handler.GetPostHandler()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if requested != "post_17" {
+		t.Errorf("Expected lookup of the raw ID post_17, got %q", requested)
+	}
+
+	var response struct {
+		Post map[string]interface{} `json:"post"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if response.Post["id"] != opaque {
+		t.Errorf("Expected opaque ID %q in response, got %v", opaque, response.Post["id"])
+	}
+	if response.Post["user_id"] != "user_1" {
+		t.Errorf("Expected user ID to be left raw, got %v", response.Post["user_id"])
+	}
+}
+
+// TestOpaquePostIDSecretGenerated tests that opaque IDs without a configured
+// secret are keyed with a random one rather than a known default
+func TestOpaquePostIDSecretGenerated(t *testing.T) {
+	t.Setenv("POST_ID_FORMAT", PostIDFormatOpaque)
+	t.Setenv("POST_ID_SECRET", "")
+
+	first := LoadOptionsFromEnv().PostIDSecret
+	second := LoadOptionsFromEnv().PostIDSecret
+	if first == "" || first == second {
+		t.Errorf("Expected a random secret per startup, got %q and %q", first, second)
+	}
+
+	t.Setenv("POST_ID_SECRET", "configured")
+	if secret := LoadOptionsFromEnv().PostIDSecret; secret != "configured" {
+		t.Errorf("Expected the configured secret, got %q", secret)
+	}
+}
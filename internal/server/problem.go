@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error response formats
+const (
+	ErrorFormatSimple  = "simple"
+	ErrorFormatProblem = "problem"
+)
+
+// problemDetails is an RFC 7807 problem details object
+type problemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the machine-readable code of the error, an extension member
+	Code string `json:"code,omitempty"`
+}
+
+// problemWriter marks a response whose errors are sent as problem details,
+// remembering the request path they are about
+type problemWriter struct {
+	http.ResponseWriter
+	instance string
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (w *problemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush passes flushes through so streamed responses still reach the client
+func (w *problemWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// ProblemErrors returns middleware that makes error responses from next
+// application/problem+json (RFC 7807) when format is problem; any other
+// format keeps the simple {"code": "...", "message": "..."} shape.
+func ProblemErrors(next http.Handler, format string) http.Handler {
+	if format != ErrorFormatProblem {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&problemWriter{ResponseWriter: w, instance: r.URL.Path}, r)
+	})
+}
+
+// problemInstance returns the request path errors on w are reported against
+// and whether they are sent as problem details, looking through the
+// middleware wrapping w
+func problemInstance(w http.ResponseWriter) (string, bool) {
+	for {
+		switch wrapped := w.(type) {
+		case *problemWriter:
+			return wrapped.instance, true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = wrapped.Unwrap()
+		default:
+			return "", false
+		}
+	}
+}
+
+// respondProblem responds with a problem details object for apiErr, with
+// its message as the detail
+func respondProblem(w http.ResponseWriter, apiErr *APIError, instance string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(problemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(apiErr.Status),
+		Status:   apiErr.Status,
+		Detail:   apiErr.Message,
+		Instance: instance,
+		Code:     apiErr.Code,
+	})
+}
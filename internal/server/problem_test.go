@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestProblemErrors tests that errors are sent as RFC 7807 problem details
+// under the problem format, also from behind other middleware, and keep the
+// simple shape otherwise
+func TestProblemErrors(t *testing.T) {
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, http.StatusBadRequest, "Invalid limit parameter")
+	})
+
+	t.Run("Problem format", func(t *testing.T) {
+		handlers := map[string]http.Handler{
+			"Direct":            ProblemErrors(failing, ErrorFormatProblem),
+			"Behind middleware": ProblemErrors(RequestLogger(Gzip(failing, 0), 0, 0), ErrorFormatProblem),
+		}
+		for name, handler := range handlers {
+			t.Run(name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/posts?limit=x", nil))
+
+				if rr.Code != http.StatusBadRequest {
+					t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+				}
+				if contentType := rr.Header().Get("Content-Type"); contentType != "application/problem+json" {
+					t.Errorf("Expected Content-Type application/problem+json, got %q", contentType)
+				}
+
+				var problem problemDetails
+				if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+					t.Fatalf("Failed to parse response body: %v", err)
+				}
+				expected := problemDetails{
+					Type:     "about:blank",
+					Title:    "Bad Request",
+					Status:   http.StatusBadRequest,
+					Detail:   "Invalid limit parameter",
+					Instance: "/api/posts",
+					Code:     "bad_request",
+				}
+				if problem != expected {
+					t.Errorf("Expected problem %+v, got %+v", expected, problem)
+				}
+			})
+		}
+	})
+
+	t.Run("Simple format", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		ProblemErrors(failing, ErrorFormatSimple).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/posts?limit=x", nil))
+
+		if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected Content-Type application/json, got %q", contentType)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to parse response body: %v", err)
+		}
+		if body["code"] != "bad_request" || body["message"] != "Invalid limit parameter" || body["error"] != "Invalid limit parameter" {
+			t.Errorf("Expected the simple error shape, got %v", body)
+		}
+	})
+}
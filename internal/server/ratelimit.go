@@ -0,0 +1,310 @@
+package server
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// limiterSweepInterval is how often idle buckets are evicted
+const limiterSweepInterval = time.Minute
+
+// tokenBucket holds the state of a single rate limit bucket
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// fullAt is when the bucket is refilled to its burst, after which it is
+	// no different from a new bucket and can be evicted
+	fullAt time.Time
+}
+
+// limiterRegistry holds one token bucket per key, created on first use and
+// evicted once idle long enough to be full again, so clients that stop
+// sending don't hold memory
+type limiterRegistry struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// newLimiterRegistry creates an empty limiter registry
+func newLimiterRegistry(now func() time.Time) *limiterRegistry {
+	return &limiterRegistry{
+		buckets: make(map[string]*tokenBucket),
+		now:     now,
+	}
+}
+
+// allow takes a token from the bucket for key, refilled at rps up to burst.
+// When the bucket is empty it returns false and how long until a token is available.
+func (l *limiterRegistry) allow(key string, rps float64, burst int) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= limiterSweepInterval {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = bucket
+	}
+
+	// Refill for the time elapsed since the last request
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens = math.Min(float64(burst), bucket.tokens+elapsed*rps)
+	bucket.last = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	bucket.fullAt = now.Add(time.Duration((float64(burst) - bucket.tokens) / rps * float64(time.Second)))
+	if allowed {
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / rps * float64(time.Second))
+	return false, wait
+}
+
+// sweep evicts the buckets that are full again; the caller holds mu
+func (l *limiterRegistry) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// routeRateLimiter limits requests per client IP with a separate rate for
+// each configured route, taking the client IP from X-Forwarded-For when the
+// request came through a trusted proxy
+type routeRateLimiter struct {
+	next           http.Handler
+	routes         map[string]float64
+	retryAfter     string
+	trustedProxies []*net.IPNet
+	registry       *limiterRegistry
+}
+
+// RouteRateLimit returns middleware that limits each client IP to routes[route]
+// requests per second on that route. A route matches its exact path and every
+// path below it; requests to routes that are not configured are not limited.
+// Limited requests get Retry-After in retryAfter format. X-Forwarded-For is
+// only honored on requests from trustedProxies, a comma-separated list of
+// IPs or CIDR ranges.
+func RouteRateLimit(next http.Handler, routes map[string]float64, retryAfter string, trustedProxies string) http.Handler {
+	return newRouteRateLimiter(next, routes, retryAfter, parseTrustedProxies(trustedProxies), time.Now)
+}
+
+// newRouteRateLimiter creates a route rate limiter with the given clock
+func newRouteRateLimiter(next http.Handler, routes map[string]float64, retryAfter string, trustedProxies []*net.IPNet, now func() time.Time) *routeRateLimiter {
+	return &routeRateLimiter{
+		next:           next,
+		routes:         routes,
+		retryAfter:     retryAfter,
+		trustedProxies: trustedProxies,
+		registry:       newLimiterRegistry(now),
+	}
+}
+
+// ServeHTTP serves the request if the client is within the route's limit
+func (l *routeRateLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, rps := l.match(r.URL.Path)
+	if rps <= 0 {
+		l.next.ServeHTTP(w, r)
+		return
+	}
+
+	allowed, wait := l.registry.allow(route+"|"+forwardedClientIP(r, l.trustedProxies), rps, defaultBurst(rps))
+	if !allowed {
+		respondRateLimited(w, wait, l.retryAfter, l.registry.now())
+		return
+	}
+
+	l.next.ServeHTTP(w, r)
+}
+
+// ipRateLimiter limits every request it serves per client IP, taking the
+// client IP from X-Forwarded-For when the request came through a trusted
+// proxy
+type ipRateLimiter struct {
+	next           http.Handler
+	rps            float64
+	burst          int
+	retryAfter     string
+	trustedProxies []*net.IPNet
+	registry       *limiterRegistry
+}
+
+// RateLimit returns middleware that limits each client IP to rps requests
+// per second with bursts of up to burst requests (at least one; 0 uses the
+// rate rounded up). A non-positive rps disables the limit. Limited requests
+// get Retry-After in retryAfter format. X-Forwarded-For is only honored on
+// requests from trustedProxies, a comma-separated list of IPs or CIDR
+// ranges.
+func RateLimit(next http.Handler, rps float64, burst int, retryAfter string, trustedProxies string) http.Handler {
+	if rps <= 0 {
+		return next
+	}
+	return newIPRateLimiter(next, rps, burst, retryAfter, parseTrustedProxies(trustedProxies), time.Now)
+}
+
+// newIPRateLimiter creates a per-IP rate limiter with the given clock
+func newIPRateLimiter(next http.Handler, rps float64, burst int, retryAfter string, trustedProxies []*net.IPNet, now func() time.Time) *ipRateLimiter {
+	if burst < 1 {
+		burst = defaultBurst(rps)
+	}
+	return &ipRateLimiter{
+		next:           next,
+		rps:            rps,
+		burst:          burst,
+		retryAfter:     retryAfter,
+		trustedProxies: trustedProxies,
+		registry:       newLimiterRegistry(now),
+	}
+}
+
+// ServeHTTP serves the request if the client is within its limit
+func (l *ipRateLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowed, wait := l.registry.allow(forwardedClientIP(r, l.trustedProxies), l.rps, l.burst)
+	if !allowed {
+		respondRateLimited(w, wait, l.retryAfter, l.registry.now())
+		return
+	}
+
+	l.next.ServeHTTP(w, r)
+}
+
+// defaultBurst is the burst allowed at rps when none is configured: one
+// second's worth of requests, and at least one
+func defaultBurst(rps float64) int {
+	return int(math.Max(1, math.Ceil(rps)))
+}
+
+// respondRateLimited responds with 429, telling the client in Retry-After
+// when a request will be allowed
+func respondRateLimited(w http.ResponseWriter, wait time.Duration, retryAfter string, now time.Time) {
+	setRetryAfter(w, wait, retryAfter, now)
+	respondError(w, http.StatusTooManyRequests, "Too many requests")
+}
+
+// match returns the longest configured route covering path and its rate
+func (l *routeRateLimiter) match(path string) (string, float64) {
+	matched := ""
+	for route := range l.routes {
+		if len(route) <= len(matched) {
+			continue
+		}
+		if path == route || strings.HasPrefix(path, strings.TrimSuffix(route, "/")+"/") {
+			matched = route
+		}
+	}
+	if matched == "" {
+		return "", 0
+	}
+	return matched, l.routes[matched]
+}
+
+// clientIP returns the IP address of the client that sent the request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedClientIP returns the client IP of a request. When the connection
+// comes from a trusted proxy, it is the last X-Forwarded-For entry not
+// added by another trusted proxy, which the client cannot forge as the
+// proxies append to the header. From any other peer the header is ignored
+// and it is the connection's address.
+func forwardedClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	peer := clientIP(r)
+	if !trustedIP(net.ParseIP(peer), trustedProxies) {
+		return peer
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		if !trustedIP(ip, trustedProxies) {
+			return ip.String()
+		}
+	}
+	return peer
+}
+
+// trustedIP reports whether ip lies in one of the trusted proxy ranges
+func trustedIP(ip net.IP, trustedProxies []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses a comma-separated list of IPs and CIDR ranges,
+// logging and skipping malformed entries
+func parseTrustedProxies(value string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * net.IPv6len
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 8*net.IPv4len
+				}
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("Ignoring invalid trusted proxy %q", entry)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// parseRouteRates parses a comma-separated list of route=rps pairs, skipping
+// malformed entries
+func parseRouteRates(value string) map[string]float64 {
+	routes := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		route, rate, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || route == "" {
+			continue
+		}
+		rps, err := strconv.ParseFloat(rate, 64)
+		if err != nil || rps <= 0 {
+			continue
+		}
+		routes[route] = rps
+	}
+	return routes
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRouteRateLimit tests that each route is limited at its own rate per client IP
+func TestRouteRateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limiter := newRouteRateLimiter(next, map[string]float64{
+		"/api/auth/login": 1,
+		"/api/posts":      10,
+	}, RetryAfterSeconds, nil, func() time.Time { return now })
+
+	send := func(path, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		limiter.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	allowed := func(path string) int {
+		count := 0
+		for i := 0; i < 5; i++ {
+			if send(path, "203.0.113.7:5000") == http.StatusOK {
+				count++
+			}
+		}
+		return count
+	}
+
+	// Same client, same instant: login is limited more aggressively than posts
+	if got := allowed("/api/auth/login"); got != 1 {
+		t.Errorf("Expected 1 login request allowed, got %d", got)
+	}
+	if got := allowed("/api/posts"); got != 5 {
+		t.Errorf("Expected 5 posts requests allowed, got %d", got)
+	}
+
+	// Sub-paths share their route's bucket
+	if code := send("/api/posts/post_1", "203.0.113.7:5001"); code != http.StatusOK {
+		t.Errorf("Expected sub-path to be allowed, got %d", code)
+	}
+
+	// Other clients have their own buckets
+	if code := send("/api/auth/login", "198.51.100.1:5000"); code != http.StatusOK {
+		t.Errorf("Expected other client to be allowed, got %d", code)
+	}
+
+	// Unconfigured routes are not limited
+	for i := 0; i < 20; i++ {
+		if code := send("/health", "203.0.113.7:5000"); code != http.StatusOK {
+			t.Fatalf("Expected unlimited route to be allowed, got %d", code)
+		}
+	}
+
+	// The login bucket refills after a second
+	now = now.Add(time.Second)
+	if code := send("/api/auth/login", "203.0.113.7:5000"); code != http.StatusOK {
+		t.Errorf("Expected login to be allowed after refill, got %d", code)
+	}
+}
+
+// TestRouteRateLimitRetryAfter tests the response when a client is limited
+func TestRouteRateLimitRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	limiter := newRouteRateLimiter(next, map[string]float64{"/api/auth/login": 0.5}, RetryAfterSeconds, nil, func() time.Time { return now })
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		rr := httptest.NewRecorder()
+		limiter.ServeHTTP(rr, req)
+
+		if i == 1 {
+			if rr.Code != http.StatusTooManyRequests {
+				t.Fatalf("Expected status code %d, got %d", http.StatusTooManyRequests, rr.Code)
+			}
+			if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "2" {
+				t.Errorf("Expected Retry-After 2, got %q", retryAfter)
+			}
+		}
+	}
+}
+
+// TestRouteRateLimitTrustedProxy tests that clients behind a trusted proxy
+// get their own route buckets, told apart by X-Forwarded-For
+func TestRouteRateLimitTrustedProxy(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limiter := newRouteRateLimiter(next, map[string]float64{loginRoute: 1}, RetryAfterSeconds, parseTrustedProxies("10.0.0.0/8"), func() time.Time { return now })
+
+	send := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, loginRoute, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rr := httptest.NewRecorder()
+		limiter.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Each forwarded client gets one login per second
+	for _, client := range []string{"198.51.100.1", "198.51.100.2"} {
+		if code := send("10.0.0.1:5000", client); code != http.StatusOK {
+			t.Errorf("Expected the first login from %s to be allowed, got %d", client, code)
+		}
+		if code := send("10.0.0.1:5000", client); code != http.StatusTooManyRequests {
+			t.Errorf("Expected the second login from %s to be limited, got %d", client, code)
+		}
+	}
+
+	// The header is ignored from an untrusted peer
+	if code := send("203.0.113.7:5000", "198.51.100.3"); code != http.StatusOK {
+		t.Errorf("Expected the first login from an untrusted peer to be allowed, got %d", code)
+	}
+	if code := send("203.0.113.7:5000", "198.51.100.4"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected X-Forwarded-For from an untrusted peer to be ignored, got %d", code)
+	}
+}
+
+// TestParseRouteRates tests parsing of the route rate configuration
+func TestParseRouteRates(t *testing.T) {
+	routes := parseRouteRates("/api/auth/login=1, /api/posts=20,bad,/api/x=abc,/api/y=-1")
+
+	if len(routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %v", routes)
+	}
+	if routes["/api/auth/login"] != 1 || routes["/api/posts"] != 20 {
+		t.Errorf("Unexpected routes: %v", routes)
+	}
+}
+
+// TestLoginRateLimit tests that the login limit is added to the route
+// limits unless they configure the login route themselves
+func TestLoginRateLimit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  Options
+		expected map[string]float64
+	}{
+		{
+			name:     "Login limit added",
+			options:  Options{LoginRateLimit: 1, RouteRateLimits: map[string]float64{"/api/posts": 20}},
+			expected: map[string]float64{"/api/posts": 20, loginRoute: 1},
+		},
+		{
+			name:     "Configured login route kept",
+			options:  Options{LoginRateLimit: 1, RouteRateLimits: map[string]float64{loginRoute: 5}},
+			expected: map[string]float64{loginRoute: 5},
+		},
+		{
+			name:     "Login limit disabled",
+			options:  Options{RouteRateLimits: map[string]float64{"/api/posts": 20}},
+			expected: map[string]float64{"/api/posts": 20},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			routes := tc.options.routeRateLimits()
+			if fmt.Sprint(routes) != fmt.Sprint(tc.expected) {
+				t.Errorf("routeRateLimits() = %v, want %v", routes, tc.expected)
+			}
+		})
+	}
+}
+
+// TestIPRateLimit tests that each client IP gets its own bucket of burst
+// requests, refilled at the configured rate
+func TestIPRateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	limiter := newIPRateLimiter(next, 1, 3, RetryAfterSeconds, parseTrustedProxies("10.0.0.0/8"), func() time.Time { return now })
+
+	send := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/posts", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rr := httptest.NewRecorder()
+		limiter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The burst is allowed, then the client is limited
+	for i := 0; i < 3; i++ {
+		if rr := send("203.0.113.7:5000", ""); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected request %d to be allowed, got %d", i+1, rr.Code)
+		}
+	}
+	rr := send("203.0.113.7:5001", "")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status code %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", retryAfter)
+	}
+	if !strings.Contains(rr.Body.String(), `"error"`) {
+		t.Errorf("Expected a JSON error body, got %s", rr.Body.String())
+	}
+
+	// Clients behind the same proxy are told apart by X-Forwarded-For, using
+	// the entry the proxy appended
+	for i := 0; i < 3; i++ {
+		if rr := send("10.0.0.1:5000", "192.0.2.1, 198.51.100.1"); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected forwarded request %d to be allowed, got %d", i+1, rr.Code)
+		}
+	}
+	if rr := send("10.0.0.1:5000", "192.0.2.99, 198.51.100.1"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a forged first entry not to escape the limit, got %d", rr.Code)
+	}
+	if rr := send("10.0.0.1:5000", "198.51.100.2"); rr.Code != http.StatusCreated {
+		t.Errorf("Expected another forwarded client to be allowed, got %d", rr.Code)
+	}
+
+	// Other peers cannot escape the limit by sending the header themselves
+	for i := 0; i < 3; i++ {
+		send("203.0.113.8:5000", fmt.Sprintf("192.0.2.%d", i))
+	}
+	if rr := send("203.0.113.8:5000", "192.0.2.50"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected X-Forwarded-For from an untrusted peer to be ignored, got %d", rr.Code)
+	}
+
+	// One token is refilled per second
+	now = now.Add(time.Second)
+	if rr := send("203.0.113.7:5000", ""); rr.Code != http.StatusCreated {
+		t.Errorf("Expected request to be allowed after refill, got %d", rr.Code)
+	}
+	if rr := send("203.0.113.7:5000", ""); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected second request after refill to be limited, got %d", rr.Code)
+	}
+}
+
+// TestRateLimitDisabled tests that a zero rate leaves requests unlimited
+func TestRateLimitDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := RateLimit(next, 0, 0, RetryAfterSeconds, "")
+
+	for i := 0; i < 20; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/posts", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected request to be allowed, got %d", rr.Code)
+		}
+	}
+}
+
+// TestLimiterRegistryEvictsIdleBuckets tests that buckets are evicted once
+// refilled, without changing the limit of clients still being limited
+func TestLimiterRegistryEvictsIdleBuckets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	registry := newLimiterRegistry(func() time.Time { return now })
+
+	registry.allow("idle", 1, 2)
+	registry.allow("busy", 0.01, 1)
+
+	now = now.Add(limiterSweepInterval)
+	registry.allow("other", 1, 2)
+
+	registry.mu.Lock()
+	_, idle := registry.buckets["idle"]
+	_, busy := registry.buckets["busy"]
+	registry.mu.Unlock()
+	if idle {
+		t.Error("Expected the refilled bucket to be evicted")
+	}
+	if !busy {
+		t.Error("Expected the bucket still refilling to be kept")
+	}
+	if allowed, _ := registry.allow("busy", 0.01, 1); allowed {
+		t.Error("Expected the client still refilling to stay limited")
+	}
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry-After header formats
+const (
+	RetryAfterSeconds = "seconds"
+	RetryAfterDate    = "date"
+)
+
+// setRetryAfter tells the client in Retry-After to wait before retrying,
+// either as delta-seconds or, in the date format, as the HTTP-date at which
+// to retry. Both are rounded up to the next whole second so clients don't
+// retry early; unknown formats use seconds.
+func setRetryAfter(w http.ResponseWriter, wait time.Duration, format string, now time.Time) {
+	if format == RetryAfterDate {
+		retryAt := now.Add(wait)
+		if truncated := retryAt.Truncate(time.Second); !truncated.Equal(retryAt) {
+			retryAt = truncated.Add(time.Second)
+		}
+		w.Header().Set("Retry-After", retryAt.UTC().Format(http.TimeFormat))
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
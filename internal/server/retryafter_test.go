@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSetRetryAfter tests that the same retry duration is sent as
+// delta-seconds or as the HTTP-date to retry at, rounded up in both formats
+func TestSetRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		wait     time.Duration
+		now      time.Time
+		format   string
+		expected string
+	}{
+		{
+			name:     "Seconds",
+			wait:     1500 * time.Millisecond,
+			now:      now,
+			format:   RetryAfterSeconds,
+			expected: "2",
+		},
+		{
+			name:     "Date",
+			wait:     1500 * time.Millisecond,
+			now:      now,
+			format:   RetryAfterDate,
+			expected: "Sat, 01 Jun 2024 12:00:02 GMT",
+		},
+		{
+			name:     "Date on a whole second",
+			wait:     5 * time.Second,
+			now:      now,
+			format:   RetryAfterDate,
+			expected: "Sat, 01 Jun 2024 12:00:05 GMT",
+		},
+		{
+			name:     "Date from a fractional second",
+			wait:     5 * time.Second,
+			now:      now.Add(700 * time.Millisecond),
+			format:   RetryAfterDate,
+			expected: "Sat, 01 Jun 2024 12:00:06 GMT",
+		},
+		{
+			name:     "Date in UTC",
+			wait:     time.Second,
+			now:      now.In(time.FixedZone("CEST", 2*60*60)),
+			format:   RetryAfterDate,
+			expected: "Sat, 01 Jun 2024 12:00:01 GMT",
+		},
+		{
+			name:     "Unknown format",
+			wait:     1500 * time.Millisecond,
+			now:      now,
+			format:   "minutes",
+			expected: "2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			setRetryAfter(rr, tc.wait, tc.format, tc.now)
+
+			if retryAfter := rr.Header().Get("Retry-After"); retryAfter != tc.expected {
+				t.Errorf("Expected Retry-After %q, got %q", tc.expected, retryAfter)
+			}
+		})
+	}
+}
+
+// TestRateLimitRetryAfterDate tests that limited requests get Retry-After in
+// the configured date format
+func TestRateLimitRetryAfterDate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	limiter := newIPRateLimiter(next, 0.5, 1, RetryAfterDate, nil, func() time.Time { return now })
+
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		rr = httptest.NewRecorder()
+		limiter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/posts", nil))
+	}
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status code %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "Mon, 01 Jan 2024 00:00:02 GMT" {
+		t.Errorf("Expected Retry-After two seconds ahead as a date, got %q", retryAfter)
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RouteRegistrar registers handlers on a mux, keeping track of the patterns
+// so a pattern registered twice is reported by name before the mux would
+// reject it
+type RouteRegistrar struct {
+	mux      *http.ServeMux
+	patterns map[string]bool
+}
+
+// NewRouteRegistrar creates a registrar for mux
+func NewRouteRegistrar(mux *http.ServeMux) *RouteRegistrar {
+	return &RouteRegistrar{
+		mux:      mux,
+		patterns: make(map[string]bool),
+	}
+}
+
+// HandleFunc registers handler for pattern. Like http.ServeMux, it panics
+// if pattern is already registered, but the panic names the route so the
+// conflict is clear at startup.
+func (r *RouteRegistrar) HandleFunc(pattern string, handler http.HandlerFunc) {
+	if r.patterns[pattern] {
+		panic(fmt.Sprintf("duplicate route registered: %s", pattern))
+	}
+	r.patterns[pattern] = true
+	r.mux.HandleFunc(pattern, handler)
+}
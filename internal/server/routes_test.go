@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRouteRegistrarDuplicate tests that a pattern registered twice panics
+// with a message naming the route and keeps its first handler
+func TestRouteRegistrarDuplicate(t *testing.T) {
+	mux := http.NewServeMux()
+	routes := NewRouteRegistrar(mux)
+
+	routes.HandleFunc("/api/posts", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+	})
+	routes.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	func() {
+		defer func() {
+			recovered := recover()
+			if recovered != "duplicate route registered: /api/posts" {
+				t.Errorf("Expected a panic naming /api/posts, got %v", recovered)
+			}
+		}()
+		routes.HandleFunc("/api/posts", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("second"))
+		})
+	}()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/posts", nil))
+	if rr.Body.String() != "first" {
+		t.Errorf("Expected the first handler to be kept, got %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// SearchPostsHandler handles GET /posts/search?q=... requests, returning a
+// page of posts matching the full-text query
+func (h *PostHandler) SearchPostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		query, ok := parseSearchQuery(w, r)
+		if !ok {
+			return
+		}
+
+		page, limit, ok := h.parsePaginationParams(w, r, h.options.PostsDefaultLimit)
+		if !ok {
+			return
+		}
+
+		posts, total, err := h.postService.Search(query, page, limit)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to search posts")
+			return
+		}
+
+		// An empty result is a successful search, unless the deployment
+		// prefers 404s for it
+		if total == 0 && h.options.SearchEmptyNotFound {
+			respondError(w, http.StatusNotFound, "No posts match the search query")
+			return
+		}
+		if posts == nil {
+			posts = []*domain.PostWithUser{}
+		}
+
+		if h.options.IncludeUnfilteredTotal {
+			h.respondJSON(w, http.StatusOK, map[string]interface{}{
+				"posts":          createPostsResponse(posts, postView{}),
+				"page":           page,
+				"limit":          limit,
+				"total":          h.unfilteredTotal(),
+				"filtered_total": total,
+				"source":         SourceDatabase,
+			})
+			return
+		}
+
+		h.respondPosts(w, posts, page, limit, total, SourceDatabase, postView{})
+	}
+}
+
+// unfilteredTotal returns the number of posts regardless of any filter,
+// preferring the cached total over counting in the database. It returns -1
+// if neither is available.
+func (h *PostHandler) unfilteredTotal() int {
+	if total, err := h.postCache.GetPostsTotal(); err == nil {
+		return total
+	}
+
+	_, total, err := h.postService.List(1, 1)
+	if err != nil {
+		log.Printf("Failed to count posts: %v", err)
+		return -1
+	}
+	return total
+}
+
+// SearchCountHandler handles GET /posts/search/count?q=... requests,
+// returning only the number of posts matching the query so clients can show
+// the result count before paging
+func (h *PostHandler) SearchCountHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		query, ok := parseSearchQuery(w, r)
+		if !ok {
+			return
+		}
+
+		count, err := h.postService.CountSearch(query)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to count posts")
+			return
+		}
+
+		// Agree with the search endpoint on empty results
+		if count == 0 && h.options.SearchEmptyNotFound {
+			respondError(w, http.StatusNotFound, "No posts match the search query")
+			return
+		}
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"count": count,
+		})
+	}
+}
+
+// parseSearchQuery reads the q query parameter, writing a 400 response and
+// returning false if it is empty
+func parseSearchQuery(w http.ResponseWriter, r *http.Request) (string, bool) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondError(w, http.StatusBadRequest, "Search query is required")
+		return "", false
+	}
+	return query, true
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// newSearchPostService returns a mock post service searching a fixed set of posts
+func newSearchPostService() *mockPostService {
+	all := []*domain.PostWithUser{
+		{Post: domain.Post{ID: "post_1", Content: "hello world"}},
+		{Post: domain.Post{ID: "post_2", Content: "goodbye world"}},
+		{Post: domain.Post{ID: "post_3", Content: "hello again"}},
+	}
+	match := func(query string) []*domain.PostWithUser {
+		matches := []*domain.PostWithUser{}
+		for _, post := range all {
+			if strings.Contains(post.Content, query) {
+				matches = append(matches, post)
+			}
+		}
+		return matches
+	}
+
+	return &mockPostService{
+		searchFunc: func(query string, page, limit int) ([]*domain.PostWithUser, int, error) {
+			matches := match(query)
+			return matches, len(matches), nil
+		},
+		countSearchFunc: func(query string) (int, error) {
+			return len(match(query)), nil
+		},
+	}
+}
+
+// TestSearchCountHandler tests that the count matches the number of results
+// returned by the search endpoint
+func TestSearchCountHandler(t *testing.T) {
+	handler := NewPostHandler(newSearchPostService(), &mockPostCache{})
+
+	for _, query := range []string{"hello", "world", "again", "missing"} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/search?q="+query, nil)
+			rr := httptest.NewRecorder()
+			handler.SearchPostsHandler()(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected search status code %d, got %d", http.StatusOK, rr.Code)
+			}
+			var results struct {
+				Posts []domain.PostWithUser `json:"posts"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+				t.Fatalf("Failed to parse search response: %v", err)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/api/posts/search/count?q="+query, nil)
+			rr = httptest.NewRecorder()
+			handler.SearchCountHandler()(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected count status code %d, got %d", http.StatusOK, rr.Code)
+			}
+			var count struct {
+				Count int `json:"count"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &count); err != nil {
+				t.Fatalf("Failed to parse count response: %v", err)
+			}
+
+			if count.Count != len(results.Posts) {
+				t.Errorf("Expected count %d to match %d search results", count.Count, len(results.Posts))
+			}
+		})
+	}
+}
+
+// TestSearchHandlers_EmptyQuery tests that a missing or blank query is rejected
+func TestSearchHandlers_EmptyQuery(t *testing.T) {
+	handler := NewPostHandler(newSearchPostService(), &mockPostCache{})
+
+	handlers := map[string]http.HandlerFunc{
+		"/api/posts/search":       handler.SearchPostsHandler(),
+		"/api/posts/search/count": handler.SearchCountHandler(),
+	}
+	for path, h := range handlers {
+		for _, query := range []string{"", "?q=", "?q=%20%20"} {
+			req := httptest.NewRequest(http.MethodGet, path+query, nil)
+			rr := httptest.NewRecorder()
+			h(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s%s: expected status code %d, got %d", path, query, http.StatusBadRequest, rr.Code)
+			}
+		}
+	}
+}
+
+// TestSearchHandlers_EmptyResults tests that a search without results is a
+// 200 with an empty list by default and a 404 from both endpoints when
+// SearchEmptyNotFound is set
+func TestSearchHandlers_EmptyResults(t *testing.T) {
+	testCases := []struct {
+		name           string
+		emptyNotFound  bool
+		expectedStatus int
+	}{
+		{name: "Empty list", expectedStatus: http.StatusOK},
+		{name: "Not found", emptyNotFound: true, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newSearchPostService()
+			searchFunc := service.searchFunc
+			service.searchFunc = func(query string, page, limit int) ([]*domain.PostWithUser, int, error) {
+				// Repositories return nil rather than an empty slice
+				posts, total, err := searchFunc(query, page, limit)
+				if len(posts) == 0 {
+					posts = nil
+				}
+				return posts, total, err
+			}
+			handler := NewPostHandler(service, &mockPostCache{})
+			handler.options.SearchEmptyNotFound = tc.emptyNotFound
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/search?q=missing", nil)
+			rr := httptest.NewRecorder()
+			handler.SearchPostsHandler()(rr, req)
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected search status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus == http.StatusOK {
+				var response map[string]json.RawMessage
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to parse search response: %v", err)
+				}
+				if string(response["posts"]) != "[]" {
+					t.Errorf("Expected an empty posts array, got %s", response["posts"])
+				}
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/api/posts/search/count?q=missing", nil)
+			rr = httptest.NewRecorder()
+			handler.SearchCountHandler()(rr, req)
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected count status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+
+			// Queries with results are unaffected
+			req = httptest.NewRequest(http.MethodGet, "/api/posts/search?q=hello", nil)
+			rr = httptest.NewRecorder()
+			handler.SearchPostsHandler()(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected search status code %d, got %d", http.StatusOK, rr.Code)
+			}
+		})
+	}
+}
+
+// TestSearchPostsHandler_UnfilteredTotal tests that with IncludeUnfilteredTotal
+// a search reports the overall number of posts from the cached count
+// alongside the number of matches
+func TestSearchPostsHandler_UnfilteredTotal(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		includeTotal          bool
+		cachedTotal           int
+		cacheMiss             bool
+		expectedTotal         int
+		expectedFilteredTotal interface{}
+	}{
+		{name: "Disabled", expectedTotal: 2},
+		{name: "From the cached count", includeTotal: true, cachedTotal: 3, expectedTotal: 3, expectedFilteredTotal: float64(2)},
+		{name: "Counted on a cache miss", includeTotal: true, cacheMiss: true, expectedTotal: 7, expectedFilteredTotal: float64(2)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newSearchPostService()
+			service.listFunc = func(page, limit int) ([]*domain.PostWithUser, int, error) {
+				return []*domain.PostWithUser{}, 7, nil
+			}
+			cache := &mockPostCache{
+				getPostsTotalFunc: func() (int, error) {
+					if tc.cacheMiss {
+						return 0, errors.New("cache miss")
+					}
+					return tc.cachedTotal, nil
+				},
+			}
+			handler := NewPostHandler(service, cache)
+			handler.options.IncludeUnfilteredTotal = tc.includeTotal
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/search?q=hello", nil)
+			rr := httptest.NewRecorder()
+			handler.SearchPostsHandler()(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse search response: %v", err)
+			}
+			if response["total"] != float64(tc.expectedTotal) {
+				t.Errorf("Expected total %d, got %v", tc.expectedTotal, response["total"])
+			}
+			if response["filtered_total"] != tc.expectedFilteredTotal {
+				t.Errorf("Expected filtered_total %v, got %v", tc.expectedFilteredTotal, response["filtered_total"])
+			}
+		})
+	}
+}
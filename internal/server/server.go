@@ -9,7 +9,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/metrics"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/requestid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Config represents the server configuration
@@ -17,6 +22,8 @@ type Config struct {
 	Host    string
 	Port    int
 	BaseURL string
+	// MaxHeaderBytes caps the size of request headers (0 uses the net/http default)
+	MaxHeaderBytes int
 }
 
 // Server represents an HTTP server
@@ -28,27 +35,184 @@ type Server struct {
 	postCache   PostCache
 	db          DBPinger
 	cache       CachePinger
+	posts       PostStreamer
+	users       domain.UserRepository
+	userService domain.UserService
+	migrator    Migrator
+	failures    FailedLoginLog
+	webhooks    WebhookDeadLetters
+	likes       domain.LikeRepository
+	comments    domain.CommentRepository
+	follows     domain.FollowRepository
+	timelines   TimelineCache
+	usernames   UsernameResolver
+	appConfig   *config.Config
+	options     Options
+	inFlight    *inFlightGauge
+	cacheWrites *AsyncCacheWrites
+	// metricsRegistry holds the metrics served at /metrics
+	metricsRegistry *prometheus.Registry
+	metrics         *metrics.Metrics
+}
+
+// ServerOption configures optional server dependencies
+type ServerOption func(*Server)
+
+// WithPostStreamer sets the source used by the admin post export
+func WithPostStreamer(posts PostStreamer) ServerOption {
+	return func(s *Server) {
+		s.posts = posts
+	}
+}
+
+// WithUserRepository sets the user repository used to authenticate requests
+// and manage credentials
+func WithUserRepository(users domain.UserRepository) ServerOption {
+	return func(s *Server) {
+		s.users = users
+	}
+}
+
+// passwordChangeNotifier is implemented by user services that report
+// password changes
+type passwordChangeNotifier interface {
+	// OnPasswordChange registers fn to be called with the user's ID after
+	// their password changes
+	OnPasswordChange(fn func(userID string))
+}
+
+// WithUserService sets the user service used for profile updates
+func WithUserService(userService domain.UserService) ServerOption {
+	return func(s *Server) {
+		s.userService = userService
+	}
+}
+
+// WithAppConfig sets the application configuration reported by the admin
+// config endpoint
+func WithAppConfig(appConfig *config.Config) ServerOption {
+	return func(s *Server) {
+		s.appConfig = appConfig
+	}
+}
+
+// loadAppConfig returns the application configuration set with
+// WithAppConfig, or the one loaded from the environment
+func (s *Server) loadAppConfig() *config.Config {
+	if s.appConfig != nil {
+		return s.appConfig
+	}
+	return config.LoadConfigFromEnv()
+}
+
+// WithMigrator sets the migrator run by the admin migrate endpoint
+func WithMigrator(migrator Migrator) ServerOption {
+	return func(s *Server) {
+		s.migrator = migrator
+	}
+}
+
+// WithFailedLoginLog sets where failed authentication attempts are recorded
+// for the admin security endpoint
+func WithFailedLoginLog(failures FailedLoginLog) ServerOption {
+	return func(s *Server) {
+		s.failures = failures
+	}
+}
+
+// WithWebhookDeadLetters sets the failed webhook deliveries exposed by the
+// admin webhook endpoints
+func WithWebhookDeadLetters(webhooks WebhookDeadLetters) ServerOption {
+	return func(s *Server) {
+		s.webhooks = webhooks
+	}
+}
+
+// WithLikeRepository sets where likes of posts are recorded
+func WithLikeRepository(likes domain.LikeRepository) ServerOption {
+	return func(s *Server) {
+		s.likes = likes
+	}
+}
+
+// WithCommentRepository sets where comments on posts are recorded
+func WithCommentRepository(comments domain.CommentRepository) ServerOption {
+	return func(s *Server) {
+		s.comments = comments
+	}
+}
+
+// WithFollowRepository sets where follows between users are recorded
+func WithFollowRepository(follows domain.FollowRepository) ServerOption {
+	return func(s *Server) {
+		s.follows = follows
+	}
+}
+
+// WithTimelineCache sets where the first page of each user's timeline is
+// cached
+func WithTimelineCache(timelines TimelineCache) ServerOption {
+	return func(s *Server) {
+		s.timelines = timelines
+	}
+}
+
+// WithUsernameResolver sets the user lookup used to resolve usernames to
+// IDs
+func WithUsernameResolver(usernames UsernameResolver) ServerOption {
+	return func(s *Server) {
+		s.usernames = usernames
+	}
+}
+
+// WithMetricsRegistry sets the registry the request and cache metrics are
+// registered with and served from at /metrics, instead of a registry of
+// the server's own
+func WithMetricsRegistry(registry *prometheus.Registry) ServerOption {
+	return func(s *Server) {
+		s.metricsRegistry = registry
+	}
 }
 
 // New creates a new server
-func New(config Config, postService domain.PostService, postCache PostCache, db DBPinger, cache CachePinger) *Server {
+func New(config Config, postService domain.PostService, postCache PostCache, db DBPinger, cache CachePinger, opts ...ServerOption) *Server {
 	router := http.NewServeMux()
 	
-	return &Server{
+	options := LoadOptionsFromEnv()
+	inFlight := newInFlightGauge(options.InFlightHighWater, options.InFlightSustain)
+
+	server := &Server{
 		config:      config,
 		router:      router,
 		postService: postService,
 		postCache:   postCache,
 		db:          db,
 		cache:       cache,
+		options:     options,
+		inFlight:    inFlight,
+		cacheWrites: &AsyncCacheWrites{},
 		httpServer: &http.Server{
-			Addr:         fmt.Sprintf("%s:%d", config.Host, config.Port),
-			Handler:      router,
-			ReadTimeout:  15 * time.Second,
-			WriteTimeout: 15 * time.Second,
-			IdleTimeout:  60 * time.Second,
+			Addr:           fmt.Sprintf("%s:%d", config.Host, config.Port),
+			ReadTimeout:    15 * time.Second,
+			WriteTimeout:   15 * time.Second,
+			IdleTimeout:    60 * time.Second,
+			MaxHeaderBytes: config.MaxHeaderBytes,
 		},
 	}
+
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	// The handler is built once the options are applied, since it records
+	// into the injected metrics registry
+	if server.metricsRegistry == nil {
+		server.metricsRegistry = prometheus.NewRegistry()
+	}
+	server.metrics = metrics.New(server.metricsRegistry)
+	server.httpServer.Handler = requestid.Middleware(ProblemErrors(InstrumentRequests(RequestLogger(inFlight.Middleware(Gzip(RouteRateLimit(MatchedRoute(router, options.DebugEchoRoute), options.routeRateLimits(), options.RetryAfterFormat, server.loadAppConfig().Server.TrustedProxies), options.GzipLevel)), options.LogSampleRate, options.LargeResponseBytes), router, server.metrics), options.ErrorFormat), options.TrustRequestID)
+
+	return server
 }
 
 // Start starts the server
@@ -61,43 +225,153 @@ func (s *Server) Start() error {
 	return s.httpServer.ListenAndServe()
 }
 
-// Stop stops the server
+// Stop stops the server, waiting for background cache writes started by
+// handlers to finish
 func (s *Server) Stop(ctx context.Context) error {
 	log.Println("Stopping server...")
-	return s.httpServer.Shutdown(ctx)
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	// Handlers may have left cache writes running after responding; let
+	// them finish within the same deadline
+	if err := s.cacheWrites.Wait(ctx); err != nil {
+		return fmt.Errorf("waiting for cache writes: %w", err)
+	}
+	return nil
 }
 
 // registerRoutes registers the server routes
 func (s *Server) registerRoutes() {
+	routes := NewRouteRegistrar(s.router)
+
 	// Health checks
-	s.router.HandleFunc("/health", s.handleHealth())
-	s.router.HandleFunc("/livez", LivezHandler())
-	s.router.HandleFunc("/readyz", ReadyzHandler(s.db, s.cache))
-	
+	routes.HandleFunc("/health", s.handleHealth())
+	routes.HandleFunc("/livez", LivezHandler())
+	var disk DiskChecker
+	if s.options.ReadyzCheckDisk {
+		disk = WritableDir(s.options.ReadyzDiskDir)
+	}
+	routes.HandleFunc("/readyz", CachedReadyzHandler(s.db, s.cache, disk, s.options.ReadyzCacheTTL))
+
 	// API routes
-	s.router.HandleFunc("/api/", s.handleAPI())
+	routes.HandleFunc("/api/", s.handleAPI())
 	
 	// Create post handler
 	postHandler := NewPostHandler(s.postService, s.postCache)
+	// One authenticator is shared so a credential change invalidates its
+	// cached results for every route
+	auth := newAuthenticator(s.users, s.options.AuthCacheTTL)
+	auth.failures = s.failures
+	auth.tokens = newTokenIssuer(s.loadAppConfig().Auth.JWTSecret, s.options.TokenTTL)
+	// A password changed through the user service drops its cached logins
+	if notifier, ok := s.userService.(passwordChangeNotifier); ok {
+		notifier.OnPasswordChange(auth.invalidateUser)
+	}
+
+	// Prometheus metrics are served on the API port, so only to the admin
+	routes.HandleFunc("/metrics", auth.adminOnly(promhttp.HandlerFor(s.metricsRegistry, promhttp.HandlerOpts{})).ServeHTTP)
+	postHandler.auth = auth
+	postHandler.cacheWrites = s.cacheWrites
+	// Cache warms triggered from any route share one limit
+	warmer := newCacheWarmer(s.options.MaxConcurrentCacheWarms)
+	postHandler.warmer = warmer
+	postHandler.baseURL = s.config.BaseURL
+	postHandler.likes = s.likes
+	postHandler.comments = s.comments
+	postHandler.timelines = s.timelines
+	postHandler.metrics = s.metrics
 	
 	// Post routes
-	s.router.HandleFunc("/api/posts", postHandler.GetPostsHandler())
-	s.router.HandleFunc("/api/posts/create", postHandler.CreatePostHandler())
+	routes.HandleFunc("/api/posts", postHandler.GetPostsHandler())
+	routes.HandleFunc("/api/posts/create", postHandler.CreatePostHandler())
+	routes.HandleFunc("/api/posts/mine", postHandler.MyPostsHandler())
+	routes.HandleFunc("/api/posts/stream", postHandler.StreamPostsHandler())
+	routes.HandleFunc("/api/posts/batch", postHandler.BatchGetPostsHandler())
+	routes.HandleFunc("/api/posts/search", postHandler.SearchPostsHandler())
+	routes.HandleFunc("/api/posts/search/count", postHandler.SearchCountHandler())
+	routes.HandleFunc("/api/posts/sync", postHandler.SyncPostsHandler())
+	routes.HandleFunc("/api/posts/by-users", postHandler.PostsByUsersHandler())
+	routes.HandleFunc("/api/posts.csv", postHandler.CSVPostsHandler())
+	routes.HandleFunc("/api/timeline", postHandler.TimelineHandler())
+
+	// Profile routes
+	userHandler := NewUserHandler(s.userService)
+	userHandler.posts = s.postService
+	userHandler.auth = auth
+	userHandler.usernames = s.usernames
+	userHandler.follows = s.follows
+	userHandler.timelines = s.timelines
+	routes.HandleFunc("/api/users/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case len(parts) == 4 && parts[3] == "follow":
+			userHandler.FollowHandler()(w, r)
+		case len(parts) == 4 && parts[3] == "followers":
+			userHandler.FollowersHandler()(w, r)
+		default:
+			postHandler.UserPostsHandler()(w, r)
+		}
+	})
+	routes.HandleFunc("/api/users/me", userHandler.UpdateProfileHandler())
+	routes.HandleFunc("/api/users/me/export", userHandler.ExportHandler())
+	routes.HandleFunc("/api/users/resolve", userHandler.ResolveUsernamesHandler())
+	routes.HandleFunc(loginRoute, userHandler.LoginHandler())
 	
 	// Individual post route - must be last to avoid conflicts
-	s.router.HandleFunc("/api/posts/", func(w http.ResponseWriter, r *http.Request) {
+	routes.HandleFunc("/api/posts/", func(w http.ResponseWriter, r *http.Request) {
 		// Extract post ID from URL
 		path := r.URL.Path
 		parts := strings.Split(path, "/")
-		if len(parts) < 4 || parts[3] == "" || parts[3] == "create" {
+		if len(parts) < 4 || parts[3] == "" || parts[3] == "create" || parts[3] == "mine" || parts[3] == "stream" || parts[3] == "batch" || parts[3] == "search" || parts[3] == "sync" || parts[3] == "by-users" {
 			// Not a post ID request, let other handlers handle it
 			http.NotFound(w, r)
 			return
 		}
 		
+		// Likes of the post
+		if len(parts) == 5 && parts[4] == "like" {
+			postHandler.LikePostHandler()(w, r)
+			return
+		}
+		
+		// Comments on the post
+		if len(parts) == 5 && parts[4] == "comments" {
+			postHandler.CommentsHandler()(w, r)
+			return
+		}
+		
 		// Handle the post request
-		postHandler.GetPostHandler()(w, r)
+		switch r.Method {
+		case http.MethodPut:
+			postHandler.UpdatePostHandler()(w, r)
+		case http.MethodDelete:
+			postHandler.DeletePostHandler()(w, r)
+		default:
+			postHandler.GetPostHandler()(w, r)
+		}
 	})
+
+	// Admin routes
+	adminHandler := NewAdminHandler(s.postService, s.postCache, s.posts, s.users)
+	adminHandler.appConfig = s.appConfig
+	adminHandler.options = s.options
+	adminHandler.migrator = s.migrator
+	adminHandler.failures = s.failures
+	adminHandler.webhooks = s.webhooks
+	adminHandler.inFlight = s.inFlight
+	adminHandler.auth = auth
+	adminHandler.warmer = warmer
+	routes.HandleFunc("/api/admin/posts/export", adminHandler.ExportPostsHandler())
+	routes.HandleFunc("/api/admin/posts/import", adminHandler.ImportPostsHandler())
+	routes.HandleFunc("/api/admin/config", adminHandler.ConfigHandler())
+	routes.HandleFunc("/api/admin/cache/rebuild", adminHandler.RebuildCacheHandler())
+	routes.HandleFunc("/api/admin/rotate-credentials", adminHandler.RotateCredentialsHandler())
+	routes.HandleFunc("/api/admin/migrate", adminHandler.MigrateHandler())
+	routes.HandleFunc("/api/admin/security/failed-logins", adminHandler.FailedLoginsHandler())
+	routes.HandleFunc("/api/admin/webhooks/failures", adminHandler.WebhookFailuresHandler())
+	routes.HandleFunc("/api/admin/webhooks/replay", adminHandler.ReplayWebhookHandler())
+	routes.HandleFunc("/api/admin/metrics", adminHandler.MetricsHandler())
 }
 
 // handleHealth returns a handler for health check requests
@@ -112,9 +386,71 @@ func (s *Server) handleHealth() http.HandlerFunc {
 	}
 }
 
-// handleAPI returns a handler for API requests
+// APIEndpoint describes a route listed by the verbose API index
+type APIEndpoint struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+}
+
+// apiEndpoints lists the routes registered by registerRoutes; keep it in
+// step when adding routes
+var apiEndpoints = []APIEndpoint{
+	{Path: "/health", Methods: []string{http.MethodGet}},
+	{Path: "/livez", Methods: []string{http.MethodGet}},
+	{Path: "/readyz", Methods: []string{http.MethodGet}},
+	{Path: "/metrics", Methods: []string{http.MethodGet}},
+	{Path: "/api/posts", Methods: []string{http.MethodGet}},
+	{Path: "/api/posts/{id}", Methods: []string{http.MethodGet, http.MethodPut, http.MethodDelete}},
+	{Path: "/api/posts/{id}/like", Methods: []string{http.MethodPost, http.MethodDelete}},
+	{Path: "/api/posts/{id}/comments", Methods: []string{http.MethodGet, http.MethodPost}},
+	{Path: "/api/posts/create", Methods: []string{http.MethodPost}},
+	{Path: "/api/posts/mine", Methods: []string{http.MethodGet}},
+	{Path: "/api/posts/stream", Methods: []string{http.MethodGet}},
+	{Path: "/api/posts/batch", Methods: []string{http.MethodPost}},
+	{Path: "/api/posts/search", Methods: []string{http.MethodGet}},
+	{Path: "/api/posts/search/count", Methods: []string{http.MethodGet}},
+	{Path: "/api/posts/sync", Methods: []string{http.MethodGet}},
+	{Path: "/api/posts/by-users", Methods: []string{http.MethodPost}},
+	{Path: "/api/posts.csv", Methods: []string{http.MethodGet}},
+	{Path: "/api/timeline", Methods: []string{http.MethodGet}},
+	{Path: "/api/users/{id}/posts", Methods: []string{http.MethodGet}},
+	{Path: "/api/users/{id}/follow", Methods: []string{http.MethodPost, http.MethodDelete}},
+	{Path: "/api/users/{id}/followers", Methods: []string{http.MethodGet}},
+	{Path: "/api/users/me", Methods: []string{http.MethodPatch}},
+	{Path: "/api/users/me/export", Methods: []string{http.MethodGet}},
+	{Path: "/api/users/resolve", Methods: []string{http.MethodPost}},
+	{Path: "/api/login", Methods: []string{http.MethodPost}},
+	{Path: "/api/admin/posts/export", Methods: []string{http.MethodGet}},
+	{Path: "/api/admin/posts/import", Methods: []string{http.MethodPost}},
+	{Path: "/api/admin/config", Methods: []string{http.MethodGet}},
+	{Path: "/api/admin/cache/rebuild", Methods: []string{http.MethodPost}},
+	{Path: "/api/admin/rotate-credentials", Methods: []string{http.MethodPost}},
+	{Path: "/api/admin/migrate", Methods: []string{http.MethodPost}},
+	{Path: "/api/admin/security/failed-logins", Methods: []string{http.MethodGet}},
+	{Path: "/api/admin/webhooks/failures", Methods: []string{http.MethodGet}},
+	{Path: "/api/admin/webhooks/replay", Methods: []string{http.MethodPost}},
+	{Path: "/api/admin/metrics", Methods: []string{http.MethodGet}},
+}
+
+// handleAPI returns a handler for API requests. With API_INDEX_VERBOSE set
+// the available endpoints are listed as well.
 func (s *Server) handleAPI() http.HandlerFunc {
+	return APIIndexHandler(s.options.APIIndexVerbose, apiEndpoints)
+}
+
+// APIIndexHandler returns a handler for the /api index. With verbose set the
+// given endpoints are listed as well.
+func APIIndexHandler(verbose bool, endpoints []APIEndpoint) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if verbose {
+			serverRespondJSON(w, http.StatusOK, map[string]interface{}{
+				"message":   "Tiger-Tail Microblog API",
+				"version":   "0.1.0",
+				"endpoints": endpoints,
+			})
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -135,8 +471,5 @@ func serverRespondJSON(w http.ResponseWriter, status int, data interface{}) {
 
 // serverRespondError sends an error response (renamed to avoid conflict with handlers.go)
 func serverRespondError(w http.ResponseWriter, status int, message string) {
-	serverRespondJSON(w, status, map[string]string{
-		"error":   http.StatusText(status),
-		"message": message,
-	})
+	respondError(w, status, message)
 }
@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
@@ -100,6 +101,34 @@ func (m *MockPostService) List(page, limit int) ([]*domain.PostWithUser, int, er
 	return posts, len(posts), nil
 }
 
+func (m *MockPostService) Search(query string, page, limit int) ([]*domain.PostWithUser, int, error) {
+	return []*domain.PostWithUser{}, 0, nil
+}
+
+func (m *MockPostService) CountSearch(query string) (int, error) {
+	return 0, nil
+}
+
+func (m *MockPostService) ListAfter(createdAt time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *MockPostService) ListModifiedSince(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *MockPostService) ListByUsers(userIDs []string, limit int) ([]*domain.PostWithUser, error) {
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *MockPostService) ListFollowedBy(userID string, page, limit int) ([]*domain.PostWithUser, int, error) {
+	return []*domain.PostWithUser{}, 0, nil
+}
+
+func (m *MockPostService) CreateMany(posts []*domain.Post, preserveTimestamps bool) ([]*domain.Post, error) {
+	return posts, nil
+}
+
 // MockPostCache is a mock implementation of PostCache and CachePinger
 type MockPostCache struct{}
 
@@ -115,14 +144,34 @@ func (m *MockPostCache) GetPostsWithUser() ([]*domain.PostWithUser, error) {
 	return nil, fmt.Errorf("cache miss")
 }
 
+func (m *MockPostCache) GetStalePostsWithUser() ([]*domain.PostWithUser, error) {
+	return nil, fmt.Errorf("cache miss")
+}
+
 func (m *MockPostCache) SetPostsWithUser(posts []*domain.PostWithUser) error {
 	return nil
 }
 
+func (m *MockPostCache) SetPostsWithUserAt(posts []*domain.PostWithUser, readAt time.Time) error {
+	return nil
+}
+
+func (m *MockPostCache) SetPostsWithUserAndTotalAt(posts []*domain.PostWithUser, total int, readAt time.Time) error {
+	return nil
+}
+
+func (m *MockPostCache) GetPostsTotal() (int, error) {
+	return 0, fmt.Errorf("cache miss")
+}
+
 func (m *MockPostCache) InvalidatePosts() error {
 	return nil
 }
 
+func (m *MockPostCache) InvalidatePost(id string) error {
+	return nil
+}
+
 func (m *MockPostCache) Ping() error {
 	return nil
 }
@@ -134,6 +183,20 @@ func (m *MockDBPinger) Ping() error {
 	return nil
 }
 
+func TestNewMaxHeaderBytes(t *testing.T) {
+	config := Config{
+		Host:           "localhost",
+		Port:           8080,
+		MaxHeaderBytes: 8192,
+	}
+
+	server := New(config, &MockPostService{}, &MockPostCache{}, &MockDBPinger{}, &MockPostCache{})
+
+	if server.httpServer.MaxHeaderBytes != 8192 {
+		t.Errorf("httpServer.MaxHeaderBytes = %d, want %d", server.httpServer.MaxHeaderBytes, 8192)
+	}
+}
+
 func TestNew(t *testing.T) {
 	// Test cases
 	testCases := []struct {
@@ -270,6 +333,53 @@ func TestRegisterRoutes(t *testing.T) {
 	}
 }
 
+// TestMatchedRouteHeader tests that DEBUG_ECHO_ROUTE names the route pattern
+// that served each request
+func TestMatchedRouteHeader(t *testing.T) {
+	t.Setenv("DEBUG_ECHO_ROUTE", "true")
+	mockPostCache := &MockPostCache{}
+	server := New(Config{Host: "localhost", Port: 8080}, &MockPostService{}, mockPostCache, &MockDBPinger{}, mockPostCache)
+	server.registerRoutes()
+
+	testServer := httptest.NewServer(server.httpServer.Handler)
+	defer testServer.Close()
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/health", expected: "GET /health"},
+		{path: "/api/posts", expected: "GET /api/posts"},
+		{path: "/api/posts/post_1", expected: "GET /api/posts/"},
+		{path: "/api/posts/search?q=hello", expected: "GET /api/posts/search"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := http.Get(testServer.URL + tc.path)
+			if err != nil {
+				t.Fatalf("Error making request to %s: %v", tc.path, err)
+			}
+			resp.Body.Close()
+
+			if got := resp.Header.Get("X-Matched-Route"); got != tc.expected {
+				t.Errorf("X-Matched-Route = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+// TestMatchedRouteHeaderDisabled tests that no route header is sent by default
+func TestMatchedRouteHeaderDisabled(t *testing.T) {
+	handler := MatchedRoute(http.NewServeMux(), false)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if got := rr.Header().Get("X-Matched-Route"); got != "" {
+		t.Errorf("Expected no X-Matched-Route header, got %q", got)
+	}
+}
+
 func TestHandleHealth(t *testing.T) {
 	// Setup
 	mockPostService := &MockPostService{}
@@ -371,6 +481,47 @@ func TestHandleAPI(t *testing.T) {
 	}
 }
 
+// TestHandleAPIVerbose tests that the verbose API index lists endpoints and
+// their methods
+func TestHandleAPIVerbose(t *testing.T) {
+	mockPostCache := &MockPostCache{}
+	server := New(Config{Host: "localhost", Port: 8080}, &MockPostService{}, mockPostCache, &MockDBPinger{}, mockPostCache)
+	server.options.APIIndexVerbose = true
+
+	rr := httptest.NewRecorder()
+	server.handleAPI().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var response struct {
+		Message   string        `json:"message"`
+		Version   string        `json:"version"`
+		Endpoints []APIEndpoint `json:"endpoints"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if response.Message != "Tiger-Tail Microblog API" || response.Version != "0.1.0" {
+		t.Errorf("message, version = %q, %q, want the minimal index fields too", response.Message, response.Version)
+	}
+
+	methods := make(map[string][]string)
+	for _, endpoint := range response.Endpoints {
+		methods[endpoint.Path] = endpoint.Methods
+	}
+	expected := map[string][]string{
+		"/api/posts":        {http.MethodGet},
+		"/api/posts/create": {http.MethodPost},
+	}
+	for path, want := range expected {
+		if !reflect.DeepEqual(methods[path], want) {
+			t.Errorf("Methods of %s = %v, want %v", path, methods[path], want)
+		}
+	}
+}
+
 func TestServerRespondJSON(t *testing.T) {
 	// Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
@@ -457,8 +608,8 @@ func TestServerRespondError(t *testing.T) {
 		t.Fatalf("Error decoding response: %v", err)
 	}
 
-	if response["error"] != http.StatusText(http.StatusBadRequest) {
-		t.Errorf("error = %s, want %s", response["error"], http.StatusText(http.StatusBadRequest))
+	if response["code"] != "bad_request" {
+		t.Errorf("code = %s, want %s", response["code"], "bad_request")
 	}
 	if response["message"] != "Invalid request" {
 		t.Errorf("message = %s, want %s", response["message"], "Invalid request")
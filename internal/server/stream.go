@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// streamBufferSize is the number of posts buffered per stream subscriber;
+// posts are dropped for subscribers that fall further behind
+const streamBufferSize = 16
+
+// streamRetryAfter is how long clients rejected for exceeding the maximum
+// stream clients are told to wait before reconnecting
+const streamRetryAfter = 5 * time.Second
+
+// postBroadcaster fans newly created posts out to stream subscribers
+type postBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan *domain.Post]struct{}
+}
+
+// newPostBroadcaster creates a new post broadcaster
+func newPostBroadcaster() *postBroadcaster {
+	return &postBroadcaster{
+		subscribers: make(map[chan *domain.Post]struct{}),
+	}
+}
+
+// subscribe registers a new subscriber and returns its channel, or false when
+// max subscribers are already registered (0 means no limit)
+func (b *postBroadcaster) subscribe(max int) (chan *domain.Post, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if max > 0 && len(b.subscribers) >= max {
+		return nil, false
+	}
+
+	ch := make(chan *domain.Post, streamBufferSize)
+	b.subscribers[ch] = struct{}{}
+	return ch, true
+}
+
+// unsubscribe removes a subscriber
+func (b *postBroadcaster) unsubscribe(ch chan *domain.Post) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, ch)
+}
+
+// publish sends a post to every subscriber without blocking on slow ones
+func (b *postBroadcaster) publish(post *domain.Post) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- post:
+		default:
+		}
+	}
+}
+
+// StreamPostsHandler handles GET /posts/stream requests, sending newly created
+// posts as server-sent events. The stream is closed with a "close" event once
+// it has been open for the maximum stream duration, so clients reconnect.
+// Connections beyond the maximum number of stream clients are rejected with
+// 503 to bound memory.
+func (h *PostHandler) StreamPostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Subscribe before the headers reach the client so no post created
+		// after it connects is missed
+		posts, ok := h.events.subscribe(h.options.MaxStreamClients)
+		if !ok {
+			setRetryAfter(w, streamRetryAfter, h.options.RetryAfterFormat, time.Now())
+			respondError(w, http.StatusServiceUnavailable, "Too many stream clients")
+			return
+		}
+		defer h.events.unsubscribe(posts)
+
+		controller := http.NewResponseController(w)
+
+		// The server write timeout would cut the stream short, so extend the
+		// deadline to cover the whole stream
+		var deadline time.Time
+		if h.options.MaxStreamDuration > 0 {
+			deadline = time.Now().Add(h.options.MaxStreamDuration + time.Second)
+		}
+		controller.SetWriteDeadline(deadline)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		if err := controller.Flush(); err != nil {
+			log.Printf("Error starting post stream: %v", err)
+			return
+		}
+
+		var expired <-chan time.Time
+		if h.options.MaxStreamDuration > 0 {
+			timer := time.NewTimer(h.options.MaxStreamDuration)
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case post := <-posts:
+				exposed := *post
+				exposed.ID = h.exposedPostID(post.ID)
+				if err := writeEvent(w, "post", &exposed); err != nil {
+					return
+				}
+			case <-expired:
+				writeEvent(w, "close", map[string]string{"reason": "max_stream_duration"})
+				controller.Flush()
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes a server-sent event with a JSON payload
+func writeEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error encoding %s event: %w", event, err)
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestStreamPostsHandlerMaxDuration tests that a stream is closed with a close
+// event once the maximum stream duration has passed
+func TestStreamPostsHandlerMaxDuration(t *testing.T) {
+	const maxDuration = 100 * time.Millisecond
+
+	handler := NewPostHandler(&mockPostService{}, &mockPostCache{})
+	handler.options.MaxStreamDuration = maxDuration
+	server := httptest.NewServer(handler.StreamPostsHandler())
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected Content-Type text/event-stream, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	// The body ends when the server closes the stream
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading stream: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if elapsed < maxDuration {
+		t.Errorf("Expected stream to stay open for %s, closed after %s", maxDuration, elapsed)
+	}
+	if elapsed > maxDuration+2*time.Second {
+		t.Errorf("Expected stream to close shortly after %s, took %s", maxDuration, elapsed)
+	}
+	if !strings.Contains(string(body), "event: close") {
+		t.Errorf("Expected a close event, got %q", body)
+	}
+}
+
+// TestStreamPostsHandlerDeliversPosts tests that created posts are sent to subscribers
+func TestStreamPostsHandlerDeliversPosts(t *testing.T) {
+	handler := NewPostHandler(&mockPostService{}, &mockPostCache{})
+	handler.options.MaxStreamDuration = 200 * time.Millisecond
+	server := httptest.NewServer(handler.StreamPostsHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The handler subscribes before sending headers, so the post is delivered
+	handler.events.publish(&domain.Post{ID: "post_1", UserID: "user_1", Content: "Streamed post"})
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading stream: %v", err)
+	}
+	if !strings.Contains(string(body), "event: post") || !strings.Contains(string(body), "Streamed post") {
+		t.Errorf("Expected the created post in the stream, got %q", body)
+	}
+}
+
+// TestStreamPostsHandlerMaxClients tests that connections past the cap are
+// rejected and that closing one frees a slot
+func TestStreamPostsHandlerMaxClients(t *testing.T) {
+	handler := NewPostHandler(&mockPostService{}, &mockPostCache{})
+	handler.options.MaxStreamDuration = time.Minute
+	handler.options.MaxStreamClients = 1
+	server := httptest.NewServer(handler.StreamPostsHandler())
+	defer server.Close()
+
+	first, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	if first.StatusCode != http.StatusOK {
+		t.Fatalf("Expected first stream to open, got status %d", first.StatusCode)
+	}
+
+	rejected, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	rejected.Body.Close()
+	if rejected.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status code %d past the cap, got %d", http.StatusServiceUnavailable, rejected.StatusCode)
+	}
+
+	// Closing the first stream unsubscribes it once the handler notices
+	first.Body.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		handler.events.mu.Lock()
+		subscribers := len(handler.events.subscribers)
+		handler.events.mu.Unlock()
+		if subscribers == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the closed stream to be unsubscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	again, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer again.Body.Close()
+	if again.StatusCode != http.StatusOK {
+		t.Errorf("Expected a freed slot to accept a stream, got status %d", again.StatusCode)
+	}
+}
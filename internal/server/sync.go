@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// SyncPostsHandler handles GET /posts/sync?since=<rfc3339>&after=<id>
+// requests from offline clients syncing incrementally. It returns posts
+// created or edited after the given position, oldest change first, along
+// with the next_since and next_after values to pass on the next request.
+// Edited posts reappear with their new updated_at. Without since the sync
+// starts from the beginning.
+func (h *PostHandler) SyncPostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		query := r.URL.Query()
+		var since time.Time
+		if value := query.Get("since"); value != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid since parameter, expected an RFC 3339 timestamp")
+				return
+			}
+			since = parsed
+		}
+
+		// The after ID breaks ties between posts edited at the same instant;
+		// clients may use either the raw or the opaque form
+		afterID, err := h.postIDs().decode(query.Get("after"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid after parameter")
+			return
+		}
+
+		_, limit, ok := h.parsePaginationParams(w, r, h.options.PostsDefaultLimit)
+		if !ok {
+			return
+		}
+
+		posts, err := h.postService.ListModifiedSince(since, afterID, limit)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to sync posts")
+			return
+		}
+
+		// Without new changes the client keeps its position
+		nextSince, nextAfter := since, afterID
+		if len(posts) > 0 {
+			last := posts[len(posts)-1]
+			nextSince, nextAfter = last.UpdatedAt, last.ID
+		}
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"posts":      posts,
+			"next_since": nextSince.UTC().Format(time.RFC3339Nano),
+			"next_after": h.exposedPostID(nextAfter),
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestSyncPostsHandler tests that the sync position is passed through and the
+// next position points at the last returned change
+func TestSyncPostsHandler(t *testing.T) {
+	since := time.Date(2025, 3, 18, 12, 0, 0, 0, time.UTC)
+	edited := since.Add(90 * time.Second)
+
+	testCases := []struct {
+		name              string
+		query             url.Values
+		posts             []*domain.PostWithUser
+		expectedStatus    int
+		expectedSince     time.Time
+		expectedAfter     string
+		expectedNextSince string
+		expectedNextAfter string
+	}{
+		{
+			name:              "Changes after the position",
+			query:             url.Values{"since": {since.Format(time.RFC3339)}, "after": {"post_1"}},
+			posts:             []*domain.PostWithUser{{Post: domain.Post{ID: "post_1", Content: "Edited", UpdatedAt: edited}}},
+			expectedStatus:    http.StatusOK,
+			expectedSince:     since,
+			expectedAfter:     "post_1",
+			expectedNextSince: edited.Format(time.RFC3339Nano),
+			expectedNextAfter: "post_1",
+		},
+		{
+			name:              "No changes keeps the position",
+			query:             url.Values{"since": {since.Format(time.RFC3339)}},
+			expectedStatus:    http.StatusOK,
+			expectedSince:     since,
+			expectedNextSince: since.Format(time.RFC3339Nano),
+		},
+		{
+			name:           "Invalid since",
+			query:          url.Values{"since": {"yesterday"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				listModifiedSinceFunc: func(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+					if !since.Equal(tc.expectedSince) || afterID != tc.expectedAfter {
+						t.Errorf("Expected sync from (%s, %q), got (%s, %q)", tc.expectedSince, tc.expectedAfter, since, afterID)
+					}
+					if tc.posts == nil {
+						return []*domain.PostWithUser{}, nil
+					}
+					return tc.posts, nil
+				},
+			}
+			handler := NewPostHandler(mockPostService, &mockPostCache{})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/sync?"+tc.query.Encode(), nil)
+			rr := httptest.NewRecorder()
+			handler.SyncPostsHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Posts     []domain.PostWithUser `json:"posts"`
+				NextSince string                `json:"next_since"`
+				NextAfter string                `json:"next_after"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if len(response.Posts) != len(tc.posts) {
+				t.Errorf("Expected %d posts, got %d", len(tc.posts), len(response.Posts))
+			}
+			if response.NextSince != tc.expectedNextSince || response.NextAfter != tc.expectedNextAfter {
+				t.Errorf("Expected next position (%s, %q), got (%s, %q)", tc.expectedNextSince, tc.expectedNextAfter, response.NextSince, response.NextAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TimelineCache caches the first page of each user's timeline
+type TimelineCache interface {
+	GetTimeline(userID string) ([]*domain.PostWithUser, int, error)
+	SetTimeline(userID string, posts []*domain.PostWithUser, total int) error
+	InvalidateTimeline(userID string) error
+}
+
+// TimelineHandler handles GET /timeline requests, returning the posts by the
+// users the caller follows, newest first, in the same envelope as the posts
+// listing. The first page is cached per user for TIMELINE_CACHE_TTL, so
+// posts by followed users may take that long to appear; following or
+// unfollowing someone drops the cached page.
+func (h *PostHandler) TimelineHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication
+		userID, err := h.auth.authenticate(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		view, ok := h.parsePostView(w, r)
+		if !ok {
+			return
+		}
+
+		page, limit, ok := h.parsePaginationParams(w, r, h.options.PostsDefaultLimit)
+		if !ok {
+			return
+		}
+
+		// Only the first page is cached; it is served as long as it covers
+		// the requested limit
+		if page == 1 && h.timelines != nil {
+			if posts, total, err := h.timelines.GetTimeline(userID); err == nil {
+				if window, ok := cachedPage(posts, total, page, limit); ok {
+					h.respondPosts(w, window, page, limit, total, SourceCache, view)
+					return
+				}
+			}
+		}
+
+		posts, total, err := h.postService.ListFollowedBy(userID, page, limit)
+		if err != nil {
+			log.Printf("Failed to get timeline: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to get timeline")
+			return
+		}
+
+		if page == 1 && h.timelines != nil {
+			h.cacheWrites.Go(func() {
+				if err := h.timelines.SetTimeline(userID, posts, total); err != nil {
+					log.Printf("Failed to cache timeline: %v", err)
+				}
+			})
+		}
+
+		h.respondPosts(w, posts, page, limit, total, SourceDatabase, view)
+	}
+}
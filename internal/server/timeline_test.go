@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/service"
+)
+
+// mockTimelineCache is an in-memory TimelineCache
+type mockTimelineCache struct {
+	posts  map[string][]*domain.PostWithUser
+	totals map[string]int
+}
+
+func newMockTimelineCache() *mockTimelineCache {
+	return &mockTimelineCache{
+		posts:  make(map[string][]*domain.PostWithUser),
+		totals: make(map[string]int),
+	}
+}
+
+func (m *mockTimelineCache) GetTimeline(userID string) ([]*domain.PostWithUser, int, error) {
+	posts, ok := m.posts[userID]
+	if !ok {
+		return nil, 0, errors.New("cache miss")
+	}
+	return posts, m.totals[userID], nil
+}
+
+func (m *mockTimelineCache) SetTimeline(userID string, posts []*domain.PostWithUser, total int) error {
+	m.posts[userID] = posts
+	m.totals[userID] = total
+	return nil
+}
+
+func (m *mockTimelineCache) InvalidateTimeline(userID string) error {
+	delete(m.posts, userID)
+	return nil
+}
+
+// TestTimelineHandler tests that the timeline lists the caller's followed
+// posts, serving the first page from the cache once it has been read and
+// reading again after the caller follows someone
+func TestTimelineHandler(t *testing.T) {
+	fetches := 0
+	mockPostService := &mockPostService{
+		listFollowedByFunc: func(userID string, page, limit int) ([]*domain.PostWithUser, int, error) {
+			fetches++
+			if userID != "user_1" {
+				t.Errorf("Expected the caller's timeline, got %s", userID)
+			}
+			return []*domain.PostWithUser{{Post: domain.Post{ID: "post_1", UserID: "user_2", Content: "Followed"}}}, 1, nil
+		},
+	}
+	timelines := newMockTimelineCache()
+	handler := NewPostHandler(mockPostService, &mockPostCache{})
+	handler.timelines = timelines
+
+	get := func(target string, authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if authenticated {
+			req.SetBasicAuth("admin", "password")
+		}
+		rr := httptest.NewRecorder()
+		handler.TimelineHandler()(rr, req)
+		handler.cacheWrites.Wait(context.Background())
+		return rr
+	}
+	source := func(rr *httptest.ResponseRecorder) string {
+		var response struct {
+			Posts  []map[string]interface{} `json:"posts"`
+			Total  int                      `json:"total"`
+			Source string                   `json:"source"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if len(response.Posts) != 1 || response.Posts[0]["id"] != "post_1" || response.Total != 1 {
+			t.Errorf("Expected post_1 of 1, got %s", rr.Body.String())
+		}
+		return response.Source
+	}
+
+	if rr := get("/api/timeline", false); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	rr := get("/api/timeline", true)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if got := source(rr); got != SourceDatabase {
+		t.Errorf("Expected the first read from %s, got %s", SourceDatabase, got)
+	}
+
+	if got := source(get("/api/timeline", true)); got != SourceCache {
+		t.Errorf("Expected the cached first page, got %s", got)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected 1 timeline fetch, got %d", fetches)
+	}
+
+	// Deeper pages are always read from the database
+	get("/api/timeline?page=2", true)
+	if fetches != 2 {
+		t.Errorf("Expected page 2 to be fetched, got %d fetches", fetches)
+	}
+
+	// Following someone drops the cached timeline
+	users := newMockUserRepository(
+		&domain.User{ID: "user_1", Username: "admin"},
+		&domain.User{ID: "user_3", Username: "carol"},
+	)
+	userHandler := NewUserHandler(service.NewUserService(users))
+	userHandler.follows = &mockFollowRepository{}
+	userHandler.timelines = timelines
+	req := httptest.NewRequest(http.MethodPost, "/api/users/user_3/follow", nil)
+	req.SetBasicAuth("admin", "password")
+	userHandler.FollowHandler()(httptest.NewRecorder(), req)
+
+	if got := source(get("/api/timeline", true)); got != SourceDatabase {
+		t.Errorf("Expected a fresh read after following, got %s", got)
+	}
+}
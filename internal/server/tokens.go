@@ -0,0 +1,134 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors for bearer tokens that cannot be accepted
+var (
+	errInvalidToken = errors.New("invalid token")
+	errExpiredToken = errors.New("token expired")
+)
+
+// tokenHeader is the encoded JWT header of every issued token
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// tokenClaims are the JWT claims of an issued token
+type tokenClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	// Version is the credentials version of the user when the token was
+	// issued; the token is revoked once the credentials change
+	Version string `json:"ver,omitempty"`
+}
+
+// tokenIssuer signs and verifies the HS256 JWTs issued at login, carrying
+// the user ID and an expiry
+type tokenIssuer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// newTokenIssuer creates an issuer of tokens valid for ttl, or nil when no
+// secret is configured
+func newTokenIssuer(secret string, ttl time.Duration) *tokenIssuer {
+	if secret == "" {
+		return nil
+	}
+	return &tokenIssuer{
+		secret: []byte(secret),
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// issue returns a signed token for userID at the given credentials version
+// and when it expires
+func (t *tokenIssuer) issue(userID, version string) (string, time.Time, error) {
+	now := t.now()
+	expiresAt := now.Add(t.ttl)
+	claims, err := json.Marshal(tokenClaims{
+		Subject:   userID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		Version:   version,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signed := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return signed + "." + t.sign(signed), expiresAt, nil
+}
+
+// verify checks a token's signature and expiry and returns its claims
+func (t *tokenIssuer) verify(token string) (tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return tokenClaims{}, errInvalidToken
+	}
+
+	// Only HS256 is accepted, whatever algorithm the header claims
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return tokenClaims{}, errInvalidToken
+	}
+	var alg struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &alg); err != nil || alg.Alg != "HS256" {
+		return tokenClaims{}, errInvalidToken
+	}
+
+	expected := t.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return tokenClaims{}, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return tokenClaims{}, errInvalidToken
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return tokenClaims{}, errInvalidToken
+	}
+	if !t.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return tokenClaims{}, errExpiredToken
+	}
+
+	return claims, nil
+}
+
+// sign returns the encoded HMAC-SHA256 signature of signed
+func (t *tokenIssuer) sign(signed string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// credentialsVersion derives the credentials version of a user from their
+// stored password hash, so any password change revokes the user's tokens.
+// It is keyed with the token secret so tokens don't reveal the hash.
+func (t *tokenIssuer) credentialsVersion(password string) string {
+	return t.sign("credentials\x00" + password)[:16]
+}
+
+// bearerToken returns the token of a Bearer Authorization header and
+// whether the request carries one
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
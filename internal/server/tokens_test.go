@@ -0,0 +1,189 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTokenIssuerVerify tests that issued tokens are accepted until they
+// expire and that tampered or foreign tokens are rejected
+func TestTokenIssuerVerify(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	issuer := newTokenIssuer("secret", time.Hour)
+	issuer.now = func() time.Time { return now }
+
+	token, expiresAt, err := issuer.issue("user_1", "v1")
+	if err != nil {
+		t.Fatalf("issue() error = %v", err)
+	}
+	if !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expiresAt = %v, want %v", expiresAt, now.Add(time.Hour))
+	}
+
+	parts := strings.Split(token, ".")
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	otherIssuer := newTokenIssuer("other", time.Hour)
+	otherIssuer.now = issuer.now
+	otherToken, _, _ := otherIssuer.issue("user_1", "v1")
+
+	testCases := []struct {
+		name          string
+		token         string
+		elapsed       time.Duration
+		expectedUser  string
+		expectedError error
+	}{
+		{
+			name:         "Valid token",
+			token:        token,
+			expectedUser: "user_1",
+		},
+		{
+			name:          "Expired token",
+			token:         token,
+			elapsed:       time.Hour,
+			expectedError: errExpiredToken,
+		},
+		{
+			name:          "Tampered claims",
+			token:         parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user_2","exp":9999999999}`)) + "." + parts[2],
+			expectedError: errInvalidToken,
+		},
+		{
+			name:          "Unsigned token",
+			token:         noneHeader + "." + parts[1] + ".",
+			expectedError: errInvalidToken,
+		},
+		{
+			name:          "Other secret",
+			token:         otherToken,
+			expectedError: errInvalidToken,
+		},
+		{
+			name:          "Not a JWT",
+			token:         "token",
+			expectedError: errInvalidToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issuer.now = func() time.Time { return now.Add(tc.elapsed) }
+
+			claims, err := issuer.verify(tc.token)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("verify() error = %v, want %v", err, tc.expectedError)
+			}
+			if claims.Subject != tc.expectedUser {
+				t.Errorf("verify() subject = %q, want %q", claims.Subject, tc.expectedUser)
+			}
+		})
+	}
+}
+
+// TestBearerAuthentication tests that bearer tokens authenticate requests
+// and that rejected tokens get a 401 naming the problem
+func TestBearerAuthentication(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	issuer := newTokenIssuer("secret", time.Hour)
+	issuer.now = func() time.Time { return now }
+	// Without a user store tokens are issued for the environment password
+	_, password := envCredentials()
+	token, _, err := issuer.issue("user_7", issuer.credentialsVersion(password))
+	if err != nil {
+		t.Fatalf("issue() error = %v", err)
+	}
+	staleToken, _, err := issuer.issue("user_7", issuer.credentialsVersion("old-password"))
+	if err != nil {
+		t.Fatalf("issue() error = %v", err)
+	}
+
+	auth := newAuthenticator(nil, 0)
+	auth.tokens = issuer
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts/mine", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	userID, err := auth.authenticate(req)
+	if err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	if userID != "user_7" {
+		t.Errorf("authenticate() = %s, want %s", userID, "user_7")
+	}
+
+	testCases := []struct {
+		name            string
+		elapsed         time.Duration
+		authorization   string
+		expectedMessage string
+	}{
+		{
+			name:            "Expired token",
+			elapsed:         2 * time.Hour,
+			authorization:   "Bearer " + token,
+			expectedMessage: "Token expired",
+		},
+		{
+			name:            "Invalid token",
+			authorization:   "Bearer not-a-token",
+			expectedMessage: "Invalid token",
+		},
+		{
+			name:            "Token for a previous password",
+			authorization:   "Bearer " + staleToken,
+			expectedMessage: "Invalid token",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issuer.now = func() time.Time { return now.Add(tc.elapsed) }
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/mine", nil)
+			req.Header.Set("Authorization", tc.authorization)
+			_, err := auth.authenticate(req)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			rr := httptest.NewRecorder()
+			respondAuthError(rr, err)
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tc.expectedMessage) {
+				t.Errorf("Expected error %q, got %s", tc.expectedMessage, rr.Body.String())
+			}
+			if challenge := rr.Header().Get("WWW-Authenticate"); !strings.HasPrefix(challenge, "Bearer") {
+				t.Errorf("Expected a Bearer challenge, got %q", challenge)
+			}
+		})
+	}
+}
+
+// TestTokenTTLFromEnv tests that a JWT_TTL of zero or less falls back to
+// the default, since tokens must expire
+func TestTokenTTLFromEnv(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "15m", expected: 15 * time.Minute},
+		{value: "0s", expected: time.Hour},
+		{value: "-1h", expected: time.Hour},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("JWT_TTL", tc.value)
+			if ttl := LoadOptionsFromEnv().TokenTTL; ttl != tc.expected {
+				t.Errorf("TokenTTL = %v, want %v", ttl, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// UpdatePostHandler handles PUT /posts/:id requests, replacing the content
+// of a post owned by the caller. The body is limited like a create; the
+// content length is checked by the post service, which may allow edits a
+// separate maximum length. Posts owned by someone else are reported as not
+// found.
+func (h *PostHandler) UpdatePostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow PUT method
+		if r.Method != http.MethodPut {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication
+		userID, err := h.auth.authenticate(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		// Extract post ID from URL; clients may use either the raw or the
+		// opaque form of the ID
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		id, err := h.postIDs().decode(parts[len(parts)-1])
+		if err != nil || id == "" {
+			respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			return
+		}
+
+		content, ok := h.decodePostContent(w, r, 0)
+		if !ok {
+			return
+		}
+
+		post, err := h.postService.Update(id, userID, content)
+		if err != nil {
+			switch {
+			case errors.Is(err, domain.ErrPostNotFound):
+				respondDomainError(w, http.StatusNotFound, domain.ErrPostNotFound, "Post not found")
+			case errors.Is(err, domain.ErrInvalidPostContent):
+				respondDomainError(w, http.StatusBadRequest, err, "Content is required")
+			case errors.Is(err, domain.ErrPostContentTooLong):
+				respondDomainError(w, http.StatusBadRequest, err, "Invalid content: "+err.Error())
+			default:
+				respondError(w, http.StatusInternalServerError, "Failed to update post")
+			}
+			return
+		}
+
+		h.cacheWrites.Go(func() {
+			h.postCache.InvalidatePost(id)
+			h.postCache.InvalidatePosts()
+		})
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"post":    post,
+			"message": "Post updated successfully",
+		})
+	}
+}
+
+// decodePostContent reads the content of a create, update or comment request
+// body, limited to the maximum post body size and maxLength characters (0
+// means no limit), writing an error response and returning false if it is
+// invalid
+func (h *PostHandler) decodePostContent(w http.ResponseWriter, r *http.Request, maxLength int) (string, bool) {
+	if h.options.PostMaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.options.PostMaxBodyBytes)
+	}
+
+	var requestBody struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+		} else {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+		}
+		return "", false
+	}
+
+	// Validate content
+	if requestBody.Content == "" {
+		respondError(w, http.StatusBadRequest, "Content is required")
+		return "", false
+	}
+	if maxLength > 0 && utf8.RuneCountInString(requestBody.Content) > maxLength {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Content exceeds %d characters", maxLength))
+		return "", false
+	}
+
+	return requestBody.Content, true
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// TestUpdatePostHandler tests that the caller's post is updated and dropped
+// from the cache, and that other requests are rejected
+func TestUpdatePostHandler(t *testing.T) {
+	testCases := []struct {
+		name              string
+		authenticated     bool
+		body              string
+		updateErr         error
+		expectedStatus    int
+		expectInvalidated bool
+	}{
+		{
+			name:              "Updated",
+			authenticated:     true,
+			body:              `{"content": "Edited"}`,
+			expectedStatus:    http.StatusOK,
+			expectInvalidated: true,
+		},
+		{
+			name:           "Missing or owned by another user",
+			authenticated:  true,
+			body:           `{"content": "Edited"}`,
+			updateErr:      domain.ErrPostNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Content too long",
+			authenticated:  true,
+			body:           `{"content": "Edited"}`,
+			updateErr:      fmt.Errorf("%w: 21 characters, more than the limit of 20", domain.ErrPostContentTooLong),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Empty content",
+			authenticated:  true,
+			body:           `{"content": ""}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unauthenticated",
+			body:           `{"content": "Edited"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPostService := &mockPostService{
+				updateFunc: func(id, userID, content string) (*domain.Post, error) {
+					if tc.updateErr != nil {
+						return nil, tc.updateErr
+					}
+					return &domain.Post{ID: id, UserID: userID, Content: content}, nil
+				},
+			}
+			var invalidatedPost string
+			invalidatedPosts := false
+			mockPostCache := &mockPostCache{
+				invalidatePostFunc: func(id string) error {
+					invalidatedPost = id
+					return nil
+				},
+				invalidatePostsFunc: func() error {
+					invalidatedPosts = true
+					return nil
+				},
+			}
+			handler := NewPostHandler(mockPostService, mockPostCache)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/posts/post_1", strings.NewReader(tc.body))
+			if tc.authenticated {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.UpdatePostHandler()(rr, req)
+			handler.cacheWrites.Wait(context.Background())
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if invalidated := invalidatedPost == "post_1" && invalidatedPosts; invalidated != tc.expectInvalidated {
+				t.Errorf("Expected cache invalidated = %t, got post %q, posts %t", tc.expectInvalidated, invalidatedPost, invalidatedPosts)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Post domain.Post `json:"post"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response.Post.ID != "post_1" || response.Post.Content != "Edited" || response.Post.UserID != "user_1" {
+				t.Errorf("Expected the updated post, got %+v", response.Post)
+			}
+		})
+	}
+}
+
+// TestPostBodyLimit tests that create and update share the body size limit
+func TestPostBodyLimit(t *testing.T) {
+	handler := NewPostHandler(&mockPostService{}, &mockPostCache{})
+	handler.options.PostMaxBodyBytes = 64
+	body := `{"content": "` + strings.Repeat("a", 100) + `"}`
+
+	testCases := []struct {
+		name    string
+		method  string
+		handler http.HandlerFunc
+	}{
+		{name: "Create", method: http.MethodPost, handler: handler.CreatePostHandler()},
+		{name: "Update", method: http.MethodPut, handler: handler.UpdatePostHandler()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/posts/post_1", strings.NewReader(body))
+			req.SetBasicAuth("admin", "password")
+			rr := httptest.NewRecorder()
+			tc.handler(rr, req)
+
+			if rr.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+			}
+		})
+	}
+}
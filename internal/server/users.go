@@ -0,0 +1,315 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// UsernameResolver looks up users by username in bulk
+type UsernameResolver interface {
+	// GetByUsernames retrieves the users with any of the given usernames;
+	// usernames without a user are left out
+	GetByUsernames(usernames []string) ([]*domain.User, error)
+}
+
+// UserHandler handles user profile requests
+type UserHandler struct {
+	users     domain.UserService
+	posts     domain.PostService
+	auth      *authenticator
+	usernames UsernameResolver
+	// follows records who follows whom; the follow endpoints respond with
+	// 503 without it
+	follows domain.FollowRepository
+	// timelines holds cached timelines, dropped when their user follows or
+	// unfollows someone
+	timelines TimelineCache
+	options   Options
+}
+
+// exportPageSize is how many posts an export reads from the database at once
+const exportPageSize = 100
+
+// resolveMaxBodyBytes caps the size of a username resolution request body,
+// so the cap on usernames can't be dodged by a huge array
+const resolveMaxBodyBytes = 64 << 10
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(users domain.UserService) *UserHandler {
+	return &UserHandler{
+		users:   users,
+		options: LoadOptionsFromEnv(),
+	}
+}
+
+// resolvedUsername is the outcome of resolving one username
+type resolvedUsername struct {
+	ID     string `json:"id,omitempty"`
+	Exists bool   `json:"exists"`
+}
+
+// ResolveUsernamesHandler handles POST /api/users/resolve requests with a
+// body of the form {"usernames": ["alice", "bob"]}, resolving @handles to
+// user IDs in bulk for clients building mention UIs. Each requested username
+// maps to its ID, or to exists: false when no such user exists. Requires
+// authentication, so anonymous clients can't enumerate usernames. The number
+// of usernames and the size of the body are capped.
+func (h *UserHandler) ResolveUsernamesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication
+		if _, err := h.auth.authenticate(r); err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		if h.usernames == nil {
+			respondError(w, http.StatusServiceUnavailable, "Username resolution is not available")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, resolveMaxBodyBytes)
+		var requestBody struct {
+			Usernames []string `json:"usernames"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+			} else {
+				respondError(w, http.StatusBadRequest, "Invalid request body")
+			}
+			return
+		}
+
+		if len(requestBody.Usernames) == 0 {
+			respondError(w, http.StatusBadRequest, "At least one username is required")
+			return
+		}
+		if h.options.MaxResolveUsernames > 0 && len(requestBody.Usernames) > h.options.MaxResolveUsernames {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d usernames are allowed", h.options.MaxResolveUsernames))
+			return
+		}
+
+		users, err := h.usernames.GetByUsernames(requestBody.Usernames)
+		if err != nil {
+			log.Printf("Error resolving usernames: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to resolve usernames")
+			return
+		}
+
+		resolved := make(map[string]resolvedUsername, len(requestBody.Usernames))
+		for _, username := range requestBody.Usernames {
+			resolved[username] = resolvedUsername{}
+		}
+		for _, user := range users {
+			resolved[user.Username] = resolvedUsername{ID: user.ID, Exists: true}
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"users": resolved,
+		})
+	}
+}
+
+// UpdateProfileHandler handles PATCH /api/users/me requests with a body of
+// the form {"bio": "..."}, updating the caller's profile. Bios over the
+// configured length are rejected with 400.
+func (h *UserHandler) UpdateProfileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow PATCH method
+		if r.Method != http.MethodPatch {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication
+		userID, err := h.auth.authenticate(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		if h.users == nil {
+			respondError(w, http.StatusServiceUnavailable, "Profile updates are not available")
+			return
+		}
+
+		var requestBody struct {
+			Bio string `json:"bio"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		user, err := h.users.UpdateProfile(userID, requestBody.Bio)
+		if err != nil {
+			switch {
+			case errors.Is(err, domain.ErrInvalidBio):
+				respondDomainError(w, http.StatusBadRequest, err, "Bio is too long")
+			case errors.Is(err, domain.ErrUserNotFound):
+				respondDomainError(w, http.StatusNotFound, domain.ErrUserNotFound, "User not found")
+			default:
+				respondError(w, http.StatusInternalServerError, "Failed to update profile")
+			}
+			return
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"user": user,
+		})
+	}
+}
+
+// LoginHandler handles POST /api/login requests with a body of the form
+// {"username": "...", "password": "..."}, returning a signed bearer token for
+// the user. It is unavailable (503) unless a JWT secret is configured.
+func (h *UserHandler) LoginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST method
+		if r.Method != http.MethodPost {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		if h.users == nil || h.auth == nil || h.auth.tokens == nil {
+			respondError(w, http.StatusServiceUnavailable, "Token login is not available")
+			return
+		}
+
+		username, password, ok := decodeLoginRequest(w, r)
+		if !ok {
+			return
+		}
+
+		user, err := h.users.Authenticate(username, password)
+		if err != nil {
+			h.auth.recordFailedLogin(r, username)
+			respondError(w, http.StatusUnauthorized, "Invalid credentials")
+			return
+		}
+
+		respondToken(w, h.auth.tokens, user.ID, h.auth.tokens.credentialsVersion(user.Password))
+	}
+}
+
+// decodeLoginRequest decodes the credentials of a login request body,
+// writing an error response and returning false if they are missing
+func decodeLoginRequest(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	var requestBody struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return "", "", false
+	}
+	if requestBody.Username == "" || requestBody.Password == "" {
+		respondError(w, http.StatusBadRequest, "Username and password are required")
+		return "", "", false
+	}
+	return requestBody.Username, requestBody.Password, true
+}
+
+// respondToken issues a bearer token for userID at the given credentials
+// version and responds with it
+func respondToken(w http.ResponseWriter, tokens *tokenIssuer, userID, version string) {
+	token, expiresAt, err := tokens.issue(userID, version)
+	if err != nil {
+		log.Printf("Error issuing token for user %s: %v", userID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": expiresAt.UTC(),
+	})
+}
+
+// ExportHandler handles GET /api/users/me/export requests, returning the
+// caller's profile and all of their posts as a downloadable JSON bundle of
+// the form {"user": {...}, "posts": [...]}. Posts are read a page at a time
+// and streamed, so large accounts are never held in memory.
+func (h *UserHandler) ExportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET method
+		if r.Method != http.MethodGet {
+			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check authentication
+		userID, err := h.auth.authenticate(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		if h.users == nil || h.posts == nil {
+			respondError(w, http.StatusServiceUnavailable, "Data export is not available")
+			return
+		}
+
+		user, err := h.users.GetByID(userID)
+		if err != nil {
+			if errors.Is(err, domain.ErrUserNotFound) {
+				respondDomainError(w, http.StatusNotFound, domain.ErrUserNotFound, "User not found")
+			} else {
+				respondError(w, http.StatusInternalServerError, "Failed to get user")
+			}
+			return
+		}
+
+		profile, err := json.Marshal(user)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to encode user")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-export.json"`, userID))
+		w.WriteHeader(http.StatusOK)
+
+		if _, err := w.Write(append(append([]byte(`{"user":`), profile...), `,"posts":`...)); err != nil {
+			return
+		}
+
+		// Once streaming has started the status can't change, so a failure
+		// cuts the response short rather than returning a partial export
+		err = writeJSONArray(w, func(emit func(interface{}) error) error {
+			for page := 1; ; page++ {
+				posts, total, err := h.posts.ListByUser(userID, page, exportPageSize)
+				if err != nil {
+					return err
+				}
+				for _, post := range posts {
+					if err := emit(post); err != nil {
+						return err
+					}
+				}
+				if len(posts) < exportPageSize || page*exportPageSize >= total {
+					return nil
+				}
+			}
+		})
+		if err != nil {
+			log.Printf("Error exporting data for %s: %v", userID, err)
+			return
+		}
+
+		w.Write([]byte("}\n"))
+	}
+}
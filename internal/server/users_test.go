@@ -0,0 +1,402 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/auth"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/service"
+)
+
+// TestUpdateProfileHandler tests that bios up to the configured length are
+// saved and longer ones are rejected
+func TestUpdateProfileHandler(t *testing.T) {
+	t.Setenv("BIO_MAX_LENGTH", "5")
+
+	testCases := []struct {
+		name           string
+		method         string
+		body           string
+		authenticated  bool
+		expectedStatus int
+		expectedBio    string
+	}{
+		{
+			name:           "Bio at the limit",
+			method:         http.MethodPatch,
+			body:           `{"bio": "héllo"}`,
+			authenticated:  true,
+			expectedStatus: http.StatusOK,
+			expectedBio:    "héllo",
+		},
+		{
+			name:           "Bio over the limit",
+			method:         http.MethodPatch,
+			body:           `{"bio": "héllo!"}`,
+			authenticated:  true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unauthenticated",
+			method:         http.MethodPatch,
+			body:           `{"bio": "hi"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodGet,
+			authenticated:  true,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := newMockUserRepository(&domain.User{ID: "user_1", Username: "admin"})
+			handler := NewUserHandler(service.NewUserService(users))
+
+			req := httptest.NewRequest(tc.method, "/api/users/me", strings.NewReader(tc.body))
+			if tc.authenticated {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.UpdateProfileHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				User domain.User `json:"user"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response.User.Bio != tc.expectedBio || users.users["user_1"].Bio != tc.expectedBio {
+				t.Errorf("Expected bio %q to be saved, got %q", tc.expectedBio, users.users["user_1"].Bio)
+			}
+		})
+	}
+}
+
+// TestExportHandler tests that the export bundles the caller's profile with
+// every one of their posts, across several pages, as an attachment
+func TestExportHandler(t *testing.T) {
+	users := newMockUserRepository(&domain.User{ID: "user_1", Username: "admin", Bio: "Hello"})
+	allPosts := []*domain.Post{{ID: "post_other", UserID: "user_2"}}
+	for i := 0; i < 2*exportPageSize+5; i++ {
+		allPosts = append(allPosts, &domain.Post{ID: fmt.Sprintf("post_%d", i), UserID: "user_1"})
+	}
+	posts := &mockPostService{
+		listByUserFunc: func(userID string, page, limit int) ([]*domain.Post, int, error) {
+			var owned []*domain.Post
+			for _, post := range allPosts {
+				if post.UserID == userID {
+					owned = append(owned, post)
+				}
+			}
+			start := (page - 1) * limit
+			if start > len(owned) {
+				start = len(owned)
+			}
+			end := start + limit
+			if end > len(owned) {
+				end = len(owned)
+			}
+			return owned[start:end], len(owned), nil
+		},
+	}
+	handler := NewUserHandler(service.NewUserService(users))
+	handler.posts = posts
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/me/export", nil)
+	req.SetBasicAuth("admin", "password")
+	rr := httptest.NewRecorder()
+	handler.ExportHandler()(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if disposition := rr.Header().Get("Content-Disposition"); disposition != `attachment; filename="user_1-export.json"` {
+		t.Errorf("Unexpected Content-Disposition %q", disposition)
+	}
+
+	var bundle struct {
+		User  domain.User   `json:"user"`
+		Posts []domain.Post `json:"posts"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &bundle); err != nil {
+		t.Fatalf("Failed to parse export: %v", err)
+	}
+	if bundle.User.ID != "user_1" || bundle.User.Username != "admin" || bundle.User.Bio != "Hello" {
+		t.Errorf("Unexpected user %+v", bundle.User)
+	}
+	if len(bundle.Posts) != 2*exportPageSize+5 {
+		t.Fatalf("Expected %d posts, got %d", 2*exportPageSize+5, len(bundle.Posts))
+	}
+	for _, post := range bundle.Posts {
+		if post.UserID != "user_1" {
+			t.Errorf("Export contains another user's post %+v", post)
+		}
+	}
+
+	// Exports require authentication
+	req = httptest.NewRequest(http.MethodGet, "/api/users/me/export", nil)
+	rr = httptest.NewRecorder()
+	handler.ExportHandler()(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
+
+// TestLoginHandler tests that valid credentials are exchanged for a bearer
+// token that then authenticates the user, and that rejected logins are
+// recorded
+func TestLoginHandler(t *testing.T) {
+	hash, err := auth.HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		body           string
+		secret           string
+		expectedStatus   int
+		expectedFailures int
+	}{
+		{
+			name:           "Valid credentials",
+			method:         http.MethodPost,
+			body:           `{"username": "admin", "password": "password"}`,
+			secret:         "secret",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Wrong password",
+			method:         http.MethodPost,
+			body:             `{"username": "admin", "password": "wrong"}`,
+			secret:           "secret",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedFailures: 1,
+		},
+		{
+			name:           "Missing password",
+			method:         http.MethodPost,
+			body:           `{"username": "admin"}`,
+			secret:         "secret",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "No JWT secret",
+			method:         http.MethodPost,
+			body:           `{"username": "admin", "password": "password"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodGet,
+			secret:         "secret",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := newMockUserRepository(&domain.User{ID: "user_1", Username: "admin", Password: hash})
+			handler := NewUserHandler(service.NewUserService(users))
+			handler.auth = newAuthenticator(users, 0)
+			handler.auth.tokens = newTokenIssuer(tc.secret, time.Hour)
+			failures := &mockFailedLoginLog{}
+			handler.auth.failures = failures
+
+			req := httptest.NewRequest(tc.method, "/api/login", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			handler.LoginHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if len(failures.attempts) != tc.expectedFailures {
+				t.Fatalf("Expected %d failed logins recorded, got %d", tc.expectedFailures, len(failures.attempts))
+			}
+			if tc.expectedFailures > 0 && failures.attempts[0].Username != "admin" {
+				t.Errorf("Expected the failed login of admin to be recorded, got %+v", failures.attempts[0])
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Token     string    `json:"token"`
+				TokenType string    `json:"token_type"`
+				ExpiresAt time.Time `json:"expires_at"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if response.TokenType != "Bearer" || response.ExpiresAt.IsZero() {
+				t.Errorf("Unexpected token response: %s", rr.Body.String())
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/api/users/me/export", nil)
+			req.Header.Set("Authorization", "Bearer "+response.Token)
+			userID, err := handler.auth.authenticate(req)
+			if err != nil {
+				t.Fatalf("authenticate() error = %v", err)
+			}
+			if userID != "user_1" {
+				t.Errorf("authenticate() = %s, want %s", userID, "user_1")
+			}
+		})
+	}
+}
+
+// TestLoginTokenRevokedOnPasswordChange tests that a token issued at login
+// stops authenticating once the user's password changes
+func TestLoginTokenRevokedOnPasswordChange(t *testing.T) {
+	hash, err := auth.HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	users := newMockUserRepository(&domain.User{ID: "user_1", Username: "admin", Password: hash})
+	handler := NewUserHandler(service.NewUserService(users))
+	handler.auth = newAuthenticator(users, time.Minute)
+	handler.auth.tokens = newTokenIssuer("secret", time.Hour)
+
+	rr := httptest.NewRecorder()
+	handler.LoginHandler()(rr, httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"username": "admin", "password": "password"}`)))
+	var response struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || response.Token == "" {
+		t.Fatalf("Expected a token, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	authenticate := func() error {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me/export", nil)
+		req.Header.Set("Authorization", "Bearer "+response.Token)
+		_, err := handler.auth.authenticate(req)
+		return err
+	}
+	if err := authenticate(); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+
+	newHash, err := auth.HashPassword("new-password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	users.users["user_1"].Password = newHash
+	handler.auth.invalidateUser("user_1")
+
+	if err := authenticate(); !errors.Is(err, errInvalidToken) {
+		t.Errorf("authenticate() error = %v, want %v", err, errInvalidToken)
+	}
+}
+
+// TestResolveUsernamesHandler tests that known usernames resolve to their
+// IDs in one lookup, unknown ones to exists: false, that the number of
+// usernames and the body size are capped, and that callers must authenticate
+func TestResolveUsernamesHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		maxUsernames   int
+		anonymous      bool
+		expectedStatus int
+		expectedUsers  map[string]resolvedUsername
+	}{
+		{
+			name:           "Known and unknown usernames",
+			body:           `{"usernames": ["alice", "nobody", "bob"]}`,
+			maxUsernames:   3,
+			expectedStatus: http.StatusOK,
+			expectedUsers: map[string]resolvedUsername{
+				"alice":  {ID: "user_2", Exists: true},
+				"nobody": {},
+				"bob":    {ID: "user_3", Exists: true},
+			},
+		},
+		{
+			name:           "Too many usernames",
+			body:           `{"usernames": ["alice", "bob", "carol", "dave"]}`,
+			maxUsernames:   3,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "No usernames",
+			body:           `{"usernames": []}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid body",
+			body:           `{"usernames": "alice"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Body too large",
+			body:           `{"usernames": ["` + strings.Repeat("a", resolveMaxBodyBytes) + `"]}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "Not authenticated",
+			body:           `{"usernames": ["alice"]}`,
+			anonymous:      true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := newMockUserRepository(
+				&domain.User{ID: "user_2", Username: "alice"},
+				&domain.User{ID: "user_3", Username: "bob"},
+			)
+			handler := NewUserHandler(nil)
+			handler.usernames = users
+			handler.options.MaxResolveUsernames = tc.maxUsernames
+
+			req := httptest.NewRequest(http.MethodPost, "/api/users/resolve", strings.NewReader(tc.body))
+			if !tc.anonymous {
+				req.SetBasicAuth("admin", "password")
+			}
+			rr := httptest.NewRecorder()
+			handler.ResolveUsernamesHandler()(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				if users.usernameLookups != 0 {
+					t.Errorf("Expected no lookup for a rejected request, got %d", users.usernameLookups)
+				}
+				return
+			}
+			if users.usernameLookups != 1 {
+				t.Errorf("Expected a single lookup, got %d", users.usernameLookups)
+			}
+
+			var response struct {
+				Users map[string]resolvedUsername `json:"users"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if fmt.Sprint(response.Users) != fmt.Sprint(tc.expectedUsers) {
+				t.Errorf("Expected users %v, got %v", tc.expectedUsers, response.Users)
+			}
+		})
+	}
+}
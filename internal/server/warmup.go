@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// errWarmInProgress is returned when a cache warm is skipped because the
+// maximum number of warms is already running
+var errWarmInProgress = errors.New("cache warm already in progress")
+
+// cacheWarmer runs cache warms, allowing only a fixed number at a time so
+// concurrent rebuilds and refreshes don't stampede the database
+type cacheWarmer struct {
+	slots chan struct{}
+	// dirty is set when a warm is turned away, so a running warm repeats
+	// and picks up the writes that prompted it
+	dirty atomic.Bool
+}
+
+// newCacheWarmer creates a warmer running at most max warms at once
+func newCacheWarmer(max int) *cacheWarmer {
+	if max < 1 {
+		max = 1
+	}
+	return &cacheWarmer{slots: make(chan struct{}, max)}
+}
+
+// warm warms the posts cache, or returns errWarmInProgress without querying
+// the database when every slot is taken. A running warm may have read the
+// posts before the write that prompted the skipped one, so it runs again
+// once it finishes; any number of skipped warms share that one re-run.
+func (c *cacheWarmer) warm(postService domain.PostService, postCache PostCache) (int, error) {
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		c.dirty.Store(true)
+		return 0, errWarmInProgress
+	}
+	defer func() { <-c.slots }()
+
+	for {
+		c.dirty.Store(false)
+		cached, err := warmPostsCache(postService, postCache)
+		if err != nil || !c.dirty.Load() {
+			return cached, err
+		}
+	}
+}
+
+// warmPostsCache loads the first page of posts from the service and stores
+// it in the posts cache, returning the number of posts cached
+func warmPostsCache(postService domain.PostService, postCache PostCache) (int, error) {
+	readAt := time.Now()
+	posts, total, err := postService.List(1, defaultPageLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := postCache.SetPostsWithUserAndTotalAt(posts, total, readAt); err != nil {
+		return 0, err
+	}
+
+	return len(posts), nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+)
+
+// Audited post mutations
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLogger records post mutations as structured entries on a log of their
+// own, separate from request logging. A nil AuditLogger records nothing.
+type AuditLogger struct {
+	logger *slog.Logger
+}
+
+// NewAuditLogger creates an audit logger writing JSON entries to w
+func NewAuditLogger(w io.Writer) *AuditLogger {
+	return &AuditLogger{
+		logger: slog.New(slog.NewJSONHandler(w, nil)),
+	}
+}
+
+// LoadAuditLoggerFromEnv creates an audit logger writing to stderr, or returns
+// nil when AUDIT_LOG is false
+func LoadAuditLoggerFromEnv() *AuditLogger {
+	if !config.GetEnvBool("AUDIT_LOG", true) {
+		return nil
+	}
+	return NewAuditLogger(os.Stderr)
+}
+
+// Record logs a mutation attempt by actorID on postID along with its outcome;
+// err is nil when the mutation succeeded. postID is empty when a create
+// fails before the post is assigned an ID.
+func (a *AuditLogger) Record(action, actorID, postID string, err error) {
+	if a == nil {
+		return
+	}
+
+	attrs := []any{
+		slog.String("action", action),
+		slog.String("actor_id", actorID),
+		slog.String("post_id", postID),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("outcome", "error"), slog.String("error", err.Error()))
+	} else {
+		attrs = append(attrs, slog.String("outcome", "success"))
+	}
+	a.logger.Info("audit", attrs...)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"sync"
+)
+
+// runBounded runs tasks concurrently with at most limit of them in flight at
+// once, so a single request cannot hold more than limit database connections.
+// It waits for every task and returns the first error in task order.
+func runBounded(limit int, tasks ...func() error) error {
+	if limit < 1 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	errs := make([]error, len(tasks))
+
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, task func() error) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = task()
+		}(i, task)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// CountingPostRepository wraps MockPostRepository and records how many queries run at once
+type CountingPostRepository struct {
+	*MockPostRepository
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+}
+
+func (c *CountingPostRepository) track() func() {
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxSeen {
+		c.maxSeen = c.inFlight
+	}
+	c.mu.Unlock()
+
+	// Hold the "connection" long enough for parallel queries to overlap
+	time.Sleep(10 * time.Millisecond)
+
+	return func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}
+}
+
+// List retrieves a list of posts while tracking concurrency
+func (c *CountingPostRepository) List(offset, limit int) ([]*domain.PostWithUser, error) {
+	defer c.track()()
+	return c.MockPostRepository.List(offset, limit)
+}
+
+// Count returns the total number of posts while tracking concurrency
+func (c *CountingPostRepository) Count() (int, error) {
+	defer c.track()()
+	return c.MockPostRepository.Count()
+}
+
+// TestListConcurrentQueryLimit tests that List never exceeds the configured query concurrency
+func TestListConcurrentQueryLimit(t *testing.T) {
+	testCases := []struct {
+		name  string
+		limit int
+	}{
+		{name: "sequential", limit: 1},
+		{name: "parallel", limit: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			postRepo := &CountingPostRepository{MockPostRepository: NewMockPostRepository()}
+			service := NewPostService(postRepo, NewMockUserRepository())
+			service.options.MaxConcurrentQueries = tc.limit
+
+			// Test
+			_, _, err := service.List(1, 10)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if postRepo.maxSeen > tc.limit {
+				t.Errorf("maxSeen = %d, want at most %d", postRepo.maxSeen, tc.limit)
+			}
+			if postRepo.maxSeen < 1 {
+				t.Error("Expected queries to run")
+			}
+		})
+	}
+}
+
+// TestRunBoundedReturnsFirstError tests that runBounded reports the first failing task
+func TestRunBoundedReturnsFirstError(t *testing.T) {
+	first := errors.New("first")
+	second := errors.New("second")
+
+	err := runBounded(2,
+		func() error { return nil },
+		func() error { return first },
+		func() error { return second },
+	)
+
+	if err != first {
+		t.Errorf("runBounded() error = %v, want %v", err, first)
+	}
+}
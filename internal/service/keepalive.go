@@ -0,0 +1,88 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Pinger checks that a backend connection is alive
+type Pinger interface {
+	Ping() error
+}
+
+// KeepaliveTarget is a backend pinged by the keepalive job
+type KeepaliveTarget struct {
+	Name   string
+	Pinger Pinger
+}
+
+// KeepaliveJob periodically pings backends so idle connections aren't
+// dropped by firewalls or proxies
+type KeepaliveJob struct {
+	targets  []KeepaliveTarget
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewKeepaliveJob creates a new keepalive job that pings targets every
+// interval
+func NewKeepaliveJob(targets []KeepaliveTarget, interval time.Duration) *KeepaliveJob {
+	return &KeepaliveJob{
+		targets:  targets,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the job in the background until Stop is called
+func (j *KeepaliveJob) Start() {
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				j.RunOnce()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the job and waits for pings in progress to finish
+func (j *KeepaliveJob) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+	<-j.done
+}
+
+// RunOnce pings every target, returning how many are unreachable. A failed
+// ping is retried once straight away: the connection pools discard the
+// broken connection and dial a new one, so the retry is a reconnect attempt.
+func (j *KeepaliveJob) RunOnce() int {
+	failed := 0
+	for _, target := range j.targets {
+		err := target.Pinger.Ping()
+		if err == nil {
+			continue
+		}
+
+		log.Printf("Keepalive ping to %s failed, reconnecting: %v", target.Name, err)
+		if err := target.Pinger.Ping(); err != nil {
+			log.Printf("Reconnecting to %s failed: %v", target.Name, err)
+			failed++
+			continue
+		}
+		log.Printf("Reconnected to %s", target.Name)
+	}
+	return failed
+}
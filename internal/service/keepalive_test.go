@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockPinger is a mock implementation of Pinger for testing
+type MockPinger struct {
+	mu    sync.Mutex
+	calls int
+	// failures is how many pings fail before they succeed again
+	failures int
+}
+
+// Ping records the call, failing while failures remain
+func (m *MockPinger) Ping() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func (m *MockPinger) callCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// TestKeepaliveJobStartStop tests that every target is pinged on the
+// configured interval and pings stop with the job
+func TestKeepaliveJobStartStop(t *testing.T) {
+	// Setup
+	database, redis := &MockPinger{}, &MockPinger{}
+	interval := 10 * time.Millisecond
+	job := NewKeepaliveJob([]KeepaliveTarget{{Name: "database", Pinger: database}, {Name: "redis", Pinger: redis}}, interval)
+
+	// Test: no ping before the first interval has passed
+	job.Start()
+	if calls := database.callCount(); calls != 0 {
+		t.Errorf("Expected no ping before the first interval, got %d", calls)
+	}
+	time.Sleep(10*interval + interval/2)
+	job.Stop()
+
+	// Assert: roughly one ping per interval, allowing for scheduling jitter
+	for name, pinger := range map[string]*MockPinger{"database": database, "redis": redis} {
+		if calls := pinger.callCount(); calls < 5 || calls > 10 {
+			t.Errorf("Expected about 10 pings to %s, got %d", name, calls)
+		}
+	}
+
+	calls := database.callCount()
+	time.Sleep(3 * interval)
+	if database.callCount() != calls {
+		t.Errorf("Expected no pings after Stop, got %d more", database.callCount()-calls)
+	}
+}
+
+// TestKeepaliveJobReconnect tests that a failed ping is retried at once and
+// only reported when the retry fails too
+func TestKeepaliveJobReconnect(t *testing.T) {
+	testCases := []struct {
+		name           string
+		failures       int
+		expectedCalls  int
+		expectedFailed int
+	}{
+		{name: "healthy", failures: 0, expectedCalls: 1, expectedFailed: 0},
+		{name: "reconnects", failures: 1, expectedCalls: 2, expectedFailed: 0},
+		{name: "down", failures: 2, expectedCalls: 2, expectedFailed: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pinger := &MockPinger{failures: tc.failures}
+			job := NewKeepaliveJob([]KeepaliveTarget{{Name: "database", Pinger: pinger}}, time.Hour)
+
+			failed := job.RunOnce()
+
+			if failed != tc.expectedFailed {
+				t.Errorf("RunOnce() = %d, want %d", failed, tc.expectedFailed)
+			}
+			if pinger.calls != tc.expectedCalls {
+				t.Errorf("Expected %d pings, got %d", tc.expectedCalls, pinger.calls)
+			}
+		})
+	}
+}
@@ -1,15 +1,102 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
 )
 
+// Whitespace trim modes applied to post content
+const (
+	TrimModeFull     = "full"
+	TrimModeTrailing = "trailing"
+	TrimModeNone     = "none"
+)
+
+// PostServiceOptions holds the tunable limits of the post service
+type PostServiceOptions struct {
+	// MaxTagsPerPost caps the number of #tags kept per post (0 means no limit)
+	MaxTagsPerPost int
+	// MaxMentionsPerPost caps the number of @mentions kept per post (0 means no limit)
+	MaxMentionsPerPost int
+	// NormalizeContent normalizes post content to Unicode NFC before storing it
+	NormalizeContent bool
+	// MaxConcurrentQueries caps the repository queries a single call runs in
+	// parallel. Each takes its own database connection, so raising it
+	// multiplies the connections a request holds; size the pool to match.
+	MaxConcurrentQueries int
+	// TrimMode is how whitespace is trimmed from post content: full trims both
+	// ends, trailing only trailing newlines (keeping intentional indentation),
+	// none keeps content as written
+	TrimMode string
+	// SanitizeHTML strips HTML markup from post content before storing it.
+	// It is off by default since it also deletes text that merely looks
+	// like markup, as in "use <div> for layout"; clients should escape
+	// content when rendering it instead.
+	SanitizeHTML bool
+	// MaxPostLength is the most characters post content may have once
+	// normalized (0 means no limit)
+	MaxPostLength int
+	// MaxUpdateLength overrides MaxPostLength for edits, in case they may be
+	// longer (0 uses MaxPostLength)
+	MaxUpdateLength int
+	// RejectDuplicatePosts rejects a post repeating its author's latest one
+	// with domain.ErrDuplicatePost. It is off by default since the check
+	// costs a query per create and repeats can be legitimate.
+	RejectDuplicatePosts bool
+}
+
+// DefaultPostServiceOptions returns the default post service options
+func DefaultPostServiceOptions() PostServiceOptions {
+	return PostServiceOptions{
+		MaxTagsPerPost:       10,
+		MaxMentionsPerPost:   10,
+		NormalizeContent:     true,
+		MaxConcurrentQueries: 1,
+		TrimMode:             TrimModeTrailing,
+		SanitizeHTML:         false,
+		MaxPostLength:        280,
+	}
+}
+
+// LoadPostServiceOptionsFromEnv loads the post service options from environment variables
+func LoadPostServiceOptionsFromEnv() PostServiceOptions {
+	options := DefaultPostServiceOptions()
+	options.MaxTagsPerPost = config.GetEnvInt("MAX_TAGS_PER_POST", options.MaxTagsPerPost)
+	options.MaxMentionsPerPost = config.GetEnvInt("MAX_MENTIONS_PER_POST", options.MaxMentionsPerPost)
+	options.NormalizeContent = config.GetEnvBool("NORMALIZE_CONTENT", options.NormalizeContent)
+	options.MaxConcurrentQueries = config.GetEnvInt("MAX_CONCURRENT_QUERIES", options.MaxConcurrentQueries)
+	switch mode := config.GetEnv("TRIM_MODE", options.TrimMode); mode {
+	case TrimModeFull, TrimModeTrailing, TrimModeNone:
+		options.TrimMode = mode
+	default:
+		log.Printf("Ignoring unknown TRIM_MODE=%q; using %q", mode, options.TrimMode)
+	}
+	options.SanitizeHTML = config.GetEnvBool("SANITIZE_HTML", options.SanitizeHTML)
+	options.MaxPostLength = config.GetEnvInt("MAX_POST_LENGTH", options.MaxPostLength)
+	options.MaxUpdateLength = config.GetEnvInt("POST_UPDATE_MAX_LENGTH", options.MaxUpdateLength)
+	options.RejectDuplicatePosts = config.GetEnvBool("REJECT_DUPLICATE_POSTS", options.RejectDuplicatePosts)
+	return options
+}
+
 // PostService implements the domain.PostService interface
 type PostService struct {
 	postRepo domain.PostRepository
 	userRepo domain.UserRepository
+	options  PostServiceOptions
+	audit    *AuditLogger
+	// likeRepo supplies the like counts of returned posts; without it they
+	// stay zero
+	likeRepo domain.LikeRepository
 }
 
 // NewPostService creates a new post service
@@ -17,9 +104,18 @@ func NewPostService(postRepo domain.PostRepository, userRepo domain.UserReposito
 	return &PostService{
 		postRepo: postRepo,
 		userRepo: userRepo,
+		options:  LoadPostServiceOptionsFromEnv(),
+		audit:    LoadAuditLoggerFromEnv(),
 	}
 }
 
+// WithLikes makes the service report the like count of the posts it
+// returns, read from likes
+func (s *PostService) WithLikes(likes domain.LikeRepository) *PostService {
+	s.likeRepo = likes
+	return s
+}
+
 // GetByID retrieves a post by ID
 func (s *PostService) GetByID(id string) (*domain.PostWithUser, error) {
 	if id == "" {
@@ -44,11 +140,26 @@ func (s *PostService) GetByID(id string) (*domain.PostWithUser, error) {
 		Username: user.Username,
 	}
 
-	return postWithUser, nil
+	counted, err := s.withLikeCounts([]*domain.PostWithUser{postWithUser})
+	if err != nil {
+		return nil, err
+	}
+	return counted[0], nil
 }
 
-// Create creates a new post
+// Create creates a new post, recording the attempt in the audit log
 func (s *PostService) Create(userID, content string) (*domain.Post, error) {
+	post, err := s.create(userID, content)
+	postID := ""
+	if post != nil {
+		postID = post.ID
+	}
+	s.audit.Record(AuditActionCreate, userID, postID, err)
+	return post, err
+}
+
+// create validates and stores a new post
+func (s *PostService) create(userID, content string) (*domain.Post, error) {
 	// Validate input
 	if userID == "" {
 		return nil, domain.ErrInvalidUserID
@@ -56,6 +167,13 @@ func (s *PostService) Create(userID, content string) (*domain.Post, error) {
 	if content == "" {
 		return nil, domain.ErrInvalidPostContent
 	}
+	content = s.normalizeContent(content)
+	if strings.TrimSpace(content) == "" {
+		return nil, domain.ErrInvalidPostContent
+	}
+	if err := CheckContentLength(content, s.options.MaxPostLength); err != nil {
+		return nil, err
+	}
 
 	// Check if user exists
 	_, err := s.userRepo.GetByID(userID)
@@ -63,6 +181,17 @@ func (s *PostService) Create(userID, content string) (*domain.Post, error) {
 		return nil, err
 	}
 
+	// Reject an immediate repost of the user's latest post
+	if s.options.RejectDuplicatePosts {
+		latest, err := s.postRepo.ListByUser(userID, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) > 0 && s.normalizeContent(latest[0].Content) == content {
+			return nil, domain.ErrDuplicatePost
+		}
+	}
+
 	// Create post
 	now := time.Now()
 	post := &domain.Post{
@@ -72,6 +201,7 @@ func (s *PostService) Create(userID, content string) (*domain.Post, error) {
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
+	s.parseEntities(post)
 
 	// Save post
 	err = s.postRepo.Create(post)
@@ -82,8 +212,88 @@ func (s *PostService) Create(userID, content string) (*domain.Post, error) {
 	return post, nil
 }
 
-// Update updates an existing post
+// CreateMany imports posts in bulk, recording each in the audit log. Every
+// post is validated before any is stored. Posts get the server time unless
+// preserveTimestamps is set, in which case their CreatedAt and UpdatedAt are
+// kept so migrated data retains its history; supplied timestamps may not be
+// in the future. Unlike Create, imports skip the repost check. The posts are
+// stored in one transaction, so a failed import stores none of them.
+func (s *PostService) CreateMany(posts []*domain.Post, preserveTimestamps bool) ([]*domain.Post, error) {
+	now := time.Now()
+	imported := make([]*domain.Post, 0, len(posts))
+	for _, source := range posts {
+		post, err := s.importPost(source, preserveTimestamps, now)
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, post)
+	}
+
+	err := s.postRepo.CreateMany(imported)
+	for _, post := range imported {
+		s.audit.Record(AuditActionCreate, post.UserID, post.ID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return imported, nil
+}
+
+// importPost validates a post to import and returns the post to store
+func (s *PostService) importPost(source *domain.Post, preserveTimestamps bool, now time.Time) (*domain.Post, error) {
+	if source.UserID == "" {
+		return nil, domain.ErrInvalidUserID
+	}
+	content := s.normalizeContent(source.Content)
+	if strings.TrimSpace(content) == "" {
+		return nil, domain.ErrInvalidPostContent
+	}
+	if err := CheckContentLength(content, s.options.MaxPostLength); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByID(source.UserID); err != nil {
+		return nil, err
+	}
+
+	createdAt, updatedAt := now, now
+	if preserveTimestamps {
+		if !source.CreatedAt.IsZero() {
+			createdAt = source.CreatedAt
+		}
+		updatedAt = createdAt
+		if !source.UpdatedAt.IsZero() {
+			updatedAt = source.UpdatedAt
+		}
+		if createdAt.After(now) || updatedAt.After(now) {
+			return nil, fmt.Errorf("%w: timestamps may not be in the future", domain.ErrInvalidTimestamp)
+		}
+		if updatedAt.Before(createdAt) {
+			return nil, fmt.Errorf("%w: updated_at is before created_at", domain.ErrInvalidTimestamp)
+		}
+	}
+
+	post := &domain.Post{
+		ID:        generatePostID(),
+		UserID:    source.UserID,
+		Content:   content,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}
+	s.parseEntities(post)
+	return post, nil
+}
+
+// Update updates an existing post, recording the attempt in the audit log
 func (s *PostService) Update(id, userID, content string) (*domain.Post, error) {
+	post, err := s.update(id, userID, content)
+	s.audit.Record(AuditActionUpdate, userID, id, err)
+	return post, err
+}
+
+// update validates and stores new content for a post
+func (s *PostService) update(id, userID, content string) (*domain.Post, error) {
 	// Validate input
 	if id == "" {
 		return nil, domain.ErrInvalidPostID
@@ -94,6 +304,13 @@ func (s *PostService) Update(id, userID, content string) (*domain.Post, error) {
 	if content == "" {
 		return nil, domain.ErrInvalidPostContent
 	}
+	content = s.normalizeContent(content)
+	if strings.TrimSpace(content) == "" {
+		return nil, domain.ErrInvalidPostContent
+	}
+	if err := CheckContentLength(content, s.updateMaxLength()); err != nil {
+		return nil, err
+	}
 
 	// Get post
 	post, err := s.postRepo.GetByID(id)
@@ -109,6 +326,7 @@ func (s *PostService) Update(id, userID, content string) (*domain.Post, error) {
 	// Update post
 	post.Content = content
 	post.UpdatedAt = time.Now()
+	s.parseEntities(post)
 
 	// Save post
 	err = s.postRepo.Update(post)
@@ -119,8 +337,15 @@ func (s *PostService) Update(id, userID, content string) (*domain.Post, error) {
 	return post, nil
 }
 
-// Delete deletes a post
+// Delete deletes a post, recording the attempt in the audit log
 func (s *PostService) Delete(id, userID string) error {
+	err := s.delete(id, userID)
+	s.audit.Record(AuditActionDelete, userID, id, err)
+	return err
+}
+
+// delete removes a post owned by userID
+func (s *PostService) delete(id, userID string) error {
 	// Validate input
 	if id == "" {
 		return domain.ErrInvalidPostID
@@ -165,23 +390,146 @@ func (s *PostService) ListByUser(userID string, page, limit int) ([]*domain.Post
 
 	offset := (page - 1) * limit
 
-	// Get posts
-	posts, err := s.postRepo.ListByUser(userID, offset, limit)
+	// Get posts and total count
+	var posts []*domain.Post
+	var count int
+	err = runBounded(s.options.MaxConcurrentQueries,
+		func() (err error) {
+			posts, err = s.postRepo.ListByUser(userID, offset, limit)
+			return err
+		},
+		func() (err error) {
+			count, err = s.postRepo.CountByUser(userID)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return posts, count, nil
+}
+
+// List retrieves a list of posts with pagination
+func (s *PostService) List(page, limit int) ([]*domain.PostWithUser, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	// Get posts and total count
+	var posts []*domain.PostWithUser
+	var count int
+	err := runBounded(s.options.MaxConcurrentQueries,
+		func() (err error) {
+			posts, err = s.postRepo.List(offset, limit)
+			return err
+		},
+		func() (err error) {
+			count, err = s.postRepo.Count()
+			return err
+		},
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	posts, err = s.withLikeCounts(posts)
 	if err != nil {
 		return nil, 0, err
 	}
+	return posts, count, nil
+}
+
+// Search retrieves posts matching a full-text query with pagination
+func (s *PostService) Search(query string, page, limit int) ([]*domain.PostWithUser, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
 
-	// Get total count
-	count, err := s.postRepo.CountByUser(userID)
+	// Get matching posts and their total count
+	var posts []*domain.PostWithUser
+	var count int
+	err := runBounded(s.options.MaxConcurrentQueries,
+		func() (err error) {
+			posts, err = s.postRepo.Search(query, offset, limit)
+			return err
+		},
+		func() (err error) {
+			count, err = s.postRepo.CountSearch(query)
+			return err
+		},
+	)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	posts, err = s.withLikeCounts(posts)
+	if err != nil {
+		return nil, 0, err
+	}
 	return posts, count, nil
 }
 
-// List retrieves a list of posts with pagination
-func (s *PostService) List(page, limit int) ([]*domain.PostWithUser, int, error) {
+// CountSearch returns the number of posts matching a full-text query
+func (s *PostService) CountSearch(query string) (int, error) {
+	return s.postRepo.CountSearch(query)
+}
+
+// ListAfter retrieves the page of posts following the (createdAt, afterID)
+// position, newest first; a zero createdAt returns the first page
+func (s *PostService) ListAfter(createdAt time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	if limit < 1 {
+		limit = 10
+	}
+	posts, err := s.postRepo.ListAfter(createdAt, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.withLikeCounts(posts)
+}
+
+// ListModifiedSince retrieves posts created or edited after the (since,
+// afterID) position, oldest change first
+func (s *PostService) ListModifiedSince(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	if limit < 1 {
+		limit = 10
+	}
+	posts, err := s.postRepo.ListModifiedSince(since, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.withLikeCounts(posts)
+}
+
+// ListByUsers retrieves the most recent posts across a set of users, newest
+// first
+func (s *PostService) ListByUsers(userIDs []string, limit int) ([]*domain.PostWithUser, error) {
+	if len(userIDs) == 0 {
+		return []*domain.PostWithUser{}, nil
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	posts, err := s.postRepo.ListByUsers(userIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.withLikeCounts(posts)
+}
+
+// ListFollowedBy retrieves the timeline of a user, the posts by the users
+// they follow, with pagination, newest first
+func (s *PostService) ListFollowedBy(userID string, page, limit int) ([]*domain.PostWithUser, int, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -191,14 +539,23 @@ func (s *PostService) List(page, limit int) ([]*domain.PostWithUser, int, error)
 
 	offset := (page - 1) * limit
 
-	// Get posts
-	posts, err := s.postRepo.List(offset, limit)
+	var posts []*domain.PostWithUser
+	var count int
+	err := runBounded(s.options.MaxConcurrentQueries,
+		func() (err error) {
+			posts, err = s.postRepo.ListFollowedBy(userID, offset, limit)
+			return err
+		},
+		func() (err error) {
+			count, err = s.postRepo.CountFollowedBy(userID)
+			return err
+		},
+	)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Get total count
-	count, err := s.postRepo.Count()
+	posts, err = s.withLikeCounts(posts)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -206,8 +563,88 @@ func (s *PostService) List(page, limit int) ([]*domain.PostWithUser, int, error)
 	return posts, count, nil
 }
 
-// generatePostID generates a unique post ID
-// In a real application, this would use a proper ID generation method
+// withLikeCounts returns copies of posts carrying their like counts, read
+// with one query and leaving the repository's posts untouched. Without a
+// like repository, or when counting fails, posts are returned unchanged, as
+// missing counts shouldn't fail the listing.
+func (s *PostService) withLikeCounts(posts []*domain.PostWithUser) ([]*domain.PostWithUser, error) {
+	if s.likeRepo == nil || len(posts) == 0 {
+		return posts, nil
+	}
+
+	ids := make([]string, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	counts, err := s.likeRepo.CountByPosts(ids)
+	if err != nil {
+		log.Printf("Error counting likes, returning posts without like counts: %v", err)
+		return posts, nil
+	}
+
+	counted := make([]*domain.PostWithUser, len(posts))
+	for i, post := range posts {
+		withCount := *post
+		withCount.LikeCount = counts[post.ID]
+		counted[i] = &withCount
+	}
+	return counted, nil
+}
+
+// CheckContentLength rejects content longer than maxLength characters
+// (0 means no limit), counted in characters rather than bytes, with
+// domain.ErrPostContentTooLong naming the limit and the actual length
+func CheckContentLength(content string, maxLength int) error {
+	length := utf8.RuneCountInString(content)
+	if maxLength > 0 && length > maxLength {
+		return fmt.Errorf("%w: %d characters, more than the limit of %d", domain.ErrPostContentTooLong, length, maxLength)
+	}
+	return nil
+}
+
+// updateMaxLength returns the maximum content length of an update, which
+// falls back to the create limit unless overridden
+func (s *PostService) updateMaxLength() int {
+	if s.options.MaxUpdateLength > 0 {
+		return s.options.MaxUpdateLength
+	}
+	return s.options.MaxPostLength
+}
+
+// normalizeContent strips HTML markup when sanitization is enabled, trims
+// content according to the trim mode and converts it to Unicode NFC when
+// normalization is enabled, so visually identical text is stored identically.
+// Callers must re-check the result for emptiness.
+func (s *PostService) normalizeContent(content string) string {
+	if s.options.SanitizeHTML {
+		content = sanitizeHTML(content)
+	}
+
+	switch s.options.TrimMode {
+	case TrimModeFull:
+		content = strings.TrimSpace(content)
+	case TrimModeTrailing:
+		content = strings.TrimRight(content, "\r\n")
+	}
+
+	if !s.options.NormalizeContent {
+		return content
+	}
+	return norm.NFC.String(content)
+}
+
+// parseEntities extracts the tags and mentions of a post, truncated to the configured caps
+func (s *PostService) parseEntities(post *domain.Post) {
+	post.Tags = domain.ExtractTags(post.Content, s.options.MaxTagsPerPost)
+	post.Mentions = domain.ExtractMentions(post.Content, s.options.MaxMentionsPerPost)
+}
+
+// generatePostID generates a unique post ID; posts created in the same
+// second, as in an import, must not collide
 func generatePostID() string {
-	return "post_" + time.Now().Format("20060102150405")
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "post_" + time.Now().Format("20060102150405.000000000")
+	}
+	return "post_" + hex.EncodeToString(b)
 }
@@ -1,8 +1,14 @@
 package service
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -50,6 +56,19 @@ func (m *MockPostRepository) Create(post *domain.Post) error {
 	return nil
 }
 
+// CreateMany creates posts, storing none if any of them exists
+func (m *MockPostRepository) CreateMany(posts []*domain.Post) error {
+	for _, post := range posts {
+		if _, ok := m.posts[post.ID]; ok {
+			return errors.New("post already exists")
+		}
+	}
+	for _, post := range posts {
+		m.posts[post.ID] = post
+	}
+	return nil
+}
+
 // Update updates an existing post
 func (m *MockPostRepository) Update(post *domain.Post) error {
 	m.updateCalled = true
@@ -135,6 +154,115 @@ func (m *MockPostRepository) Count() (int, error) {
 	return len(m.posts), nil
 }
 
+// Search retrieves posts whose content contains the query with pagination
+func (m *MockPostRepository) Search(query string, offset, limit int) ([]*domain.PostWithUser, error) {
+	posts := make([]*domain.PostWithUser, 0)
+	for _, post := range m.posts {
+		if strings.Contains(post.Content, query) {
+			posts = append(posts, &domain.PostWithUser{
+				Post:     *post,
+				Username: "user_" + post.UserID, // Mock username
+			})
+		}
+	}
+	
+	// Apply pagination
+	if offset >= len(posts) {
+		return []*domain.PostWithUser{}, nil
+	}
+	
+	end := offset + limit
+	if end > len(posts) {
+		end = len(posts)
+	}
+	
+	return posts[offset:end], nil
+}
+
+// CountSearch returns the number of posts whose content contains the query
+func (m *MockPostRepository) CountSearch(query string) (int, error) {
+	count := 0
+	for _, post := range m.posts {
+		if strings.Contains(post.Content, query) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ListAfter retrieves posts older than the (createdAt, afterID) position,
+// newest first
+func (m *MockPostRepository) ListAfter(createdAt time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	posts := make([]*domain.PostWithUser, 0)
+	for _, post := range m.posts {
+		if createdAt.IsZero() || post.CreatedAt.Before(createdAt) || (post.CreatedAt.Equal(createdAt) && post.ID < afterID) {
+			posts = append(posts, &domain.PostWithUser{Post: *post})
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// ListModifiedSince retrieves posts modified after the (since, afterID) position
+func (m *MockPostRepository) ListModifiedSince(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	posts := make([]*domain.PostWithUser, 0)
+	for _, post := range m.posts {
+		if post.UpdatedAt.After(since) || (post.UpdatedAt.Equal(since) && post.ID > afterID) {
+			posts = append(posts, &domain.PostWithUser{Post: *post})
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].UpdatedAt.Equal(posts[j].UpdatedAt) {
+			return posts[i].UpdatedAt.Before(posts[j].UpdatedAt)
+		}
+		return posts[i].ID < posts[j].ID
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// ListByUsers retrieves the most recent posts by any of the users
+func (m *MockPostRepository) ListByUsers(userIDs []string, limit int) ([]*domain.PostWithUser, error) {
+	wanted := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+	posts := make([]*domain.PostWithUser, 0)
+	for _, post := range m.posts {
+		if wanted[post.UserID] {
+			posts = append(posts, &domain.PostWithUser{Post: *post})
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// ListFollowedBy retrieves the posts by the users a user follows; the mock
+// has no follows
+func (m *MockPostRepository) ListFollowedBy(userID string, offset, limit int) ([]*domain.PostWithUser, error) {
+	return []*domain.PostWithUser{}, nil
+}
+
+// CountFollowedBy returns the number of posts by the users a user follows
+func (m *MockPostRepository) CountFollowedBy(userID string) (int, error) {
+	return 0, nil
+}
+
 // TestNewPostService tests the NewPostService function
 func TestNewPostService(t *testing.T) {
 	// Setup
@@ -376,6 +504,689 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+// TestCreateCapsTagsAndMentions tests that only the first N tags and mentions are stored
+func TestCreateCapsTagsAndMentions(t *testing.T) {
+	// Setup
+	postRepo := NewMockPostRepository()
+	userRepo := NewMockUserRepository()
+	userRepo.users["user_123"] = &domain.User{
+		ID:       "user_123",
+		Username: "testuser",
+	}
+	service := NewPostService(postRepo, userRepo)
+	service.options.MaxTagsPerPost = 2
+	service.options.MaxMentionsPerPost = 1
+
+	// Test
+	post, err := service.Create("user_123", "#one #two #three hello @alice @bob @carol")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Assert
+	stored := postRepo.posts[post.ID]
+	if stored == nil {
+		t.Fatalf("Expected post %s to be stored", post.ID)
+	}
+	if len(stored.Tags) != 2 || stored.Tags[0] != "one" || stored.Tags[1] != "two" {
+		t.Errorf("stored.Tags = %v, want [one two]", stored.Tags)
+	}
+	if len(stored.Mentions) != 1 || stored.Mentions[0] != "alice" {
+		t.Errorf("stored.Mentions = %v, want [alice]", stored.Mentions)
+	}
+
+	// A cap of 0 keeps every tag
+	delete(postRepo.posts, post.ID)
+	service.options.MaxTagsPerPost = 0
+	post, err = service.Create("user_123", "#a #b #c #d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(post.Tags) != 4 {
+		t.Errorf("len(post.Tags) = %d, want 4", len(post.Tags))
+	}
+}
+
+// TestCreateDeduplicatesTags tests that a tag repeated in different cases is
+// stored once, and that duplicates don't count against the tag cap
+func TestCreateDeduplicatesTags(t *testing.T) {
+	postRepo := NewMockPostRepository()
+	userRepo := NewMockUserRepository()
+	userRepo.users["user_123"] = &domain.User{
+		ID:       "user_123",
+		Username: "testuser",
+	}
+	service := NewPostService(postRepo, userRepo)
+	service.options.MaxTagsPerPost = 2
+
+	post, err := service.Create("user_123", "#go #go #Go #GO")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stored := postRepo.posts[post.ID]
+	if stored == nil {
+		t.Fatalf("Expected post %s to be stored", post.ID)
+	}
+	if len(stored.Tags) != 1 || stored.Tags[0] != "go" {
+		t.Errorf("stored.Tags = %v, want [go]", stored.Tags)
+	}
+
+	delete(postRepo.posts, post.ID)
+	post, err = service.Create("user_123", "#Go #go #rust #GO #zig")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(post.Tags) != 2 || post.Tags[0] != "go" || post.Tags[1] != "rust" {
+		t.Errorf("post.Tags = %v, want [go rust]", post.Tags)
+	}
+}
+
+// TestCreateNormalizesContent tests that NFD and NFC variants are stored identically
+func TestCreateNormalizesContent(t *testing.T) {
+	// Setup
+	postRepo := NewMockPostRepository()
+	userRepo := NewMockUserRepository()
+	userRepo.users["user_123"] = &domain.User{
+		ID:       "user_123",
+		Username: "testuser",
+	}
+	service := NewPostService(postRepo, userRepo)
+	service.options.NormalizeContent = true
+	service.options.RejectDuplicatePosts = true
+
+	nfc := "caf\u00e9"
+	nfd := "cafe\u0301"
+	if nfc == nfd {
+		t.Fatal("Expected test strings to differ before normalization")
+	}
+
+	// Test
+	post, err := service.Create("user_123", nfd)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Assert
+	if post.Content != nfc {
+		t.Errorf("post.Content = %q, want %q", post.Content, nfc)
+	}
+
+	// The NFC variant is the same post
+	_, err = service.Create("user_123", nfc)
+	if err != domain.ErrDuplicatePost {
+		t.Errorf("Expected error %v, got %v", domain.ErrDuplicatePost, err)
+	}
+
+	// Without normalization the variants differ
+	service.options.NormalizeContent = false
+	delete(postRepo.posts, post.ID)
+	post, err = service.Create("user_123", nfd)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if post.Content != nfd {
+		t.Errorf("post.Content = %q, want %q", post.Content, nfd)
+	}
+}
+
+// TestCreateDuplicatePosts tests that repeating the latest post is allowed
+// unless duplicate posts are rejected
+func TestCreateDuplicatePosts(t *testing.T) {
+	testCases := []struct {
+		name          string
+		reject        bool
+		expectedError error
+	}{
+		{
+			name: "Allowed by default",
+		},
+		{
+			name:          "Rejected",
+			reject:        true,
+			expectedError: domain.ErrDuplicatePost,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			postRepo := NewMockPostRepository()
+			userRepo := NewMockUserRepository()
+			userRepo.users["user_123"] = &domain.User{
+				ID:       "user_123",
+				Username: "testuser",
+			}
+			service := NewPostService(postRepo, userRepo)
+			service.options.RejectDuplicatePosts = tc.reject
+
+			// Test
+			if _, err := service.Create("user_123", "Same again"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_, err := service.Create("user_123", "Same again")
+
+			// Assert
+			if err != tc.expectedError {
+				t.Errorf("Expected error %v, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+// TestCreateMaxPostLength tests that content is limited to the maximum
+// number of characters on create and update
+func TestCreateMaxPostLength(t *testing.T) {
+	testCases := []struct {
+		name          string
+		length        int
+		expectedError error
+	}{
+		{
+			name:   "One under the limit",
+			length: 9,
+		},
+		{
+			name:   "At the limit",
+			length: 10,
+		},
+		{
+			name:          "One over the limit",
+			length:        11,
+			expectedError: domain.ErrPostContentTooLong,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			postRepo := NewMockPostRepository()
+			userRepo := NewMockUserRepository()
+			userRepo.users["user_123"] = &domain.User{
+				ID:       "user_123",
+				Username: "testuser",
+			}
+			postRepo.posts["post_123"] = &domain.Post{
+				ID:      "post_123",
+				UserID:  "user_123",
+				Content: "Original content",
+			}
+			service := NewPostService(postRepo, userRepo)
+			service.options.MaxPostLength = 10
+
+			// Multi-byte characters count once
+			content := strings.Repeat("é", tc.length)
+
+			// Test
+			_, createErr := service.Create("user_123", content)
+			_, updateErr := service.Update("post_123", "user_123", content)
+
+			// Assert
+			for operation, err := range map[string]error{"Create": createErr, "Update": updateErr} {
+				if !errors.Is(err, tc.expectedError) {
+					t.Fatalf("%s() error = %v, want %v", operation, err, tc.expectedError)
+				}
+				if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("%d characters, more than the limit of 10", tc.length)) {
+					t.Errorf("%s() error = %q, want the length and the limit", operation, err)
+				}
+			}
+		})
+	}
+}
+
+// TestUpdateMaxLength tests that updates are limited by the update override
+// when set, independently of the create limit
+func TestUpdateMaxLength(t *testing.T) {
+	testCases := []struct {
+		name          string
+		updateMax     int
+		length        int
+		expectedError error
+	}{
+		{
+			name:      "At the override",
+			updateMax: 20,
+			length:    20,
+		},
+		{
+			name:          "Over the override",
+			updateMax:     20,
+			length:        21,
+			expectedError: domain.ErrPostContentTooLong,
+		},
+		{
+			name:          "Without override the create limit applies",
+			length:        11,
+			expectedError: domain.ErrPostContentTooLong,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			postRepo := NewMockPostRepository()
+			userRepo := NewMockUserRepository()
+			postRepo.posts["post_123"] = &domain.Post{
+				ID:      "post_123",
+				UserID:  "user_123",
+				Content: "Original content",
+			}
+			service := NewPostService(postRepo, userRepo)
+			service.options.MaxPostLength = 10
+			service.options.MaxUpdateLength = tc.updateMax
+
+			// Test
+			_, err := service.Update("post_123", "user_123", strings.Repeat("a", tc.length))
+
+			// Assert
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("Update() error = %v, want %v", err, tc.expectedError)
+			}
+		})
+	}
+}
+
+// TestCreateTrimMode tests each whitespace trim mode
+func TestCreateTrimMode(t *testing.T) {
+	testCases := []struct {
+		name            string
+		trimMode        string
+		content         string
+		expectedContent string
+		expectedError   error
+	}{
+		{
+			name:            "Full trims both ends",
+			trimMode:        TrimModeFull,
+			content:         "  indented\n\n",
+			expectedContent: "indented",
+		},
+		{
+			name:            "Trailing keeps indentation",
+			trimMode:        TrimModeTrailing,
+			content:         "  indented\r\n\n",
+			expectedContent: "  indented",
+		},
+		{
+			name:            "None keeps content as written",
+			trimMode:        TrimModeNone,
+			content:         "  indented\n",
+			expectedContent: "  indented\n",
+		},
+		{
+			name:          "Whitespace only is rejected",
+			trimMode:      TrimModeNone,
+			content:       " \n ",
+			expectedError: domain.ErrInvalidPostContent,
+		},
+		{
+			name:          "Newlines only are rejected",
+			trimMode:      TrimModeTrailing,
+			content:       "\n\n",
+			expectedError: domain.ErrInvalidPostContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			postRepo := NewMockPostRepository()
+			userRepo := NewMockUserRepository()
+			userRepo.users["user_123"] = &domain.User{
+				ID:       "user_123",
+				Username: "testuser",
+			}
+			service := NewPostService(postRepo, userRepo)
+			service.options.TrimMode = tc.trimMode
+
+			// Test
+			post, err := service.Create("user_123", tc.content)
+
+			// Assert
+			if err != tc.expectedError {
+				t.Fatalf("Expected error %v, got %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if post.Content != tc.expectedContent {
+				t.Errorf("post.Content = %q, want %q", post.Content, tc.expectedContent)
+			}
+		})
+	}
+}
+
+// TestLoadTrimMode tests that an unknown TRIM_MODE is logged and the default
+// is used instead
+func TestLoadTrimMode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected string
+		logged   bool
+	}{
+		{name: "Full", value: "full", expected: TrimModeFull},
+		{name: "None", value: "none", expected: TrimModeNone},
+		{name: "Unknown", value: "both", expected: TrimModeTrailing, logged: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("TRIM_MODE", tc.value)
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			options := LoadPostServiceOptionsFromEnv()
+
+			if options.TrimMode != tc.expected {
+				t.Errorf("TrimMode = %q, want %q", options.TrimMode, tc.expected)
+			}
+			if logged := strings.Contains(logs.String(), "Ignoring unknown TRIM_MODE"); logged != tc.logged {
+				t.Errorf("Logged the unknown mode = %v, want %v: %q", logged, tc.logged, logs.String())
+			}
+		})
+	}
+}
+
+// TestCreateSanitizeHTML tests that markup is stripped and content that
+// sanitizes to nothing is rejected
+func TestCreateSanitizeHTML(t *testing.T) {
+	testCases := []struct {
+		name            string
+		sanitize        bool
+		content         string
+		expectedContent string
+		expectedError   error
+	}{
+		{
+			name:            "Tags are stripped, text is kept",
+			sanitize:        true,
+			content:         "<b>bold</b> and <i>italic</i>",
+			expectedContent: "bold and italic",
+		},
+		{
+			name:            "Script content is dropped",
+			sanitize:        true,
+			content:         "hi<script>alert(1)</script>",
+			expectedContent: "hi",
+		},
+		{
+			name:            "Lone angle brackets are kept",
+			sanitize:        true,
+			content:         "a < b <3",
+			expectedContent: "a < b <3",
+		},
+		{
+			name:            "Nested tags cannot rebuild a script",
+			sanitize:        true,
+			content:         "hi<<b>script>alert(1)<</b>/script>",
+			expectedContent: "hi",
+		},
+		{
+			name:          "Nested script only is rejected",
+			sanitize:      true,
+			content:       "<<b>script>alert(1)<</b>/script>",
+			expectedError: domain.ErrInvalidPostContent,
+		},
+		{
+			name:          "Script only is rejected",
+			sanitize:      true,
+			content:       "<script>alert(1)</script>",
+			expectedError: domain.ErrInvalidPostContent,
+		},
+		{
+			name:          "Markup around whitespace is rejected",
+			sanitize:      true,
+			content:       "<p> </p><!-- note -->",
+			expectedError: domain.ErrInvalidPostContent,
+		},
+		{
+			name:            "Disabled keeps markup",
+			sanitize:        false,
+			content:         "<script>alert(1)</script>",
+			expectedContent: "<script>alert(1)</script>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			postRepo := NewMockPostRepository()
+			userRepo := NewMockUserRepository()
+			userRepo.users["user_123"] = &domain.User{
+				ID:       "user_123",
+				Username: "testuser",
+			}
+			service := NewPostService(postRepo, userRepo)
+			service.options.SanitizeHTML = tc.sanitize
+
+			// Test
+			post, err := service.Create("user_123", tc.content)
+
+			// Assert
+			if err != tc.expectedError {
+				t.Fatalf("Expected error %v, got %v", tc.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if post.Content != tc.expectedContent {
+				t.Errorf("post.Content = %q, want %q", post.Content, tc.expectedContent)
+			}
+		})
+	}
+}
+
+// TestCreateAuditLog tests that creates are audited with their actor and
+// target, including failed attempts
+func TestCreateAuditLog(t *testing.T) {
+	// Setup
+	postRepo := NewMockPostRepository()
+	userRepo := NewMockUserRepository()
+	userRepo.users["user_123"] = &domain.User{
+		ID:       "user_123",
+		Username: "testuser",
+	}
+	service := NewPostService(postRepo, userRepo)
+	var buf bytes.Buffer
+	service.audit = NewAuditLogger(&buf)
+
+	// Test
+	post, err := service.Create("user_123", "Audited post")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := service.Create("user_123", ""); err == nil {
+		t.Fatal("Expected empty content to be rejected")
+	}
+
+	// Assert
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d: %s", len(lines), buf.String())
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse audit entry %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	success := entries[0]
+	if success["action"] != AuditActionCreate || success["actor_id"] != "user_123" ||
+		success["post_id"] != post.ID || success["outcome"] != "success" {
+		t.Errorf("Unexpected audit entry for successful create: %v", success)
+	}
+	if _, ok := success["time"]; !ok {
+		t.Errorf("Expected audit entry to have a timestamp: %v", success)
+	}
+
+	failure := entries[1]
+	if failure["actor_id"] != "user_123" || failure["outcome"] != "error" ||
+		failure["error"] != domain.ErrInvalidPostContent.Error() {
+		t.Errorf("Unexpected audit entry for failed create: %v", failure)
+	}
+}
+
+// TestCreateManyTimestamps tests that imports keep supplied timestamps only
+// when asked to, and reject timestamps in the future
+func TestCreateManyTimestamps(t *testing.T) {
+	createdAt := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	updatedAt := createdAt.Add(time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name               string
+		createdAt          time.Time
+		updatedAt          time.Time
+		preserveTimestamps bool
+		expectedCreatedAt  time.Time
+		expectedUpdatedAt  time.Time
+		expectedError      error
+	}{
+		{
+			name:               "Preserved",
+			createdAt:          createdAt,
+			updatedAt:          updatedAt,
+			preserveTimestamps: true,
+			expectedCreatedAt:  createdAt,
+			expectedUpdatedAt:  updatedAt,
+		},
+		{
+			name:               "Missing updated_at defaults to created_at",
+			createdAt:          createdAt,
+			preserveTimestamps: true,
+			expectedCreatedAt:  createdAt,
+			expectedUpdatedAt:  createdAt,
+		},
+		{
+			name:               "Future created_at",
+			createdAt:          future,
+			preserveTimestamps: true,
+			expectedError:      domain.ErrInvalidTimestamp,
+		},
+		{
+			name:               "Future updated_at",
+			createdAt:          createdAt,
+			updatedAt:          future,
+			preserveTimestamps: true,
+			expectedError:      domain.ErrInvalidTimestamp,
+		},
+		{
+			name:               "updated_at before created_at",
+			createdAt:          updatedAt,
+			updatedAt:          createdAt,
+			preserveTimestamps: true,
+			expectedError:      domain.ErrInvalidTimestamp,
+		},
+		{
+			// Without the flag supplied timestamps, even future ones, are
+			// replaced with the server time
+			name:      "Not preserved",
+			createdAt: future,
+			updatedAt: future,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			postRepo := NewMockPostRepository()
+			userRepo := NewMockUserRepository()
+			userRepo.users["user_123"] = &domain.User{ID: "user_123", Username: "testuser"}
+			service := NewPostService(postRepo, userRepo)
+
+			// Test
+			before := time.Now()
+			posts, err := service.CreateMany([]*domain.Post{
+				{UserID: "user_123", Content: "Imported #history", CreatedAt: tc.createdAt, UpdatedAt: tc.updatedAt},
+			}, tc.preserveTimestamps)
+
+			// Assert
+			if tc.expectedError != nil {
+				if !errors.Is(err, tc.expectedError) {
+					t.Fatalf("Expected error %v, got %v", tc.expectedError, err)
+				}
+				if len(postRepo.posts) != 0 {
+					t.Errorf("Expected nothing to be stored, got %d posts", len(postRepo.posts))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(posts) != 1 {
+				t.Fatalf("Expected 1 imported post, got %d", len(posts))
+			}
+			stored := postRepo.posts[posts[0].ID]
+			if stored == nil {
+				t.Fatalf("Expected post %s to be stored", posts[0].ID)
+			}
+			if len(stored.Tags) != 1 || stored.Tags[0] != "history" {
+				t.Errorf("stored.Tags = %v, want [history]", stored.Tags)
+			}
+
+			if !tc.preserveTimestamps {
+				if stored.CreatedAt.Before(before) || stored.UpdatedAt.Before(before) || stored.CreatedAt.After(time.Now()) {
+					t.Errorf("Expected server timestamps, got created %v updated %v", stored.CreatedAt, stored.UpdatedAt)
+				}
+				return
+			}
+			if !stored.CreatedAt.Equal(tc.expectedCreatedAt) || !stored.UpdatedAt.Equal(tc.expectedUpdatedAt) {
+				t.Errorf("Timestamps = %v, %v, want %v, %v", stored.CreatedAt, stored.UpdatedAt, tc.expectedCreatedAt, tc.expectedUpdatedAt)
+			}
+		})
+	}
+}
+
+// TestCreateManyMultiplePosts tests that posts imported together, within
+// the same second, all get their own IDs and are stored
+func TestCreateManyMultiplePosts(t *testing.T) {
+	// Setup
+	postRepo := NewMockPostRepository()
+	userRepo := NewMockUserRepository()
+	userRepo.users["user_123"] = &domain.User{ID: "user_123", Username: "testuser"}
+	service := NewPostService(postRepo, userRepo)
+
+	// Test
+	posts, err := service.CreateMany([]*domain.Post{
+		{UserID: "user_123", Content: "First"},
+		{UserID: "user_123", Content: "Second"},
+		{UserID: "user_123", Content: "Third"},
+	}, false)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(posts) != 3 {
+		t.Fatalf("Expected 3 imported posts, got %d", len(posts))
+	}
+	if len(postRepo.posts) != 3 {
+		t.Errorf("Expected 3 stored posts, got %d", len(postRepo.posts))
+	}
+	for _, post := range posts {
+		if postRepo.posts[post.ID] != post {
+			t.Errorf("Expected post %s to be stored", post.ID)
+		}
+	}
+
+	// An invalid post anywhere in the batch stores none of them
+	_, err = service.CreateMany([]*domain.Post{
+		{UserID: "user_123", Content: "Fourth"},
+		{UserID: "user_123", Content: ""},
+	}, false)
+	if !errors.Is(err, domain.ErrInvalidPostContent) {
+		t.Fatalf("Expected error %v, got %v", domain.ErrInvalidPostContent, err)
+	}
+	if len(postRepo.posts) != 3 {
+		t.Errorf("Expected the failed batch to store nothing, got %d posts", len(postRepo.posts))
+	}
+}
+
 // TestUpdate tests the Update method
 func TestUpdate(t *testing.T) {
 	// Test cases
@@ -520,6 +1331,52 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+// TestListModifiedSinceIncludesEdits tests that an edited post reappears
+// when syncing from the position reached before the edit
+func TestListModifiedSinceIncludesEdits(t *testing.T) {
+	postRepo := NewMockPostRepository()
+	created := time.Now().Add(-time.Hour)
+	for _, id := range []string{"post_1", "post_2", "post_3"} {
+		postRepo.posts[id] = &domain.Post{ID: id, UserID: "user_1", Content: "Original " + id, CreatedAt: created, UpdatedAt: created}
+	}
+	service := NewPostService(postRepo, NewMockUserRepository())
+
+	// A full sync returns every post, paging on the (updated_at, ID) position
+	first, err := service.ListModifiedSince(time.Time{}, "", 2)
+	if err != nil {
+		t.Fatalf("ListModifiedSince() error = %v", err)
+	}
+	if len(first) != 2 || first[0].ID != "post_1" || first[1].ID != "post_2" {
+		t.Fatalf("Expected post_1 and post_2 in the first page, got %v", first)
+	}
+	last := first[len(first)-1]
+	rest, err := service.ListModifiedSince(last.UpdatedAt, last.ID, 2)
+	if err != nil {
+		t.Fatalf("ListModifiedSince() error = %v", err)
+	}
+	if len(rest) != 1 || rest[0].ID != "post_3" {
+		t.Fatalf("Expected only post_3 in the second page, got %v", rest)
+	}
+
+	// Nothing changed since the end of the sync
+	since, afterID := rest[0].UpdatedAt, rest[0].ID
+	if posts, _ := service.ListModifiedSince(since, afterID, 10); len(posts) != 0 {
+		t.Fatalf("Expected no changes, got %v", posts)
+	}
+
+	// Editing an already synced post makes it reappear
+	if _, err := service.Update("post_1", "user_1", "Edited post_1"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	changed, err := service.ListModifiedSince(since, afterID, 10)
+	if err != nil {
+		t.Fatalf("ListModifiedSince() error = %v", err)
+	}
+	if len(changed) != 1 || changed[0].ID != "post_1" || changed[0].Content != "Edited post_1" {
+		t.Errorf("Expected the edited post_1 in the sync, got %v", changed)
+	}
+}
+
 // TestPostDelete tests the Delete method for posts
 func TestPostDelete(t *testing.T) {
 	// Test cases
@@ -889,3 +1746,86 @@ func TestPostList(t *testing.T) {
 		})
 	}
 }
+
+// mockLikeRepository is an in-memory domain.LikeRepository counting likes
+// per post
+type mockLikeRepository struct {
+	counts map[string]int
+	err    error
+	// batches is how many times CountByPosts was called
+	batches int
+}
+
+func (m *mockLikeRepository) Create(like *domain.Like) (bool, error) { return true, nil }
+
+func (m *mockLikeRepository) Delete(postID, userID string) (bool, error) { return true, nil }
+
+func (m *mockLikeRepository) CountByPost(postID string) (int, error) {
+	return m.counts[postID], m.err
+}
+
+func (m *mockLikeRepository) CountByPosts(postIDs []string) (map[string]int, error) {
+	m.batches++
+	if m.err != nil {
+		return nil, m.err
+	}
+	counts := make(map[string]int)
+	for _, postID := range postIDs {
+		if count, ok := m.counts[postID]; ok {
+			counts[postID] = count
+		}
+	}
+	return counts, nil
+}
+
+func (m *mockLikeRepository) ExistsByUserAndPost(userID, postID string) (bool, error) {
+	return false, nil
+}
+
+// TestPostsIncludeLikeCounts tests that returned posts carry their like
+// counts, read in one batch, and that failing to count them leaves the
+// counts out instead of failing the call
+func TestPostsIncludeLikeCounts(t *testing.T) {
+	postRepo := NewMockPostRepository()
+	userRepo := NewMockUserRepository()
+	userRepo.users["user_1"] = &domain.User{ID: "user_1", Username: "testuser"}
+	created := time.Now().Add(-time.Hour)
+	for _, id := range []string{"post_1", "post_2"} {
+		postRepo.posts[id] = &domain.Post{ID: id, UserID: "user_1", Content: "Post " + id, CreatedAt: created, UpdatedAt: created}
+	}
+	likes := &mockLikeRepository{counts: map[string]int{"post_1": 3}}
+	service := NewPostService(postRepo, userRepo).WithLikes(likes)
+
+	post, err := service.GetByID("post_1")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if post.LikeCount != 3 {
+		t.Errorf("post.LikeCount = %d, want 3", post.LikeCount)
+	}
+
+	likes.batches = 0
+	posts, err := service.ListAfter(time.Time{}, "", 10)
+	if err != nil {
+		t.Fatalf("ListAfter() error = %v", err)
+	}
+	if likes.batches != 1 {
+		t.Errorf("Expected the like counts to be read in 1 batch, got %d", likes.batches)
+	}
+	counts := make(map[string]int)
+	for _, post := range posts {
+		counts[post.ID] = post.LikeCount
+	}
+	if counts["post_1"] != 3 || counts["post_2"] != 0 {
+		t.Errorf("Like counts = %v, want post_1: 3, post_2: 0", counts)
+	}
+
+	likes.err = errors.New("database down")
+	posts, err = service.ListModifiedSince(time.Time{}, "", 10)
+	if err != nil {
+		t.Fatalf("ListModifiedSince() error = %v, want the posts without like counts", err)
+	}
+	if len(posts) != 2 {
+		t.Errorf("Expected 2 posts without like counts, got %d", len(posts))
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// PostPurger deletes posts created before a cutoff, returning their IDs
+type PostPurger interface {
+	DeleteOlderThan(cutoff time.Time) ([]string, error)
+}
+
+// PostInvalidator drops cached copies of posts
+type PostInvalidator interface {
+	InvalidatePostsByIDs(ids []string) error
+}
+
+// RetentionJob periodically hard-deletes posts older than the retention period
+type RetentionJob struct {
+	purger      PostPurger
+	invalidator PostInvalidator
+	retention   time.Duration
+	interval    time.Duration
+	now         func() time.Time
+	stop        chan struct{}
+	done        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewRetentionJob creates a new retention job that keeps posts for retention,
+// purging older ones every interval. Purged posts are dropped from
+// invalidator, which may be nil.
+func NewRetentionJob(purger PostPurger, invalidator PostInvalidator, retention, interval time.Duration) *RetentionJob {
+	return &RetentionJob{
+		purger:      purger,
+		invalidator: invalidator,
+		retention:   retention,
+		interval:    interval,
+		now:         time.Now,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start runs the job in the background until Stop is called
+func (j *RetentionJob) Start() {
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if deleted, err := j.RunOnce(); err != nil {
+					log.Printf("Error purging old posts: %v", err)
+				} else if deleted > 0 {
+					log.Printf("Purged %d posts older than %s", deleted, j.retention)
+				}
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the job and waits for a purge in progress to finish
+func (j *RetentionJob) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+	<-j.done
+}
+
+// RunOnce deletes posts older than the retention period and returns how many were deleted
+func (j *RetentionJob) RunOnce() (int, error) {
+	ids, err := j.purger.DeleteOlderThan(j.now().Add(-j.retention))
+	if j.invalidator != nil && len(ids) > 0 {
+		// Cached copies would otherwise outlive the purge until they expire
+		if cacheErr := j.invalidator.InvalidatePostsByIDs(ids); cacheErr != nil {
+			log.Printf("Error invalidating purged posts: %v", cacheErr)
+		}
+	}
+	return len(ids), err
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
+)
+
+// MockPostPurger is a mock implementation of PostPurger for testing
+type MockPostPurger struct {
+	mu    sync.Mutex
+	posts map[string]*domain.Post
+	calls int
+}
+
+// DeleteOlderThan deletes posts created before cutoff
+func (m *MockPostPurger) DeleteOlderThan(cutoff time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	var deleted []string
+	for id, post := range m.posts {
+		if post.CreatedAt.Before(cutoff) {
+			delete(m.posts, id)
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+// MockPostInvalidator is a mock implementation of PostInvalidator for testing
+type MockPostInvalidator struct {
+	invalidated [][]string
+}
+
+// InvalidatePostsByIDs records the invalidated IDs
+func (m *MockPostInvalidator) InvalidatePostsByIDs(ids []string) error {
+	m.invalidated = append(m.invalidated, ids)
+	return nil
+}
+
+// TestRetentionJobRunOnce tests that posts older than the cutoff are deleted
+func TestRetentionJobRunOnce(t *testing.T) {
+	// Setup
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	purger := &MockPostPurger{
+		posts: map[string]*domain.Post{
+			"post_old":    {ID: "post_old", CreatedAt: now.AddDate(0, 0, -31)},
+			"post_recent": {ID: "post_recent", CreatedAt: now.AddDate(0, 0, -29)},
+			"post_new":    {ID: "post_new", CreatedAt: now},
+		},
+	}
+	invalidator := &MockPostInvalidator{}
+	job := NewRetentionJob(purger, invalidator, 30*24*time.Hour, time.Hour)
+	job.now = func() time.Time { return now }
+
+	// Test
+	deleted, err := job.RunOnce()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+	if _, ok := purger.posts["post_old"]; ok {
+		t.Error("Expected post_old to be deleted")
+	}
+	if _, ok := purger.posts["post_recent"]; !ok {
+		t.Error("Expected post_recent to remain")
+	}
+	if _, ok := purger.posts["post_new"]; !ok {
+		t.Error("Expected post_new to remain")
+	}
+	if len(invalidator.invalidated) != 1 || len(invalidator.invalidated[0]) != 1 || invalidator.invalidated[0][0] != "post_old" {
+		t.Errorf("Expected post_old to be invalidated in one call, got %v", invalidator.invalidated)
+	}
+}
+
+// TestRetentionJobStartStop tests that the job purges on its interval and stops
+func TestRetentionJobStartStop(t *testing.T) {
+	// Setup
+	purger := &MockPostPurger{posts: map[string]*domain.Post{}}
+	job := NewRetentionJob(purger, nil, time.Hour, time.Millisecond)
+
+	// Test
+	job.Start()
+	time.Sleep(20 * time.Millisecond)
+	job.Stop()
+
+	purger.mu.Lock()
+	calls := purger.calls
+	purger.mu.Unlock()
+
+	// Assert
+	if calls == 0 {
+		t.Error("Expected the job to run at least once")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	purger.mu.Lock()
+	defer purger.mu.Unlock()
+	if purger.calls != calls {
+		t.Errorf("Expected no runs after Stop, got %d more", purger.calls-calls)
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import "regexp"
+
+var (
+	// htmlScriptBlock matches script elements with their content; an unclosed
+	// script element runs to the end of the content
+	htmlScriptBlock = regexp.MustCompile(`(?is)<script\b[^>]*>.*?(</script\s*>|$)`)
+	// htmlStyleBlock matches style elements with their content
+	htmlStyleBlock = regexp.MustCompile(`(?is)<style\b[^>]*>.*?(</style\s*>|$)`)
+	// htmlTag matches any remaining opening, closing or self-closing tag and
+	// HTML comments. A lone "<" that does not start a tag, as in "a < b" or
+	// "<3", is left alone.
+	htmlTag = regexp.MustCompile(`(?s)<!--.*?-->|</?[a-zA-Z][^>]*>`)
+)
+
+// sanitizeHTML strips HTML markup from content, dropping script and style
+// elements entirely and keeping the text of every other element. Stripping
+// repeats until nothing changes, since removing one tag can join the text
+// around it into another, as in "<<b>script>".
+func sanitizeHTML(content string) string {
+	for {
+		stripped := htmlScriptBlock.ReplaceAllString(content, "")
+		stripped = htmlStyleBlock.ReplaceAllString(stripped, "")
+		stripped = htmlTag.ReplaceAllString(stripped, "")
+		if stripped == content {
+			return content
+		}
+		content = stripped
+	}
+}
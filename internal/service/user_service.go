@@ -2,23 +2,42 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"time"
+	"unicode/utf8"
 
+	"github.com/JoobyPM/tiger-tail-microblog/internal/auth"
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
 )
 
+// defaultMaxBioLength is the most characters a bio may have by default
+const defaultMaxBioLength = 160
+
 // UserService implements the domain.UserService interface
 type UserService struct {
 	userRepo domain.UserRepository
+	// maxBioLength is the most characters a bio may have (0 means no limit)
+	maxBioLength int
+	// passwordChanged is called with the user's ID after a password change
+	passwordChanged func(userID string)
 }
 
-// NewUserService creates a new user service
+// NewUserService creates a new user service. Bios are limited to
+// BIO_MAX_LENGTH characters (default 160, 0 means no limit).
 func NewUserService(userRepo domain.UserRepository) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:     userRepo,
+		maxBioLength: config.GetEnvInt("BIO_MAX_LENGTH", defaultMaxBioLength),
 	}
 }
 
+// OnPasswordChange registers fn to be called with the user's ID after their
+// password changes, e.g. to drop cached authentications of the old one
+func (s *UserService) OnPasswordChange(fn func(userID string)) {
+	s.passwordChanged = fn
+}
+
 // GetByID retrieves a user by ID
 func (s *UserService) GetByID(id string) (*domain.User, error) {
 	if id == "" {
@@ -62,14 +81,19 @@ func (s *UserService) Register(username, email, password string) (*domain.User,
 		return nil, domain.ErrUserAlreadyExists
 	}
 
+	// Only the password hash is stored
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing password: %w", err)
+	}
+
 	// Create user
-	// In a real application, we would hash the password here
 	now := time.Now()
 	user := &domain.User{
 		ID:        generateID(), // This would be a real ID generation function
 		Username:  username,
 		Email:     email,
-		Password:  password, // This would be hashed in a real application
+		Password:  hash,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -103,19 +127,23 @@ func (s *UserService) Authenticate(usernameOrEmail, password string) (*domain.Us
 	}
 
 	// Check password
-	// In a real application, we would compare hashed passwords
-	if user.Password != password {
+	if !auth.CheckPassword(user.Password, password) {
 		return nil, errors.New("invalid credentials")
 	}
 
 	return user, nil
 }
 
-// UpdateProfile updates a user's profile
+// UpdateProfile updates a user's profile. Bios longer than the maximum,
+// counted in characters rather than bytes, are rejected with
+// domain.ErrInvalidBio.
 func (s *UserService) UpdateProfile(id, bio string) (*domain.User, error) {
 	if id == "" {
 		return nil, domain.ErrInvalidUserID
 	}
+	if s.maxBioLength > 0 && utf8.RuneCountInString(bio) > s.maxBioLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", domain.ErrInvalidBio, s.maxBioLength)
+	}
 
 	// Get user
 	user, err := s.userRepo.GetByID(id)
@@ -152,18 +180,27 @@ func (s *UserService) ChangePassword(id, currentPassword, newPassword string) er
 	}
 
 	// Check current password
-	// In a real application, we would compare hashed passwords
-	if user.Password != currentPassword {
+	if !auth.CheckPassword(user.Password, currentPassword) {
 		return errors.New("invalid current password")
 	}
 
 	// Update password
-	// In a real application, we would hash the new password
-	user.Password = newPassword
+	hash, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+	user.Password = hash
 	user.UpdatedAt = time.Now()
 
 	// Save user
-	return s.userRepo.Update(user)
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if s.passwordChanged != nil {
+		s.passwordChanged(user.ID)
+	}
+	return nil
 }
 
 // Delete deletes a user
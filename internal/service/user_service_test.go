@@ -3,9 +3,11 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/JoobyPM/tiger-tail-microblog/internal/auth"
 	"github.com/JoobyPM/tiger-tail-microblog/internal/domain"
 )
 
@@ -410,8 +412,11 @@ func TestRegister(t *testing.T) {
 				if user.Email != tc.email {
 					t.Errorf("user.Email = %q, want %q", user.Email, tc.email)
 				}
-				if user.Password != tc.password {
-					t.Errorf("user.Password = %q, want %q", user.Password, tc.password)
+				if user.Password == tc.password {
+					t.Errorf("user.Password is stored in plaintext")
+				}
+				if !auth.CheckPassword(user.Password, tc.password) {
+					t.Errorf("user.Password does not match %q", tc.password)
 				}
 				if user.ID == "" {
 					t.Errorf("user.ID is empty")
@@ -485,6 +490,36 @@ func TestAuthenticate(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name:           "valid authentication with hashed password",
+			usernameOrEmail: "testuser",
+			password:       "password123",
+			setupRepo: func(repo *MockUserRepository) {
+				hash, _ := auth.HashPassword("password123")
+				repo.users["user_123"] = &domain.User{
+					ID:       "user_123",
+					Username: "testuser",
+					Email:    "test@example.com",
+					Password: hash,
+				}
+			},
+			expectError: false,
+		},
+		{
+			name:           "wrong password against hashed password",
+			usernameOrEmail: "testuser",
+			password:       "wrongpassword",
+			setupRepo: func(repo *MockUserRepository) {
+				hash, _ := auth.HashPassword("password123")
+				repo.users["user_123"] = &domain.User{
+					ID:       "user_123",
+					Username: "testuser",
+					Email:    "test@example.com",
+					Password: hash,
+				}
+			},
+			expectError: true,
+		},
 		{
 			name:           "empty username or email",
 			usernameOrEmail: "",
@@ -610,6 +645,26 @@ func TestUpdateProfile(t *testing.T) {
 			expectError: true,
 			errorType:   domain.ErrUserNotFound,
 		},
+		{
+			// Multi-byte characters count once each
+			name: "bio at the length limit",
+			id:   "user_123",
+			bio:  strings.Repeat("é", defaultMaxBioLength),
+			setupRepo: func(repo *MockUserRepository) {
+				repo.users["user_123"] = &domain.User{ID: "user_123", Username: "testuser"}
+			},
+			expectError: false,
+		},
+		{
+			name: "bio over the length limit",
+			id:   "user_123",
+			bio:  strings.Repeat("é", defaultMaxBioLength+1),
+			setupRepo: func(repo *MockUserRepository) {
+				repo.users["user_123"] = &domain.User{ID: "user_123", Username: "testuser"}
+			},
+			expectError: true,
+			errorType:   domain.ErrInvalidBio,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -649,7 +704,7 @@ func TestUpdateProfile(t *testing.T) {
 			}
 			
 			// Verify repository methods were called
-			if !tc.expectError || tc.errorType != domain.ErrInvalidUserID {
+			if !tc.expectError || (tc.errorType != domain.ErrInvalidUserID && tc.errorType != domain.ErrInvalidBio) {
 				if !repo.getByIDCalled {
 					t.Errorf("Expected GetByID to be called")
 				}
@@ -754,6 +809,10 @@ func TestChangePassword(t *testing.T) {
 			repo := NewMockUserRepository()
 			tc.setupRepo(repo)
 			service := NewUserService(repo)
+			var changed []string
+			service.OnPasswordChange(func(userID string) {
+				changed = append(changed, userID)
+			})
 			
 			// Record the time before the update
 			beforeUpdate := time.Now()
@@ -779,8 +838,11 @@ func TestChangePassword(t *testing.T) {
 				if err != nil {
 					t.Errorf("Unexpected error getting user: %v", err)
 				}
-				if user.Password != tc.newPassword {
-					t.Errorf("user.Password = %q, want %q", user.Password, tc.newPassword)
+				if user.Password == tc.newPassword {
+					t.Errorf("user.Password is stored in plaintext")
+				}
+				if !auth.CheckPassword(user.Password, tc.newPassword) {
+					t.Errorf("user.Password does not match %q", tc.newPassword)
 				}
 				if user.UpdatedAt.Before(beforeUpdate) {
 					t.Errorf("user.UpdatedAt was not updated")
@@ -799,6 +861,15 @@ func TestChangePassword(t *testing.T) {
 					t.Errorf("Expected Update to be called")
 				}
 			}
+			
+			// Only a successful change is reported
+			expectedChanged := "[]"
+			if !tc.expectError {
+				expectedChanged = "[" + tc.id + "]"
+			}
+			if fmt.Sprint(changed) != expectedChanged {
+				t.Errorf("Expected password changes %s reported, got %v", expectedChanged, changed)
+			}
 		})
 	}
 }
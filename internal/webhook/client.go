@@ -0,0 +1,82 @@
+// Package webhook delivers post events to receivers registered by operators.
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+)
+
+// defaultTimeout bounds a single delivery attempt
+const defaultTimeout = 5 * time.Second
+
+// defaultMaxResponseBytes is the most of a receiver's response body read
+const defaultMaxResponseBytes = 64 << 10
+
+// Client delivers webhook payloads over HTTP. Every attempt has its own
+// timeout and reads at most a capped amount of the receiver's response, so
+// a slow or malicious receiver can't hold a delivery open or make it
+// buffer unbounded data.
+type Client struct {
+	httpClient       *http.Client
+	maxResponseBytes int64
+}
+
+// NewClient creates a webhook client whose attempts time out after
+// WEBHOOK_TIMEOUT (default 5s) and read at most WEBHOOK_MAX_RESPONSE_BYTES
+// (default 64 KiB) of each response
+func NewClient() *Client {
+	// A zero timeout would let an attempt hang forever
+	timeout := config.GetEnvDuration("WEBHOOK_TIMEOUT", defaultTimeout)
+	if timeout <= 0 {
+		log.Printf("Ignoring WEBHOOK_TIMEOUT=%v, attempts must time out; using %v", timeout, defaultTimeout)
+		timeout = defaultTimeout
+	}
+	return NewClientWithOptions(
+		timeout,
+		int64(config.GetEnvInt("WEBHOOK_MAX_RESPONSE_BYTES", defaultMaxResponseBytes)),
+	)
+}
+
+// NewClientWithOptions creates a webhook client with the given attempt
+// timeout and response size cap
+func NewClientWithOptions(timeout time.Duration, maxResponseBytes int64) *Client {
+	return &Client{
+		httpClient:       &http.Client{Timeout: timeout},
+		maxResponseBytes: maxResponseBytes,
+	}
+}
+
+// Deliver posts a JSON payload to url and returns the receiver's status
+// code. Responses other than 2xx are reported as errors. Only the first
+// maxResponseBytes of the response body are read; the rest is discarded
+// when the connection is closed.
+func (c *Client) Deliver(url string, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error delivering webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The body isn't used, but reading a bounded amount lets short responses
+	// keep the connection reusable
+	if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, c.maxResponseBytes)); err != nil {
+		return resp.StatusCode, fmt.Errorf("error reading webhook response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
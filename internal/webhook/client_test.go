@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDeliver tests that payloads are posted as JSON and non-2xx statuses
+// are reported as errors
+func TestDeliver(t *testing.T) {
+	testCases := []struct {
+		name          string
+		status        int
+		expectedError bool
+	}{
+		{name: "Accepted", status: http.StatusNoContent},
+		{name: "Rejected", status: http.StatusInternalServerError, expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body, contentType string
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				body, contentType = string(data), r.Header.Get("Content-Type")
+				w.WriteHeader(tc.status)
+			}))
+			defer receiver.Close()
+
+			status, err := NewClientWithOptions(time.Second, 1024).Deliver(receiver.URL, []byte(`{"event":"post.created"}`))
+
+			if (err != nil) != tc.expectedError {
+				t.Errorf("Deliver() error = %v, expected error: %v", err, tc.expectedError)
+			}
+			if status != tc.status {
+				t.Errorf("Deliver() status = %d, want %d", status, tc.status)
+			}
+			if body != `{"event":"post.created"}` || contentType != "application/json" {
+				t.Errorf("Receiver got %q (%s)", body, contentType)
+			}
+		})
+	}
+}
+
+// TestDeliverTimesOut tests that a receiver that never answers fails the
+// attempt after the timeout
+func TestDeliverTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer receiver.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := NewClientWithOptions(50*time.Millisecond, 1024).Deliver(receiver.URL, []byte(`{}`))
+
+	if err == nil {
+		t.Fatal("Expected the delivery to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the delivery to give up after the timeout, took %s", elapsed)
+	}
+}
+
+// TestDeliverCapsResponse tests that a receiver streaming an endless
+// response is not read past the cap
+func TestDeliverCapsResponse(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := []byte(strings.Repeat("x", 1024))
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			default:
+			}
+		}
+	}))
+	defer receiver.Close()
+
+	// The timeout is long, so only the cap can end the delivery early
+	start := time.Now()
+	status, err := NewClientWithOptions(10*time.Second, 4096).Deliver(receiver.URL, []byte(`{}`))
+
+	if err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("Deliver() status = %d, want %d", status, http.StatusOK)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the response to be abandoned at the cap, took %s", elapsed)
+	}
+}
+
+// TestNewClientTimeout tests that WEBHOOK_TIMEOUT sets the attempt timeout
+// and that a value that would disable it falls back to the default
+func TestNewClientTimeout(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "default", value: "", expected: defaultTimeout},
+		{name: "configured", value: "2s", expected: 2 * time.Second},
+		{name: "zero", value: "0s", expected: defaultTimeout},
+		{name: "negative", value: "-1s", expected: defaultTimeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Setenv("WEBHOOK_TIMEOUT", tc.value)
+			defer os.Unsetenv("WEBHOOK_TIMEOUT")
+
+			if timeout := NewClient().httpClient.Timeout; timeout != tc.expected {
+				t.Errorf("Timeout = %v, want %v", timeout, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+)
+
+// ErrFailureNotFound is returned when a dead-letter entry does not exist
+var ErrFailureNotFound = errors.New("failed delivery not found")
+
+// defaultMaxDeadLetters is how many failed deliveries are kept by default
+const defaultMaxDeadLetters = 100
+
+// FailedDelivery is a delivery that exhausted its retries, kept so operators
+// can inspect and replay it
+type FailedDelivery struct {
+	ID         string          `json:"id"`
+	URL        string          `json:"url"`
+	PostID     string          `json:"post_id"`
+	Payload    json.RawMessage `json:"payload"`
+	LastStatus int             `json:"last_status"`
+	LastError  string          `json:"last_error"`
+	Attempts   int             `json:"attempts"`
+	FailedAt   time.Time       `json:"failed_at"`
+}
+
+// DeadLetterStore keeps deliveries that exhausted their retries
+type DeadLetterStore interface {
+	// Add records a failed delivery, replacing any entry with the same ID
+	Add(failure FailedDelivery) error
+	// List returns the recorded failures, newest first
+	List() ([]FailedDelivery, error)
+	// Get returns the failure with the given ID or ErrFailureNotFound
+	Get(id string) (FailedDelivery, error)
+	// Remove drops the failure with the given ID
+	Remove(id string) error
+}
+
+// MemoryDeadLetterStore is a DeadLetterStore held in memory, keeping only
+// the most recent failures
+type MemoryDeadLetterStore struct {
+	mu       sync.Mutex
+	failures []FailedDelivery
+	max      int
+}
+
+// NewMemoryDeadLetterStore creates a dead-letter store keeping the most
+// recent WEBHOOK_DEAD_LETTER_MAX (default 100) failures
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	max := config.GetEnvInt("WEBHOOK_DEAD_LETTER_MAX", defaultMaxDeadLetters)
+	if max < 1 {
+		max = defaultMaxDeadLetters
+	}
+	return &MemoryDeadLetterStore{max: max}
+}
+
+// Add records a failure as the newest entry, dropping the oldest beyond the
+// cap
+func (s *MemoryDeadLetterStore) Add(failure FailedDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(failure.ID)
+	s.failures = append([]FailedDelivery{failure}, s.failures...)
+	if len(s.failures) > s.max {
+		s.failures = s.failures[:s.max]
+	}
+	return nil
+}
+
+// List returns the recorded failures, newest first
+func (s *MemoryDeadLetterStore) List() ([]FailedDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := make([]FailedDelivery, len(s.failures))
+	copy(failures, s.failures)
+	return failures, nil
+}
+
+// Get returns the failure with the given ID
+func (s *MemoryDeadLetterStore) Get(id string) (FailedDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, failure := range s.failures {
+		if failure.ID == id {
+			return failure, nil
+		}
+	}
+	return FailedDelivery{}, ErrFailureNotFound
+}
+
+// Remove drops the failure with the given ID
+func (s *MemoryDeadLetterStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+	return nil
+}
+
+// remove drops the failure with the given ID; the caller holds mu
+func (s *MemoryDeadLetterStore) remove(id string) {
+	for i, failure := range s.failures {
+		if failure.ID == id {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			return
+		}
+	}
+}
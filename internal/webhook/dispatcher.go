@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/JoobyPM/tiger-tail-microblog/internal/config"
+)
+
+// defaultMaxAttempts is how many times a delivery is tried by default
+const defaultMaxAttempts = 3
+
+// defaultRetryDelay is the pause between attempts by default
+const defaultRetryDelay = time.Second
+
+// Delivery is a payload to deliver to a receiver
+type Delivery struct {
+	URL     string
+	PostID  string
+	Payload []byte
+}
+
+// Dispatcher delivers webhooks with retries. A delivery that fails every
+// attempt is recorded in the dead-letter store, where it can be inspected
+// and replayed.
+type Dispatcher struct {
+	client      *Client
+	store       DeadLetterStore
+	maxAttempts int
+	retryDelay  time.Duration
+	nextID      int64
+}
+
+// NewDispatcher creates a dispatcher trying each delivery up to
+// WEBHOOK_MAX_ATTEMPTS (default 3) times, WEBHOOK_RETRY_DELAY (default 1s)
+// apart
+func NewDispatcher(client *Client, store DeadLetterStore) *Dispatcher {
+	return NewDispatcherWithOptions(client, store,
+		config.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", defaultMaxAttempts),
+		config.GetEnvDuration("WEBHOOK_RETRY_DELAY", defaultRetryDelay),
+	)
+}
+
+// NewDispatcherWithOptions creates a dispatcher with the given number of
+// attempts and delay between them
+func NewDispatcherWithOptions(client *Client, store DeadLetterStore, maxAttempts int, retryDelay time.Duration) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Dispatcher{
+		client:      client,
+		store:       store,
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+	}
+}
+
+// Dispatch delivers a payload, retrying failed attempts. When every attempt
+// fails the delivery is added to the dead-letter store and the last error
+// is returned.
+func (d *Dispatcher) Dispatch(delivery Delivery) error {
+	status, err := d.attempt(delivery)
+	if err == nil {
+		return nil
+	}
+
+	id := fmt.Sprintf("dl_%d", atomic.AddInt64(&d.nextID, 1))
+	d.recordFailure(id, delivery, status, err, d.maxAttempts)
+	return err
+}
+
+// Failures returns the deliveries in the dead-letter store, newest first
+func (d *Dispatcher) Failures() ([]FailedDelivery, error) {
+	return d.store.List()
+}
+
+// Replay retries a failed delivery. On success it is removed from the
+// dead-letter store; otherwise its entry is updated with the new attempts
+// and error. ErrFailureNotFound is returned for unknown IDs.
+func (d *Dispatcher) Replay(id string) error {
+	failure, err := d.store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	delivery := Delivery{URL: failure.URL, PostID: failure.PostID, Payload: failure.Payload}
+	status, err := d.attempt(delivery)
+	if err != nil {
+		d.recordFailure(id, delivery, status, err, failure.Attempts+d.maxAttempts)
+		return err
+	}
+
+	return d.store.Remove(id)
+}
+
+// attempt tries a delivery up to maxAttempts times, returning the status and
+// error of the last attempt
+func (d *Dispatcher) attempt(delivery Delivery) (int, error) {
+	var status int
+	var err error
+	for i := 0; i < d.maxAttempts; i++ {
+		if i > 0 {
+			time.Sleep(d.retryDelay)
+		}
+		status, err = d.client.Deliver(delivery.URL, delivery.Payload)
+		if err == nil {
+			return status, nil
+		}
+	}
+	return status, err
+}
+
+// recordFailure stores a delivery that exhausted its attempts. Failing to
+// record it is logged rather than returned, as the delivery error matters
+// more to the caller.
+func (d *Dispatcher) recordFailure(id string, delivery Delivery, status int, deliveryErr error, attempts int) {
+	failure := FailedDelivery{
+		ID:         id,
+		URL:        delivery.URL,
+		PostID:     delivery.PostID,
+		Payload:    json.RawMessage(delivery.Payload),
+		LastStatus: status,
+		LastError:  deliveryErr.Error(),
+		Attempts:   attempts,
+		FailedAt:   time.Now(),
+	}
+	if err := d.store.Add(failure); err != nil {
+		log.Printf("Error recording failed webhook delivery to %s: %v", delivery.URL, err)
+	}
+}
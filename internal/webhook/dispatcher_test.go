@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDispatchDeadLetter tests that a permanently failing delivery lands in
+// the dead-letter store after every attempt and can be replayed once the
+// receiver recovers
+func TestDispatchDeadLetter(t *testing.T) {
+	var healthy int32
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := &MemoryDeadLetterStore{max: 10}
+	dispatcher := NewDispatcherWithOptions(NewClientWithOptions(time.Second, 1024), store, 3, time.Millisecond)
+
+	err := dispatcher.Dispatch(Delivery{URL: receiver.URL, PostID: "post_1", Payload: []byte(`{"event":"post.created"}`)})
+	if err == nil {
+		t.Fatal("Dispatch() expected an error from a failing receiver")
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("Receiver called %d times, want 3", got)
+	}
+
+	failures, err := dispatcher.Failures()
+	if err != nil {
+		t.Fatalf("Failures() error = %v", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 failed delivery, got %+v", failures)
+	}
+	failure := failures[0]
+	if failure.URL != receiver.URL || failure.PostID != "post_1" || failure.LastStatus != http.StatusBadGateway ||
+		failure.Attempts != 3 || failure.LastError == "" || failure.FailedAt.IsZero() {
+		t.Errorf("Unexpected failed delivery %+v", failure)
+	}
+
+	// Replaying while the receiver is still down keeps the entry
+	if err := dispatcher.Replay(failure.ID); err == nil {
+		t.Fatal("Replay() expected an error from a failing receiver")
+	}
+	if failures, _ := dispatcher.Failures(); len(failures) != 1 || failures[0].Attempts != 6 {
+		t.Fatalf("Expected the failed delivery to be kept with 6 attempts, got %+v", failures)
+	}
+
+	// Once the receiver recovers the replay succeeds and the entry is dropped
+	atomic.StoreInt32(&healthy, 1)
+	if err := dispatcher.Replay(failure.ID); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if failures, _ := dispatcher.Failures(); len(failures) != 0 {
+		t.Errorf("Expected an empty dead-letter store, got %+v", failures)
+	}
+
+	if err := dispatcher.Replay(failure.ID); err != ErrFailureNotFound {
+		t.Errorf("Replay() of a removed entry error = %v, want %v", err, ErrFailureNotFound)
+	}
+}
+
+// TestDispatchSucceeds tests that a successful delivery is not recorded
+func TestDispatchSucceeds(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := &MemoryDeadLetterStore{max: 10}
+	dispatcher := NewDispatcherWithOptions(NewClientWithOptions(time.Second, 1024), store, 3, time.Millisecond)
+
+	if err := dispatcher.Dispatch(Delivery{URL: receiver.URL, PostID: "post_1", Payload: []byte(`{}`)}); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if failures, _ := dispatcher.Failures(); len(failures) != 0 {
+		t.Errorf("Expected an empty dead-letter store, got %+v", failures)
+	}
+}
@@ -112,11 +112,40 @@ func (m *MockPostService) List(page, limit int) ([]*domain.PostWithUser, int, er
 	return posts, len(posts), nil
 }
 
+func (m *MockPostService) Search(query string, page, limit int) ([]*domain.PostWithUser, int, error) {
+	return []*domain.PostWithUser{}, 0, nil
+}
+
+func (m *MockPostService) CountSearch(query string) (int, error) {
+	return 0, nil
+}
+
+func (m *MockPostService) ListAfter(createdAt time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *MockPostService) ListModifiedSince(since time.Time, afterID string, limit int) ([]*domain.PostWithUser, error) {
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *MockPostService) ListByUsers(userIDs []string, limit int) ([]*domain.PostWithUser, error) {
+	return []*domain.PostWithUser{}, nil
+}
+
+func (m *MockPostService) ListFollowedBy(userID string, page, limit int) ([]*domain.PostWithUser, int, error) {
+	return []*domain.PostWithUser{}, 0, nil
+}
+
+func (m *MockPostService) CreateMany(posts []*domain.Post, preserveTimestamps bool) ([]*domain.Post, error) {
+	return posts, nil
+}
+
 // MockPostCache is a mock implementation of server.PostCache
 type MockPostCache struct {
 	GetPostFunc          func(id string) (*domain.Post, error)
 	SetPostFunc          func(post *domain.Post) error
 	GetPostsWithUserFunc func() ([]*domain.PostWithUser, error)
+	GetStalePostsFunc    func() ([]*domain.PostWithUser, error)
 	SetPostsWithUserFunc func(posts []*domain.PostWithUser) error
 	InvalidatePostsFunc  func() error
 	PingFunc             func() error
@@ -143,6 +172,13 @@ func (m *MockPostCache) GetPostsWithUser() ([]*domain.PostWithUser, error) {
 	return nil, domain.ErrPostNotFound
 }
 
+func (m *MockPostCache) GetStalePostsWithUser() ([]*domain.PostWithUser, error) {
+	if m.GetStalePostsFunc != nil {
+		return m.GetStalePostsFunc()
+	}
+	return nil, domain.ErrPostNotFound
+}
+
 func (m *MockPostCache) SetPostsWithUser(posts []*domain.PostWithUser) error {
 	if m.SetPostsWithUserFunc != nil {
 		return m.SetPostsWithUserFunc(posts)
@@ -150,6 +186,18 @@ func (m *MockPostCache) SetPostsWithUser(posts []*domain.PostWithUser) error {
 	return nil
 }
 
+func (m *MockPostCache) SetPostsWithUserAt(posts []*domain.PostWithUser, readAt time.Time) error {
+	return m.SetPostsWithUser(posts)
+}
+
+func (m *MockPostCache) SetPostsWithUserAndTotalAt(posts []*domain.PostWithUser, total int, readAt time.Time) error {
+	return m.SetPostsWithUser(posts)
+}
+
+func (m *MockPostCache) GetPostsTotal() (int, error) {
+	return 0, domain.ErrPostNotFound
+}
+
 func (m *MockPostCache) InvalidatePosts() error {
 	if m.InvalidatePostsFunc != nil {
 		return m.InvalidatePostsFunc()
@@ -157,6 +205,10 @@ func (m *MockPostCache) InvalidatePosts() error {
 	return nil
 }
 
+func (m *MockPostCache) InvalidatePost(id string) error {
+	return nil
+}
+
 func (m *MockPostCache) Ping() error {
 	if m.PingFunc != nil {
 		return m.PingFunc()